@@ -0,0 +1,112 @@
+// ABOUTME: Tests for the REST API access log.
+// ABOUTME: Captures the plugin logger's output and checks the fields logged, and that bodies are not.
+
+package dynupdate
+
+import (
+	"bytes"
+	golog "log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// lockedBuffer is a bytes.Buffer safe to write from the logger while the
+// test reads it.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// captureLog redirects the standard logger, which the plugin logger writes
+// through, for the rest of the test.
+func captureLog(t *testing.T) *lockedBuffer {
+	t.Helper()
+	var out lockedBuffer
+	prev := golog.Writer()
+	golog.SetOutput(&out)
+	t.Cleanup(func() { golog.SetOutput(prev) })
+	return &out
+}
+
+// accessLogLine returns the access log line for path in out.
+func accessLogLine(t *testing.T, out *lockedBuffer, path string) string {
+	t.Helper()
+	for line := range strings.Lines(out.String()) {
+		if strings.Contains(line, "[INFO] plugin/dynupdate: ") && strings.Contains(line, path) {
+			return line
+		}
+	}
+	t.Fatalf("no access log line for %s in:\n%s", path, out.String())
+	return ""
+}
+
+// Not parallel: it redirects the process-wide logger.
+func TestAPI_AccessLog(t *testing.T) {
+	_, s := newTestAPIHandler(t)
+	api := NewAPIServer(s, &Auth{Tokens: map[string]string{"ci-secret": "ci"}}, ":0", nil, WithAccessLog())
+	h := api.handler()
+	out := captureLog(t)
+
+	body := `{"name":"app.example.org.","type":"TXT","ttl":300,"value":"body-secret"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/records?token=query-secret", strings.NewReader(body))
+	req.RemoteAddr = "192.0.2.10:40000"
+	req.Header.Set("Authorization", "Bearer ci-secret")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201; body = %s", rec.Code, rec.Body.String())
+	}
+
+	line := accessLogLine(t, out, "/api/v1/records")
+	for _, want := range []string{`POST "/api/v1/records" 201 `, "client=192.0.2.10", "identity=ci"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("access log line %q lacks %q", line, want)
+		}
+	}
+	for _, secret := range []string{"body-secret", "query-secret", "ci-secret"} {
+		if strings.Contains(out.String(), secret) {
+			t.Errorf("log output holds %q:\n%s", secret, out.String())
+		}
+	}
+
+	// Requests rejected by auth are logged too, without an identity.
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/records/"+strings.Repeat("a", 2*maxAccessLogPath), nil)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	line = accessLogLine(t, out, "/api/v1/records/aaa")
+	for _, want := range []string{" 401 ", "identity=-", `..."`} {
+		if !strings.Contains(line, want) {
+			t.Errorf("access log line %q lacks %q", line, want)
+		}
+	}
+	if len(line) > 2*maxAccessLogPath {
+		t.Errorf("access log line is %d bytes, want the path truncated", len(line))
+	}
+}
+
+func TestAPI_AccessLog_Disabled(t *testing.T) {
+	api, _ := newTestAPIHandler(t)
+	out := captureLog(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/records", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	api.handler().ServeHTTP(httptest.NewRecorder(), req)
+	if strings.Contains(out.String(), "/api/v1/records") {
+		t.Errorf("access log written without WithAccessLog:\n%s", out.String())
+	}
+}
@@ -6,24 +6,128 @@ package dynupdate
 import (
 	"context"
 	"crypto/tls"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"mime"
 	"net"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // apiListResponse wraps a list of records for JSON serialisation.
+// Total counts every matching record across all pages. NextCursor is set
+// when more records follow the page.
 type apiListResponse struct {
-	Records []Record `json:"records"`
+	Records    []Record `json:"records"`
+	Total      int      `json:"total"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+	// Generation is set on ?since_generation= listings: the generation to
+	// pass as since_generation on the next incremental fetch.
+	Generation uint64 `json:"generation,omitempty"`
+	// Deleted lists, on the first page of a ?since_generation= listing, the
+	// records removed since that generation.
+	Deleted []RecordKey `json:"deleted,omitempty"`
+}
+
+// apiBatchResult is the outcome of one record in a batch. Status uses HTTP
+// codes: 201 created, 200 updated, or the error status for a rejected
+// record. Records that were valid but not applied because another record
+// failed carry 424 (Failed Dependency).
+type apiBatchResult struct {
+	Index  int     `json:"index"`
+	Status int     `json:"status"`
+	Record *Record `json:"record,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// apiBatchResponse lists per-record results in request order. Error is set
+// when the batch was rejected.
+type apiBatchResponse struct {
+	Results []apiBatchResult `json:"results"`
+	Error   string           `json:"error,omitempty"`
+}
+
+// apiTTLPatchRequest selects a record of the path's name and type by value
+// and gives its new TTL.
+type apiTTLPatchRequest struct {
+	Value string `json:"value"`
+	TTL   uint32 `json:"ttl"`
+}
+
+// apiTTLWindowRequest opens a TTL maintenance window. Exactly one of Name and
+// Suffix selects the records.
+type apiTTLWindowRequest struct {
+	Name      string    `json:"name,omitempty"`
+	Suffix    string    `json:"suffix,omitempty"`
+	TTL       uint32    `json:"ttl"`
+	RestoreAt time.Time `json:"restore_at"`
+}
+
+// apiTTLWindowResponse reports how many records a TTL window lowered.
+type apiTTLWindowResponse struct {
+	Updated   int       `json:"updated"`
+	RestoreAt time.Time `json:"restore_at"`
+}
+
+// apiToggleResponse reports how many records a bulk enable or disable
+// changed.
+type apiToggleResponse struct {
+	Updated int `json:"updated"`
+}
+
+// apiZonesResponse lists the served zones.
+type apiZonesResponse struct {
+	Zones []ZoneStats `json:"zones"`
+}
+
+// apiExplainResponse is a created or updated record returned with
+// ?explain=true, listing each field the server changed from what the client
+// sent.
+type apiExplainResponse struct {
+	Record         Record             `json:"record"`
+	Normalizations []apiNormalization `json:"normalizations"`
+}
+
+// apiNormalization is one field the server filled in or rewrote.
+type apiNormalization struct {
+	Field   string `json:"field"`
+	Sent    any    `json:"sent"`
+	Applied any    `json:"applied"`
 }
 
 // apiErrorResponse wraps an error message for JSON serialisation.
 type apiErrorResponse struct {
 	Error string `json:"error"`
+	// Field and Code identify the offending field of a rejected record.
+	Field string `json:"field,omitempty"`
+	Code  string `json:"code,omitempty"`
+}
+
+// invalidRecordResponse returns the error body for a record that failed
+// validation, carrying the field and code when err is a ValidationError.
+func invalidRecordResponse(err error) apiErrorResponse {
+	resp := apiErrorResponse{Error: err.Error()}
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		resp.Field = verr.Field
+		resp.Code = verr.Code
+	}
+	return resp
+}
+
+// cnameConflictResponse returns the 409 body for a write refused with
+// ErrCNAMEConflict. The record's type is what clashes with the name's
+// existing records, so the body points at it.
+func cnameConflictResponse(err error) apiErrorResponse {
+	return apiErrorResponse{Error: err.Error(), Field: "type", Code: CodeConflict}
 }
 
 // APIServer serves the REST management API.
@@ -32,12 +136,86 @@ type APIServer struct {
 	auth   *Auth
 	listen string
 	tls    *tlsConfig
+	h2c    bool
+	plugin *DynUpdate       // source of configuration dumps and zone exports; nil disables those endpoints
+	limit  *rateLimiter     // per-client request limit; nil disables it
+	flight *inflightLimiter // cap on concurrent requests; nil disables it
+
+	maxTemplate int  // records one template may expand to; 0 means defaultMaxTemplateRecords
+	metrics     bool // serve GET /metrics without authentication
+	accessLog   bool // log every request at info level
+
 	server *http.Server
+	addr   net.Addr
+}
+
+// APIOption configures optional APIServer behaviour.
+type APIOption func(*APIServer)
+
+// WithH2C enables cleartext HTTP/2 (prior knowledge) on a plaintext listener.
+// HTTP/2 over TLS is always negotiated via ALPN and needs no option.
+func WithH2C() APIOption {
+	return func(a *APIServer) {
+		a.h2c = true
+	}
+}
+
+// WithMetrics serves the plugin's Prometheus metrics at GET /metrics on the
+// API listener, without authentication, for scrapers that cannot reach the
+// CoreDNS metrics port.
+func WithMetrics() APIOption {
+	return func(a *APIServer) {
+		a.metrics = true
+	}
+}
+
+// WithAccessLog logs each request's method, path, status, duration, client
+// IP and caller identity at info level.
+func WithAccessLog() APIOption {
+	return func(a *APIServer) {
+		a.accessLog = true
+	}
+}
+
+// WithConfigDump enables the admin configuration dump and restore endpoints,
+// and the zone file export, for the zones served by d.
+func WithConfigDump(d *DynUpdate) APIOption {
+	return func(a *APIServer) {
+		a.plugin = d
+	}
+}
+
+// WithRateLimit limits each client IP to rps requests per second, with
+// bursts of up to burst requests. Requests over the limit get 429.
+func WithRateLimit(rps float64, burst int) APIOption {
+	return func(a *APIServer) {
+		a.limit = newRateLimiter(rps, burst)
+	}
+}
+
+// WithMaxInflight caps the number of requests served at once at n.
+// Requests beyond it get 503 instead of waiting.
+func WithMaxInflight(n int) APIOption {
+	return func(a *APIServer) {
+		a.flight = newInflightLimiter(n)
+	}
+}
+
+// WithMaxTemplateRecords caps the records one template sent to
+// records:template may expand to at n.
+func WithMaxTemplateRecords(n int) APIOption {
+	return func(a *APIServer) {
+		a.maxTemplate = n
+	}
 }
 
 // NewAPIServer creates an API server (not yet started).
-func NewAPIServer(store *Store, auth *Auth, listen string, tls *tlsConfig) *APIServer {
-	return &APIServer{store: store, auth: auth, listen: listen, tls: tls}
+func NewAPIServer(store *Store, auth *Auth, listen string, tls *tlsConfig, opts ...APIOption) *APIServer {
+	a := &APIServer{store: store, auth: auth, listen: listen, tls: tls}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
 }
 
 // handler builds the http.Handler with routing and middleware.
@@ -48,10 +226,53 @@ func (a *APIServer) handler() http.Handler {
 	mux.HandleFunc("GET /api/v1/records/{name}", a.handleGetByName)
 	mux.HandleFunc("POST /api/v1/records", a.handleCreate)
 	mux.HandleFunc("PUT /api/v1/records", a.handleUpdate)
+	mux.HandleFunc("POST /api/v1/records:batch", a.handleBatch)
+	mux.HandleFunc("POST /api/v1/records:template", a.handleTemplate)
+	mux.HandleFunc("POST /api/v1/records:disable", a.handleSetDisabled(true))
+	mux.HandleFunc("POST /api/v1/records:enable", a.handleSetDisabled(false))
+	mux.HandleFunc("PUT /api/v1/records/{name}", a.handleReplace)
+	mux.HandleFunc("PATCH /api/v1/records/{name}/{type}", a.handlePatchTTL)
 	mux.HandleFunc("DELETE /api/v1/records/{name}/{type}", a.handleDeleteByType)
 	mux.HandleFunc("DELETE /api/v1/records/{name}", a.handleDeleteAll)
+	mux.HandleFunc("POST /api/v1/zones/{zone}/import", a.handleImportZone)
+	mux.HandleFunc("POST /api/v1/admin/ttl-window", a.handleTTLWindow)
+	mux.HandleFunc("POST /api/v1/admin/verify", a.handleVerify)
+	mux.HandleFunc("POST /api/v1/admin/compact", a.handleCompact)
+	if a.plugin != nil {
+		mux.HandleFunc("GET /api/v1/zones", a.handleZones)
+		mux.HandleFunc("GET /api/v1/export", a.handleExport)
+		mux.HandleFunc("GET /api/v1/admin/config", a.handleConfigExport)
+		mux.HandleFunc("PUT /api/v1/admin/config", a.handleConfigImport)
+	}
 
-	return metricsMiddleware(a.auth.HTTPMiddleware(mux))
+	// Readiness, probes and metrics are served without authentication so
+	// startup scripts, Kubernetes probes and scrapers can poll them.
+	root := http.NewServeMux()
+	root.HandleFunc("GET /api/v1/ready", a.handleReady)
+	root.HandleFunc("GET /healthz", a.handleProbe)
+	root.HandleFunc("GET /readyz", a.handleReadyz)
+	if a.metrics {
+		// gzipMiddleware compresses the response; promhttp must not as well.
+		root.Handle("GET /metrics", promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{DisableCompression: true}))
+	}
+	var inner http.Handler = mux
+	if a.accessLog {
+		inner = noteCaller(inner)
+	}
+	api := a.auth.HTTPMiddleware(inner)
+	if a.flight != nil {
+		api = a.flight.middleware(api)
+	}
+	if a.limit != nil {
+		api = a.limit.middleware(api)
+	}
+	root.Handle("/", api)
+
+	h := metricsMiddleware(gzipMiddleware(root))
+	if a.accessLog {
+		h = accessLogMiddleware(h)
+	}
+	return h
 }
 
 // statusRecorder wraps http.ResponseWriter to capture the status code.
@@ -65,7 +286,8 @@ func (sr *statusRecorder) WriteHeader(code int) {
 	sr.ResponseWriter.WriteHeader(code)
 }
 
-// metricsMiddleware records API request count by method and status.
+// metricsMiddleware records API request count by method and final status.
+// It sits outside the auth middleware so rejected requests are counted too.
 func metricsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		sr := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
@@ -81,22 +303,32 @@ func (a *APIServer) Start() error {
 		return fmt.Errorf("listening on %s: %w", a.listen, err)
 	}
 
+	var tlsCfg *tls.Config
 	if a.tls != nil {
-		tlsCfg, err := buildTLSConfig(a.tls)
+		tlsCfg, err = buildTLSConfig(a.tls)
 		if err != nil {
 			ln.Close()
 			return fmt.Errorf("building API TLS config: %w", err)
 		}
-		ln = tls.NewListener(ln, tlsCfg)
 	}
 
 	a.server = &http.Server{
 		Handler:           a.handler(),
 		ReadHeaderTimeout: 10 * time.Second,
+		TLSConfig:         tlsCfg,
+		Protocols:         a.protocols(),
 	}
+	a.addr = ln.Addr()
 
 	go func() {
-		if err := a.server.Serve(ln); err != nil && err != http.ErrServerClosed {
+		var err error
+		if tlsCfg != nil {
+			// Certificates come from TLSConfig; ServeTLS also sets up ALPN for h2.
+			err = a.server.ServeTLS(ln, "", "")
+		} else {
+			err = a.server.Serve(ln)
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Errorf("API server error: %v", err)
 		}
 	}()
@@ -104,6 +336,16 @@ func (a *APIServer) Start() error {
 	return nil
 }
 
+// protocols returns the HTTP versions the server accepts. HTTP/2 is only
+// negotiated over TLS unless h2c is enabled.
+func (a *APIServer) protocols() *http.Protocols {
+	p := new(http.Protocols)
+	p.SetHTTP1(true)
+	p.SetHTTP2(true)
+	p.SetUnencryptedHTTP2(a.h2c)
+	return p
+}
+
 // Stop gracefully shuts down the API server.
 func (a *APIServer) Stop() {
 	if a.server == nil {
@@ -114,21 +356,141 @@ func (a *APIServer) Stop() {
 	_ = a.server.Shutdown(ctx)
 }
 
+// List pagination bounds. A request without ?limit= gets defaultListLimit
+// records; larger limits are capped at maxListLimit.
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
 func (a *APIServer) handleList(w http.ResponseWriter, r *http.Request) {
-	nameFilter := r.URL.Query().Get("name")
+	q := r.URL.Query()
+	nameFilter := q.Get("name")
+	valueFilter := q.Get("value")
+	typeFilter := strings.ToUpper(q.Get("type"))
+
+	limit := defaultListLimit
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: fmt.Sprintf("invalid limit %q", v)})
+			return
+		}
+		limit = min(n, maxListLimit)
+	}
 
-	var records []Record
-	if nameFilter != "" {
-		records = a.store.GetAll(nameFilter)
-	} else {
-		records = a.store.List()
+	offset := 0
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: fmt.Sprintf("invalid offset %q", v)})
+			return
+		}
+		offset = n
 	}
 
+	var after *RecordKey
+	if v := q.Get("cursor"); v != "" {
+		if offset > 0 {
+			writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: "cursor and offset are mutually exclusive"})
+			return
+		}
+		key, err := decodeCursor(v)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: fmt.Sprintf("invalid cursor: %v", err)})
+			return
+		}
+		after = &key
+	}
+
+	var (
+		since      uint64
+		sinceGiven bool
+	)
+	if v := q.Get("since_generation"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: fmt.Sprintf("invalid since_generation %q", v)})
+			return
+		}
+		since, sinceGiven = n, true
+	}
+
+	var (
+		records []Record
+		changes Changes
+	)
+	switch {
+	case sinceGiven:
+		var err error
+		if changes, err = a.store.ChangedSince(r.Context(), since); err != nil {
+			writeJSON(w, http.StatusGone, apiErrorResponse{Error: fmt.Sprintf("%v; list without since_generation to resync", err)})
+			return
+		}
+		records = slices.DeleteFunc(changes.Records, func(rec Record) bool {
+			return (nameFilter != "" && !strings.EqualFold(rec.Name, nameFilter)) ||
+				(valueFilter != "" && rec.Value != valueFilter)
+		})
+		changes.Deleted = slices.DeleteFunc(changes.Deleted, func(k RecordKey) bool {
+			return (nameFilter != "" && !strings.EqualFold(k.Name, nameFilter)) ||
+				(typeFilter != "" && k.Type != typeFilter) ||
+				(valueFilter != "" && k.Value != valueFilter)
+		})
+	case nameFilter != "" && typeFilter != "":
+		records = a.store.Get(r.Context(), nameFilter, typeFilter)
+		if valueFilter != "" {
+			records = slices.DeleteFunc(records, func(rec Record) bool { return rec.Value != valueFilter })
+		}
+		sortRecords(records)
+	case nameFilter != "":
+		records = a.store.GetAll(r.Context(), nameFilter)
+		if valueFilter != "" {
+			records = slices.DeleteFunc(records, func(rec Record) bool { return rec.Value != valueFilter })
+		}
+		sortRecords(records)
+	case valueFilter != "":
+		records = a.store.GetByValue(r.Context(), valueFilter)
+	default:
+		records = a.store.List(r.Context())
+	}
+	if typeFilter != "" && (nameFilter == "" || sinceGiven) {
+		records = slices.DeleteFunc(records, func(rec Record) bool { return rec.Type != typeFilter })
+	}
+
+	total := len(records)
+	records = records[min(offset, total):]
+	records, more := pageRecords(records, after, limit)
 	if records == nil {
 		records = []Record{}
 	}
 
-	writeJSON(w, http.StatusOK, apiListResponse{Records: records})
+	resp := apiListResponse{Records: records, Total: total, Generation: changes.Generation}
+	if after == nil && offset == 0 {
+		resp.Deleted = changes.Deleted
+	}
+	if more {
+		resp.NextCursor = encodeCursor(records[len(records)-1].Key())
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// encodeCursor serialises a sort key into an opaque, URL-safe cursor.
+func encodeCursor(k RecordKey) string {
+	raw, _ := json.Marshal(k)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeCursor parses a cursor produced by encodeCursor.
+func decodeCursor(s string) (RecordKey, error) {
+	var k RecordKey
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return k, err
+	}
+	if err := json.Unmarshal(raw, &k); err != nil {
+		return k, err
+	}
+	return k, nil
 }
 
 func (a *APIServer) handleGetByName(w http.ResponseWriter, r *http.Request) {
@@ -138,62 +500,377 @@ func (a *APIServer) handleGetByName(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	records := a.store.GetAll(name)
+	records := a.store.GetAll(r.Context(), name)
 	if records == nil {
 		records = []Record{}
 	}
 
-	writeJSON(w, http.StatusOK, apiListResponse{Records: records})
+	writeJSON(w, http.StatusOK, apiListResponse{Records: records, Total: len(records)})
 }
 
+// handleCreate upserts a record. With ?if_absent=true it only creates it,
+// answering 409 when a record with the same name, type and value exists.
+// With ?explain=true the response also lists the normalizations applied.
 func (a *APIServer) handleCreate(w http.ResponseWriter, r *http.Request) {
+	ifAbsent, err := queryBool(r, "if_absent")
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: err.Error()})
+		return
+	}
+	explain, err := queryBool(r, "explain")
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: err.Error()})
+		return
+	}
+
 	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1 MiB
 	var rec Record
 	if err := json.NewDecoder(r.Body).Decode(&rec); err != nil {
 		writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: fmt.Sprintf("invalid JSON: %v", err)})
 		return
 	}
+	sent := rec
 
-	if err := rec.Validate(); err != nil {
-		writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: err.Error()})
+	a.store.ApplyDefaults(r.Context(), &rec)
+	if err := a.store.validateRecord(&rec); err != nil {
+		writeJSON(w, http.StatusBadRequest, invalidRecordResponse(err))
 		return
 	}
 
-	if err := a.store.Upsert(rec); err != nil {
-		if errors.Is(err, ErrPolicyDenied) {
+	create := a.store.Upsert
+	if ifAbsent {
+		create = a.store.Create
+	}
+	if err := create(r.Context(), rec); err != nil {
+		if errors.Is(err, ErrDuplicateRecord) {
+			writeJSON(w, http.StatusConflict, apiErrorResponse{Error: err.Error()})
+			return
+		}
+		if errors.Is(err, ErrCNAMEConflict) {
+			writeJSON(w, http.StatusConflict, cnameConflictResponse(err))
+			return
+		}
+		if errors.Is(err, ErrPolicyDenied) || errors.Is(err, ErrNotOwner) {
 			writeJSON(w, http.StatusForbidden, apiErrorResponse{Error: err.Error()})
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, apiErrorResponse{Error: err.Error()})
+		if limitReached(err) {
+			writeJSON(w, http.StatusTooManyRequests, apiErrorResponse{Error: err.Error()})
+			return
+		}
+		writeStoreError(w, err)
 		return
 	}
 
-	writeJSON(w, http.StatusCreated, rec)
+	writeRecord(w, http.StatusCreated, sent, rec, explain)
 }
 
+// handleUpdate upserts a record. With ?explain=true the response also lists
+// the normalizations applied; ?force=true lets it clear the record's
+// protected flag.
 func (a *APIServer) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	explain, err := queryBool(r, "explain")
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: err.Error()})
+		return
+	}
+	if r, err = withForce(r); err != nil {
+		writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: err.Error()})
+		return
+	}
+
 	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1 MiB
 	var rec Record
 	if err := json.NewDecoder(r.Body).Decode(&rec); err != nil {
 		writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: fmt.Sprintf("invalid JSON: %v", err)})
 		return
 	}
+	sent := rec
+
+	a.store.ApplyDefaults(r.Context(), &rec)
+	if err := a.store.validateRecord(&rec); err != nil {
+		writeJSON(w, http.StatusBadRequest, invalidRecordResponse(err))
+		return
+	}
+
+	if err := a.store.Upsert(r.Context(), rec); err != nil {
+		if errors.Is(err, ErrPolicyDenied) || errors.Is(err, ErrNotOwner) {
+			writeJSON(w, http.StatusForbidden, apiErrorResponse{Error: err.Error()})
+			return
+		}
+		if errors.Is(err, ErrCNAMEConflict) {
+			writeJSON(w, http.StatusConflict, cnameConflictResponse(err))
+			return
+		}
+		if limitReached(err) {
+			writeJSON(w, http.StatusTooManyRequests, apiErrorResponse{Error: err.Error()})
+			return
+		}
+		writeStoreError(w, err)
+		return
+	}
+
+	writeRecord(w, http.StatusOK, sent, rec, explain)
+}
+
+// queryBool parses the boolean query parameter name, which defaults to false.
+func queryBool(r *http.Request, name string) (bool, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s %q", name, v)
+	}
+	return b, nil
+}
+
+// withForce returns r with a forced context when ?force=true is set, so the
+// store may delete protected records or clear their flag.
+func withForce(r *http.Request) (*http.Request, error) {
+	force, err := queryBool(r, "force")
+	if err != nil || !force {
+		return r, err
+	}
+	return r.WithContext(ContextWithForce(r.Context())), nil
+}
+
+// writeRecord writes the stored record rec. With explain it is wrapped in an
+// apiExplainResponse describing how rec differs from sent, the record as the
+// client sent it.
+func writeRecord(w http.ResponseWriter, status int, sent, rec Record, explain bool) {
+	if !explain {
+		writeJSON(w, status, rec)
+		return
+	}
+	writeJSON(w, status, apiExplainResponse{Record: rec, Normalizations: normalizations(sent, rec)})
+}
 
-	if err := rec.Validate(); err != nil {
+// normalizations lists the client-settable fields whose value in applied
+// differs from sent, in field order.
+func normalizations(sent, applied Record) []apiNormalization {
+	out := []apiNormalization{}
+	add := func(field string, from, to any) {
+		if from != to {
+			out = append(out, apiNormalization{Field: field, Sent: from, Applied: to})
+		}
+	}
+	add("name", sent.Name, applied.Name)
+	add("type", sent.Type, applied.Type)
+	add("ttl", sent.TTL, applied.TTL)
+	add("value", sent.Value, applied.Value)
+	add("priority", sent.Priority, applied.Priority)
+	add("weight", sent.Weight, applied.Weight)
+	add("port", sent.Port, applied.Port)
+	add("flag", sent.Flag, applied.Flag)
+	add("tag", sent.Tag, applied.Tag)
+	add("os", sent.OS, applied.OS)
+	add("txt_name", sent.TXTName, applied.TXTName)
+	add("data", sent.Data, applied.Data)
+	return out
+}
+
+// maxBatchBytes caps the body of a batch request, and the records of a gRPC
+// Import stream.
+const maxBatchBytes = 8 << 20 // 8 MiB
+
+// handleBatch creates or updates every record in a JSON array as one atomic
+// Store.Import: all records are validated first, and a batch with any
+// invalid or rejected record leaves the store untouched.
+func (a *APIServer) handleBatch(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBatchBytes)
+	var recs []Record
+	if err := json.NewDecoder(r.Body).Decode(&recs); err != nil {
+		writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: fmt.Sprintf("invalid JSON: %v", err)})
+		return
+	}
+	if len(recs) == 0 {
+		writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: "batch must contain at least one record"})
+		return
+	}
+	a.applyBatch(w, r, recs)
+}
+
+// handleTemplate expands one record template, whose name and value may
+// hold {START..END} ranges, and applies the records it describes as one
+// batch, answering like handleBatch. A template expanding to more than the
+// configured maximum is rejected with 400 before anything is applied.
+func (a *APIServer) handleTemplate(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1 MiB
+	var tmpl Record
+	if err := json.NewDecoder(r.Body).Decode(&tmpl); err != nil {
+		writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: fmt.Sprintf("invalid JSON: %v", err)})
+		return
+	}
+	limit := a.maxTemplate
+	if limit == 0 {
+		limit = defaultMaxTemplateRecords
+	}
+	recs, err := ExpandTemplate(tmpl, limit)
+	if err != nil {
 		writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: err.Error()})
 		return
 	}
+	a.applyBatch(w, r, recs)
+}
+
+// applyBatch validates recs and applies them as one atomic Store.Import,
+// writing a result per record in order.
+func (a *APIServer) applyBatch(w http.ResponseWriter, r *http.Request, recs []Record) {
+	results := make([]apiBatchResult, len(recs))
+	invalid := false
+	for i := range recs {
+		results[i].Index = i
+		a.store.ApplyDefaults(r.Context(), &recs[i])
+		if err := a.store.validateRecord(&recs[i]); err != nil {
+			results[i].Status = http.StatusBadRequest
+			results[i].Error = err.Error()
+			invalid = true
+		}
+	}
+	if invalid {
+		writeJSON(w, http.StatusBadRequest, apiBatchResponse{Results: failBatch(results, -1), Error: "batch contains invalid records"})
+		return
+	}
 
-	if err := a.store.Upsert(rec); err != nil {
-		if errors.Is(err, ErrPolicyDenied) {
+	res, err := a.store.Import(r.Context(), recs, ImportOptions{})
+	if err != nil {
+		code := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, ErrPolicyDenied) || errors.Is(err, ErrNotOwner):
+			code = http.StatusForbidden
+		case errors.Is(err, ErrCNAMEConflict):
+			code = http.StatusConflict
+		case limitReached(err):
+			code = http.StatusTooManyRequests
+		case errors.Is(err, ErrBackendUnavailable):
+			code = http.StatusServiceUnavailable
+		}
+		failed := -1
+		var recErr *RecordError
+		if errors.As(err, &recErr) {
+			failed = recErr.Index
+			results[failed].Status = code
+			results[failed].Error = recErr.Err.Error()
+		}
+		writeJSON(w, code, apiBatchResponse{Results: failBatch(results, failed), Error: err.Error()})
+		return
+	}
+
+	for i, outcome := range res.Outcomes {
+		results[i].Record = &recs[i]
+		results[i].Status = http.StatusOK
+		if outcome == OutcomeCreated {
+			results[i].Status = http.StatusCreated
+		}
+	}
+	writeJSON(w, http.StatusOK, apiBatchResponse{Results: results})
+}
+
+// failBatch marks every record without a result of its own as not applied.
+// failed is the index of the record that rejected the batch, or -1.
+func failBatch(results []apiBatchResult, failed int) []apiBatchResult {
+	for i := range results {
+		if i != failed && results[i].Status == 0 {
+			results[i].Status = http.StatusFailedDependency
+			results[i].Error = "not applied: batch rejected"
+		}
+	}
+	return results
+}
+
+// handleReplace replaces every record of a name with the records in a JSON
+// array, in one Store.Transaction, so the old and new sets never coexist and
+// the name never briefly disappears. An empty array removes the name.
+// Protected records make it fail with 409 unless ?force=true is set.
+func (a *APIServer) handleReplace(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if name == "" {
+		writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: "name is required"})
+		return
+	}
+	r, err := withForce(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: err.Error()})
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1 MiB
+	var recs []Record
+	if err := json.NewDecoder(r.Body).Decode(&recs); err != nil {
+		writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: fmt.Sprintf("invalid JSON: %v", err)})
+		return
+	}
+	for i := range recs {
+		if recs[i].Name == "" {
+			recs[i].Name = name
+		}
+		if !strings.EqualFold(recs[i].Name, name) {
+			writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: fmt.Sprintf("record %d: name %q does not match %q", i, recs[i].Name, name)})
+			return
+		}
+		a.store.ApplyDefaults(r.Context(), &recs[i])
+		if err := a.store.validateRecord(&recs[i]); err != nil {
+			writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: fmt.Sprintf("record %d: %v", i, err)})
+			return
+		}
+	}
+
+	mayDelete := a.auth.allows(r.Context(), ScopeDelete)
+	err = a.store.Transaction(r.Context(), func(tx *Tx) error {
+		if !mayDelete && dropsRecords(tx.GetAll(name), recs) {
+			return errDeleteScope
+		}
+		if err := tx.DeleteAll(name); err != nil {
+			return err
+		}
+		for _, rec := range recs {
+			if err := tx.Upsert(rec); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrPolicyDenied) || errors.Is(err, ErrNotOwner) || errors.Is(err, errDeleteScope) {
 			writeJSON(w, http.StatusForbidden, apiErrorResponse{Error: err.Error()})
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, apiErrorResponse{Error: err.Error()})
+		if errors.Is(err, ErrProtected) || errors.Is(err, ErrCNAMEConflict) {
+			writeJSON(w, http.StatusConflict, apiErrorResponse{Error: err.Error()})
+			return
+		}
+		if limitReached(err) {
+			writeJSON(w, http.StatusTooManyRequests, apiErrorResponse{Error: err.Error()})
+			return
+		}
+		writeStoreError(w, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, rec)
+	records := a.store.GetAll(r.Context(), name)
+	if records == nil {
+		records = []Record{}
+	}
+	writeJSON(w, http.StatusOK, apiListResponse{Records: records, Total: len(records)})
+}
+
+// errDeleteScope rejects a replace that would remove records when the caller
+// only holds the write scope.
+var errDeleteScope = errors.New("replacing these records removes some: delete scope required")
+
+// dropsRecords reports whether replacing existing with recs removes any
+// record, that is, whether some existing type and value is not in recs.
+func dropsRecords(existing, recs []Record) bool {
+	for _, old := range existing {
+		if !slices.ContainsFunc(recs, func(r Record) bool {
+			return strings.EqualFold(r.Type, old.Type) && r.Value == old.Value
+		}) {
+			return true
+		}
+	}
+	return false
 }
 
 func (a *APIServer) handleDeleteAll(w http.ResponseWriter, r *http.Request) {
@@ -202,19 +879,64 @@ func (a *APIServer) handleDeleteAll(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: "name is required"})
 		return
 	}
+	r, err := withForce(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: err.Error()})
+		return
+	}
 
-	if err := a.store.DeleteAll(name); err != nil {
-		if errors.Is(err, ErrPolicyDenied) {
+	if err := a.store.DeleteAll(r.Context(), name); err != nil {
+		if errors.Is(err, ErrPolicyDenied) || errors.Is(err, ErrNotOwner) {
 			writeJSON(w, http.StatusForbidden, apiErrorResponse{Error: err.Error()})
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, apiErrorResponse{Error: err.Error()})
+		if errors.Is(err, ErrProtected) {
+			writeJSON(w, http.StatusConflict, apiErrorResponse{Error: err.Error()})
+			return
+		}
+		writeStoreError(w, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// handlePatchTTL changes only the TTL of one existing record.
+func (a *APIServer) handlePatchTTL(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	qtype := strings.ToUpper(r.PathValue("type"))
+
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1 MiB
+	var req apiTTLPatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: fmt.Sprintf("invalid JSON: %v", err)})
+		return
+	}
+	if req.Value == "" {
+		writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: "value is required"})
+		return
+	}
+	if req.TTL < MinTTL || req.TTL > MaxTTL {
+		writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: fmt.Sprintf("TTL %d out of range [%d, %d]", req.TTL, MinTTL, MaxTTL)})
+		return
+	}
+
+	rec, err := a.store.SetTTL(r.Context(), name, qtype, req.Value, req.TTL)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrRecordNotFound):
+			writeJSON(w, http.StatusNotFound, apiErrorResponse{Error: err.Error()})
+		case errors.Is(err, ErrPolicyDenied) || errors.Is(err, ErrNotOwner):
+			writeJSON(w, http.StatusForbidden, apiErrorResponse{Error: err.Error()})
+		default:
+			writeStoreError(w, err)
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rec)
+}
+
 func (a *APIServer) handleDeleteByType(w http.ResponseWriter, r *http.Request) {
 	name := r.PathValue("name")
 	qtype := strings.ToUpper(r.PathValue("type"))
@@ -223,17 +945,375 @@ func (a *APIServer) handleDeleteByType(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: "name and type are required"})
 		return
 	}
+	r, err := withForce(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := a.store.DeleteByType(r.Context(), name, qtype); err != nil {
+		if errors.Is(err, ErrPolicyDenied) || errors.Is(err, ErrNotOwner) {
+			writeJSON(w, http.StatusForbidden, apiErrorResponse{Error: err.Error()})
+			return
+		}
+		if errors.Is(err, ErrProtected) {
+			writeJSON(w, http.StatusConflict, apiErrorResponse{Error: err.Error()})
+			return
+		}
+		writeStoreError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleSetDisabled returns the handler for records:disable (disabled true)
+// or records:enable. The body is a Selector.
+func (a *APIServer) handleSetDisabled(disabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1 MiB
+		var sel Selector
+		if err := json.NewDecoder(r.Body).Decode(&sel); err != nil {
+			writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: fmt.Sprintf("invalid JSON: %v", err)})
+			return
+		}
+		if err := sel.Validate(); err != nil {
+			writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: err.Error()})
+			return
+		}
+
+		n, err := a.store.SetDisabled(r.Context(), sel, disabled)
+		if err != nil {
+			if errors.Is(err, ErrPolicyDenied) {
+				writeJSON(w, http.StatusForbidden, apiErrorResponse{Error: err.Error()})
+				return
+			}
+			writeStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, apiToggleResponse{Updated: n})
+	}
+}
+
+// maxZoneImportBytes caps the size of a zone file accepted by the import
+// endpoint. Zone files are far larger than single JSON records.
+const maxZoneImportBytes = 32 << 20 // 32 MiB
 
-	if err := a.store.DeleteByType(name, qtype); err != nil {
+func (a *APIServer) handleImportZone(w http.ResponseWriter, r *http.Request) {
+	zone := r.PathValue("zone")
+	if zone == "" {
+		writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: "zone is required"})
+		return
+	}
+	if mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mt != "text/dns" {
+		writeJSON(w, http.StatusUnsupportedMediaType, apiErrorResponse{Error: "Content-Type must be text/dns"})
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxZoneImportBytes)
+	res, err := a.store.ImportZone(r.Context(), zone, r.Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			writeJSON(w, http.StatusRequestEntityTooLarge, apiErrorResponse{Error: err.Error()})
+		case errors.Is(err, ErrInvalidZone):
+			writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: err.Error()})
+		case errors.Is(err, ErrPolicyDenied) || errors.Is(err, ErrNotOwner):
+			writeJSON(w, http.StatusForbidden, apiErrorResponse{Error: err.Error()})
+		case errors.Is(err, ErrCNAMEConflict):
+			writeJSON(w, http.StatusConflict, apiErrorResponse{Error: err.Error()})
+		case limitReached(err):
+			writeJSON(w, http.StatusTooManyRequests, apiErrorResponse{Error: err.Error()})
+		default:
+			writeStoreError(w, err)
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (a *APIServer) handleTTLWindow(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1 MiB
+	var req apiTTLWindowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: fmt.Sprintf("invalid JSON: %v", err)})
+		return
+	}
+
+	name, suffix := req.Name, false
+	if req.Suffix != "" {
+		name, suffix = req.Suffix, true
+	}
+	switch {
+	case (req.Name == "") == (req.Suffix == ""):
+		writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: "exactly one of name and suffix is required"})
+		return
+	case req.TTL < MinTTL || req.TTL > MaxTTL:
+		writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: fmt.Sprintf("TTL %d out of range [%d, %d]", req.TTL, MinTTL, MaxTTL)})
+		return
+	case !req.RestoreAt.After(time.Now()):
+		writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: "restore_at must be in the future"})
+		return
+	}
+
+	n, err := a.store.LowerTTL(r.Context(), name, suffix, req.TTL, req.RestoreAt)
+	if err != nil {
 		if errors.Is(err, ErrPolicyDenied) {
 			writeJSON(w, http.StatusForbidden, apiErrorResponse{Error: err.Error()})
 			return
 		}
+		writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, apiTTLWindowResponse{Updated: n, RestoreAt: req.RestoreAt})
+}
+
+// handleVerify compares memory with the backend. With ?repair=true,
+// ?source=memory or ?source=backend names the side that overwrites the
+// other when they disagree.
+func (a *APIServer) handleVerify(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	repair, err := queryBool(r, "repair")
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: err.Error()})
+		return
+	}
+	source := RepairNone
+	if repair {
+		if source, err = ParseRepairSource(r.URL.Query().Get("source")); err != nil {
+			writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: err.Error()})
+			return
+		}
+	}
+
+	report, err := a.store.Verify(r.Context(), source)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// handleCompact purges expired records and rewrites the backend with the
+// live ones.
+func (a *APIServer) handleCompact(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	res, err := a.store.Compact(r.Context())
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// maxConfigDumpBytes caps the body of a configuration restore.
+const maxConfigDumpBytes = 64 << 20 // 64 MiB
+
+// requireAdmin rejects callers scoped to a single owner. Configuration dumps
+// span every tenant.
+func requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if _, scoped := scopedOwner(r.Context()); scoped {
+		writeJSON(w, http.StatusForbidden, apiErrorResponse{Error: "admin identity required"})
+		return false
+	}
+	return true
+}
+
+// handleZones lists every configured zone with its record count and apex
+// SOA/NS presence.
+func (a *APIServer) handleZones(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, apiZonesResponse{Zones: a.store.ZoneStats(r.Context(), a.plugin.Zones)})
+}
+
+// handleExport writes a zone as a zone file. ?format=zone is the only
+// format; ?zone= may be left out when a single zone is configured, and
+// ?sign=true signs the export with the zone's DNSSEC key.
+func (a *APIServer) handleExport(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	if f := r.URL.Query().Get("format"); f != "zone" {
+		writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: fmt.Sprintf("invalid format %q: want zone", f)})
+		return
+	}
+	sign, err := queryBool(r, "sign")
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: err.Error()})
+		return
+	}
+	zone := r.URL.Query().Get("zone")
+	if zone == "" {
+		if len(a.plugin.Zones) != 1 {
+			writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: "zone is required when several zones are configured"})
+			return
+		}
+		zone = a.plugin.Zones[0]
+	}
+
+	body, err := a.plugin.ZoneFile(r.Context(), zone, sign)
+	switch {
+	case errors.Is(err, ErrUnknownZone):
+		writeJSON(w, http.StatusNotFound, apiErrorResponse{Error: err.Error()})
+		return
+	case errors.Is(err, ErrZoneUnsigned):
+		writeJSON(w, http.StatusConflict, apiErrorResponse{Error: err.Error()})
+		return
+	case err != nil:
 		writeJSON(w, http.StatusInternalServerError, apiErrorResponse{Error: err.Error()})
 		return
 	}
+	w.Header().Set("Content-Type", "text/dns")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
 
-	w.WriteHeader(http.StatusNoContent)
+func (a *APIServer) handleConfigExport(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	opts, err := exportOptions(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: err.Error()})
+		return
+	}
+	if opts == (ExportOptions{}) {
+		writeJSON(w, http.StatusOK, a.plugin.Dump())
+		return
+	}
+	body, err := a.plugin.Export(opts)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiErrorResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, json.RawMessage(body))
+}
+
+// exportOptions reads the export shape from ?fields= ("all" to emit zero
+// priority, weight, port, flag and tag) and ?naming= ("legacy" for Go
+// field names).
+func exportOptions(r *http.Request) (ExportOptions, error) {
+	var opts ExportOptions
+	switch v := r.URL.Query().Get("fields"); v {
+	case "", "default":
+	case "all":
+		opts.AllFields = true
+	default:
+		return opts, fmt.Errorf("invalid fields %q: want all or default", v)
+	}
+	switch v := r.URL.Query().Get("naming"); v {
+	case "", "default":
+	case "legacy":
+		opts.LegacyNames = true
+	default:
+		return opts, fmt.Errorf("invalid naming %q: want legacy or default", v)
+	}
+	return opts, nil
+}
+
+func (a *APIServer) handleConfigImport(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxConfigDumpBytes)
+	var dump ConfigDump
+	if err := json.NewDecoder(r.Body).Decode(&dump); err != nil {
+		writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: fmt.Sprintf("invalid JSON: %v", err)})
+		return
+	}
+
+	res, err := a.plugin.Restore(r.Context(), dump)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidDump):
+			writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: err.Error()})
+		case errors.Is(err, ErrPolicyDenied) || errors.Is(err, ErrNotOwner):
+			writeJSON(w, http.StatusForbidden, apiErrorResponse{Error: err.Error()})
+		default:
+			writeStoreError(w, err)
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+// maxReadyWait bounds the ?wait= long-poll of the readiness endpoint.
+const maxReadyWait = time.Minute
+
+// handleReady reports the store status with 200 when ready and 503
+// otherwise. With ?wait=DURATION it first waits up to that long (at most
+// maxReadyWait) for the store to become ready.
+func (a *APIServer) handleReady(w http.ResponseWriter, r *http.Request) {
+	var wait time.Duration
+	if v := r.URL.Query().Get("wait"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: fmt.Sprintf("invalid wait %q", v)})
+			return
+		}
+		wait = min(d, maxReadyWait)
+	}
+
+	st := a.store.Status()
+	if !st.Ready && wait > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), wait)
+		st = a.store.WaitReady(ctx)
+		cancel()
+	}
+
+	code := http.StatusOK
+	if !st.Ready {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, st)
+}
+
+// apiProbeResponse is the body of /healthz and /readyz.
+type apiProbeResponse struct {
+	Status string `json:"status"`
+}
+
+// handleProbe answers the liveness probe with 200 once the store has
+// loaded its records and 503 before.
+func (a *APIServer) handleProbe(w http.ResponseWriter, _ *http.Request) {
+	if !a.store.Ready() {
+		writeJSON(w, http.StatusServiceUnavailable, apiProbeResponse{Status: "not ready"})
+		return
+	}
+	writeJSON(w, http.StatusOK, apiProbeResponse{Status: "ok"})
+}
+
+// handleReadyz answers the readiness probe with 200 while the store is
+// ready, as Store.Status reports it, and 503 before it loads or while its
+// backend or last reload is failing.
+func (a *APIServer) handleReadyz(w http.ResponseWriter, _ *http.Request) {
+	st := a.store.Status()
+	switch {
+	case !st.Loaded:
+		writeJSON(w, http.StatusServiceUnavailable, apiProbeResponse{Status: "not ready"})
+	case !st.Ready:
+		writeJSON(w, http.StatusServiceUnavailable, apiProbeResponse{Status: "degraded"})
+	default:
+		writeJSON(w, http.StatusOK, apiProbeResponse{Status: "ok"})
+	}
+}
+
+// writeStoreError reports a store failure not covered by a more specific
+// status: 503 while the backend is unavailable, 500 otherwise.
+func writeStoreError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrBackendUnavailable) {
+		w.Header().Set("Retry-After", "1")
+		writeJSON(w, http.StatusServiceUnavailable, apiErrorResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusInternalServerError, apiErrorResponse{Error: err.Error()})
 }
 
 func writeJSON(w http.ResponseWriter, status int, v any) {
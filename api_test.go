@@ -5,11 +5,22 @@ package dynupdate
 
 import (
 	"bytes"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+	dto "github.com/prometheus/client_model/go"
 )
 
 func newTestAPIHandler(t *testing.T, opts ...StoreOption) (*APIServer, *Store) {
@@ -85,10 +96,127 @@ func TestAPI_CreateAndList(t *testing.T) {
 	}
 }
 
+func TestAPI_Annotations(t *testing.T) {
+	t.Parallel()
+	api, _ := newTestAPIHandler(t)
+
+	body := `{"name":"app.example.org.","type":"A","ttl":300,"value":"10.0.0.1","comment":"INC-1234","labels":{"team":"netops"}}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/records", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer test-token")
+	rec := httptest.NewRecorder()
+	api.handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, want %d; body = %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/records/app.example.org.", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	rec = httptest.NewRecorder()
+	api.handler().ServeHTTP(rec, req)
+	var resp apiListResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if len(resp.Records) != 1 || resp.Records[0].Comment != "INC-1234" || resp.Records[0].Labels["team"] != "netops" {
+		t.Errorf("records = %+v, want the comment and labels returned", resp.Records)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/records",
+		strings.NewReader(`{"name":"b.example.org.","type":"A","ttl":300,"value":"10.0.0.2","comment":"`+strings.Repeat("x", maxCommentLen+1)+`"}`))
+	req.Header.Set("Authorization", "Bearer test-token")
+	rec = httptest.NewRecorder()
+	api.handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("oversized comment: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestAPI_Disabled_Listed(t *testing.T) {
+	t.Parallel()
+	api, store := newTestAPIHandler(t)
+	d := &DynUpdate{Zones: []string{"example.org."}, Store: store}
+
+	body := `{"name":"app.example.org.","type":"A","ttl":300,"value":"10.0.0.1","disabled":true}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/records", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer test-token")
+	rec := httptest.NewRecorder()
+	api.handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, want %d; body = %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion("app.example.org.", dns.TypeA)
+	w := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := d.ServeDNS(t.Context(), w, m); err != nil {
+		t.Fatalf("ServeDNS() error: %v", err)
+	}
+	if len(w.Msg.Answer) != 0 {
+		t.Errorf("DNS answers = %v, want none for a disabled record", w.Msg.Answer)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/records", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	rec = httptest.NewRecorder()
+	api.handler().ServeHTTP(rec, req)
+	var resp apiListResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if len(resp.Records) != 1 || !resp.Records[0].Disabled {
+		t.Errorf("records = %+v, want the disabled record listed", resp.Records)
+	}
+
+	// Upserting the same record without the flag serves it again.
+	req = httptest.NewRequest(http.MethodPut, "/api/v1/records", strings.NewReader(`{"name":"app.example.org.","type":"A","ttl":300,"value":"10.0.0.1"}`))
+	req.Header.Set("Authorization", "Bearer test-token")
+	rec = httptest.NewRecorder()
+	api.handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update status = %d, want %d; body = %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	w = dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := d.ServeDNS(t.Context(), w, m); err != nil {
+		t.Fatalf("ServeDNS() error: %v", err)
+	}
+	if len(w.Msg.Answer) != 1 {
+		t.Errorf("DNS answers = %v, want the re-enabled record", w.Msg.Answer)
+	}
+}
+
+func TestAPI_NameCasePreserved(t *testing.T) {
+	t.Parallel()
+	api, _ := newTestAPIHandler(t)
+	h := api.handler()
+
+	body := `{"name":"App.Example.Org.","type":"A","ttl":300,"value":"10.0.0.1"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/records", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer test-token")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, want %d; body = %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+
+	for _, path := range []string{"/api/v1/records/app.example.org.", "/api/v1/records?name=app.example.org."} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer test-token")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		var resp apiListResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("GET %s: decode error: %v", path, err)
+		}
+		if len(resp.Records) != 1 || resp.Records[0].Name != "App.Example.Org." {
+			t.Errorf("GET %s = %+v, want one record named App.Example.Org.", path, resp.Records)
+		}
+	}
+}
+
 func TestAPI_GetByName(t *testing.T) {
 	t.Parallel()
 	api, store := newTestAPIHandler(t)
-	_ = store.Upsert(Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
+	_ = store.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
 
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/records/app.example.org.", nil)
 	req.Header.Set("Authorization", "Bearer test-token")
@@ -124,11 +252,53 @@ func TestAPI_CreateValidationError(t *testing.T) {
 	}
 }
 
+func TestAPI_ValidationErrorFields(t *testing.T) {
+	t.Parallel()
+	api, _ := newTestAPIHandler(t)
+
+	tests := []struct {
+		name   string
+		method string
+		rec    Record
+		field  string
+		code   string
+	}{
+		{"create TTL too low", http.MethodPost, Record{Name: "app.example.org.", Type: "A", TTL: 5, Value: "10.0.0.1"}, "ttl", CodeOutOfRange},
+		{"create bad IPv4", http.MethodPost, Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.300"}, "value", CodeInvalid},
+		{"update TTL too high", http.MethodPut, Record{Name: "app.example.org.", Type: "A", TTL: 100000, Value: "10.0.0.1"}, "ttl", CodeOutOfRange},
+		{"update bad IPv4", http.MethodPut, Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "2001:db8::1"}, "value", CodeInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			body, _ := json.Marshal(tt.rec)
+			req := httptest.NewRequest(tt.method, "/api/v1/records", bytes.NewReader(body))
+			req.Header.Set("Authorization", "Bearer test-token")
+			rec := httptest.NewRecorder()
+
+			api.handler().ServeHTTP(rec, req)
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+			var resp apiErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Unmarshal() error: %v", err)
+			}
+			if resp.Field != tt.field || resp.Code != tt.code {
+				t.Errorf("field, code = %q, %q, want %q, %q", resp.Field, resp.Code, tt.field, tt.code)
+			}
+			if resp.Error == "" {
+				t.Error("error message is empty")
+			}
+		})
+	}
+}
+
 func TestAPI_DeleteAll(t *testing.T) {
 	t.Parallel()
 	api, store := newTestAPIHandler(t)
-	_ = store.Upsert(Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
-	_ = store.Upsert(Record{Name: "app.example.org.", Type: "AAAA", TTL: 300, Value: "2001:db8::1"})
+	_ = store.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
+	_ = store.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "AAAA", TTL: 300, Value: "2001:db8::1"})
 
 	req := httptest.NewRequest(http.MethodDelete, "/api/v1/records/app.example.org.", nil)
 	req.Header.Set("Authorization", "Bearer test-token")
@@ -139,7 +309,7 @@ func TestAPI_DeleteAll(t *testing.T) {
 		t.Errorf("status = %d, want %d", rec.Code, http.StatusNoContent)
 	}
 
-	records := store.GetAll("app.example.org.")
+	records := store.GetAll(t.Context(), "app.example.org.")
 	if len(records) != 0 {
 		t.Errorf("got %d records after delete, want 0", len(records))
 	}
@@ -148,8 +318,8 @@ func TestAPI_DeleteAll(t *testing.T) {
 func TestAPI_DeleteByNameAndType(t *testing.T) {
 	t.Parallel()
 	api, store := newTestAPIHandler(t)
-	_ = store.Upsert(Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
-	_ = store.Upsert(Record{Name: "app.example.org.", Type: "AAAA", TTL: 300, Value: "2001:db8::1"})
+	_ = store.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
+	_ = store.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "AAAA", TTL: 300, Value: "2001:db8::1"})
 
 	req := httptest.NewRequest(http.MethodDelete, "/api/v1/records/app.example.org./A", nil)
 	req.Header.Set("Authorization", "Bearer test-token")
@@ -161,11 +331,11 @@ func TestAPI_DeleteByNameAndType(t *testing.T) {
 	}
 
 	// A should be gone; AAAA should remain
-	aRecords := store.Get("app.example.org.", "A")
+	aRecords := store.Get(t.Context(), "app.example.org.", "A")
 	if len(aRecords) != 0 {
 		t.Errorf("A records = %d, want 0", len(aRecords))
 	}
-	aaaaRecords := store.Get("app.example.org.", "AAAA")
+	aaaaRecords := store.Get(t.Context(), "app.example.org.", "AAAA")
 	if len(aaaaRecords) != 1 {
 		t.Errorf("AAAA records = %d, want 1", len(aaaaRecords))
 	}
@@ -174,7 +344,7 @@ func TestAPI_DeleteByNameAndType(t *testing.T) {
 func TestAPI_Upsert_PUT(t *testing.T) {
 	t.Parallel()
 	api, store := newTestAPIHandler(t)
-	_ = store.Upsert(Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
+	_ = store.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
 
 	body, _ := json.Marshal(Record{Name: "app.example.org.", Type: "A", TTL: 600, Value: "10.0.0.1"})
 	req := httptest.NewRequest(http.MethodPut, "/api/v1/records", bytes.NewReader(body))
@@ -187,7 +357,7 @@ func TestAPI_Upsert_PUT(t *testing.T) {
 		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
 	}
 
-	records := store.Get("app.example.org.", "A")
+	records := store.Get(t.Context(), "app.example.org.", "A")
 	if len(records) != 1 || records[0].TTL != 600 {
 		t.Errorf("upsert failed: got %v", records)
 	}
@@ -207,11 +377,53 @@ func TestAPI_Unauthorized(t *testing.T) {
 	}
 }
 
+// counterValue reads the current value of one apiRequestCount series.
+func counterValue(t *testing.T, method, status string) float64 {
+	t.Helper()
+	var m dto.Metric
+	if err := apiRequestCount.WithLabelValues(method, status).Write(&m); err != nil {
+		t.Fatalf("reading counter: %v", err)
+	}
+	return m.GetCounter().GetValue()
+}
+
+// TestAPI_RequestCountMetric is not parallel: apiRequestCount is a global
+// counter that every other API test also increments.
+func TestAPI_RequestCountMetric(t *testing.T) {
+	api, _ := newTestAPIHandler(t)
+
+	tests := []struct {
+		method string
+		path   string
+		token  string
+		body   string
+		status string
+	}{
+		{method: http.MethodGet, path: "/api/v1/records", token: "test-token", status: "200"},
+		{method: http.MethodGet, path: "/api/v1/records", status: "401"},
+		{method: http.MethodPost, path: "/api/v1/records", token: "test-token", body: "{", status: "400"},
+		{method: http.MethodDelete, path: "/api/v1/records/a.example.org.", token: "test-token", status: "204"},
+	}
+	for _, tt := range tests {
+		before := counterValue(t, tt.method, tt.status)
+
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		api.handler().ServeHTTP(httptest.NewRecorder(), req)
+
+		if got := counterValue(t, tt.method, tt.status) - before; got != 1 {
+			t.Errorf("%s %s: counter{method=%q,status=%q} grew by %v, want 1", tt.method, tt.path, tt.method, tt.status, got)
+		}
+	}
+}
+
 func TestAPI_ListWithNameFilter(t *testing.T) {
 	t.Parallel()
 	api, store := newTestAPIHandler(t)
-	_ = store.Upsert(Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
-	_ = store.Upsert(Record{Name: "other.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"})
+	_ = store.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
+	_ = store.Upsert(t.Context(), Record{Name: "other.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"})
 
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/records?name=app.example.org.", nil)
 	req.Header.Set("Authorization", "Bearer test-token")
@@ -231,6 +443,150 @@ func TestAPI_ListWithNameFilter(t *testing.T) {
 	}
 }
 
+func TestAPI_ListSinceGeneration(t *testing.T) {
+	t.Parallel()
+	api, store := newTestAPIHandler(t)
+	_ = store.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
+	_ = store.Upsert(t.Context(), Record{Name: "db.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"})
+	known := store.Generation()
+	_ = store.Upsert(t.Context(), Record{Name: "db.example.org.", Type: "A", TTL: 600, Value: "10.0.0.2"})
+	_ = store.Upsert(t.Context(), Record{Name: "web.example.org.", Type: "AAAA", TTL: 300, Value: "2001:db8::1"})
+	_ = store.Delete(t.Context(), "app.example.org.", "A", "10.0.0.1")
+
+	list := func(query string) (int, apiListResponse) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/records"+query, nil)
+		req.Header.Set("Authorization", "Bearer test-token")
+		rec := httptest.NewRecorder()
+		api.handler().ServeHTTP(rec, req)
+		var resp apiListResponse
+		_ = json.NewDecoder(rec.Body).Decode(&resp)
+		return rec.Code, resp
+	}
+
+	code, resp := list(fmt.Sprintf("?since_generation=%d", known))
+	if code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", code, http.StatusOK)
+	}
+	var names []string
+	for _, r := range resp.Records {
+		names = append(names, r.Name)
+	}
+	if want := []string{"db.example.org.", "web.example.org."}; !slices.Equal(names, want) {
+		t.Errorf("changed records = %v, want %v", names, want)
+	}
+	if resp.Generation != store.Generation() {
+		t.Errorf("generation = %d, want %d", resp.Generation, store.Generation())
+	}
+	if want := []RecordKey{{Name: "app.example.org.", Type: "A", Value: "10.0.0.1"}}; !slices.Equal(resp.Deleted, want) {
+		t.Errorf("deleted = %v, want %v", resp.Deleted, want)
+	}
+
+	if _, resp := list(fmt.Sprintf("?since_generation=%d&type=AAAA", known)); len(resp.Records) != 1 || resp.Records[0].Name != "web.example.org." || len(resp.Deleted) != 0 {
+		t.Errorf("type-filtered changes = %+v, deleted %v, want only web.example.org.", resp.Records, resp.Deleted)
+	}
+	if _, resp := list(fmt.Sprintf("?since_generation=%d&limit=1", known)); len(resp.Deleted) != 1 {
+		t.Errorf("first page deleted = %v, want the removed record", resp.Deleted)
+	} else if _, next := list(fmt.Sprintf("?since_generation=%d&limit=1&cursor=%s", known, resp.NextCursor)); len(next.Deleted) != 0 {
+		t.Errorf("second page deleted = %v, want none", next.Deleted)
+	}
+	if _, resp := list(fmt.Sprintf("?since_generation=%d", resp.Generation)); len(resp.Records) != 0 {
+		t.Errorf("changes since current generation = %+v, want none", resp.Records)
+	}
+	if code, _ := list("?since_generation=-1"); code != http.StatusBadRequest {
+		t.Errorf("invalid since_generation status = %d, want %d", code, http.StatusBadRequest)
+	}
+
+	// Once the history no longer reaches back to known, the client must
+	// resync.
+	store.mu.Lock()
+	store.history.reset(store.generation)
+	store.mu.Unlock()
+	if code, _ := list(fmt.Sprintf("?since_generation=%d", known)); code != http.StatusGone {
+		t.Errorf("stale since_generation status = %d, want %d", code, http.StatusGone)
+	}
+}
+
+func TestAPI_ListWithValueFilter(t *testing.T) {
+	t.Parallel()
+	api, store := newTestAPIHandler(t)
+	_ = store.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
+	_ = store.Upsert(t.Context(), Record{Name: "db.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
+	_ = store.Upsert(t.Context(), Record{Name: "db.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"})
+	_ = store.Upsert(t.Context(), Record{Name: "other.example.org.", Type: "A", TTL: 300, Value: "10.0.0.3"})
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{query: "value=10.0.0.1", want: 2},
+		{query: "value=10.0.0.1&name=db.example.org.", want: 1},
+		{query: "value=10.0.0.9", want: 0},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/records?"+tt.query, nil)
+		req.Header.Set("Authorization", "Bearer test-token")
+		rec := httptest.NewRecorder()
+
+		api.handler().ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, want %d", tt.query, rec.Code, http.StatusOK)
+		}
+
+		var resp apiListResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode error: %v", err)
+		}
+		if len(resp.Records) != tt.want {
+			t.Errorf("%s: got %d records, want %d", tt.query, len(resp.Records), tt.want)
+		}
+		for _, r := range resp.Records {
+			if r.Value != "10.0.0.1" {
+				t.Errorf("%s: record %s has value %s", tt.query, r.Name, r.Value)
+			}
+		}
+	}
+}
+
+func TestAPI_ListWithTypeFilter(t *testing.T) {
+	t.Parallel()
+	api, store := newTestAPIHandler(t)
+	_ = store.Upsert(t.Context(), Record{Name: "example.org.", Type: "MX", TTL: 300, Value: "mx1.example.org.", Priority: 10})
+	_ = store.Upsert(t.Context(), Record{Name: "example.org.", Type: "MX", TTL: 300, Value: "mx2.example.org.", Priority: 20})
+	_ = store.Upsert(t.Context(), Record{Name: "example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
+	_ = store.Upsert(t.Context(), Record{Name: "other.example.org.", Type: "MX", TTL: 300, Value: "mx1.example.org.", Priority: 10})
+	_ = store.Upsert(t.Context(), Record{Name: "other.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"})
+
+	tests := []struct {
+		name     string
+		query    string
+		want     int
+		wantType string
+	}{
+		{name: "type only", query: "type=MX", want: 3, wantType: "MX"},
+		{name: "type lowercase", query: "type=mx", want: 3, wantType: "MX"},
+		{name: "name only", query: "name=example.org.", want: 3},
+		{name: "name and type", query: "name=example.org.&type=mx", want: 2, wantType: "MX"},
+		{name: "name, type and value", query: "name=example.org.&type=MX&value=mx2.example.org.", want: 1, wantType: "MX"},
+		{name: "no match", query: "name=other.example.org.&type=TXT", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			resp := listPage(t, api, tt.query)
+			if resp.Records == nil {
+				t.Fatal("records is null, want an empty array")
+			}
+			if len(resp.Records) != tt.want || resp.Total != tt.want {
+				t.Errorf("got %d records (total %d), want %d", len(resp.Records), resp.Total, tt.want)
+			}
+			for _, r := range resp.Records {
+				if tt.wantType != "" && r.Type != tt.wantType {
+					t.Errorf("record %s has type %s, want %s", r.Name, r.Type, tt.wantType)
+				}
+			}
+		})
+	}
+}
 func TestAPI_Create_PolicyUpdateOnly_Returns403(t *testing.T) {
 	t.Parallel()
 	api, _ := newTestAPIHandler(t, WithSyncPolicy(PolicyUpdateOnly))
@@ -269,6 +625,249 @@ func TestAPI_Update_PolicyCreateOnly_Returns403(t *testing.T) {
 	}
 }
 
+func TestAPI_Create_RootName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		opts       []StoreOption
+		want       int
+		wantStored int
+	}{
+		{name: "rejected by default", want: http.StatusBadRequest},
+		{name: "allowed with allow_root", opts: []StoreOption{WithAllowRoot()}, want: http.StatusCreated, wantStored: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			api, store := newTestAPIHandler(t, tt.opts...)
+
+			body := `{"name":".","type":"TXT","ttl":300,"value":"hello"}`
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/records", strings.NewReader(body))
+			req.Header.Set("Authorization", "Bearer test-token")
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			api.handler().ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, tt.want, rec.Body.String())
+			}
+			if got := len(store.GetAll(t.Context(), ".")); got != tt.wantStored {
+				t.Errorf("stored %d root records, want %d", got, tt.wantStored)
+			}
+		})
+	}
+}
+
+func TestAPI_Create_PTRCheck(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		check PTRCheck
+		owner string
+		want  int
+	}{
+		{name: "reverse owner under reject", check: PTRCheckReject, owner: "1.0.0.10.in-addr.arpa.", want: http.StatusCreated},
+		{name: "forward owner under reject", check: PTRCheckReject, owner: "host.example.org.", want: http.StatusBadRequest},
+		{name: "forward owner under warn", check: PTRCheckWarn, owner: "host.example.org.", want: http.StatusCreated},
+		{name: "forward owner by default", check: PTRCheckOff, owner: "host.example.org.", want: http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			api, _ := newTestAPIHandler(t, WithPTRCheck(tt.check))
+
+			body := `{"name":"` + tt.owner + `","type":"PTR","ttl":300,"value":"host.example.org."}`
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/records", strings.NewReader(body))
+			req.Header.Set("Authorization", "Bearer test-token")
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			api.handler().ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, tt.want, rec.Body.String())
+			}
+			if tt.want == http.StatusBadRequest {
+				var resp apiErrorResponse
+				if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+					t.Fatalf("decode: %v", err)
+				}
+				if resp.Field != "name" || resp.Code != CodeNotAllowed {
+					t.Errorf("error = %+v, want field name, code %s", resp, CodeNotAllowed)
+				}
+			}
+		})
+	}
+}
+
+func TestAPI_Create_IfAbsent(t *testing.T) {
+	t.Parallel()
+	api, store := newTestAPIHandler(t)
+
+	post := func(query string, ttl int) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"name":"app.example.org.","type":"A","ttl":%d,"value":"10.0.0.1"}`, ttl)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/records"+query, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test-token")
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		api.handler().ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := post("?if_absent=true", 300); rec.Code != http.StatusCreated {
+		t.Fatalf("first create: status = %d, want %d; body = %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	if rec := post("?if_absent=true", 600); rec.Code != http.StatusConflict {
+		t.Fatalf("repeat create: status = %d, want %d; body = %s", rec.Code, http.StatusConflict, rec.Body.String())
+	}
+	if got := store.Get(t.Context(), "app.example.org.", "A"); len(got) != 1 || got[0].TTL != 300 {
+		t.Fatalf("after conflict: records = %+v, want one with TTL 300", got)
+	}
+
+	// Without if_absent the same request still upserts.
+	if rec := post("", 600); rec.Code != http.StatusCreated {
+		t.Fatalf("upsert: status = %d, want %d; body = %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	if got := store.Get(t.Context(), "app.example.org.", "A"); len(got) != 1 || got[0].TTL != 600 {
+		t.Errorf("after upsert: records = %+v, want one with TTL 600", got)
+	}
+
+	if rec := post("?if_absent=maybe", 300); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid if_absent: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestAPI_Create_SRVData(t *testing.T) {
+	t.Parallel()
+	api, store := newTestAPIHandler(t)
+
+	body := `{"name":"_sip._tcp.example.org.","type":"SRV","ttl":300,"data":"10 60 5060 sip.example.org."}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/records", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer test-token")
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	api.handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d; body = %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+
+	want := Record{Name: "_sip._tcp.example.org.", Type: "SRV", TTL: 300, Value: "sip.example.org.", Priority: 10, Weight: 60, Port: 5060}
+	got := store.Get(t.Context(), "_sip._tcp.example.org.", "SRV")
+	if len(got) != 1 || !got[0].equal(want) {
+		t.Errorf("stored = %+v, want [%+v]", got, want)
+	}
+}
+
+func TestAPI_Create_Explain(t *testing.T) {
+	t.Parallel()
+	api, _ := newTestAPIHandler(t)
+
+	body := `{"name":"app.example.org.","type":"a","ttl":0,"value":"10.0.0.1"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/records?explain=true", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer test-token")
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	api.handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d; body = %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	var resp apiExplainResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Record.Type != "A" || resp.Record.TTL != DefaultTTL {
+		t.Errorf("record = %+v, want type A with the default TTL", resp.Record)
+	}
+
+	var got []string
+	for _, n := range resp.Normalizations {
+		got = append(got, fmt.Sprintf("%s: %v -> %v", n.Field, n.Sent, n.Applied))
+	}
+	want := []string{"type: a -> A", fmt.Sprintf("ttl: 0 -> %d", DefaultTTL)}
+	if !slices.Equal(got, want) {
+		t.Errorf("normalizations = %q, want %q", got, want)
+	}
+}
+
+func TestAPI_Update_ExplainNothingChanged(t *testing.T) {
+	t.Parallel()
+	api, _ := newTestAPIHandler(t)
+
+	body := `{"name":"app.example.org.","type":"A","ttl":300,"value":"10.0.0.1"}`
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/records?explain=1", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer test-token")
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	api.handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d; body = %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), `"normalizations":[]`) {
+		t.Errorf("body = %s, want an empty normalizations list", rec.Body.String())
+	}
+}
+
+func TestAPI_PatchTTL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		policy  SyncPolicy
+		path    string
+		body    string
+		want    int
+		wantTTL uint32
+	}{
+		{name: "updates TTL", policy: PolicySync, path: "/api/v1/records/a.example.org./a", body: `{"value":"10.0.0.1","ttl":600}`, want: http.StatusOK, wantTTL: 600},
+		{name: "allowed under update-only", policy: PolicyUpdateOnly, path: "/api/v1/records/a.example.org./A", body: `{"value":"10.0.0.1","ttl":600}`, want: http.StatusOK, wantTTL: 600},
+		{name: "unknown value", policy: PolicySync, path: "/api/v1/records/a.example.org./A", body: `{"value":"10.0.0.9","ttl":600}`, want: http.StatusNotFound, wantTTL: 300},
+		{name: "unknown name", policy: PolicySync, path: "/api/v1/records/b.example.org./A", body: `{"value":"10.0.0.1","ttl":600}`, want: http.StatusNotFound, wantTTL: 300},
+		{name: "denied under create-only", policy: PolicyCreateOnly, path: "/api/v1/records/a.example.org./A", body: `{"value":"10.0.0.1","ttl":600}`, want: http.StatusForbidden, wantTTL: 300},
+		{name: "TTL out of range", policy: PolicySync, path: "/api/v1/records/a.example.org./A", body: `{"value":"10.0.0.1","ttl":5}`, want: http.StatusBadRequest, wantTTL: 300},
+		{name: "missing value", policy: PolicySync, path: "/api/v1/records/a.example.org./A", body: `{"ttl":600}`, want: http.StatusBadRequest, wantTTL: 300},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			api, store := newTestAPIHandler(t, WithSyncPolicy(tt.policy))
+			// Seed directly to bypass policy
+			store.mu.Lock()
+			store.records["a.example.org."] = []Record{
+				{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1", Owner: "team-a"},
+			}
+			store.mu.Unlock()
+
+			req := httptest.NewRequest(http.MethodPatch, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer test-token")
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			api.handler().ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, tt.want, rec.Body.String())
+			}
+
+			got := store.Get(t.Context(), "a.example.org.", "A")
+			if len(got) != 1 || got[0].TTL != tt.wantTTL || got[0].Owner != "team-a" {
+				t.Errorf("stored = %+v, want one record with TTL %d and owner kept", got, tt.wantTTL)
+			}
+			if tt.want == http.StatusOK {
+				var resp Record
+				if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+					t.Fatalf("decode error: %v", err)
+				}
+				if resp.TTL != tt.wantTTL || resp.Value != "10.0.0.1" {
+					t.Errorf("response = %+v, want TTL %d", resp, tt.wantTTL)
+				}
+			}
+		})
+	}
+}
+
 func TestAPI_DeleteAll_PolicyUpsertOnly_Returns403(t *testing.T) {
 	t.Parallel()
 	api, store := newTestAPIHandler(t, WithSyncPolicy(PolicyUpsertOnly))
@@ -322,3 +921,935 @@ func TestAPI_Create_PolicySync_Returns201(t *testing.T) {
 		t.Errorf("status = %d, want %d; body = %s", rec.Code, http.StatusCreated, rec.Body.String())
 	}
 }
+
+func TestAPI_H2C(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	s, err := NewStore(filepath.Join(dir, "records.json"), 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	t.Cleanup(func() { s.Stop() })
+
+	api := NewAPIServer(s, &Auth{Token: "test-token"}, "127.0.0.1:0", nil, WithH2C())
+	if err := api.Start(); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	t.Cleanup(api.Stop)
+
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: protocols}}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://"+api.addr.String()+"/api/v1/records", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("h2c request error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.ProtoMajor != 2 {
+		t.Errorf("proto = %s, want HTTP/2", resp.Proto)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	var body apiListResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+}
+
+func TestAPI_HTTP2OverTLS(t *testing.T) {
+	t.Parallel()
+	certs := generateTestCerts(t)
+	dir := t.TempDir()
+	s, err := NewStore(filepath.Join(dir, "records.json"), 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	t.Cleanup(func() { s.Stop() })
+
+	tlsCfg := &tlsConfig{cert: certs.ServerCert, key: certs.ServerKey}
+	api := NewAPIServer(s, &Auth{Token: "test-token"}, "127.0.0.1:0", tlsCfg)
+	if err := api.Start(); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	t.Cleanup(api.Stop)
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{ServerName: "localhost", InsecureSkipVerify: true}, //nolint:gosec // test-only self-signed cert
+		ForceAttemptHTTP2: true,
+	}}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://"+api.addr.String()+"/api/v1/records", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("HTTPS request error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.ProtoMajor != 2 {
+		t.Errorf("proto = %s, want HTTP/2", resp.Proto)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
+
+func TestAPI_PlaintextWithoutH2C_IsHTTP1(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	s, err := NewStore(filepath.Join(dir, "records.json"), 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	t.Cleanup(func() { s.Stop() })
+
+	api := NewAPIServer(s, &Auth{Token: "test-token"}, "127.0.0.1:0", nil)
+	if err := api.Start(); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	t.Cleanup(api.Stop)
+
+	req, _ := http.NewRequest(http.MethodGet, "http://"+api.addr.String()+"/api/v1/records", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.ProtoMajor != 1 {
+		t.Errorf("proto = %s, want HTTP/1.x", resp.Proto)
+	}
+}
+
+func TestAPI_ListCursorPagination_StableUnderWrites(t *testing.T) {
+	t.Parallel()
+	api, store := newTestAPIHandler(t)
+
+	want := make(map[string]bool)
+	for i := range 10 {
+		name := fmt.Sprintf("host%02d.example.org.", i)
+		_ = store.Upsert(t.Context(), Record{Name: name, Type: "A", TTL: 300, Value: "10.0.0.1"})
+		want[name] = true
+	}
+
+	seen := make(map[string]int)
+	cursor := ""
+	for page := 0; ; page++ {
+		if page > 20 {
+			t.Fatal("pagination did not terminate")
+		}
+		url := "/api/v1/records?limit=3"
+		if cursor != "" {
+			url += "&cursor=" + cursor
+		}
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		req.Header.Set("Authorization", "Bearer test-token")
+		rec := httptest.NewRecorder()
+		api.handler().ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+		}
+
+		var resp apiListResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode error: %v", err)
+		}
+		for _, r := range resp.Records {
+			seen[r.Name]++
+		}
+
+		// Concurrent writers insert before and after the cursor position.
+		_ = store.Upsert(t.Context(), Record{Name: fmt.Sprintf("aaa%02d.example.org.", page), Type: "A", TTL: 300, Value: "10.0.0.2"})
+		_ = store.Upsert(t.Context(), Record{Name: fmt.Sprintf("zzz%02d.example.org.", page), Type: "A", TTL: 300, Value: "10.0.0.2"})
+
+		if resp.NextCursor == "" {
+			break
+		}
+		cursor = resp.NextCursor
+	}
+
+	for name := range want {
+		if seen[name] != 1 {
+			t.Errorf("%s seen %d times, want exactly 1", name, seen[name])
+		}
+	}
+	for name, n := range seen {
+		if n > 1 {
+			t.Errorf("%s seen %d times, want at most 1", name, n)
+		}
+	}
+}
+
+func listPage(t *testing.T, api *APIServer, query string) apiListResponse {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/records?"+query, nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	rec := httptest.NewRecorder()
+	api.handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET ?%s: status = %d, want %d", query, rec.Code, http.StatusOK)
+	}
+	var resp apiListResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	return resp
+}
+
+func TestAPI_ListOffsetPagination(t *testing.T) {
+	t.Parallel()
+	api, store := newTestAPIHandler(t)
+
+	// Insert in reverse so the listing order comes from sorting, not insertion.
+	for i := 249; i >= 0; i-- {
+		r := Record{Name: fmt.Sprintf("host%03d.example.org.", i), Type: "A", TTL: 300, Value: "10.0.0.1"}
+		if err := store.Upsert(t.Context(), r); err != nil {
+			t.Fatalf("Upsert() error: %v", err)
+		}
+	}
+	if err := store.Upsert(t.Context(), Record{Name: "host000.example.org.", Type: "A", TTL: 300, Value: "10.0.0.0"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+
+	def := listPage(t, api, "")
+	if len(def.Records) != defaultListLimit || def.Total != 251 || def.NextCursor == "" {
+		t.Errorf("default page: %d records, total %d, cursor %q; want %d, 251, a cursor", len(def.Records), def.Total, def.NextCursor, defaultListLimit)
+	}
+	// Same name sorts by type then value.
+	if def.Records[0].Value != "10.0.0.0" || def.Records[1].Value != "10.0.0.1" || def.Records[2].Name != "host001.example.org." {
+		t.Errorf("first records = %v, want host000 (.0, .1) then host001", def.Records[:3])
+	}
+
+	var walked []Record
+	for offset := 0; offset < 251; offset += 40 {
+		page := listPage(t, api, fmt.Sprintf("limit=40&offset=%d", offset))
+		if page.Total != 251 {
+			t.Errorf("offset %d: total = %d, want 251", offset, page.Total)
+		}
+		if want := min(40, 251-offset); len(page.Records) != want {
+			t.Errorf("offset %d: got %d records, want %d", offset, len(page.Records), want)
+		}
+		if (offset+40 < 251) != (page.NextCursor != "") {
+			t.Errorf("offset %d: next_cursor = %q, want one only when records follow", offset, page.NextCursor)
+		}
+		walked = append(walked, page.Records...)
+	}
+	all := listPage(t, api, "limit=1000").Records
+	if len(walked) != len(all) {
+		t.Fatalf("walked %d records, want %d", len(walked), len(all))
+	}
+	for i := range all {
+		if !walked[i].equal(all[i]) {
+			t.Fatalf("record %d differs between paged and single listing: %v vs %v", i, walked[i], all[i])
+		}
+	}
+	// Repeated calls return the same page.
+	if a, b := listPage(t, api, "limit=5&offset=120"), listPage(t, api, "limit=5&offset=120"); !slices.EqualFunc(a.Records, b.Records, Record.equal) {
+		t.Errorf("repeated page differs: %v vs %v", a.Records, b.Records)
+	}
+
+	if past := listPage(t, api, "offset=999"); len(past.Records) != 0 || past.Total != 251 || past.Records == nil {
+		t.Errorf("offset past the end = %+v, want an empty records array and total 251", past)
+	}
+}
+
+func TestAPI_ListLimitCapped(t *testing.T) {
+	t.Parallel()
+	api, store := newTestAPIHandler(t)
+	for i := range maxListLimit + 5 {
+		r := Record{Name: fmt.Sprintf("h%04d.example.org.", i), Type: "A", TTL: 300, Value: "10.0.0.1"}
+		if err := store.Upsert(t.Context(), r); err != nil {
+			t.Fatalf("Upsert() error: %v", err)
+		}
+	}
+	page := listPage(t, api, "limit=100000")
+	if len(page.Records) != maxListLimit || page.NextCursor == "" {
+		t.Errorf("got %d records (cursor %q), want the %d cap and a cursor", len(page.Records), page.NextCursor, maxListLimit)
+	}
+}
+
+func TestAPI_ListPagination_InvalidParams(t *testing.T) {
+	t.Parallel()
+	api, _ := newTestAPIHandler(t)
+
+	for _, query := range []string{"limit=0", "limit=abc", "cursor=!!!", "offset=-1", "offset=x", "offset=2&cursor=abc"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/records?"+query, nil)
+		req.Header.Set("Authorization", "Bearer test-token")
+		rec := httptest.NewRecorder()
+		api.handler().ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", query, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestAPI_Ownership_TenantsIsolated(t *testing.T) {
+	t.Parallel()
+	store, err := NewStore(filepath.Join(t.TempDir(), "records.json"), 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	t.Cleanup(func() { store.Stop() })
+
+	auth := &Auth{
+		Tokens:    map[string]string{"tok-a": "tenant-a", "tok-b": "tenant-b"},
+		Ownership: true,
+	}
+	h := NewAPIServer(store, auth, ":0", nil).handler()
+
+	do := func(method, path, token string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		var buf bytes.Buffer
+		if body != nil {
+			_ = json.NewEncoder(&buf).Encode(body)
+		}
+		req := httptest.NewRequest(method, path, &buf)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodPost, "/api/v1/records", "tok-b",
+		Record{Name: "b.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"}); rec.Code != http.StatusCreated {
+		t.Fatalf("tenant B create: status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+
+	rec := do(http.MethodGet, "/api/v1/records", "tok-a", nil)
+	var resp apiListResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if len(resp.Records) != 0 {
+		t.Errorf("tenant A sees %d records, want 0", len(resp.Records))
+	}
+
+	if rec := do(http.MethodDelete, "/api/v1/records/b.example.org.", "tok-a", nil); rec.Code != http.StatusNoContent {
+		t.Errorf("tenant A delete: status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if got := store.GetAll(t.Context(), "b.example.org."); len(got) != 1 {
+		t.Errorf("tenant B record removed by tenant A: %v", got)
+	}
+
+	if rec := do(http.MethodPut, "/api/v1/records", "tok-a",
+		Record{Name: "b.example.org.", Type: "A", TTL: 300, Value: "10.6.6.6"}); rec.Code != http.StatusForbidden {
+		t.Errorf("tenant A overwrite: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestAPI_Ownership_IgnoresClientOwner(t *testing.T) {
+	t.Parallel()
+	store, err := NewStore(filepath.Join(t.TempDir(), "records.json"), 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	t.Cleanup(func() { store.Stop() })
+
+	auth := &Auth{
+		Tokens:    map[string]string{"tok-a": "tenant-a", "tok-ops": "ops"},
+		Ownership: true,
+		Admins:    []string{"ops"},
+	}
+	h := NewAPIServer(store, auth, ":0", nil).handler()
+
+	do := func(method, token string, rec Record) {
+		t.Helper()
+		var buf bytes.Buffer
+		_ = json.NewEncoder(&buf).Encode(rec)
+		req := httptest.NewRequest(method, "/api/v1/records", &buf)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != http.StatusOK && w.Code != http.StatusCreated {
+			t.Fatalf("%s %s: status = %d; body = %s", method, token, w.Code, w.Body.String())
+		}
+	}
+	owner := func(name string) string {
+		t.Helper()
+		recs := store.GetAll(t.Context(), name)
+		if len(recs) != 1 {
+			t.Fatalf("GetAll(%s) = %v, want 1 record", name, recs)
+		}
+		return recs[0].Owner
+	}
+
+	// A tenant cannot file a record under another tenant's name.
+	do(http.MethodPost, "tok-a", Record{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1", Owner: "tenant-b"})
+	if got := owner("a.example.org."); got != "tenant-a" {
+		t.Errorf("tenant create: Owner = %q, want %q", got, "tenant-a")
+	}
+
+	// An admin creates records as itself and updates keep the owner.
+	do(http.MethodPost, "tok-ops", Record{Name: "ops.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2", Owner: "tenant-b"})
+	if got := owner("ops.example.org."); got != "ops" {
+		t.Errorf("admin create: Owner = %q, want %q", got, "ops")
+	}
+	do(http.MethodPut, "tok-ops", Record{Name: "a.example.org.", Type: "A", TTL: 600, Value: "10.0.0.1", Owner: "tenant-b"})
+	if got := owner("a.example.org."); got != "tenant-a" {
+		t.Errorf("admin update: Owner = %q, want %q", got, "tenant-a")
+	}
+}
+
+func TestAPI_NoOwnership_IgnoresClientOwner(t *testing.T) {
+	t.Parallel()
+	api, store := newTestAPIHandler(t)
+
+	body := `{"name":"app.example.org.","type":"A","ttl":300,"value":"10.0.0.1","owner":"team-a"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/records", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer test-token")
+	rec := httptest.NewRecorder()
+	api.handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d; body = %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	if got := store.GetAll(t.Context(), "app.example.org."); len(got) != 1 || got[0].Owner != "" {
+		t.Errorf("GetAll() = %v, want one record without owner", got)
+	}
+}
+
+func TestAPI_Quota_TooManyRequests(t *testing.T) {
+	t.Parallel()
+	store, err := NewStore(filepath.Join(t.TempDir(), "records.json"), 0,
+		WithQuotas(map[string]int{"tenant-a": 1}))
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	t.Cleanup(func() { store.Stop() })
+
+	auth := &Auth{
+		Tokens:    map[string]string{"tok-a": "tenant-a", "tok-b": "tenant-b"},
+		Ownership: true,
+	}
+	h := NewAPIServer(store, auth, ":0", nil).handler()
+
+	create := func(token, name string) int {
+		t.Helper()
+		var buf bytes.Buffer
+		_ = json.NewEncoder(&buf).Encode(Record{Name: name, Type: "A", TTL: 300, Value: "10.0.0.1"})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/records", &buf)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := create("tok-a", "a1.example.org."); code != http.StatusCreated {
+		t.Fatalf("first create: status = %d, want %d", code, http.StatusCreated)
+	}
+	if code := create("tok-a", "a2.example.org."); code != http.StatusTooManyRequests {
+		t.Errorf("create over quota: status = %d, want %d", code, http.StatusTooManyRequests)
+	}
+	if code := create("tok-b", "b1.example.org."); code != http.StatusCreated {
+		t.Errorf("other tenant create: status = %d, want %d", code, http.StatusCreated)
+	}
+}
+
+func TestAPI_ZoneLimit_TooManyRequests(t *testing.T) {
+	t.Parallel()
+	api, _ := newTestAPIHandler(t, WithZones("example.org.", "example.net."), WithMaxRecordsPerZone(1))
+	h := api.handler()
+
+	create := func(name string) int {
+		t.Helper()
+		body := `{"name":"` + name + `","type":"A","ttl":300,"value":"10.0.0.1"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/records", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test-token")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := create("a.example.org."); code != http.StatusCreated {
+		t.Fatalf("first create: status = %d, want %d", code, http.StatusCreated)
+	}
+	if code := create("b.example.org."); code != http.StatusTooManyRequests {
+		t.Errorf("create over zone cap: status = %d, want %d", code, http.StatusTooManyRequests)
+	}
+	if code := create("a.example.net."); code != http.StatusCreated {
+		t.Errorf("create in other zone: status = %d, want %d", code, http.StatusCreated)
+	}
+}
+
+func TestAPI_TenantPolicy(t *testing.T) {
+	t.Parallel()
+	store, err := NewStore(filepath.Join(t.TempDir(), "records.json"), 0,
+		WithTenantPolicies(map[string]TenantPolicy{
+			"tenant-a": {SyncPolicy: PolicyCreateOnly, DefaultTTL: 90},
+		}))
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	t.Cleanup(func() { store.Stop() })
+
+	auth := &Auth{
+		Tokens:    map[string]string{"tok-a": "tenant-a", "tok-b": "tenant-b"},
+		Ownership: true,
+	}
+	h := NewAPIServer(store, auth, ":0", nil).handler()
+
+	do := func(method, token string, rec Record) *httptest.ResponseRecorder {
+		t.Helper()
+		var buf bytes.Buffer
+		_ = json.NewEncoder(&buf).Encode(rec)
+		req := httptest.NewRequest(method, "/api/v1/records", &buf)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, tok := range []string{"tok-a", "tok-b"} {
+		name := tok + ".example.org."
+		w := do(http.MethodPost, tok, Record{Name: name, Type: "A", Value: "10.0.0.1"})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("%s create: status = %d, want %d", tok, w.Code, http.StatusCreated)
+		}
+		var got Record
+		_ = json.NewDecoder(w.Body).Decode(&got)
+		want := uint32(DefaultTTL)
+		if tok == "tok-a" {
+			want = 90
+		}
+		if got.TTL != want {
+			t.Errorf("%s create: TTL = %d, want %d", tok, got.TTL, want)
+		}
+	}
+
+	// Updates are denied for the create-only tenant only.
+	if w := do(http.MethodPut, "tok-a", Record{Name: "tok-a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); w.Code != http.StatusForbidden {
+		t.Errorf("tenant A update: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if w := do(http.MethodPut, "tok-b", Record{Name: "tok-b.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); w.Code != http.StatusOK {
+		t.Errorf("tenant B update: status = %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestAPI_ImportZone(t *testing.T) {
+	t.Parallel()
+	api, store := newTestAPIHandler(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/zones/example.org./import", strings.NewReader(testZoneFile))
+	req.Header.Set("Authorization", "Bearer test-token")
+	req.Header.Set("Content-Type", "text/dns")
+	rec := httptest.NewRecorder()
+
+	api.handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d; body = %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var resp ZoneImportResult
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if resp.Imported != 3 || resp.Skipped != 2 || len(resp.Unsupported) != 2 {
+		t.Errorf("response = %+v, want 3 imported and 2 unsupported", resp)
+	}
+	if !strings.Contains(resp.Unsupported[1], "SSHFP") {
+		t.Errorf("unsupported = %v, want the SSHFP RR reported", resp.Unsupported)
+	}
+	if got := store.Get(t.Context(), "mail.example.org.", "A"); len(got) != 1 {
+		t.Errorf("mail.example.org. A = %v, want 1 record", got)
+	}
+}
+
+func TestAPI_ImportZone_Rejected(t *testing.T) {
+	t.Parallel()
+	api, _ := newTestAPIHandler(t)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        int
+	}{
+		{name: "wrong content type", contentType: "application/json", body: testZoneFile, want: http.StatusUnsupportedMediaType},
+		{name: "parse error", contentType: "text/dns", body: "www IN A bogus\n", want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/zones/example.org./import", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer test-token")
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+
+			api.handler().ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d; body = %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestAPI_TTLWindow(t *testing.T) {
+	t.Parallel()
+	api, store := newTestAPIHandler(t)
+	if err := store.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 3600, Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+
+	restoreAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{name: "name and suffix", body: `{"name":"a.example.org.","suffix":"example.org.","ttl":60,"restore_at":"` + restoreAt.Format(time.RFC3339) + `"}`, want: http.StatusBadRequest},
+		{name: "ttl below minimum", body: `{"suffix":"example.org.","ttl":5,"restore_at":"` + restoreAt.Format(time.RFC3339) + `"}`, want: http.StatusBadRequest},
+		{name: "restore in the past", body: `{"suffix":"example.org.","ttl":60,"restore_at":"2001-01-01T00:00:00Z"}`, want: http.StatusBadRequest},
+		{name: "valid", body: `{"suffix":"example.org.","ttl":60,"restore_at":"` + restoreAt.Format(time.RFC3339) + `"}`, want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/ttl-window", strings.NewReader(tt.body))
+		req.Header.Set("Authorization", "Bearer test-token")
+		rec := httptest.NewRecorder()
+
+		api.handler().ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d; body = %s", tt.name, rec.Code, tt.want, rec.Body.String())
+		}
+	}
+
+	got := store.Get(t.Context(), "app.example.org.", "A")[0]
+	if got.TTL != 60 || got.TTLWindow.OriginalTTL != 3600 || !got.TTLWindow.RestoreAt.Equal(restoreAt) {
+		t.Errorf("record = TTL %d window %+v, want TTL 60 restoring 3600 at %v", got.TTL, got.TTLWindow, restoreAt)
+	}
+}
+
+func postBatch(t *testing.T, api *APIServer, body string) (int, apiBatchResponse) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/records:batch", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer test-token")
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	api.handler().ServeHTTP(rec, req)
+	var resp apiBatchResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	return rec.Code, resp
+}
+
+func TestAPI_Batch(t *testing.T) {
+	t.Parallel()
+	api, store := newTestAPIHandler(t)
+	if err := store.Upsert(t.Context(), Record{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	gen := store.Generation()
+
+	code, resp := postBatch(t, api, `[
+		{"name": "a.example.org.", "type": "A", "ttl": 600, "value": "10.0.0.1"},
+		{"name": "b.example.org.", "type": "a", "value": "10.0.0.2"},
+		{"name": "c.example.org.", "type": "MX", "ttl": 300, "value": "mail.example.org.", "priority": 10}
+	]`)
+	if code != http.StatusOK {
+		t.Fatalf("status = %d, want %d; resp = %+v", code, http.StatusOK, resp)
+	}
+	wantStatus := []int{http.StatusOK, http.StatusCreated, http.StatusCreated}
+	for i, r := range resp.Results {
+		if r.Index != i || r.Status != wantStatus[i] || r.Record == nil {
+			t.Errorf("result %d = %+v, want status %d with the record", i, r, wantStatus[i])
+		}
+	}
+	if resp.Results[1].Record.Type != "A" || resp.Results[1].Record.TTL != DefaultTTL {
+		t.Errorf("result 1 record = %+v, want normalised type and default TTL", resp.Results[1].Record)
+	}
+	if got := store.Generation(); got != gen+1 {
+		t.Errorf("generation moved from %d to %d, want a single persisted mutation", gen, got)
+	}
+	if n := len(store.List(t.Context())); n != 3 {
+		t.Errorf("store holds %d records, want 3", n)
+	}
+}
+
+func TestAPI_Batch_InvalidRejectedAtomically(t *testing.T) {
+	t.Parallel()
+	api, store := newTestAPIHandler(t)
+	gen := store.Generation()
+
+	code, resp := postBatch(t, api, `[
+		{"name": "a.example.org.", "type": "A", "ttl": 300, "value": "10.0.0.1"},
+		{"name": "b.example.org.", "type": "A", "ttl": 300, "value": "not-an-ip"},
+		{"name": "c.example.org.", "type": "BOGUS", "ttl": 300, "value": "x"}
+	]`)
+	if code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", code, http.StatusBadRequest)
+	}
+	wantStatus := []int{http.StatusFailedDependency, http.StatusBadRequest, http.StatusBadRequest}
+	for i, r := range resp.Results {
+		if r.Status != wantStatus[i] || r.Error == "" {
+			t.Errorf("result %d = %+v, want status %d with an error", i, r, wantStatus[i])
+		}
+	}
+	if n := len(store.List(t.Context())); n != 0 {
+		t.Errorf("store holds %d records after a rejected batch, want 0", n)
+	}
+	if store.Generation() != gen {
+		t.Errorf("generation moved on a rejected batch")
+	}
+}
+
+func TestAPI_Batch_PolicyRejectedAtomically(t *testing.T) {
+	t.Parallel()
+	api, store := newTestAPIHandler(t, WithSyncPolicy(PolicyCreateOnly))
+	if err := store.Upsert(t.Context(), Record{Name: "b.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+
+	code, resp := postBatch(t, api, `[
+		{"name": "a.example.org.", "type": "A", "ttl": 300, "value": "10.0.0.1"},
+		{"name": "b.example.org.", "type": "A", "ttl": 600, "value": "10.0.0.2"}
+	]`)
+	if code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", code, http.StatusForbidden)
+	}
+	if resp.Results[0].Status != http.StatusFailedDependency || resp.Results[1].Status != http.StatusForbidden {
+		t.Errorf("results = %+v, want [424 403]", resp.Results)
+	}
+	if got := store.Get(t.Context(), "a.example.org.", "A"); len(got) != 0 {
+		t.Errorf("a.example.org. = %v, want the batch rolled back", got)
+	}
+}
+
+func TestAPI_ReplaceName(t *testing.T) {
+	t.Parallel()
+	api, store := newTestAPIHandler(t)
+	for _, r := range []Record{
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"},
+		{Name: "app.example.org.", Type: "TXT", TTL: 300, Value: "old"},
+	} {
+		if err := store.Upsert(t.Context(), r); err != nil {
+			t.Fatalf("Upsert() error: %v", err)
+		}
+	}
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/records/app.example.org.", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test-token")
+		rec := httptest.NewRecorder()
+		api.handler().ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := put(`[{"type": "A", "ttl": 300, "value": "10.0.0.2"}, {"type": "A", "ttl": 300, "value": "10.0.0.3"}]`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d; body = %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var resp apiListResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if len(resp.Records) != 2 || resp.Records[0].Value != "10.0.0.2" || resp.Records[1].Value != "10.0.0.3" {
+		t.Errorf("records = %v, want exactly the two new A records", resp.Records)
+	}
+
+	// An invalid replacement is rejected before anything changes.
+	if rec := put(`[{"type": "A", "ttl": 300, "value": "10.0.0.4"}, {"type": "A", "ttl": 300, "value": "bad"}]`); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid replace status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec := put(`[{"name": "other.example.org.", "type": "A", "ttl": 300, "value": "10.0.0.4"}]`); rec.Code != http.StatusBadRequest {
+		t.Errorf("mismatched name status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if got := store.GetAll(t.Context(), "app.example.org."); len(got) != 2 {
+		t.Errorf("records after rejected replaces = %v, want the 2 from the first replace", got)
+	}
+}
+
+func TestAPI_ReplaceName_NeedsDeleteScope(t *testing.T) {
+	t.Parallel()
+	s := newTestStore(t, filepath.Join(t.TempDir(), "records.json"))
+	auth := &Auth{
+		Tokens: map[string]string{"tok-ci": "ci", "tok-ops": "ops"},
+		Scopes: map[string][]Scope{
+			"ci":  {ScopeRead, ScopeWrite},
+			"ops": {ScopeWrite, ScopeDelete},
+		},
+	}
+	api := NewAPIServer(s, auth, ":0", nil)
+	if err := s.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+
+	put := func(token, body string) int {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/records/app.example.org.", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		api.handler().ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := put("tok-ci", `[]`); code != http.StatusForbidden {
+		t.Errorf("write-only PUT [] status = %d, want %d", code, http.StatusForbidden)
+	}
+	if code := put("tok-ci", `[{"type": "A", "ttl": 300, "value": "10.0.0.2"}]`); code != http.StatusForbidden {
+		t.Errorf("write-only replace dropping a value status = %d, want %d", code, http.StatusForbidden)
+	}
+	if got := s.GetAll(t.Context(), "app.example.org."); len(got) != 1 || got[0].Value != "10.0.0.1" {
+		t.Errorf("records after refused replaces = %v, want the original", got)
+	}
+
+	// Keeping every existing value needs no delete scope.
+	if code := put("tok-ci", `[{"type": "A", "ttl": 600, "value": "10.0.0.1"}, {"type": "A", "ttl": 300, "value": "10.0.0.2"}]`); code != http.StatusOK {
+		t.Errorf("write-only additive replace status = %d, want %d", code, http.StatusOK)
+	}
+	if code := put("tok-ops", `[]`); code != http.StatusOK {
+		t.Errorf("delete-scoped PUT [] status = %d, want %d", code, http.StatusOK)
+	}
+	if got := s.GetAll(t.Context(), "app.example.org."); len(got) != 0 {
+		t.Errorf("records after PUT [] = %v, want none", got)
+	}
+}
+
+func TestAPI_Ready(t *testing.T) {
+	t.Parallel()
+	api, store := newTestAPIHandler(t)
+	if err := store.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+
+	// No Authorization header: readiness is public.
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/ready", nil)
+	rec := httptest.NewRecorder()
+	api.handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d; body = %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var st StoreStatus
+	if err := json.NewDecoder(rec.Body).Decode(&st); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	want := StoreStatus{Ready: true, Loaded: true, Records: 1, Generation: store.Generation(), BackendConnected: true, LastReloadOK: true}
+	if st != want {
+		t.Errorf("status = %+v, want %+v", st, want)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/ready?wait=soon", nil)
+	rec = httptest.NewRecorder()
+	api.handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid wait: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestAPI_Ready_Wait(t *testing.T) {
+	t.Parallel()
+	fb := &flakyBackend{Backend: NewFileBackend(filepath.Join(t.TempDir(), "records.json")), failSaves: 1}
+	store, err := NewStoreWithBackend(fb, 0)
+	if err != nil {
+		t.Fatalf("NewStoreWithBackend() error: %v", err)
+	}
+	t.Cleanup(store.Stop)
+	api := NewAPIServer(store, &Auth{Token: "test-token"}, ":0", nil)
+
+	// A failed write leaves the backend marked disconnected.
+	if err := store.Upsert(t.Context(), Record{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err == nil {
+		t.Fatal("Upsert() with failing backend: expected error")
+	}
+
+	get := func(wait string) (int, StoreStatus) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/ready?wait="+wait, nil)
+		rec := httptest.NewRecorder()
+		api.handler().ServeHTTP(rec, req)
+		var st StoreStatus
+		if err := json.NewDecoder(rec.Body).Decode(&st); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return rec.Code, st
+	}
+
+	code, st := get("20ms")
+	if code != http.StatusServiceUnavailable || st.BackendConnected || st.BackendError == "" {
+		t.Fatalf("while failing: status %d, %+v; want 503 with a backend error", code, st)
+	}
+
+	// The next successful write makes the store ready while the request waits.
+	time.AfterFunc(50*time.Millisecond, func() {
+		_ = store.Upsert(t.Context(), Record{Name: "b.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"})
+	})
+	start := time.Now()
+	code, st = get("5s")
+	if code != http.StatusOK || !st.Ready {
+		t.Fatalf("after recovery: status %d, %+v; want 200 and ready", code, st)
+	}
+	if elapsed := time.Since(start); elapsed > 4*time.Second {
+		t.Errorf("wait returned after %v, want soon after recovery", elapsed)
+	}
+}
+
+func TestAPI_Probes(t *testing.T) {
+	t.Parallel()
+	api, store := newTestAPIHandler(t)
+	h := api.handler()
+
+	probe := func(path string) int {
+		// No Authorization header: probes cannot carry credentials.
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+
+	// Simulate a store still loading its records.
+	store.ready = false
+	for _, path := range []string{"/healthz", "/readyz"} {
+		if code := probe(path); code != http.StatusServiceUnavailable {
+			t.Errorf("GET %s before load = %d, want %d", path, code, http.StatusServiceUnavailable)
+		}
+	}
+
+	store.ready = true
+	for _, path := range []string{"/healthz", "/readyz"} {
+		if code := probe(path); code != http.StatusOK {
+			t.Errorf("GET %s after load = %d, want %d", path, code, http.StatusOK)
+		}
+	}
+
+	// A failing backend takes the instance out of rotation but keeps it live.
+	store.setBackendErr(errors.New("backend down"))
+	if code := probe("/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("GET /readyz with backend down = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+	if code := probe("/healthz"); code != http.StatusOK {
+		t.Errorf("GET /healthz with backend down = %d, want %d", code, http.StatusOK)
+	}
+	store.setBackendErr(nil)
+	if code := probe("/api/v1/records"); code != http.StatusUnauthorized {
+		t.Errorf("GET /api/v1/records without token = %d, want %d", code, http.StatusUnauthorized)
+	}
+}
+
+func TestAPI_Metrics(t *testing.T) {
+	t.Parallel()
+	api, store := newTestAPIHandler(t)
+	if err := store.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	get := func(api *APIServer) *httptest.ResponseRecorder {
+		// No Authorization header: scrapers are not given API credentials.
+		rec := httptest.NewRecorder()
+		api.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		return rec
+	}
+
+	if rec := get(api); rec.Code != http.StatusUnauthorized {
+		t.Errorf("GET /metrics without the metrics option = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	WithMetrics()(api)
+	rec := get(api)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /metrics = %d, want %d", rec.Code, http.StatusOK)
+	}
+	for _, series := range []string{"coredns_dynupdate_store_records{type=\"A\"}", "coredns_dynupdate_store_bytes"} {
+		if !strings.Contains(rec.Body.String(), series) {
+			t.Errorf("GET /metrics body lacks %s", series)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// ABOUTME: Append-only audit log of store mutations, one JSON object per changed record.
+// ABOUTME: Each entry carries the time, the operation, the record and the authenticated caller.
+
+package dynupdate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// AuditEntry is one line of the audit log.
+type AuditEntry struct {
+	Time time.Time `json:"time"`
+	// Op is "added", "updated" or "deleted".
+	Op string `json:"op"`
+	// Identity is the token name or certificate name the change was made
+	// under. It is empty for an unnamed token, without authentication, and
+	// for changes the plugin makes itself (such as removing expired records).
+	Identity string `json:"identity,omitempty"`
+	// Record is the record after the change, or as it was before removal.
+	Record Record `json:"record"`
+}
+
+// AuditLog appends AuditEntry lines to a file. It is safe for concurrent
+// use.
+type AuditLog struct {
+	mu  sync.Mutex
+	f   *os.File
+	enc *json.Encoder
+}
+
+// OpenAuditLog opens path for appending, creating it with mode 0600 if it
+// does not exist.
+func OpenAuditLog(path string) (*AuditLog, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("opening audit log: %w", err)
+	}
+	return &AuditLog{f: f, enc: json.NewEncoder(f)}, nil
+}
+
+// write appends one entry per event. A failed write is logged and does not
+// undo the mutation, which is already persisted.
+func (a *AuditLog) write(at time.Time, identity string, events []ChangeEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, ev := range events {
+		entry := AuditEntry{Time: at.UTC(), Op: ev.Op.String(), Identity: identity, Record: ev.Record}
+		if err := a.enc.Encode(entry); err != nil {
+			log.Errorf("audit log: %v", err)
+			return
+		}
+	}
+}
+
+// Close closes the underlying file.
+func (a *AuditLog) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.f.Close()
+}
+
+// auditPending writes the events of the mutation being committed to the
+// audit log, attributed to the caller in ctx. Caller must hold persistMu.
+func (s *Store) auditPending(ctx context.Context) {
+	if s.audit == nil || len(s.pending) == 0 {
+		return
+	}
+	identity, _ := IdentityFromContext(ctx)
+	s.audit.write(s.now(), identity.Name, s.pending)
+}
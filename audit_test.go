@@ -0,0 +1,155 @@
+// ABOUTME: Tests for the mutation audit log.
+// ABOUTME: Drives a create and a delete through REST and gRPC and parses the resulting JSON lines.
+
+package dynupdate
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/mauromedda/coredns-updater-plugin/proto"
+)
+
+// readAudit parses every entry in the audit log at path.
+func readAudit(t *testing.T, path string) []AuditEntry {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open audit log: %v", err)
+	}
+	defer f.Close()
+
+	var entries []AuditEntry
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var e AuditEntry
+		dec := json.NewDecoder(strings.NewReader(sc.Text()))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&e); err != nil {
+			t.Fatalf("audit line %q: %v", sc.Text(), err)
+		}
+		entries = append(entries, e)
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatalf("read audit log: %v", err)
+	}
+	return entries
+}
+
+func openTestAudit(t *testing.T) (*AuditLog, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "audit.log")
+	audit, err := OpenAuditLog(path)
+	if err != nil {
+		t.Fatalf("OpenAuditLog() error: %v", err)
+	}
+	return audit, path
+}
+
+func TestAudit_REST(t *testing.T) {
+	t.Parallel()
+	audit, path := openTestAudit(t)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	s, err := NewStore(filepath.Join(t.TempDir(), "records.json"), 0, WithAuditLog(audit), WithClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	t.Cleanup(s.Stop)
+	h := NewAPIServer(s, &Auth{Tokens: map[string]string{"ci-secret": "ci"}}, ":0", nil).handler()
+
+	do := func(method, target, body string) {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer ci-secret")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code >= 300 {
+			t.Fatalf("%s %s: status %d, body %s", method, target, rec.Code, rec.Body.String())
+		}
+	}
+	do(http.MethodPost, "/api/v1/records", `{"name":"app.example.org.","type":"A","ttl":300,"value":"10.0.0.1"}`)
+	do(http.MethodDelete, "/api/v1/records/app.example.org./A", "")
+
+	entries := readAudit(t, path)
+	if len(entries) != 2 {
+		t.Fatalf("got %d audit entries, want 2: %+v", len(entries), entries)
+	}
+	for i, want := range []string{"added", "deleted"} {
+		e := entries[i]
+		if e.Op != want || e.Identity != "ci" || !e.Time.Equal(now) {
+			t.Errorf("entry %d = %+v, want op %s by ci at %v", i, e, want, now)
+		}
+		if e.Record.Name != "app.example.org." || e.Record.Type != "A" || e.Record.Value != "10.0.0.1" {
+			t.Errorf("entry %d record = %+v, want app.example.org. A 10.0.0.1", i, e.Record)
+		}
+	}
+}
+
+func TestAudit_GRPC(t *testing.T) {
+	t.Parallel()
+	audit, path := openTestAudit(t)
+	client, _ := newTestGRPCClient(t, "grpc-secret", WithAuditLog(audit))
+	ctx := authCtx("grpc-secret")
+
+	if _, err := client.Upsert(ctx, &pb.UpsertRequest{Record: &pb.Record{Name: "app.example.org.", Type: "A", Ttl: 300, Value: "10.0.0.1"}}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	if _, err := client.Delete(ctx, &pb.DeleteRequest{Name: "app.example.org.", Type: "A", Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("Delete() error: %v", err)
+	}
+
+	entries := readAudit(t, path)
+	if len(entries) != 2 || entries[0].Op != "added" || entries[1].Op != "deleted" {
+		t.Fatalf("audit entries = %+v, want added then deleted", entries)
+	}
+	// The helper's single unnamed token carries no identity.
+	if entries[0].Identity != "" {
+		t.Errorf("identity = %q, want empty for an unnamed token", entries[0].Identity)
+	}
+}
+
+func TestAudit_NoEntryForNoop(t *testing.T) {
+	t.Parallel()
+	audit, path := openTestAudit(t)
+	_, s := newTestAPIHandler(t, WithAuditLog(audit))
+	if err := s.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	// Deleting a name that holds nothing changes nothing.
+	if err := s.Delete(t.Context(), "missing.example.org.", "A", ""); err != nil {
+		t.Fatalf("Delete() error: %v", err)
+	}
+	if entries := readAudit(t, path); len(entries) != 1 {
+		t.Errorf("got %d audit entries, want 1 for the only change", len(entries))
+	}
+}
+
+func TestAudit_NoEntryForFailedWrite(t *testing.T) {
+	t.Parallel()
+	audit, path := openTestAudit(t)
+	fb := &flakyBackend{Backend: NewFileBackend(filepath.Join(t.TempDir(), "records.json")), failSaves: 1}
+	s, err := NewStoreWithBackend(fb, 0, WithAuditLog(audit))
+	if err != nil {
+		t.Fatalf("NewStoreWithBackend() error: %v", err)
+	}
+	t.Cleanup(s.Stop)
+
+	if err := s.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err == nil {
+		t.Fatal("Upsert() with failing backend: expected error")
+	}
+	if entries := readAudit(t, path); len(entries) != 0 {
+		t.Errorf("failed write logged %v", entries)
+	}
+	if err := s.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	if entries := readAudit(t, path); len(entries) != 1 || entries[0].Record.Value != "10.0.0.2" {
+		t.Errorf("got audit entries %v, want only the persisted change", entries)
+	}
+}
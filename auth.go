@@ -1,4 +1,4 @@
-// ABOUTME: Dual-mode authentication: Bearer token + mTLS CN validation.
+// ABOUTME: Dual-mode authentication: Bearer token + mTLS certificate name (CN or SAN) validation.
 // ABOUTME: Provides HTTP middleware and gRPC unary interceptor for access control.
 
 package dynupdate
@@ -7,22 +7,133 @@ import (
 	"context"
 	"crypto/subtle"
 	"crypto/tls"
+	"crypto/x509"
+	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 
+	pb "github.com/mauromedda/coredns-updater-plugin/proto"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/peer"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionpbalpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
 	"google.golang.org/grpc/status"
 )
 
 // Auth holds authentication configuration for the management APIs.
 type Auth struct {
 	Token     string
-	AllowedCN []string
+	Tokens    map[string]string // secret -> identity name
+	AllowedCN []string          // matched against the client certificate CN and SAN DNS names; "*.SUFFIX" matches one label
 	NoAuth    bool
+
+	// Ownership attaches the authenticated identity (token name or client
+	// certificate CN) to the request context as its Owner. Identities listed
+	// in Admins are not scoped.
+	Ownership bool
+	Admins    []string
+
+	// Scopes limits what each identity (token name or CN) may do. Identities
+	// without an entry hold every scope, so credentials configured without
+	// one keep full access.
+	Scopes map[string][]Scope
+}
+
+// Scope is a permission granted to an authenticated identity.
+type Scope string
+
+const (
+	// ScopeRead allows listing, getting and watching records.
+	ScopeRead Scope = "read"
+	// ScopeWrite allows creating, updating and importing records.
+	ScopeWrite Scope = "write"
+	// ScopeDelete allows deleting records.
+	ScopeDelete Scope = "delete"
+	// ScopeAdmin allows everything, including the /api/v1/admin endpoints.
+	ScopeAdmin Scope = "admin"
+)
+
+// ParseScope parses "read", "write", "delete" or "admin".
+func ParseScope(s string) (Scope, error) {
+	switch sc := Scope(strings.ToLower(s)); sc {
+	case ScopeRead, ScopeWrite, ScopeDelete, ScopeAdmin:
+		return sc, nil
+	default:
+		return "", fmt.Errorf("unknown scope %q: valid values are read, write, delete, admin", s)
+	}
+}
+
+// permits reports whether identity holds the scope need.
+func (a *Auth) permits(identity string, need Scope) bool {
+	scopes, ok := a.scopesFor(identity)
+	if !ok {
+		return true
+	}
+	return slices.Contains(scopes, need) || slices.Contains(scopes, ScopeAdmin)
+}
+
+// allows reports whether the authenticated caller in ctx holds the scope
+// need. Handlers use it where the scope depends on more than the method.
+func (a *Auth) allows(ctx context.Context, need Scope) bool {
+	if !a.authRequired() {
+		return true
+	}
+	identity, _ := IdentityFromContext(ctx)
+	return a.permits(identity.Name, need)
+}
+
+// scopesFor returns the scopes configured for identity, either directly or
+// through a wildcard entry such as "*.clients.example.org".
+func (a *Auth) scopesFor(identity string) ([]Scope, bool) {
+	if scopes, ok := a.Scopes[identity]; ok {
+		return scopes, true
+	}
+	for pattern, scopes := range a.Scopes {
+		if strings.HasPrefix(pattern, "*.") && matchName(pattern, identity) {
+			return scopes, true
+		}
+	}
+	return nil, false
+}
+
+// httpScope returns the scope an HTTP request needs: admin for the admin
+// endpoints, otherwise read for GET and HEAD, delete for DELETE and write
+// for everything else. A PUT replacing a name also needs delete if it
+// removes records, which handleReplace checks against the stored records.
+func httpScope(r *http.Request) Scope {
+	if strings.HasPrefix(r.URL.Path, "/api/v1/admin/") {
+		return ScopeAdmin
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		return ScopeRead
+	case http.MethodDelete:
+		return ScopeDelete
+	default:
+		return ScopeWrite
+	}
+}
+
+// grpcScope returns the scope a gRPC method needs. Methods not listed need
+// admin, so an RPC added later is closed to scoped identities until mapped.
+func grpcScope(fullMethod string) Scope {
+	switch fullMethod {
+	case pb.DynUpdateService_List_FullMethodName, pb.DynUpdateService_ListStream_FullMethodName,
+		pb.DynUpdateService_Get_FullMethodName, pb.DynUpdateService_Watch_FullMethodName,
+		reflectionpb.ServerReflection_ServerReflectionInfo_FullMethodName,
+		reflectionpbalpha.ServerReflection_ServerReflectionInfo_FullMethodName:
+		return ScopeRead
+	case pb.DynUpdateService_Upsert_FullMethodName, pb.DynUpdateService_Import_FullMethodName:
+		return ScopeWrite
+	case pb.DynUpdateService_Delete_FullMethodName, pb.DynUpdateService_DeleteByType_FullMethodName, pb.DynUpdateService_DeleteBySuffix_FullMethodName:
+		return ScopeDelete
+	default:
+		return ScopeAdmin
+	}
 }
 
 // authRequired returns true unless the operator has explicitly opted out with no_auth.
@@ -31,7 +142,8 @@ func (a *Auth) authRequired() bool {
 }
 
 // HTTPMiddleware returns an http.Handler that validates Bearer token or mTLS CN
-// before calling next.
+// before calling next. An authenticated caller lacking the scope the request
+// needs gets 403.
 func (a *Auth) HTTPMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if !a.authRequired() {
@@ -39,69 +151,217 @@ func (a *Auth) HTTPMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		// Try Bearer token
-		if a.Token != "" {
-			if token := extractBearerHTTP(r); token != "" {
-				if constantTimeEqual(token, a.Token) {
-					next.ServeHTTP(w, r)
-					return
-				}
-				http.Error(w, "unauthorized", http.StatusUnauthorized)
-				return
-			}
+		identity, ok := a.authenticateHTTP(r)
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if need := httpScope(r); !a.permits(identity.Name, need) {
+			http.Error(w, fmt.Sprintf("forbidden: %s scope required", need), http.StatusForbidden)
+			return
 		}
+		next.ServeHTTP(w, r.WithContext(a.callerContext(r.Context(), identity)))
+	})
+}
 
-		// Try mTLS CN
-		if len(a.AllowedCN) > 0 {
-			if cn := extractCNFromTLS(r.TLS); cn != "" {
-				if a.cnAllowed(cn) {
-					next.ServeHTTP(w, r)
-					return
-				}
-			}
+// authenticateHTTP validates the caller of an HTTP request and returns its
+// identity.
+func (a *Auth) authenticateHTTP(r *http.Request) (Identity, bool) {
+	// Try Bearer token
+	if a.hasTokens() {
+		if token := extractBearerHTTP(r); token != "" {
+			name, ok := a.matchToken(token)
+			return Identity{Kind: IdentityToken, Name: name}, ok
 		}
+	}
 
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
-	})
+	// Try mTLS CN or SAN
+	if len(a.AllowedCN) > 0 {
+		name, ok := a.certIdentity(leafCert(r.TLS))
+		return Identity{Kind: IdentityCertificate, Name: name}, ok
+	}
+
+	return Identity{}, false
 }
 
 // UnaryInterceptor is a gRPC interceptor that validates Bearer token or mTLS CN.
-func (a *Auth) UnaryInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
-	if !a.authRequired() {
-		return handler(ctx, req)
+func (a *Auth) UnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	method := ""
+	if info != nil {
+		method = info.FullMethod
+	}
+	ctx, err := a.authorizeGRPC(ctx, method)
+	if err != nil {
+		return nil, err
 	}
+	return handler(ctx, req)
+}
 
+// StreamInterceptor applies the same checks as UnaryInterceptor to streaming RPCs.
+func (a *Auth) StreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	method := ""
+	if info != nil {
+		method = info.FullMethod
+	}
+	ctx, err := a.authorizeGRPC(ss.Context(), method)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authedStream{ServerStream: ss, ctx: ctx})
+}
+
+// authorizeGRPC authenticates the caller of a gRPC request, checks it holds
+// the scope method needs, and returns the context the handler should run
+// with.
+func (a *Auth) authorizeGRPC(ctx context.Context, method string) (context.Context, error) {
+	// Health probes come from orchestrators that carry no credentials.
+	if !a.authRequired() || isHealthMethod(method) {
+		return ctx, nil
+	}
+
+	identity, err := a.authenticateGRPC(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if need := grpcScope(method); !a.permits(identity.Name, need) {
+		return nil, status.Errorf(codes.PermissionDenied, "%s scope required", need)
+	}
+	return a.callerContext(ctx, identity), nil
+}
+
+// authenticateGRPC validates the caller of a gRPC request and returns its
+// identity.
+func (a *Auth) authenticateGRPC(ctx context.Context) (Identity, error) {
 	// Try Bearer token from metadata
-	if a.Token != "" {
+	if a.hasTokens() {
 		if token := extractBearerGRPC(ctx); token != "" {
-			if constantTimeEqual(token, a.Token) {
-				return handler(ctx, req)
+			if name, ok := a.matchToken(token); ok {
+				return Identity{Kind: IdentityToken, Name: name}, nil
 			}
-			return nil, status.Error(codes.Unauthenticated, "invalid token")
+			return Identity{}, status.Error(codes.Unauthenticated, "invalid token")
 		}
 	}
 
-	// Try mTLS CN from peer
+	// Try mTLS CN or SAN from peer
 	if len(a.AllowedCN) > 0 {
-		if cn := extractCNFromPeer(ctx); cn != "" {
-			if a.cnAllowed(cn) {
-				return handler(ctx, req)
-			}
+		if name, ok := a.certIdentity(peerCert(ctx)); ok {
+			return Identity{Kind: IdentityCertificate, Name: name}, nil
+		}
+	}
+
+	return Identity{}, status.Error(codes.Unauthenticated, "authentication required")
+}
+
+// authedStream overrides the context of a server stream with the one
+// carrying the caller's identity.
+type authedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authedStream) Context() context.Context { return s.ctx }
+
+func (a *Auth) hasTokens() bool {
+	return a.Token != "" || len(a.Tokens) > 0
+}
+
+// matchToken reports whether token is a configured secret and returns the
+// identity it is bound to. Every secret is compared so timing does not
+// reveal which one matched.
+func (a *Auth) matchToken(token string) (identity string, ok bool) {
+	if a.Token != "" && constantTimeEqual(token, a.Token) {
+		ok = true
+	}
+	for secret, name := range a.Tokens {
+		if constantTimeEqual(token, secret) {
+			identity, ok = name, true
 		}
 	}
+	return identity, ok
+}
+
+// callerContext attaches identity to the request context, and its name as
+// the request Owner when ownership is enabled.
+func (a *Auth) callerContext(ctx context.Context, identity Identity) context.Context {
+	ctx = ContextWithIdentity(ctx, identity)
+	if !a.Ownership {
+		return ctx
+	}
+	return ContextWithOwner(ctx, Owner{Name: identity.Name, Admin: slices.Contains(a.Admins, identity.Name)})
+}
+
+// IdentityKind is how a caller authenticated.
+type IdentityKind string
+
+const (
+	// IdentityToken is a caller that presented a Bearer token.
+	IdentityToken IdentityKind = "token"
+	// IdentityCertificate is a caller that presented an accepted client
+	// certificate.
+	IdentityCertificate IdentityKind = "certificate"
+)
+
+// Identity is the authenticated caller of a management request.
+type Identity struct {
+	Kind IdentityKind
+	// Name is the token name, or the certificate CN or SAN DNS name that
+	// matched AllowedCN. It is empty for the unnamed Token.
+	Name string
+}
+
+type identityKey struct{}
+
+// ContextWithIdentity returns a context carrying identity.
+func ContextWithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext returns the identity HTTPMiddleware or the gRPC
+// interceptors attached to ctx. ok is false when authentication is disabled,
+// and for gRPC health checks, which are not authenticated.
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(Identity)
+	return identity, ok
+}
 
-	return nil, status.Error(codes.Unauthenticated, "authentication required")
+// certIdentity returns the name a client certificate is accepted under:
+// its CN if AllowedCN matches it, otherwise the first matching SAN DNS name.
+func (a *Auth) certIdentity(cert *x509.Certificate) (string, bool) {
+	if cert == nil {
+		return "", false
+	}
+	if cn := cert.Subject.CommonName; cn != "" && a.cnAllowed(cn) {
+		return cn, true
+	}
+	for _, name := range cert.DNSNames {
+		if a.cnAllowed(name) {
+			return name, true
+		}
+	}
+	return "", false
 }
 
-func (a *Auth) cnAllowed(cn string) bool {
+// cnAllowed reports whether name matches an AllowedCN entry.
+func (a *Auth) cnAllowed(name string) bool {
 	for _, allowed := range a.AllowedCN {
-		if allowed == cn {
+		if matchName(allowed, name) {
 			return true
 		}
 	}
 	return false
 }
 
+// matchName reports whether name matches pattern: either the same name, or
+// for a pattern "*.SUFFIX", a name made of exactly one label followed by
+// SUFFIX, as in TLS wildcard certificates.
+func matchName(pattern, name string) bool {
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		label, rest, found := strings.Cut(name, ".")
+		return found && label != "" && rest == suffix
+	}
+	return pattern == name
+}
+
 func extractBearerHTTP(r *http.Request) string {
 	h := r.Header.Get("Authorization")
 	if !strings.HasPrefix(h, "Bearer ") {
@@ -126,23 +386,25 @@ func extractBearerGRPC(ctx context.Context) string {
 	return strings.TrimPrefix(h, "Bearer ")
 }
 
-func extractCNFromTLS(state *tls.ConnectionState) string {
+// leafCert returns the client certificate of a TLS connection, or nil.
+func leafCert(state *tls.ConnectionState) *x509.Certificate {
 	if state == nil || len(state.PeerCertificates) == 0 {
-		return ""
+		return nil
 	}
-	return state.PeerCertificates[0].Subject.CommonName
+	return state.PeerCertificates[0]
 }
 
-func extractCNFromPeer(ctx context.Context) string {
+// peerCert returns the client certificate of a gRPC peer, or nil.
+func peerCert(ctx context.Context) *x509.Certificate {
 	p, ok := peer.FromContext(ctx)
 	if !ok || p.AuthInfo == nil {
-		return ""
+		return nil
 	}
 	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
 	if !ok {
-		return ""
+		return nil
 	}
-	return extractCNFromTLS(&tlsInfo.State)
+	return leafCert(&tlsInfo.State)
 }
 
 func constantTimeEqual(a, b string) bool {
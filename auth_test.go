@@ -12,6 +12,8 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	pb "github.com/mauromedda/coredns-updater-plugin/proto"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
@@ -277,3 +279,299 @@ func TestAuth_GRPCInterceptor_NoAuth(t *testing.T) {
 		t.Errorf("code = %v, want Unauthenticated", err)
 	}
 }
+
+func TestAuth_HTTPMiddleware_NamedTokenAttachesOwner(t *testing.T) {
+	t.Parallel()
+	auth := &Auth{
+		Tokens:    map[string]string{"tok-a": "tenant-a", "tok-root": "root"},
+		Ownership: true,
+		Admins:    []string{"root"},
+	}
+
+	var got Owner
+	var ok bool
+	handler := auth.HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok = OwnerFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		token string
+		want  Owner
+	}{
+		{token: "tok-a", want: Owner{Name: "tenant-a"}},
+		{token: "tok-root", want: Owner{Name: "root", Admin: true}},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/records", nil)
+		req.Header.Set("Authorization", "Bearer "+tt.token)
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, want %d", tt.token, rec.Code, http.StatusOK)
+		}
+		if !ok || got != tt.want {
+			t.Errorf("%s: owner = %+v (ok=%v), want %+v", tt.token, got, ok, tt.want)
+		}
+	}
+}
+
+func TestAuth_GRPCInterceptor_OwnershipDisabled_NoOwner(t *testing.T) {
+	t.Parallel()
+	auth := &Auth{Tokens: map[string]string{"tok-a": "tenant-a"}}
+
+	md := metadata.Pairs("authorization", "Bearer tok-a")
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+
+	_, err := auth.UnaryInterceptor(ctx, nil, nil, func(ctx context.Context, _ any) (any, error) {
+		if _, ok := OwnerFromContext(ctx); ok {
+			t.Error("owner attached with ownership disabled")
+		}
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("UnaryInterceptor() error: %v", err)
+	}
+}
+
+func TestAuth_HTTPMiddleware_Scopes(t *testing.T) {
+	t.Parallel()
+	auth := &Auth{
+		Tokens: map[string]string{"tok-mon": "monitor", "tok-ci": "ci", "tok-full": "full"},
+		Scopes: map[string][]Scope{
+			"monitor": {ScopeRead},
+			"ci":      {ScopeRead, ScopeWrite},
+		},
+	}
+	handler := auth.HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name   string
+		token  string
+		method string
+		path   string
+		want   int
+	}{
+		{name: "read-only GET", token: "tok-mon", method: http.MethodGet, path: "/api/v1/records", want: http.StatusOK},
+		{name: "read-only POST", token: "tok-mon", method: http.MethodPost, path: "/api/v1/records", want: http.StatusForbidden},
+		{name: "read-only DELETE", token: "tok-mon", method: http.MethodDelete, path: "/api/v1/records/a.example.org.", want: http.StatusForbidden},
+		{name: "write PATCH", token: "tok-ci", method: http.MethodPatch, path: "/api/v1/records/a.example.org./A", want: http.StatusOK},
+		{name: "write DELETE", token: "tok-ci", method: http.MethodDelete, path: "/api/v1/records/a.example.org.", want: http.StatusForbidden},
+		{name: "write admin endpoint", token: "tok-ci", method: http.MethodPost, path: "/api/v1/admin/ttl-window", want: http.StatusForbidden},
+		{name: "unscoped identity", token: "tok-full", method: http.MethodGet, path: "/api/v1/admin/config", want: http.StatusOK},
+		{name: "bad token still 401", token: "wrong", method: http.MethodPost, path: "/api/v1/records", want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuth_GRPCInterceptor_Scopes(t *testing.T) {
+	t.Parallel()
+	auth := &Auth{
+		Tokens: map[string]string{"tok-mon": "monitor"},
+		Scopes: map[string][]Scope{"monitor": {ScopeRead}},
+	}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer tok-mon"))
+	handler := func(ctx context.Context, _ any) (any, error) { return "ok", nil }
+
+	if _, err := auth.UnaryInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: pb.DynUpdateService_List_FullMethodName}, handler); err != nil {
+		t.Errorf("List: error = %v, want nil", err)
+	}
+	_, err := auth.UnaryInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: pb.DynUpdateService_Upsert_FullMethodName}, handler)
+	if s, ok := status.FromError(err); !ok || s.Code() != codes.PermissionDenied {
+		t.Errorf("Upsert: error = %v, want PermissionDenied", err)
+	}
+}
+
+func TestAuth_HTTPMiddleware_mTLS_SAN(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		allowed      []string
+		cert         *x509.Certificate
+		want         int
+		wantIdentity string
+	}{
+		{
+			name:         "empty CN, matching SAN",
+			allowed:      []string{"client.example.org"},
+			cert:         &x509.Certificate{DNSNames: []string{"other.example.org", "client.example.org"}},
+			want:         http.StatusOK,
+			wantIdentity: "client.example.org",
+		},
+		{
+			name:         "wildcard entry matches SAN",
+			allowed:      []string{"*.clients.example.org"},
+			cert:         &x509.Certificate{DNSNames: []string{"build-7.clients.example.org"}},
+			want:         http.StatusOK,
+			wantIdentity: "build-7.clients.example.org",
+		},
+		{
+			name:         "wildcard entry matches CN",
+			allowed:      []string{"*.clients.example.org"},
+			cert:         &x509.Certificate{Subject: pkix.Name{CommonName: "a.clients.example.org"}},
+			want:         http.StatusOK,
+			wantIdentity: "a.clients.example.org",
+		},
+		{
+			name:    "wildcard matches one label only",
+			allowed: []string{"*.clients.example.org"},
+			cert:    &x509.Certificate{DNSNames: []string{"a.b.clients.example.org", "clients.example.org"}},
+			want:    http.StatusUnauthorized,
+		},
+		{
+			name:    "no matching name",
+			allowed: []string{"client.example.org"},
+			cert:    &x509.Certificate{Subject: pkix.Name{CommonName: "rogue.example.org"}, DNSNames: []string{"rogue.example.org"}},
+			want:    http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			auth := &Auth{AllowedCN: tt.allowed, Ownership: true}
+
+			var identity string
+			handler := auth.HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				o, _ := OwnerFromContext(r.Context())
+				identity = o.Name
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/records", nil)
+			req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{tt.cert}}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if identity != tt.wantIdentity {
+				t.Errorf("identity = %q, want %q", identity, tt.wantIdentity)
+			}
+		})
+	}
+}
+
+func TestAuth_Scopes_WildcardIdentity(t *testing.T) {
+	t.Parallel()
+	auth := &Auth{
+		AllowedCN: []string{"*.clients.example.org"},
+		Scopes:    map[string][]Scope{"*.clients.example.org": {ScopeRead}},
+	}
+	if !auth.permits("a.clients.example.org", ScopeRead) {
+		t.Error("read denied, want permitted through the wildcard scope")
+	}
+	if auth.permits("a.clients.example.org", ScopeWrite) {
+		t.Error("write permitted, want denied by the wildcard scope")
+	}
+}
+
+func TestAuth_HTTPMiddleware_AttachesIdentity(t *testing.T) {
+	t.Parallel()
+	auth := &Auth{
+		Tokens:    map[string]string{"tok-a": "tenant-a"},
+		AllowedCN: []string{"*.clients.example.org"},
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		cert  *x509.Certificate
+		want  Identity
+	}{
+		{name: "token", token: "tok-a", want: Identity{Kind: IdentityToken, Name: "tenant-a"}},
+		{name: "certificate CN", cert: &x509.Certificate{Subject: pkix.Name{CommonName: "ci.clients.example.org"}}, want: Identity{Kind: IdentityCertificate, Name: "ci.clients.example.org"}},
+		{name: "certificate SAN", cert: &x509.Certificate{DNSNames: []string{"deploy.clients.example.org"}}, want: Identity{Kind: IdentityCertificate, Name: "deploy.clients.example.org"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var got Identity
+			var ok bool
+			handler := auth.HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got, ok = IdentityFromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/records", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			if tt.cert != nil {
+				req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{tt.cert}}
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+			}
+			if !ok || got != tt.want {
+				t.Errorf("identity = %+v (ok=%v), want %+v", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuth_GRPCInterceptor_AttachesIdentity(t *testing.T) {
+	t.Parallel()
+	auth := &Auth{
+		Tokens:    map[string]string{"tok-a": "tenant-a"},
+		AllowedCN: []string{"grpc-client.example.org"},
+	}
+
+	tokenCtx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer tok-a"))
+	certCtx := peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{
+		State: tls.ConnectionState{PeerCertificates: []*x509.Certificate{
+			{Subject: pkix.Name{CommonName: "grpc-client.example.org"}},
+		}},
+	}})
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want Identity
+	}{
+		{name: "token", ctx: tokenCtx, want: Identity{Kind: IdentityToken, Name: "tenant-a"}},
+		{name: "certificate", ctx: certCtx, want: Identity{Kind: IdentityCertificate, Name: "grpc-client.example.org"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := auth.UnaryInterceptor(tt.ctx, nil, nil, func(ctx context.Context, _ any) (any, error) {
+				if got, ok := IdentityFromContext(ctx); !ok || got != tt.want {
+					t.Errorf("identity = %+v (ok=%v), want %+v", got, ok, tt.want)
+				}
+				return nil, nil
+			})
+			if err != nil {
+				t.Fatalf("UnaryInterceptor() error: %v", err)
+			}
+		})
+	}
+}
+
+func TestAuth_NoAuth_NoIdentity(t *testing.T) {
+	t.Parallel()
+	auth := &Auth{NoAuth: true}
+	handler := auth.HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id, ok := IdentityFromContext(r.Context()); ok {
+			t.Errorf("identity = %+v attached without authentication", id)
+		}
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/records", nil))
+}
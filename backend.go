@@ -0,0 +1,187 @@
+// ABOUTME: Persistence interface behind the Store, plus the default JSON file implementation.
+// ABOUTME: Backends persist mutations and report external changes; reads are always served from memory.
+
+package dynupdate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Backend persists the records held by a Store. The Store keeps the full
+// record set in memory and enforces the sync policy, record limits and
+// ownership itself, so a backend only has to load and save records: DNS
+// queries and API reads never reach it. The Store serializes all calls.
+type Backend interface {
+	// Load returns every persisted record and the persisted generation.
+	// A backend with no data yet returns no records and generation 0.
+	Load(ctx context.Context) ([]Record, uint64, error)
+	// Save persists a single mutation.
+	Save(ctx context.Context, c Change) error
+	// Modified reports whether the persisted data was changed by another
+	// writer since the last Load or Save.
+	Modified(ctx context.Context) (bool, error)
+	// Close releases resources held by the backend.
+	Close() error
+}
+
+// backendName identifies b in metric labels: its String method if it has
+// one, otherwise its type.
+func backendName(b Backend) string {
+	if s, ok := b.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", b)
+}
+
+// Change describes one Store mutation.
+type Change struct {
+	// Generation is the store generation after the mutation.
+	Generation uint64
+	// Names maps each touched lowercase FQDN to its complete record set
+	// after the mutation; an empty set means the name was removed. Names is
+	// nil when the backend must rewrite everything from All.
+	Names map[string][]Record
+
+	all func() []Record
+}
+
+// All returns every record in the store after the mutation.
+func (c Change) All() []Record {
+	if c.all == nil {
+		return nil
+	}
+	return c.all()
+}
+
+// storeFile is the JSON envelope for persisted records.
+type storeFile struct {
+	Generation uint64   `json:"generation,omitempty"`
+	Records    []Record `json:"records"`
+}
+
+// FileBackend stores records in a single JSON file, rewritten atomically
+// (temp file + rename) on every mutation.
+type FileBackend struct {
+	path    string
+	lastMod time.Time // mtime after our last load or save
+	dirOK   bool      // the directory was created or found writable
+}
+
+// NewFileBackend returns a backend for the JSON file at path. The file, and
+// any missing parent directories, are created on first Load.
+func NewFileBackend(path string) *FileBackend {
+	return &FileBackend{path: path}
+}
+
+// Load reads the file, creating an empty one if it does not exist. The first
+// Load also makes sure the file's directory exists and is writable, so a
+// misconfigured datafile fails at startup rather than on every mutation.
+func (f *FileBackend) Load(_ context.Context) ([]Record, uint64, error) {
+	if !f.dirOK {
+		if err := prepareDataDir(filepath.Dir(f.path)); err != nil {
+			return nil, 0, err
+		}
+		f.dirOK = true
+	}
+
+	raw, err := os.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return nil, 0, f.write(storeFile{})
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("reading %s: %w", f.path, err)
+	}
+
+	var data storeFile
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, 0, fmt.Errorf("parsing JSON: %w", err)
+	}
+	storeBytesGauge.Set(float64(len(raw)))
+	f.stat()
+	return data.Records, data.Generation, nil
+}
+
+// Save rewrites the whole file from the change's full record set.
+func (f *FileBackend) Save(_ context.Context, c Change) error {
+	return f.write(storeFile{Generation: c.Generation, Records: c.All()})
+}
+
+// Modified reports whether the file's mtime moved past our last load or save.
+func (f *FileBackend) Modified(_ context.Context) (bool, error) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return false, fmt.Errorf("stat %s: %w", f.path, err)
+	}
+	return info.ModTime().After(f.lastMod), nil
+}
+
+// String returns "file:" and the file's path.
+func (f *FileBackend) String() string {
+	return "file:" + f.path
+}
+
+// Close is a no-op.
+func (f *FileBackend) Close() error {
+	return nil
+}
+
+// write persists data to the file atomically.
+func (f *FileBackend) write(data storeFile) error {
+	raw, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshalling store: %w", err)
+	}
+
+	dir := filepath.Dir(f.path)
+	tmp, err := os.CreateTemp(dir, "dynupdate-*.json.tmp")
+	if err != nil {
+		return fmt.Errorf("creating temp file: %w", err)
+	}
+	tmpName := tmp.Name()
+
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return fmt.Errorf("writing temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return fmt.Errorf("closing temp file: %w", err)
+	}
+
+	if err := os.Rename(tmpName, f.path); err != nil {
+		os.Remove(tmpName)
+		return fmt.Errorf("renaming temp to %s: %w", f.path, err)
+	}
+	storeBytesGauge.Set(float64(len(raw)))
+
+	f.stat()
+	return nil
+}
+
+// prepareDataDir creates dir if it is missing and checks that temp files,
+// which every write renames over the data file, can be created in it.
+func prepareDataDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating data directory: %w", err)
+	}
+	probe, err := os.CreateTemp(dir, "dynupdate-*.json.tmp")
+	if err != nil {
+		return fmt.Errorf("data directory %s is not writable: %w", dir, err)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	return nil
+}
+
+// stat records the file's current mtime so our own writes do not trigger a reload.
+func (f *FileBackend) stat() {
+	if info, err := os.Stat(f.path); err == nil {
+		f.lastMod = info.ModTime()
+	}
+}
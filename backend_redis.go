@@ -0,0 +1,156 @@
+// ABOUTME: Redis implementation of the Store Backend, for sharing records between replicas.
+// ABOUTME: Keeps one hash field per lowercase FQDN plus a version counter used to detect other writers.
+
+package dynupdate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Keys used by the Redis backend.
+const (
+	redisRecordsKey    = "dynupdate:records"    // hash: lowercase FQDN -> JSON record set
+	redisGenerationKey = "dynupdate:generation" // highest persisted store generation
+	redisVersionKey    = "dynupdate:version"    // incremented on every save
+)
+
+// redisMaxGeneration stores ARGV[1] in KEYS[1] unless the key already holds a
+// larger value, so a replica that is behind never moves the serial backwards.
+const redisMaxGeneration = `
+local cur = tonumber(redis.call('GET', KEYS[1]) or '0')
+if tonumber(ARGV[1]) > cur then
+	redis.call('SET', KEYS[1], ARGV[1])
+end
+return 0`
+
+// RedisBackend stores records in a Redis hash keyed by lowercase FQDN, each
+// field holding the JSON record set for that name. Every save increments a
+// version counter; a counter that moved without us means another replica
+// wrote, which Modified reports so the Store reloads.
+//
+// Replicas sharing a Redis instance resolve concurrent writes to the same
+// name within one reload interval as last writer wins.
+type RedisBackend struct {
+	client  *redis.Client
+	version int64 // version counter after our last load or save
+	stale   bool  // another writer saved since our last load
+}
+
+// NewRedisBackend returns a backend for the Redis server at addr, given as
+// host:port or as a redis:// or rediss:// URL.
+func NewRedisBackend(addr string) (*RedisBackend, error) {
+	opts := &redis.Options{Addr: addr}
+	if strings.Contains(addr, "://") {
+		var err error
+		if opts, err = redis.ParseURL(addr); err != nil {
+			return nil, fmt.Errorf("parsing redis address: %w", err)
+		}
+	}
+	return &RedisBackend{client: redis.NewClient(opts)}, nil
+}
+
+// Load reads every record set, the generation and the version counter in a
+// single transaction.
+func (r *RedisBackend) Load(ctx context.Context) ([]Record, uint64, error) {
+	pipe := r.client.TxPipeline()
+	recsCmd := pipe.HGetAll(ctx, redisRecordsKey)
+	genCmd := pipe.Get(ctx, redisGenerationKey)
+	verCmd := pipe.Get(ctx, redisVersionKey)
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, 0, fmt.Errorf("loading from redis: %w", err)
+	}
+
+	var records []Record
+	for name, raw := range recsCmd.Val() {
+		var recs []Record
+		if err := json.Unmarshal([]byte(raw), &recs); err != nil {
+			return nil, 0, fmt.Errorf("parsing records for %s: %w", name, err)
+		}
+		records = append(records, recs...)
+	}
+
+	gen, err := genCmd.Uint64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, 0, fmt.Errorf("parsing generation: %w", err)
+	}
+	ver, err := verCmd.Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, 0, fmt.Errorf("parsing version: %w", err)
+	}
+
+	r.version = ver
+	r.stale = false
+	return records, gen, nil
+}
+
+// Save writes the touched names, or every name for a full rewrite, together
+// with the generation and a version bump in a single transaction.
+func (r *RedisBackend) Save(ctx context.Context, c Change) error {
+	names := c.Names
+	full := names == nil
+	if full {
+		names = make(map[string][]Record)
+		for _, rec := range c.All() {
+			key := strings.ToLower(rec.Name)
+			names[key] = append(names[key], rec)
+		}
+	}
+
+	pipe := r.client.TxPipeline()
+	if full {
+		pipe.Del(ctx, redisRecordsKey)
+	}
+	for name, recs := range names {
+		if len(recs) == 0 {
+			pipe.HDel(ctx, redisRecordsKey, name)
+			continue
+		}
+		raw, err := json.Marshal(recs)
+		if err != nil {
+			return fmt.Errorf("marshalling records for %s: %w", name, err)
+		}
+		pipe.HSet(ctx, redisRecordsKey, name, raw)
+	}
+	pipe.Eval(ctx, redisMaxGeneration, []string{redisGenerationKey}, c.Generation)
+	verCmd := pipe.Incr(ctx, redisVersionKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("saving to redis: %w", err)
+	}
+
+	ver := verCmd.Val()
+	if ver-1 != r.version {
+		r.stale = true
+	}
+	r.version = ver
+	return nil
+}
+
+// Modified reports whether another writer saved since our last load or save.
+func (r *RedisBackend) Modified(ctx context.Context) (bool, error) {
+	if r.stale {
+		return true, nil
+	}
+	ver, err := r.client.Get(ctx, redisVersionKey).Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return false, fmt.Errorf("reading redis version: %w", err)
+	}
+	return ver != r.version, nil
+}
+
+// String returns "redis:" and the server address and database. Credentials
+// given in a redis:// URL are left out.
+func (r *RedisBackend) String() string {
+	opts := r.client.Options()
+	return fmt.Sprintf("redis:%s/%d", opts.Addr, opts.DB)
+}
+
+// Close closes the Redis client.
+func (r *RedisBackend) Close() error {
+	return r.client.Close()
+}
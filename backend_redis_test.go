@@ -0,0 +1,163 @@
+// ABOUTME: Tests for the Redis Store backend against an in-process miniredis server.
+// ABOUTME: Covers persistence, key layout, sync policy, cross-replica reload, and write-failure recovery.
+
+package dynupdate
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// newRedisStore returns a Store on a fresh RedisBackend pointed at mr.
+func newRedisStore(t *testing.T, mr *miniredis.Miniredis, opts ...StoreOption) *Store {
+	t.Helper()
+	b, err := NewRedisBackend(mr.Addr())
+	if err != nil {
+		t.Fatalf("NewRedisBackend() error: %v", err)
+	}
+	s, err := NewStoreWithBackend(b, 0, opts...)
+	if err != nil {
+		t.Fatalf("NewStoreWithBackend() error: %v", err)
+	}
+	t.Cleanup(s.Stop)
+	return s
+}
+
+func TestRedisBackend_RoundTrip(t *testing.T) {
+	t.Parallel()
+	mr := miniredis.RunT(t)
+
+	s1 := newRedisStore(t, mr)
+	if err := s1.Upsert(t.Context(), Record{Name: "App.Example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	if err := s1.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "TXT", TTL: 300, Value: "hello"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	if err := s1.Upsert(t.Context(), Record{Name: "gone.example.org.", Type: "A", TTL: 300, Value: "10.0.0.9"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	if err := s1.DeleteAll(t.Context(), "gone.example.org."); err != nil {
+		t.Fatalf("DeleteAll() error: %v", err)
+	}
+
+	// Records are keyed by lowercase FQDN, one field per name.
+	raw := mr.HGet(redisRecordsKey, "app.example.org.")
+	var recs []Record
+	if err := json.Unmarshal([]byte(raw), &recs); err != nil {
+		t.Fatalf("unmarshal field: %v (raw %q)", err, raw)
+	}
+	if len(recs) != 2 {
+		t.Errorf("field app.example.org. holds %d records, want 2", len(recs))
+	}
+	if keys, _ := mr.HKeys(redisRecordsKey); len(keys) != 1 {
+		t.Errorf("hash fields = %v, want only app.example.org.", keys)
+	}
+
+	s2 := newRedisStore(t, mr)
+	if got := s2.GetAll(t.Context(), "app.example.org."); len(got) != 2 {
+		t.Errorf("second store loaded %d records, want 2", len(got))
+	}
+	if s2.Generation() < s1.Generation() {
+		t.Errorf("second store generation = %d, want >= %d", s2.Generation(), s1.Generation())
+	}
+}
+
+func TestRedisBackend_SyncPolicy(t *testing.T) {
+	t.Parallel()
+	mr := miniredis.RunT(t)
+	s := newRedisStore(t, mr, WithSyncPolicy(PolicyCreateOnly))
+
+	r := Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}
+	if err := s.Upsert(t.Context(), r); err != nil {
+		t.Fatalf("Upsert() create error: %v", err)
+	}
+	r.TTL = 600
+	if err := s.Upsert(t.Context(), r); !errors.Is(err, ErrPolicyDenied) {
+		t.Errorf("Upsert() update error = %v, want ErrPolicyDenied", err)
+	}
+	if err := s.Delete(t.Context(), r.Name, r.Type, r.Value); !errors.Is(err, ErrPolicyDenied) {
+		t.Errorf("Delete() error = %v, want ErrPolicyDenied", err)
+	}
+
+	s2 := newRedisStore(t, mr)
+	got := s2.Get(t.Context(), "app.example.org.", "A")
+	if len(got) != 1 || got[0].TTL != 300 {
+		t.Errorf("persisted records = %v, want the original TTL 300 record", got)
+	}
+}
+
+func TestRedisBackend_ReloadsOtherWriters(t *testing.T) {
+	t.Parallel()
+	mr := miniredis.RunT(t)
+	s1 := newRedisStore(t, mr)
+	s2 := newRedisStore(t, mr)
+
+	if err := s1.Upsert(t.Context(), Record{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	// s2 writes without having seen s1's change; its own save must not hide it.
+	if err := s2.Upsert(t.Context(), Record{Name: "b.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+
+	s2.checkReload()
+	if got := s2.Get(t.Context(), "a.example.org.", "A"); len(got) != 1 {
+		t.Errorf("s2 did not pick up s1's record after reload: %v", got)
+	}
+	s1.checkReload()
+	if got := s1.Get(t.Context(), "b.example.org.", "A"); len(got) != 1 {
+		t.Errorf("s1 did not pick up s2's record after reload: %v", got)
+	}
+
+	// Nothing changed since: a reload must be a no-op.
+	gen := s1.Generation()
+	s1.checkReload()
+	if s1.Generation() != gen {
+		t.Errorf("generation moved from %d to %d without external changes", gen, s1.Generation())
+	}
+}
+
+func TestRedisBackend_RecoversFromFailedWrite(t *testing.T) {
+	t.Parallel()
+	mr := miniredis.RunT(t)
+	s := newRedisStore(t, mr)
+
+	mr.SetError("LOADING")
+	if err := s.Upsert(t.Context(), Record{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err == nil {
+		t.Fatal("Upsert() with redis failing: expected error")
+	}
+	mr.SetError("")
+
+	if err := s.Upsert(t.Context(), Record{Name: "b.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+
+	// The failed mutation was rolled back; the second save rewrites
+	// everything, dropping whatever part of the first one reached redis.
+	s2 := newRedisStore(t, mr)
+	if got := s2.List(t.Context()); len(got) != 1 || got[0].Name != "b.example.org." {
+		t.Errorf("persisted %v, want only b.example.org.", got)
+	}
+}
+
+func TestNewRedisBackend_URL(t *testing.T) {
+	t.Parallel()
+	mr := miniredis.RunT(t)
+
+	b, err := NewRedisBackend("redis://" + mr.Addr() + "/0")
+	if err != nil {
+		t.Fatalf("NewRedisBackend() error: %v", err)
+	}
+	defer b.Close()
+	if _, _, err := b.Load(t.Context()); err != nil {
+		t.Errorf("Load() error: %v", err)
+	}
+
+	if _, err := NewRedisBackend("redis://:bad:port"); err == nil {
+		t.Error("NewRedisBackend() with invalid URL: expected error")
+	}
+}
@@ -0,0 +1,202 @@
+// ABOUTME: SQLite implementation of the Store Backend, for durable and queryable record storage.
+// ABOUTME: One row per record keyed by lowercase name+type+value; every save runs in a single transaction.
+
+package dynupdate
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3" // registers the "sqlite3" driver
+)
+
+// sqliteSchema creates the tables on first use. The primary key leads with
+// the lowercase name, so it doubles as the name index used by saves.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS records (
+	name  TEXT NOT NULL, -- lowercase FQDN
+	type  TEXT NOT NULL,
+	value TEXT NOT NULL,
+	data  TEXT NOT NULL, -- JSON-encoded Record
+	PRIMARY KEY (name, type, value)
+);
+CREATE TABLE IF NOT EXISTS meta (
+	key   TEXT PRIMARY KEY,
+	value INTEGER NOT NULL
+);
+INSERT OR IGNORE INTO meta (key, value) VALUES ('generation', 0), ('version', 0);`
+
+// SQLiteBackend stores records in a SQLite database. Like RedisBackend it
+// bumps a version counter on every save, so several processes sharing the
+// database file reload each other's changes.
+type SQLiteBackend struct {
+	db      *sql.DB
+	name    string // dsn without its parameters, which may carry credentials
+	version int64  // version counter after our last load or save
+	stale   bool   // another writer saved since our last load
+}
+
+// NewSQLiteBackend opens (creating if needed) the SQLite database at dsn,
+// which is a file path or any DSN accepted by github.com/mattn/go-sqlite3.
+// The driver needs cgo.
+func NewSQLiteBackend(dsn string) (*SQLiteBackend, error) {
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening sqlite %s: %w", dsn, err)
+	}
+	// The Store serializes backend calls, and a single connection keeps
+	// in-memory databases alive for the lifetime of the backend.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating sqlite schema: %w", err)
+	}
+	name, _, _ := strings.Cut(dsn, "?")
+	return &SQLiteBackend{db: db, name: name}, nil
+}
+
+// Load reads every record, the generation and the version counter in one
+// read transaction.
+func (b *SQLiteBackend) Load(ctx context.Context) ([]Record, uint64, error) {
+	tx, err := b.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, 0, fmt.Errorf("beginning sqlite transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT data FROM records`)
+	if err != nil {
+		return nil, 0, fmt.Errorf("querying records: %w", err)
+	}
+	defer rows.Close()
+
+	var records []Record
+	for rows.Next() {
+		var raw string
+		if err := rows.Scan(&raw); err != nil {
+			return nil, 0, fmt.Errorf("scanning record: %w", err)
+		}
+		var r Record
+		if err := json.Unmarshal([]byte(raw), &r); err != nil {
+			return nil, 0, fmt.Errorf("parsing record: %w", err)
+		}
+		records = append(records, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("querying records: %w", err)
+	}
+
+	gen, err := sqliteMeta(ctx, tx, "generation")
+	if err != nil {
+		return nil, 0, err
+	}
+	ver, err := sqliteMeta(ctx, tx, "version")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	b.version = ver
+	b.stale = false
+	return records, uint64(gen), nil
+}
+
+// Save replaces the rows of every touched name, or the whole table for a
+// full rewrite, and bumps the generation and version in one transaction.
+func (b *SQLiteBackend) Save(ctx context.Context, c Change) error {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning sqlite transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var recs []Record
+	if c.Names == nil {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM records`); err != nil {
+			return fmt.Errorf("clearing records: %w", err)
+		}
+		recs = c.All()
+	} else {
+		for name, set := range c.Names {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM records WHERE name = ?`, name); err != nil {
+				return fmt.Errorf("deleting records for %s: %w", name, err)
+			}
+			recs = append(recs, set...)
+		}
+	}
+
+	insert, err := tx.PrepareContext(ctx, `INSERT OR REPLACE INTO records (name, type, value, data) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("preparing insert: %w", err)
+	}
+	defer insert.Close()
+	for _, r := range recs {
+		raw, err := json.Marshal(r)
+		if err != nil {
+			return fmt.Errorf("marshalling record %s: %w", r.Name, err)
+		}
+		if _, err := insert.ExecContext(ctx, strings.ToLower(r.Name), strings.ToUpper(r.Type), r.Value, string(raw)); err != nil {
+			return fmt.Errorf("inserting record %s: %w", r.Name, err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE meta SET value = MAX(value, ?) WHERE key = 'generation'`, int64(c.Generation)); err != nil {
+		return fmt.Errorf("updating generation: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE meta SET value = value + 1 WHERE key = 'version'`); err != nil {
+		return fmt.Errorf("updating version: %w", err)
+	}
+	ver, err := sqliteMeta(ctx, tx, "version")
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing sqlite transaction: %w", err)
+	}
+
+	if ver-1 != b.version {
+		b.stale = true
+	}
+	b.version = ver
+	return nil
+}
+
+// Modified reports whether another writer saved since our last load or save.
+func (b *SQLiteBackend) Modified(ctx context.Context) (bool, error) {
+	if b.stale {
+		return true, nil
+	}
+	var ver int64
+	err := b.db.QueryRowContext(ctx, `SELECT value FROM meta WHERE key = 'version'`).Scan(&ver)
+	if err != nil {
+		return false, fmt.Errorf("reading sqlite version: %w", err)
+	}
+	return ver != b.version, nil
+}
+
+// String returns "sqlite:" and the database's DSN without its parameters.
+func (b *SQLiteBackend) String() string {
+	return "sqlite:" + b.name
+}
+
+// Close closes the database.
+func (b *SQLiteBackend) Close() error {
+	return b.db.Close()
+}
+
+// sqliteMeta reads one counter from the meta table.
+func sqliteMeta(ctx context.Context, tx *sql.Tx, key string) (int64, error) {
+	var v int64
+	err := tx.QueryRowContext(ctx, `SELECT value FROM meta WHERE key = ?`, key).Scan(&v)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("reading %s: %w", key, err)
+	}
+	return v, nil
+}
@@ -0,0 +1,162 @@
+// ABOUTME: Tests for the SQLite Store backend using in-memory and temporary-file databases.
+// ABOUTME: Covers CRUD persistence, table layout, max records, sync policy, restarts, and cross-process reload.
+
+package dynupdate
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// newSQLiteStore returns a Store on a SQLiteBackend for dsn, plus the backend
+// so tests can inspect what was persisted.
+func newSQLiteStore(t *testing.T, dsn string, opts ...StoreOption) (*Store, *SQLiteBackend) {
+	t.Helper()
+	b, err := NewSQLiteBackend(dsn)
+	if err != nil {
+		t.Fatalf("NewSQLiteBackend() error: %v", err)
+	}
+	s, err := NewStoreWithBackend(b, 0, opts...)
+	if err != nil {
+		t.Fatalf("NewStoreWithBackend() error: %v", err)
+	}
+	t.Cleanup(s.Stop)
+	return s, b
+}
+
+// sqliteRows returns the number of rows persisted for the lowercase name.
+func sqliteRows(t *testing.T, b *SQLiteBackend, name string) int {
+	t.Helper()
+	var n int
+	if err := b.db.QueryRow(`SELECT COUNT(*) FROM records WHERE name = ?`, name).Scan(&n); err != nil {
+		t.Fatalf("counting rows: %v", err)
+	}
+	return n
+}
+
+func TestSQLiteBackend_CRUD(t *testing.T) {
+	t.Parallel()
+	s, b := newSQLiteStore(t, ":memory:")
+	ctx := t.Context()
+
+	recs := []Record{
+		{Name: "App.Example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"},
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"},
+		{Name: "app.example.org.", Type: "TXT", TTL: 300, Value: "hello"},
+		{Name: "other.example.org.", Type: "A", TTL: 300, Value: "10.0.0.3"},
+	}
+	for _, r := range recs {
+		if err := s.Upsert(ctx, r); err != nil {
+			t.Fatalf("Upsert(%s) error: %v", r.Value, err)
+		}
+	}
+	if got := sqliteRows(t, b, "app.example.org."); got != 3 {
+		t.Errorf("rows for app.example.org. = %d, want 3", got)
+	}
+
+	// Update replaces the row instead of adding one.
+	if err := s.Upsert(ctx, Record{Name: "app.example.org.", Type: "A", TTL: 60, Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("Upsert(update) error: %v", err)
+	}
+	if got := sqliteRows(t, b, "app.example.org."); got != 3 {
+		t.Errorf("rows after update = %d, want 3", got)
+	}
+
+	if err := s.Delete(ctx, "app.example.org.", "A", "10.0.0.2"); err != nil {
+		t.Fatalf("Delete() error: %v", err)
+	}
+	if err := s.DeleteByType(ctx, "app.example.org.", "TXT"); err != nil {
+		t.Fatalf("DeleteByType() error: %v", err)
+	}
+	if err := s.DeleteAll(ctx, "other.example.org."); err != nil {
+		t.Fatalf("DeleteAll() error: %v", err)
+	}
+
+	loaded, gen, err := b.Load(ctx)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if len(loaded) != 1 || loaded[0].Value != "10.0.0.1" || loaded[0].TTL != 60 {
+		t.Errorf("persisted records = %v, want only the updated 10.0.0.1 record", loaded)
+	}
+	if gen != s.Generation() {
+		t.Errorf("persisted generation = %d, want %d", gen, s.Generation())
+	}
+}
+
+func TestSQLiteBackend_MaxRecordsAndSyncPolicy(t *testing.T) {
+	t.Parallel()
+
+	s, b := newSQLiteStore(t, ":memory:", WithMaxRecords(1))
+	if err := s.Upsert(t.Context(), Record{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	if err := s.Upsert(t.Context(), Record{Name: "b.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"}); err == nil {
+		t.Error("Upsert() past max_records: expected error")
+	}
+	if got := sqliteRows(t, b, "b.example.org."); got != 0 {
+		t.Errorf("rejected record was persisted (%d rows)", got)
+	}
+
+	s, b = newSQLiteStore(t, ":memory:", WithSyncPolicy(PolicyUpsertOnly))
+	if err := s.Upsert(t.Context(), Record{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	if err := s.DeleteAll(t.Context(), "a.example.org."); !errors.Is(err, ErrPolicyDenied) {
+		t.Errorf("DeleteAll() error = %v, want ErrPolicyDenied", err)
+	}
+	if got := sqliteRows(t, b, "a.example.org."); got != 1 {
+		t.Errorf("rows after denied delete = %d, want 1", got)
+	}
+}
+
+func TestSQLiteBackend_SurvivesRestart(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "records.db")
+
+	b1, err := NewSQLiteBackend(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteBackend() error: %v", err)
+	}
+	s1, err := NewStoreWithBackend(b1, 0)
+	if err != nil {
+		t.Fatalf("NewStoreWithBackend() error: %v", err)
+	}
+	for _, v := range []string{"10.0.0.1", "10.0.0.2"} {
+		if err := s1.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: v}); err != nil {
+			t.Fatalf("Upsert() error: %v", err)
+		}
+	}
+	gen := s1.Generation()
+	s1.Stop()
+
+	s2, _ := newSQLiteStore(t, path)
+	if got := s2.Get(t.Context(), "app.example.org.", "A"); len(got) != 2 {
+		t.Errorf("after restart: %d records, want 2", len(got))
+	}
+	if s2.Generation() < gen {
+		t.Errorf("after restart: generation = %d, want >= %d", s2.Generation(), gen)
+	}
+}
+
+func TestSQLiteBackend_ReloadsOtherWriters(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "records.db")
+	s1, _ := newSQLiteStore(t, path)
+	s2, _ := newSQLiteStore(t, path)
+
+	if err := s1.Upsert(t.Context(), Record{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	s2.checkReload()
+	if got := s2.Get(t.Context(), "a.example.org.", "A"); len(got) != 1 {
+		t.Errorf("s2 did not pick up s1's record after reload: %v", got)
+	}
+
+	gen := s1.Generation()
+	s1.checkReload()
+	if s1.Generation() != gen {
+		t.Errorf("generation moved from %d to %d without external changes", gen, s1.Generation())
+	}
+}
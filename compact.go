@@ -0,0 +1,41 @@
+// ABOUTME: On-demand compaction of the backend: purges expired records and rewrites the rest.
+// ABOUTME: The full rewrite holds only live records, in canonical order.
+
+package dynupdate
+
+import (
+	"context"
+	"fmt"
+)
+
+// CompactResult reports what Compact did.
+type CompactResult struct {
+	Purged  int `json:"purged"`  // expired records removed
+	Records int `json:"records"` // records written to the backend
+}
+
+// Compact removes every expired record without waiting for the next sweep,
+// then rewrites the whole backend from memory, so the data file holds only
+// live records in canonical order. Like the sweep it ignores the sync
+// policy: the expiry was requested when each record was written.
+func (s *Store) Compact(ctx context.Context) (CompactResult, error) {
+	purged, err := s.purgeExpired(ctx)
+	if err != nil {
+		return CompactResult{}, fmt.Errorf("purging expired records: %w", err)
+	}
+
+	s.persistMu.Lock()
+	defer s.persistMu.Unlock()
+	s.mu.RLock()
+	gen := s.generation
+	var n int
+	for _, recs := range s.records {
+		n += len(recs)
+	}
+	s.mu.RUnlock()
+
+	if err := s.saveAll(ctx, gen); err != nil {
+		return CompactResult{}, fmt.Errorf("%w: compacting records: %w", ErrBackendUnavailable, err)
+	}
+	return CompactResult{Purged: purged, Records: n}, nil
+}
@@ -0,0 +1,131 @@
+// ABOUTME: Tests for backend compaction and the admin compact endpoint.
+// ABOUTME: Checks expired entries leave the data file, live ones stay, and the file is in canonical order.
+
+package dynupdate
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// readStoreFile returns the records in the JSON data file at path.
+func readStoreFile(t *testing.T, path string) []Record {
+	t.Helper()
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error: %v", err)
+	}
+	var data storeFile
+	if err := json.Unmarshal(raw, &data); err != nil {
+		t.Fatalf("parsing data file: %v", err)
+	}
+	return data.Records
+}
+
+func TestAPI_Compact(t *testing.T) {
+	t.Parallel()
+	clock := &fakeClock{t: time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)}
+	fp := filepath.Join(t.TempDir(), "records.json")
+	// No sweep runs during the test, so expired records stay in the file.
+	s, err := NewStore(fp, 0, WithClock(clock.Now), WithSweepInterval(time.Hour))
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	t.Cleanup(s.Stop)
+	api := NewAPIServer(s, &Auth{Token: "test-token"}, ":0", nil)
+
+	for _, r := range []Record{
+		{Name: "z.example.org.", Type: "A", TTL: 300, Value: "10.0.0.3"},
+		{Name: "old.example.org.", Type: "A", TTL: 300, Value: "10.0.0.9", ExpiresAt: clock.Now().Add(time.Minute)},
+		{Name: "a.example.org.", Type: "TXT", TTL: 300, Value: "keep"},
+		{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"},
+		{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2", ExpiresAt: clock.Now().Add(time.Minute)},
+	} {
+		if err := s.Upsert(t.Context(), r); err != nil {
+			t.Fatalf("Upsert(%s) error: %v", r.Name, err)
+		}
+	}
+	clock.Advance(2 * time.Minute)
+	if n := len(readStoreFile(t, fp)); n != 5 {
+		t.Fatalf("data file holds %d records before compaction, want all 5", n)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/compact", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	rec := httptest.NewRecorder()
+	api.handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body = %s", rec.Code, rec.Body.String())
+	}
+	var res CompactResult
+	if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if res != (CompactResult{Purged: 2, Records: 3}) {
+		t.Errorf("response = %+v, want 2 purged and 3 written", res)
+	}
+
+	want := []RecordKey{
+		{Name: "a.example.org.", Type: "A", Value: "10.0.0.1"},
+		{Name: "a.example.org.", Type: "TXT", Value: "keep"},
+		{Name: "z.example.org.", Type: "A", Value: "10.0.0.3"},
+	}
+	got := readStoreFile(t, fp)
+	if len(got) != len(want) {
+		t.Fatalf("data file holds %d records after compaction, want %d: %+v", len(got), len(want), got)
+	}
+	for i, r := range got {
+		if r.Key() != want[i] {
+			t.Errorf("data file record %d = %+v, want %+v", i, r.Key(), want[i])
+		}
+	}
+}
+
+func TestStore_Compact_NothingExpired(t *testing.T) {
+	t.Parallel()
+	fp := filepath.Join(t.TempDir(), "records.json")
+	s, err := NewStore(fp, 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	t.Cleanup(s.Stop)
+	if err := s.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	gen := s.Generation()
+
+	res, err := s.Compact(t.Context())
+	if err != nil {
+		t.Fatalf("Compact() error: %v", err)
+	}
+	if res != (CompactResult{Records: 1}) {
+		t.Errorf("Compact() = %+v, want nothing purged and 1 written", res)
+	}
+	// A rewrite without changes is not a mutation: the SOA serial stays.
+	if s.Generation() != gen {
+		t.Errorf("generation = %d after compaction, want %d", s.Generation(), gen)
+	}
+	if n := len(readStoreFile(t, fp)); n != 1 {
+		t.Errorf("data file holds %d records, want 1", n)
+	}
+}
+
+func TestStore_Compact_BackendDown(t *testing.T) {
+	t.Parallel()
+	fb := &flakyBackend{Backend: NewFileBackend(filepath.Join(t.TempDir(), "records.json")), failSaves: 1}
+	s, err := NewStoreWithBackend(fb, 0, WithFlushTimeout(0))
+	if err != nil {
+		t.Fatalf("NewStoreWithBackend() error: %v", err)
+	}
+	t.Cleanup(s.Stop)
+
+	if _, err := s.Compact(t.Context()); !errors.Is(err, ErrBackendUnavailable) {
+		t.Errorf("Compact() error = %v, want ErrBackendUnavailable", err)
+	}
+}
@@ -0,0 +1,114 @@
+// ABOUTME: Gzip response compression middleware for the REST API.
+// ABOUTME: Compresses bodies above a size threshold when the client sends Accept-Encoding: gzip.
+
+package dynupdate
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// gzipMinSize is the smallest response body worth compressing. Smaller
+// bodies are sent as-is since gzip framing would outweigh the savings.
+const gzipMinSize = 1024
+
+// gzipMiddleware compresses responses for clients that accept gzip.
+func gzipMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &gzipResponseWriter{ResponseWriter: w}
+		defer gw.finish()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			q, err := strconv.ParseFloat(v, 64)
+			return err == nil && q > 0
+		}
+		return true
+	}
+	return false
+}
+
+// gzipResponseWriter buffers the start of the body until it knows whether the
+// response is large enough to compress, then either streams through a gzip
+// writer or flushes the buffer uncompressed.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	status int
+	buf    []byte
+	gz     *gzip.Writer
+	done   bool // headers sent to the underlying writer
+}
+
+func (g *gzipResponseWriter) WriteHeader(code int) {
+	if g.status == 0 {
+		g.status = code
+	}
+}
+
+func (g *gzipResponseWriter) Write(p []byte) (int, error) {
+	if g.status == 0 {
+		g.status = http.StatusOK
+	}
+	if g.gz != nil {
+		return g.gz.Write(p)
+	}
+
+	g.buf = append(g.buf, p...)
+	if len(g.buf) < gzipMinSize {
+		return len(p), nil
+	}
+
+	h := g.Header()
+	h.Set("Content-Encoding", "gzip")
+	h.Del("Content-Length")
+	g.sendHeader()
+
+	g.gz = gzip.NewWriter(g.ResponseWriter)
+	if _, err := g.gz.Write(g.buf); err != nil {
+		return 0, err
+	}
+	g.buf = nil
+	return len(p), nil
+}
+
+// finish flushes whatever the handler produced.
+func (g *gzipResponseWriter) finish() {
+	if g.gz != nil {
+		if err := g.gz.Close(); err != nil {
+			log.Errorf("closing gzip response: %v", err)
+		}
+		return
+	}
+	if g.status == 0 {
+		return
+	}
+	g.sendHeader()
+	if len(g.buf) > 0 {
+		_, _ = g.ResponseWriter.Write(g.buf)
+	}
+}
+
+func (g *gzipResponseWriter) sendHeader() {
+	if g.done {
+		return
+	}
+	g.done = true
+	g.ResponseWriter.WriteHeader(g.status)
+}
@@ -0,0 +1,172 @@
+// ABOUTME: Tests for the gzip response compression middleware.
+// ABOUTME: Covers large compressed lists, small uncompressed bodies, metrics compressed once, and Accept-Encoding parsing.
+
+package dynupdate
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGzip_LargeListCompressed(t *testing.T) {
+	t.Parallel()
+	api, store := newTestAPIHandler(t)
+	for i := 0; i < 200; i++ {
+		r := Record{Name: fmt.Sprintf("host%d.example.org.", i), Type: "A", TTL: 300, Value: fmt.Sprintf("10.0.%d.%d", i/256, i%256)}
+		if err := store.Upsert(t.Context(), r); err != nil {
+			t.Fatalf("Upsert() error: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/records?limit=200", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+
+	api.handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", got)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("gzip.NewReader() error: %v", err)
+	}
+	var resp apiListResponse
+	if err := json.NewDecoder(zr).Decode(&resp); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if len(resp.Records) != 200 {
+		t.Errorf("got %d records, want 200", len(resp.Records))
+	}
+}
+
+func TestGzip_MetricsCompressedOnce(t *testing.T) {
+	t.Parallel()
+	api, _ := newTestAPIHandler(t)
+	WithMetrics()(api)
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	api.handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := rec.Header().Values("Content-Encoding"); len(got) != 1 || got[0] != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want a single gzip", got)
+	}
+
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("gzip.NewReader() error: %v", err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("reading body: %v", err)
+	}
+	if !strings.Contains(string(body), "coredns_dynupdate_") {
+		t.Errorf("decompressed body is not the metrics text: %.100q", body)
+	}
+}
+
+func TestGzip_SmallResponseUncompressed(t *testing.T) {
+	t.Parallel()
+	api, _ := newTestAPIHandler(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/records", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+
+	api.handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := rec.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("Content-Encoding = %q, want none for small body", got)
+	}
+	var resp apiListResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+}
+
+func TestGzip_NotRequested(t *testing.T) {
+	t.Parallel()
+	api, store := newTestAPIHandler(t)
+	for i := 0; i < 200; i++ {
+		_ = store.Upsert(t.Context(), Record{Name: fmt.Sprintf("host%d.example.org.", i), Type: "A", TTL: 300, Value: "10.0.0.1"})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/records?limit=200", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	rec := httptest.NewRecorder()
+
+	api.handler().ServeHTTP(rec, req)
+	if got := rec.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("Content-Encoding = %q, want none without Accept-Encoding", got)
+	}
+	var resp apiListResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if len(resp.Records) != 200 {
+		t.Errorf("got %d records, want 200", len(resp.Records))
+	}
+}
+
+func TestGzip_NoContentPassesThrough(t *testing.T) {
+	t.Parallel()
+	api, store := newTestAPIHandler(t)
+	_ = store.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/records/app.example.org.", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+
+	api.handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("body length = %d, want 0", rec.Body.Len())
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"GZIP", true},
+		{"deflate, gzip", true},
+		{"br;q=1.0, gzip;q=0.8", true},
+		{"gzip;q=0", false},
+		{"identity", false},
+		{"x-gzip", false},
+	}
+
+	for _, tt := range tests {
+		if got := acceptsGzip(tt.header); got != tt.want {
+			t.Errorf("acceptsGzip(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// ABOUTME: Detection of record sets DNS forbids: several CNAMEs, or a CNAME beside other types.
+// ABOUTME: Writes creating one fail with ErrCNAMEConflict; LoadConflictMode picks how loads handle them.
+
+package dynupdate
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrLoadConflict is returned by a load under ConflictFail when a name holds
+// more than one CNAME or a CNAME beside other records.
+var ErrLoadConflict = errors.New("conflicting records at load")
+
+// ErrCNAMEConflict is returned when a write would leave a CNAME beside
+// other records at a name, a second CNAME, or a CNAME at a zone apex.
+var ErrCNAMEConflict = errors.New("CNAME cannot coexist with other records")
+
+// cnameConflictLocked returns ErrCNAMEConflict if adding r to recs, the
+// records already stored at its name, would break RFC 1034 section 3.6.2:
+// a CNAME must be the only record at its name. A zone apex always holds the
+// synthesized SOA, so it never takes a CNAME. Caller must hold Lock.
+func (s *Store) cnameConflictLocked(r Record, recs []Record) error {
+	isCNAME := strings.EqualFold(r.Type, "CNAME")
+	if zone := s.zoneOf(strings.ToLower(r.Name)); isCNAME && zone != "" && strings.EqualFold(zone, r.Name) {
+		return fmt.Errorf("cannot add CNAME at zone apex %s: %w", r.Name, ErrCNAMEConflict)
+	}
+	for _, existing := range recs {
+		if isCNAME || strings.EqualFold(existing.Type, "CNAME") {
+			return fmt.Errorf("cannot add %s (type %s): the name already holds type %s: %w", r.Name, r.Type, existing.Type, ErrCNAMEConflict)
+		}
+	}
+	return nil
+}
+
+// LoadConflictMode controls what a load does with a name whose records
+// cannot coexist: more than one CNAME, or a CNAME and any other type.
+// Backends only hold such sets when edited outside the plugin.
+type LoadConflictMode uint8
+
+const (
+	// ConflictKeepFirst keeps the first record in backend order and
+	// whatever can coexist with it, logging a warning (default zero-value).
+	ConflictKeepFirst LoadConflictMode = iota
+	// ConflictReject drops every record at the name, logging a warning.
+	ConflictReject
+	// ConflictFail fails the load. The initial load makes NewStore fail; a
+	// reload keeps the records already in memory.
+	ConflictFail
+)
+
+// ParseLoadConflictMode parses "keep-first", "reject" or "fail".
+func ParseLoadConflictMode(s string) (LoadConflictMode, error) {
+	switch strings.ToLower(s) {
+	case "keep-first", "":
+		return ConflictKeepFirst, nil
+	case "reject":
+		return ConflictReject, nil
+	case "fail":
+		return ConflictFail, nil
+	default:
+		return 0, fmt.Errorf("unknown on_load_conflict mode %q: valid values are keep-first, reject, fail", s)
+	}
+}
+
+// String returns the canonical string representation of the mode.
+func (m LoadConflictMode) String() string {
+	switch m {
+	case ConflictReject:
+		return "reject"
+	case ConflictFail:
+		return "fail"
+	default:
+		return "keep-first"
+	}
+}
+
+// resolveLoadConflicts applies the store's LoadConflictMode to a freshly
+// loaded record set. Records at names without a conflict are returned as
+// they are, in their original order.
+func (s *Store) resolveLoadConflicts(loaded []Record) ([]Record, error) {
+	var order []string
+	byName := make(map[string][]int)
+	for i, r := range loaded {
+		key := strings.ToLower(r.Name)
+		if _, ok := byName[key]; !ok {
+			order = append(order, key)
+		}
+		byName[key] = append(byName[key], i)
+	}
+
+	drop := make(map[int]bool)
+	for _, key := range order {
+		idx := byName[key]
+		problem := cnameConflict(loaded, idx)
+		if problem == "" {
+			continue
+		}
+		switch s.loadConflict {
+		case ConflictFail:
+			return nil, fmt.Errorf("%s has %s: %w", key, problem, ErrLoadConflict)
+		case ConflictReject:
+			log.Warningf("load: %s has %s; dropping its %d records", key, problem, len(idx))
+			for _, i := range idx {
+				drop[i] = true
+			}
+		default:
+			first := loaded[idx[0]]
+			log.Warningf("load: %s has %s; keeping the first record (%s %s)", key, problem, first.Type, first.Value)
+			firstIsCNAME := strings.EqualFold(first.Type, "CNAME")
+			for _, i := range idx[1:] {
+				if firstIsCNAME || strings.EqualFold(loaded[i].Type, "CNAME") {
+					drop[i] = true
+				}
+			}
+		}
+	}
+	if len(drop) == 0 {
+		return loaded, nil
+	}
+
+	kept := make([]Record, 0, len(loaded)-len(drop))
+	for i, r := range loaded {
+		if !drop[i] {
+			kept = append(kept, r)
+		}
+	}
+	return kept, nil
+}
+
+// cnameConflict describes why the records at idx cannot coexist, or returns
+// "" if they can.
+func cnameConflict(loaded []Record, idx []int) string {
+	var cnames, others int
+	for _, i := range idx {
+		if strings.EqualFold(loaded[i].Type, "CNAME") {
+			cnames++
+		} else {
+			others++
+		}
+	}
+	switch {
+	case cnames > 1:
+		return fmt.Sprintf("%d CNAME records", cnames)
+	case cnames == 1 && others > 0:
+		return "a CNAME beside other records"
+	}
+	return ""
+}
@@ -0,0 +1,238 @@
+// ABOUTME: Tests for CNAME conflict handling on writes and when loading a hand-edited data file.
+// ABOUTME: Covers Store and API writes refused with ErrCNAMEConflict, and each LoadConflictMode.
+
+package dynupdate
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/mauromedda/coredns-updater-plugin/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// conflictingRecords holds two CNAMEs and an A at alias, an A then a CNAME
+// at web, and a name without conflict.
+var conflictingRecords = []Record{
+	{Name: "alias.example.org.", Type: "CNAME", TTL: 300, Value: "one.example.org."},
+	{Name: "web.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"},
+	{Name: "alias.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"},
+	{Name: "Alias.example.org.", Type: "cname", TTL: 300, Value: "two.example.org."},
+	{Name: "web.example.org.", Type: "CNAME", TTL: 300, Value: "three.example.org."},
+	{Name: "ok.example.org.", Type: "A", TTL: 300, Value: "10.0.0.3"},
+	{Name: "ok.example.org.", Type: "TXT", TTL: 300, Value: "fine"},
+}
+
+func TestStore_LoadConflicts(t *testing.T) {
+	t.Parallel()
+	ok := []Record{conflictingRecords[5], conflictingRecords[6]}
+	tests := []struct {
+		mode    LoadConflictMode
+		want    map[string][]Record
+		wantErr bool
+	}{
+		{mode: ConflictKeepFirst, want: map[string][]Record{
+			"alias.example.org.": {conflictingRecords[0]},
+			"web.example.org.":   {conflictingRecords[1]},
+			"ok.example.org.":    ok,
+		}},
+		{mode: ConflictReject, want: map[string][]Record{
+			"alias.example.org.": nil,
+			"web.example.org.":   nil,
+			"ok.example.org.":    ok,
+		}},
+		{mode: ConflictFail, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode.String(), func(t *testing.T) {
+			t.Parallel()
+			fp := filepath.Join(t.TempDir(), "records.json")
+			writeDataFile(t, fp, conflictingRecords...)
+
+			s, err := NewStore(fp, 0, WithLoadConflictMode(tt.mode))
+			if tt.wantErr {
+				if !errors.Is(err, ErrLoadConflict) {
+					t.Fatalf("NewStore() error = %v, want ErrLoadConflict", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewStore() error: %v", err)
+			}
+			defer s.Stop()
+
+			for name, want := range tt.want {
+				got := s.GetAll(t.Context(), name)
+				slices.SortFunc(got, func(a, b Record) int { return a.Key().Compare(b.Key()) })
+				if !slices.EqualFunc(got, want, Record.equal) {
+					t.Errorf("GetAll(%s) = %v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestStore_LoadConflicts_FailedReloadKeepsMemory(t *testing.T) {
+	t.Parallel()
+	fp := filepath.Join(t.TempDir(), "records.json")
+	s, err := NewStore(fp, 0, WithLoadConflictMode(ConflictFail))
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	defer s.Stop()
+	if err := s.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.9"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+
+	writeDataFile(t, fp, conflictingRecords...)
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(fp, future, future); err != nil {
+		t.Fatalf("Chtimes() error: %v", err)
+	}
+	s.checkReload()
+
+	if got := s.GetAll(t.Context(), "app.example.org."); len(got) != 1 {
+		t.Errorf("GetAll(app) = %v, want the record from before the failed reload", got)
+	}
+	if got := s.GetAll(t.Context(), "alias.example.org."); len(got) != 0 {
+		t.Errorf("GetAll(alias) = %v, want nothing from the conflicting file", got)
+	}
+	if st := s.Status(); st.LastReloadOK || st.ReloadError == "" {
+		t.Errorf("Status() = %+v, want the reload failure reported", st)
+	}
+}
+
+func TestParseLoadConflictMode(t *testing.T) {
+	t.Parallel()
+	for in, want := range map[string]LoadConflictMode{"keep-first": ConflictKeepFirst, "REJECT": ConflictReject, "fail": ConflictFail} {
+		if got, err := ParseLoadConflictMode(in); err != nil || got != want {
+			t.Errorf("ParseLoadConflictMode(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseLoadConflictMode("first"); err == nil {
+		t.Error("ParseLoadConflictMode(\"first\") expected error")
+	}
+}
+
+func TestStore_Upsert_CNAMEConflict(t *testing.T) {
+	t.Parallel()
+	a := Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}
+	cname := Record{Name: "app.example.org.", Type: "CNAME", TTL: 300, Value: "target.example.org."}
+	tests := []struct {
+		name     string
+		existing []Record
+		add      Record
+		wantErr  bool
+	}{
+		{name: "CNAME beside A", existing: []Record{a}, add: cname, wantErr: true},
+		{name: "A beside CNAME", existing: []Record{cname}, add: a, wantErr: true},
+		{name: "second CNAME", existing: []Record{cname}, add: Record{Name: "APP.example.org.", Type: "CNAME", TTL: 300, Value: "other.example.org."}, wantErr: true},
+		{name: "CNAME at zone apex", add: Record{Name: "example.org.", Type: "CNAME", TTL: 300, Value: "target.example.net."}, wantErr: true},
+		{name: "CNAME update", existing: []Record{cname}, add: Record{Name: "app.example.org.", Type: "CNAME", TTL: 60, Value: "target.example.org."}},
+		{name: "CNAME alone", add: cname},
+		{name: "A beside TXT", existing: []Record{{Name: "app.example.org.", Type: "TXT", TTL: 300, Value: "hi"}}, add: a},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			s, err := NewStore(filepath.Join(t.TempDir(), "records.json"), 0, WithZones("example.org."))
+			if err != nil {
+				t.Fatalf("NewStore() error: %v", err)
+			}
+			t.Cleanup(s.Stop)
+			for _, r := range tt.existing {
+				if err := s.Upsert(t.Context(), r); err != nil {
+					t.Fatalf("Upsert(%s %s) error: %v", r.Name, r.Type, err)
+				}
+			}
+
+			err = s.Upsert(t.Context(), tt.add)
+			if tt.wantErr != errors.Is(err, ErrCNAMEConflict) {
+				t.Fatalf("Upsert(%s %s) error = %v, want ErrCNAMEConflict %v", tt.add.Name, tt.add.Type, err, tt.wantErr)
+			}
+			if tt.wantErr && len(s.List(t.Context())) != len(tt.existing) {
+				t.Errorf("List() = %+v after refused upsert, want the existing records only", s.List(t.Context()))
+			}
+		})
+	}
+}
+
+func TestAPI_CNAMEConflict(t *testing.T) {
+	t.Parallel()
+	api, _ := newTestAPIHandler(t)
+	h := api.handler()
+
+	tests := []struct {
+		method, target, body string
+		want                 int
+	}{
+		{http.MethodPost, "/api/v1/records", `{"name": "app.example.org.", "type": "A", "value": "10.0.0.1"}`, http.StatusCreated},
+		{http.MethodPost, "/api/v1/records", `{"name": "app.example.org.", "type": "CNAME", "value": "target.example.org."}`, http.StatusConflict},
+		{http.MethodPost, "/api/v1/records", `{"name": "www.example.org.", "type": "CNAME", "value": "target.example.org."}`, http.StatusCreated},
+		{http.MethodPut, "/api/v1/records", `{"name": "www.example.org.", "type": "AAAA", "value": "2001:db8::1"}`, http.StatusConflict},
+		{http.MethodPost, "/api/v1/records:batch", `[{"name": "new.example.org.", "type": "A", "value": "10.0.0.2"}, {"name": "new.example.org.", "type": "CNAME", "value": "target.example.org."}]`, http.StatusConflict},
+		{http.MethodPut, "/api/v1/records/app.example.org.", `[{"type": "CNAME", "value": "target.example.org."}]`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+		req.Header.Set("Authorization", "Bearer test-token")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s %s %s: status = %d, want %d; body = %s", tt.method, tt.target, tt.body, rec.Code, tt.want, rec.Body.String())
+		}
+	}
+}
+
+func TestAPI_CNAMEConflict_Body(t *testing.T) {
+	t.Parallel()
+	api, s := newTestAPIHandler(t)
+	if err := s.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	h := api.handler()
+
+	for _, method := range []string{http.MethodPost, http.MethodPut} {
+		req := httptest.NewRequest(method, "/api/v1/records", strings.NewReader(`{"name": "app.example.org.", "type": "CNAME", "value": "target.example.org."}`))
+		req.Header.Set("Authorization", "Bearer test-token")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusConflict {
+			t.Fatalf("%s: status = %d, want 409", method, rec.Code)
+		}
+		var body apiErrorResponse
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("%s: decoding body: %v", method, err)
+		}
+		if body.Field != "type" || body.Code != CodeConflict || !strings.Contains(body.Error, "already holds type A") {
+			t.Errorf("%s: body = %+v, want field type, code conflict and the clashing type named", method, body)
+		}
+	}
+}
+
+func TestGRPC_CNAMEConflict(t *testing.T) {
+	t.Parallel()
+	client, _ := newTestGRPCClient(t, "grpc-secret")
+	ctx := authCtx("grpc-secret")
+
+	if _, err := client.Upsert(ctx, &pb.UpsertRequest{Record: &pb.Record{Name: "app.example.org.", Type: "CNAME", Ttl: 300, Value: "target.example.org."}}); err != nil {
+		t.Fatalf("Upsert(CNAME) error: %v", err)
+	}
+	_, err := client.Upsert(ctx, &pb.UpsertRequest{Record: &pb.Record{Name: "app.example.org.", Type: "A", Ttl: 300, Value: "10.0.0.1"}})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Upsert(A beside CNAME) error = %v, want FailedPrecondition", err)
+	}
+	if msg := status.Convert(err).Message(); !strings.Contains(msg, "already holds type CNAME") {
+		t.Errorf("Upsert(A beside CNAME) message = %q, want the clashing type named", msg)
+	}
+}
@@ -0,0 +1,105 @@
+// ABOUTME: Bulk enable/disable of records picked by a selector.
+// ABOUTME: Disabled records stay stored and listed but are not served in DNS.
+
+package dynupdate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// Selector picks records for a bulk operation. Every field that is set must
+// match: Name is an exact owner name, Suffix a name and everything below it,
+// Type a record type and Labels label values the record must carry. At
+// least one of Name, Suffix and Labels is required, so an empty selector
+// never matches everything by accident.
+type Selector struct {
+	Name   string            `json:"name,omitempty"`
+	Suffix string            `json:"suffix,omitempty"`
+	Type   string            `json:"type,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// Validate reports whether the selector is usable.
+func (sel Selector) Validate() error {
+	switch {
+	case sel.Name != "" && sel.Suffix != "":
+		return errors.New("name and suffix are mutually exclusive")
+	case sel.Name == "" && sel.Suffix == "" && len(sel.Labels) == 0:
+		return errors.New("one of name, suffix or labels is required")
+	case sel.Suffix != "" && dns.Fqdn(sel.Suffix) == ".":
+		return errors.New("suffix must not be the root")
+	}
+	return nil
+}
+
+// matches reports whether r, stored under the lowercase name key, is
+// selected.
+func (sel Selector) matches(key string, r Record) bool {
+	if sel.Name != "" && key != strings.ToLower(dns.Fqdn(sel.Name)) {
+		return false
+	}
+	if sel.Suffix != "" && !dns.IsSubDomain(strings.ToLower(dns.Fqdn(sel.Suffix)), key) {
+		return false
+	}
+	if sel.Type != "" && !strings.EqualFold(r.Type, sel.Type) {
+		return false
+	}
+	for k, v := range sel.Labels {
+		if got, ok := r.Labels[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// SetDisabled sets Disabled on every record matched by sel and visible to the
+// owner in ctx, in one mutation and one backend write. Records already in the
+// requested state are left alone. It returns the number of records changed.
+func (s *Store) SetDisabled(ctx context.Context, sel Selector, disabled bool) (int, error) {
+	if err := sel.Validate(); err != nil {
+		return 0, err
+	}
+
+	var n int
+	err := s.commit(ctx, func() (Change, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if s.policyFor(ctx) == PolicyCreateOnly {
+			return Change{}, fmt.Errorf("cannot toggle records: %w", ErrPolicyDenied)
+		}
+
+		owner, scoped := scopedOwner(ctx)
+		var keys []string
+		for key, recs := range s.records {
+			touched := false
+			for i, r := range recs {
+				if r.Disabled == disabled || (scoped && r.Owner != owner) || !sel.matches(key, r) {
+					continue
+				}
+				s.keepLocked(key)
+				r.Disabled = disabled
+				recs[i] = r
+				s.emitLocked(OpUpdated, r)
+				touched = true
+				n++
+			}
+			if touched {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) == 0 {
+			return Change{}, nil
+		}
+		return s.changeLocked(keys...), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
@@ -0,0 +1,193 @@
+// ABOUTME: Tests for selector matching and bulk enable/disable of records.
+// ABOUTME: Checks disabled records drop out of DNS answers but stay listed over REST.
+
+package dynupdate
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+)
+
+func TestSelector_Validate(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		sel     Selector
+		wantErr bool
+	}{
+		{"name", Selector{Name: "a.example.org."}, false},
+		{"suffix and type", Selector{Suffix: "example.org", Type: "A"}, false},
+		{"labels only", Selector{Labels: map[string]string{"env": "dev"}}, false},
+		{"empty", Selector{}, true},
+		{"type only", Selector{Type: "A"}, true},
+		{"name and suffix", Selector{Name: "a.example.org.", Suffix: "example.org."}, true},
+		{"root suffix", Selector{Suffix: "."}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if err := tt.sel.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSelector_Matches(t *testing.T) {
+	t.Parallel()
+	r := Record{Name: "App.Dev.example.org.", Type: "A", Value: "10.0.0.1", Labels: map[string]string{"env": "dev", "team": "web"}}
+	key := "app.dev.example.org."
+	tests := []struct {
+		name string
+		sel  Selector
+		want bool
+	}{
+		{"exact name", Selector{Name: "APP.dev.example.org"}, true},
+		{"other name", Selector{Name: "dev.example.org."}, false},
+		{"suffix", Selector{Suffix: "Dev.Example.org."}, true},
+		{"suffix is the name", Selector{Suffix: "app.dev.example.org."}, true},
+		{"unrelated suffix", Selector{Suffix: "prod.example.org."}, false},
+		{"type", Selector{Suffix: "example.org.", Type: "a"}, true},
+		{"other type", Selector{Suffix: "example.org.", Type: "AAAA"}, false},
+		{"label subset", Selector{Labels: map[string]string{"env": "dev"}}, true},
+		{"label mismatch", Selector{Labels: map[string]string{"env": "prod"}}, false},
+		{"label absent", Selector{Labels: map[string]string{"owner": "x"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tt.sel.matches(key, r); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPI_DisableBySuffix(t *testing.T) {
+	t.Parallel()
+	api, store := newTestAPIHandler(t)
+	h := api.handler()
+	d := &DynUpdate{Zones: []string{"example.org."}, Store: store}
+
+	seed := []Record{
+		{Name: "a.dev.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"},
+		{Name: "b.dev.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"},
+		{Name: "b.dev.example.org.", Type: "TXT", TTL: 300, Value: "hello"},
+		{Name: "prod.example.org.", Type: "A", TTL: 300, Value: "10.0.1.1"},
+	}
+	for _, r := range seed {
+		if err := store.Upsert(t.Context(), r); err != nil {
+			t.Fatalf("Upsert() error: %v", err)
+		}
+	}
+
+	toggle := func(action, body string) (int, int) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/records:"+action, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test-token")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		var resp apiToggleResponse
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+		}
+		return rec.Code, resp.Updated
+	}
+	query := func(name string, qtype uint16) *dns.Msg {
+		req := new(dns.Msg)
+		req.SetQuestion(name, qtype)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := d.ServeDNS(t.Context(), rec, req); err != nil {
+			t.Fatalf("ServeDNS(%s) error: %v", name, err)
+		}
+		return rec.Msg
+	}
+
+	if code, n := toggle("disable", `{"suffix":"dev.example.org."}`); code != http.StatusOK || n != 3 {
+		t.Fatalf("disable: status %d, updated %d; want 200 and 3", code, n)
+	}
+	// Disabling again changes nothing.
+	if code, n := toggle("disable", `{"suffix":"dev.example.org."}`); code != http.StatusOK || n != 0 {
+		t.Errorf("disable again: status %d, updated %d; want 200 and 0", code, n)
+	}
+
+	for _, q := range []struct {
+		name  string
+		qtype uint16
+	}{
+		{"a.dev.example.org.", dns.TypeA},
+		{"b.dev.example.org.", dns.TypeA},
+		{"b.dev.example.org.", dns.TypeTXT},
+	} {
+		if m := query(q.name, q.qtype); m.Rcode != dns.RcodeNameError || len(m.Answer) != 0 {
+			t.Errorf("%s %s: rcode %d with %d answers, want NXDOMAIN and none", q.name, dns.TypeToString[q.qtype], m.Rcode, len(m.Answer))
+		}
+	}
+	if m := query("prod.example.org.", dns.TypeA); len(m.Answer) != 1 {
+		t.Errorf("prod.example.org. A: %d answers, want 1; it is outside the selector", len(m.Answer))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/records", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	var resp apiListResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode list: %v", err)
+	}
+	listed := resp.Records
+	if len(listed) != len(seed) {
+		t.Fatalf("listed %d records, want all %d", len(listed), len(seed))
+	}
+	for _, r := range listed {
+		if want := r.Name != "prod.example.org."; r.Disabled != want {
+			t.Errorf("%s %s: Disabled = %v, want %v", r.Name, r.Type, r.Disabled, want)
+		}
+	}
+
+	if code, n := toggle("enable", `{"name":"b.dev.example.org.","type":"TXT"}`); code != http.StatusOK || n != 1 {
+		t.Fatalf("enable: status %d, updated %d; want 200 and 1", code, n)
+	}
+	if m := query("b.dev.example.org.", dns.TypeTXT); len(m.Answer) != 1 {
+		t.Errorf("re-enabled TXT: %d answers, want 1", len(m.Answer))
+	}
+	if m := query("b.dev.example.org.", dns.TypeA); m.Rcode != dns.RcodeSuccess || len(m.Answer) != 0 {
+		t.Errorf("still-disabled A: rcode %d with %d answers, want NODATA", m.Rcode, len(m.Answer))
+	}
+}
+
+func TestAPI_SetDisabled_Errors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		policy SyncPolicy
+		body   string
+		want   int
+	}{
+		{"invalid JSON", PolicySync, `{`, http.StatusBadRequest},
+		{"empty selector", PolicySync, `{}`, http.StatusBadRequest},
+		{"name and suffix", PolicySync, `{"name":"a.example.org.","suffix":"example.org."}`, http.StatusBadRequest},
+		{"denied under create-only", PolicyCreateOnly, `{"suffix":"example.org."}`, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			api, _ := newTestAPIHandler(t, WithSyncPolicy(tt.policy))
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/records:disable", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer test-token")
+			rec := httptest.NewRecorder()
+			api.handler().ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+// ABOUTME: Online DNSSEC signing with a single zone key loaded from a BIND key pair.
+// ABOUTME: Adds RRSIGs over positive answers for queries with the DO bit set.
+
+package dynupdate
+
+import (
+	"crypto"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Signatures are valid from a little before they are made, to allow for
+// clock skew at validators, until well after any answer TTL runs out.
+const (
+	sigInception = 3 * time.Hour
+	sigValidity  = 8 * 24 * time.Hour
+)
+
+// DNSSECKey is the key a zone is signed with: the public DNSKEY served at
+// the apex and the private key that makes the RRSIGs.
+type DNSSECKey struct {
+	DNSKEY *dns.DNSKEY
+	signer crypto.Signer
+	tag    uint16
+}
+
+// LoadDNSSECKey reads the BIND key pair base.key and base.private, as
+// written by dnssec-keygen. base may also name either file.
+func LoadDNSSECKey(base string) (*DNSSECKey, error) {
+	base = strings.TrimSuffix(strings.TrimSuffix(base, ".key"), ".private")
+
+	pub, err := os.Open(base + ".key")
+	if err != nil {
+		return nil, fmt.Errorf("opening dnssec public key: %w", err)
+	}
+	defer pub.Close()
+	rr, err := dns.ReadRR(pub, base+".key")
+	if err != nil {
+		return nil, fmt.Errorf("reading dnssec public key: %w", err)
+	}
+	dnskey, ok := rr.(*dns.DNSKEY)
+	if !ok {
+		return nil, fmt.Errorf("dnssec public key %s.key holds no DNSKEY record", base)
+	}
+	if dnskey.Flags&dns.ZONE == 0 {
+		return nil, fmt.Errorf("dnssec key %s.key is not a zone key (flags %d)", base, dnskey.Flags)
+	}
+
+	priv, err := os.Open(base + ".private")
+	if err != nil {
+		return nil, fmt.Errorf("opening dnssec private key: %w", err)
+	}
+	defer priv.Close()
+	pk, err := dnskey.ReadPrivateKey(priv, base+".private")
+	if err != nil {
+		return nil, fmt.Errorf("reading dnssec private key: %w", err)
+	}
+	signer, ok := pk.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("dnssec private key %s.private cannot sign", base)
+	}
+
+	dnskey.Hdr.Name = strings.ToLower(dnskey.Hdr.Name)
+	return &DNSSECKey{DNSKEY: dnskey, signer: signer, tag: dnskey.KeyTag()}, nil
+}
+
+// Zone returns the zone the key signs, the owner name of its DNSKEY.
+func (k *DNSSECKey) Zone() string { return k.DNSKEY.Hdr.Name }
+
+// sign returns an RRSIG for each RRset in rrs that lies in the key's zone,
+// valid around now. RRsets are the records sharing owner name, type and
+// class, wherever they appear in rrs. A set that fails to sign is logged
+// and left unsigned.
+func (k *DNSSECKey) sign(rrs []dns.RR, now time.Time) []dns.RR {
+	type rrsetKey struct {
+		name  string
+		rtype uint16
+		class uint16
+	}
+	var order []rrsetKey
+	sets := make(map[rrsetKey][]dns.RR)
+	for _, rr := range rrs {
+		hdr := rr.Header()
+		if hdr.Rrtype == dns.TypeRRSIG || hdr.Rrtype == dns.TypeOPT || !dns.IsSubDomain(k.Zone(), strings.ToLower(hdr.Name)) {
+			continue
+		}
+		key := rrsetKey{strings.ToLower(hdr.Name), hdr.Rrtype, hdr.Class}
+		if _, ok := sets[key]; !ok {
+			order = append(order, key)
+		}
+		sets[key] = append(sets[key], rr)
+	}
+
+	sigs := make([]dns.RR, 0, len(order))
+	for _, key := range order {
+		set := sets[key]
+		sig := &dns.RRSIG{
+			Hdr:        dns.RR_Header{Ttl: set[0].Header().Ttl},
+			Algorithm:  k.DNSKEY.Algorithm,
+			KeyTag:     k.tag,
+			SignerName: k.Zone(),
+			Inception:  uint32(now.Add(-sigInception).Unix()),
+			Expiration: uint32(now.Add(sigValidity).Unix()),
+		}
+		if err := sig.Sign(k.signer, set); err != nil {
+			log.Errorf("signing %s %s: %v", key.name, dns.TypeToString[key.rtype], err)
+			continue
+		}
+		sigs = append(sigs, sig)
+	}
+	return sigs
+}
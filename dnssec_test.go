@@ -0,0 +1,153 @@
+// ABOUTME: Tests for online DNSSEC signing.
+// ABOUTME: Generates a BIND key pair on disk, loads it and verifies the RRSIGs ServeDNS adds.
+
+package dynupdate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+)
+
+// writeTestDNSSECKey generates an ECDSA P-256 zone key for zone and writes
+// it as base.key and base.private, returning base.
+func writeTestDNSSECKey(t *testing.T, zone string) string {
+	t.Helper()
+	k := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: zone, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     dns.ZONE,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+	}
+	priv, err := k.Generate(256)
+	if err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	base := filepath.Join(t.TempDir(), "K"+zone+"+013+test")
+	if err := os.WriteFile(base+".key", []byte(k.String()+"\n"), 0o644); err != nil {
+		t.Fatalf("write public key: %v", err)
+	}
+	if err := os.WriteFile(base+".private", []byte(k.PrivateKeyString(priv)), 0o600); err != nil {
+		t.Fatalf("write private key: %v", err)
+	}
+	return base
+}
+
+func newSignedTestHandler(t *testing.T, records []Record) *DynUpdate {
+	t.Helper()
+	key, err := LoadDNSSECKey(writeTestDNSSECKey(t, "example.org."))
+	if err != nil {
+		t.Fatalf("LoadDNSSECKey() error: %v", err)
+	}
+	d := newTestHandler(t, records)
+	d.DNSSEC = key
+	return d
+}
+
+// queryDO sends a query for qname and qtype, with the DO bit when do is set.
+func queryDO(t *testing.T, d *DynUpdate, qname string, qtype uint16, do bool) *dns.Msg {
+	t.Helper()
+	req := new(dns.Msg)
+	req.SetQuestion(qname, qtype)
+	if do {
+		req.SetEdns0(4096, true)
+	}
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := d.ServeDNS(t.Context(), rec, req); err != nil {
+		t.Fatalf("ServeDNS() error: %v", err)
+	}
+	return rec.Msg
+}
+
+func TestServeDNS_DNSSEC_SignsAnswer(t *testing.T) {
+	t.Parallel()
+	d := newSignedTestHandler(t, []Record{
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"},
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"},
+	})
+
+	msg := queryDO(t, d, "app.example.org.", dns.TypeA, true)
+	var rrset []dns.RR
+	var sig *dns.RRSIG
+	for _, rr := range msg.Answer {
+		switch v := rr.(type) {
+		case *dns.A:
+			rrset = append(rrset, v)
+		case *dns.RRSIG:
+			sig = v
+		}
+	}
+	if len(rrset) != 2 || sig == nil {
+		t.Fatalf("answer = %v, want two A records and an RRSIG", msg.Answer)
+	}
+	if sig.TypeCovered != dns.TypeA || sig.SignerName != "example.org." || sig.OrigTtl != 300 {
+		t.Errorf("RRSIG = %v, want covering A, signed by example.org., original TTL 300", sig)
+	}
+	if err := sig.Verify(d.DNSSEC.DNSKEY, rrset); err != nil {
+		t.Errorf("RRSIG does not verify: %v", err)
+	}
+	if !sig.ValidityPeriod(time.Now()) {
+		t.Errorf("RRSIG not valid now: inception %d, expiration %d", sig.Inception, sig.Expiration)
+	}
+	if opt := msg.IsEdns0(); opt == nil || !opt.Do() {
+		t.Error("response OPT missing or without DO")
+	}
+
+	// Without DO the answer carries no signatures.
+	for _, rr := range queryDO(t, d, "app.example.org.", dns.TypeA, false).Answer {
+		if rr.Header().Rrtype == dns.TypeRRSIG {
+			t.Errorf("answer without DO holds %v", rr)
+		}
+	}
+}
+
+func TestServeDNS_DNSSEC_DNSKEY(t *testing.T) {
+	t.Parallel()
+	d := newSignedTestHandler(t, nil)
+
+	msg := queryDO(t, d, "example.org.", dns.TypeDNSKEY, true)
+	if len(msg.Answer) != 2 {
+		t.Fatalf("answer = %v, want the DNSKEY and its RRSIG", msg.Answer)
+	}
+	key, ok := msg.Answer[0].(*dns.DNSKEY)
+	if !ok || key.KeyTag() != d.DNSSEC.DNSKEY.KeyTag() {
+		t.Fatalf("answer[0] = %v, want the zone DNSKEY", msg.Answer[0])
+	}
+	sig, ok := msg.Answer[1].(*dns.RRSIG)
+	if !ok {
+		t.Fatalf("answer[1] = %v, want an RRSIG", msg.Answer[1])
+	}
+	if err := sig.Verify(key, []dns.RR{key}); err != nil {
+		t.Errorf("DNSKEY RRSIG does not verify: %v", err)
+	}
+}
+
+func TestLoadDNSSECKey(t *testing.T) {
+	t.Parallel()
+	base := writeTestDNSSECKey(t, "Example.ORG.")
+
+	for _, path := range []string{base, base + ".key", base + ".private"} {
+		k, err := LoadDNSSECKey(path)
+		if err != nil {
+			t.Fatalf("LoadDNSSECKey(%q) error: %v", path, err)
+		}
+		if k.Zone() != "example.org." {
+			t.Errorf("Zone() = %q, want example.org.", k.Zone())
+		}
+	}
+
+	if _, err := LoadDNSSECKey(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("LoadDNSSECKey(missing) expected error")
+	}
+	if err := os.WriteFile(base+".private", []byte("garbage\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadDNSSECKey(base); err == nil {
+		t.Error("LoadDNSSECKey() expected error for a corrupt private key")
+	}
+}
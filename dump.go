@@ -0,0 +1,322 @@
+// ABOUTME: Full configuration-as-data dump and restore for disaster recovery.
+// ABOUTME: Captures records, zones, SOA and store policy; credentials are never included.
+
+package dynupdate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// configDumpVersion is the format version written by Dump and accepted by Restore.
+const configDumpVersion = 1
+
+// ErrInvalidDump is returned by Restore for a dump it cannot apply.
+var ErrInvalidDump = errors.New("invalid configuration dump")
+
+// ConfigDump is everything needed to reconstruct the served zones: the
+// records plus the Corefile settings that shape answers and mutations.
+// Authentication settings (tokens, allowed CNs, TLS files) are deliberately
+// not part of it.
+type ConfigDump struct {
+	Version    int          `json:"version"`
+	Generation uint64       `json:"generation"`
+	Zones      []string     `json:"zones"`
+	SOA        SOAConfig    `json:"soa"`
+	Policy     PolicyConfig `json:"policy"`
+	Records    []Record     `json:"records"`
+}
+
+// PolicyConfig is the store's mutation policy as configured in the Corefile.
+type PolicyConfig struct {
+	SyncPolicy        SyncPolicy              `json:"sync_policy"`
+	MaxRecords        int                     `json:"max_records,omitempty"`
+	MaxNames          int                     `json:"max_names,omitempty"`
+	MaxRecordsPerZone int                     `json:"max_records_per_zone,omitempty"`
+	MaxValuesPerRRset int                     `json:"max_values_per_rrset,omitempty"`
+	Quotas            map[string]int          `json:"quotas,omitempty"`
+	Tenants           map[string]TenantPolicy `json:"tenants,omitempty"`
+}
+
+// RestoreResult reports what a Restore applied. Drift lists the settings in
+// the dump that differ from the running configuration; they are not applied,
+// since the Corefile stays the source of truth for configuration.
+type RestoreResult struct {
+	Records    int      `json:"records"`
+	Generation uint64   `json:"generation"`
+	Drift      []string `json:"drift"`
+}
+
+// Dump returns the full configuration and every stored record, in canonical
+// order.
+func (d *DynUpdate) Dump() ConfigDump {
+	records := d.Store.snapshot()
+	sortRecords(records)
+	if records == nil {
+		records = []Record{}
+	}
+	return ConfigDump{
+		Version:    configDumpVersion,
+		Generation: d.Store.Generation(),
+		Zones:      slices.Clone(d.Zones),
+		SOA:        d.SOA,
+		Policy:     d.Store.policyConfig(),
+		Records:    records,
+	}
+}
+
+// Restore replaces every stored record with the records in dump, as one
+// atomic change. Every record is validated first and nothing is applied if
+// any is invalid or duplicated. The store generation does not move below the
+// dump's, so the SOA serial never goes backwards for secondaries.
+func (d *DynUpdate) Restore(ctx context.Context, dump ConfigDump) (RestoreResult, error) {
+	if dump.Version != configDumpVersion {
+		return RestoreResult{}, fmt.Errorf("%w: unsupported version %d", ErrInvalidDump, dump.Version)
+	}
+
+	seen := make(map[RecordKey]bool, len(dump.Records))
+	for i := range dump.Records {
+		r := &dump.Records[i]
+		if err := d.Store.validateRecord(r); err != nil {
+			return RestoreResult{}, fmt.Errorf("%w: record %d: %w", ErrInvalidDump, i, err)
+		}
+		if seen[r.Key()] {
+			return RestoreResult{}, fmt.Errorf("%w: record %d: %s %s %s appears twice", ErrInvalidDump, i, r.Name, r.Type, r.Value)
+		}
+		seen[r.Key()] = true
+	}
+
+	if err := d.Store.Restore(ctx, dump.Records, dump.Generation); err != nil {
+		return RestoreResult{}, err
+	}
+	return RestoreResult{
+		Records:    len(dump.Records),
+		Generation: d.Store.Generation(),
+		Drift:      d.configDrift(dump),
+	}, nil
+}
+
+// configDrift names the settings in dump that differ from the running ones.
+func (d *DynUpdate) configDrift(dump ConfigDump) []string {
+	drift := []string{}
+	if !slices.Equal(d.Zones, dump.Zones) {
+		drift = append(drift, "zones")
+	}
+	if d.SOA.withDefaults() != dump.SOA.withDefaults() {
+		drift = append(drift, "soa")
+	}
+	running := d.Store.policyConfig()
+	if running.SyncPolicy != dump.Policy.SyncPolicy {
+		drift = append(drift, "policy.sync_policy")
+	}
+	if running.MaxRecords != dump.Policy.MaxRecords {
+		drift = append(drift, "policy.max_records")
+	}
+	if running.MaxNames != dump.Policy.MaxNames {
+		drift = append(drift, "policy.max_names")
+	}
+	if running.MaxRecordsPerZone != dump.Policy.MaxRecordsPerZone {
+		drift = append(drift, "policy.max_records_per_zone")
+	}
+	if running.MaxValuesPerRRset != dump.Policy.MaxValuesPerRRset {
+		drift = append(drift, "policy.max_values_per_rrset")
+	}
+	if !maps.Equal(running.Quotas, dump.Policy.Quotas) {
+		drift = append(drift, "policy.quotas")
+	}
+	if !maps.Equal(running.Tenants, dump.Policy.Tenants) {
+		drift = append(drift, "policy.tenants")
+	}
+	return drift
+}
+
+// ExportOptions shapes how records are written in a configuration export.
+// They change only the export; the persisted format and what Restore
+// accepts stay the same.
+type ExportOptions struct {
+	// AllFields emits priority, weight, port, flag and tag on every record,
+	// even when zero or empty.
+	AllFields bool
+	// LegacyNames writes record fields under their Go names (Name, Type,
+	// TTL, ...), as tooling that marshalled records without JSON tags did.
+	LegacyNames bool
+}
+
+// Export returns the configuration dump encoded as JSON with opts applied
+// to the records.
+func (d *DynUpdate) Export(opts ExportOptions) ([]byte, error) {
+	dump := d.Dump()
+	records := make([]exportRecord, len(dump.Records))
+	for i, r := range dump.Records {
+		records[i] = exportRecord{rec: r, opts: opts}
+	}
+	return json.Marshal(struct {
+		ConfigDump
+		Records []exportRecord `json:"records"`
+	}{dump, records})
+}
+
+// exportRecord marshals a record according to the export options.
+type exportRecord struct {
+	rec  Record
+	opts ExportOptions
+}
+
+// exportField is one record field: its JSON and legacy names, its value,
+// and whether it is left out when zero unless all fields are requested.
+type exportField struct {
+	name, legacy string
+	value        any
+	zero         bool
+	optional     bool
+}
+
+func (e exportRecord) MarshalJSON() ([]byte, error) {
+	r := e.rec
+	fields := []exportField{
+		{name: "name", legacy: "Name", value: r.Name},
+		{name: "type", legacy: "Type", value: r.Type},
+		{name: "ttl", legacy: "TTL", value: r.TTL},
+		{name: "value", legacy: "Value", value: r.Value},
+		{name: "priority", legacy: "Priority", value: r.Priority, zero: r.Priority == 0, optional: true},
+		{name: "weight", legacy: "Weight", value: r.Weight, zero: r.Weight == 0, optional: true},
+		{name: "port", legacy: "Port", value: r.Port, zero: r.Port == 0, optional: true},
+		{name: "flag", legacy: "Flag", value: r.Flag, zero: r.Flag == 0, optional: true},
+		{name: "tag", legacy: "Tag", value: r.Tag, zero: r.Tag == "", optional: true},
+		{name: "os", legacy: "OS", value: r.OS, zero: r.OS == "", optional: true},
+		{name: "txt_name", legacy: "TXTName", value: r.TXTName, zero: r.TXTName == "", optional: true},
+		// Ownership and lifecycle fields keep their usual omission rules.
+		{name: "owner", legacy: "Owner", value: r.Owner, zero: r.Owner == ""},
+		{name: "expires_at", legacy: "ExpiresAt", value: r.ExpiresAt, zero: r.ExpiresAt.IsZero()},
+		{name: "ttl_window", legacy: "TTLWindow", value: r.TTLWindow, zero: r.TTLWindow == (TTLWindow{})},
+		{name: "changed_at", legacy: "ChangedAt", value: r.ChangedAt, zero: r.ChangedAt == 0},
+		{name: "created_at", legacy: "CreatedAt", value: r.CreatedAt, zero: r.CreatedAt.IsZero()},
+		{name: "updated_at", legacy: "UpdatedAt", value: r.UpdatedAt, zero: r.UpdatedAt.IsZero()},
+		{name: "comment", legacy: "Comment", value: r.Comment, zero: r.Comment == ""},
+		{name: "labels", legacy: "Labels", value: r.Labels, zero: len(r.Labels) == 0},
+		{name: "disabled", legacy: "Disabled", value: r.Disabled, zero: !r.Disabled},
+		{name: "protected", legacy: "Protected", value: r.Protected, zero: !r.Protected},
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for _, f := range fields {
+		if f.zero && !(f.optional && e.opts.AllFields) {
+			continue
+		}
+		name := f.name
+		if e.opts.LegacyNames {
+			name = f.legacy
+		}
+		v, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, fmt.Errorf("marshal %s: %w", f.name, err)
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		fmt.Fprintf(&buf, "%q:", name)
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// policyConfig returns the store's configured mutation policy.
+func (s *Store) policyConfig() PolicyConfig {
+	return PolicyConfig{
+		SyncPolicy:        s.syncPolicy,
+		MaxRecords:        s.maxRecords,
+		MaxNames:          s.maxNames,
+		MaxRecordsPerZone: s.maxPerZone,
+		MaxValuesPerRRset: s.maxPerSet,
+		Quotas:            maps.Clone(s.quotas),
+		Tenants:           maps.Clone(s.tenants),
+	}
+}
+
+// Restore replaces the whole record set with records and persists it with a
+// full backend rewrite. Only unscoped or admin callers may restore. Because
+// it deletes records, any sync policy other than sync denies it unless the
+// store is empty. gen is the generation the records were dumped at; the
+// store generation is raised to it if behind.
+func (s *Store) Restore(ctx context.Context, records []Record, gen uint64) error {
+	if _, scoped := scopedOwner(ctx); scoped {
+		return fmt.Errorf("restore requires an admin: %w", ErrNotOwner)
+	}
+	return s.commit(ctx, func() (Change, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if s.policyFor(ctx) != PolicySync && s.countLocked() > 0 {
+			return Change{}, fmt.Errorf("restore over existing records: %w", ErrPolicyDenied)
+		}
+		if s.maxRecords > 0 && len(records) > s.maxRecords {
+			return Change{}, fmt.Errorf("restore of %d records exceeds the record limit of %d", len(records), s.maxRecords)
+		}
+		if n := countNames(records); s.maxNames > 0 && n > s.maxNames {
+			return Change{}, fmt.Errorf("restore of %d names exceeds the limit of %d: %w", n, s.maxNames, ErrNameLimit)
+		}
+		if s.maxPerZone > 0 {
+			if zone, n := s.fullestZone(records); n > s.maxPerZone {
+				return Change{}, fmt.Errorf("restore of %d records into zone %s exceeds the limit of %d: %w", n, zone, s.maxPerZone, ErrZoneLimit)
+			}
+		}
+		if s.maxPerSet > 0 {
+			if set, n := largestRRset(records); n > s.maxPerSet {
+				return Change{}, fmt.Errorf("restore of %d values for %s exceeds the limit of %d: %w", n, set, s.maxPerSet, ErrRRsetLimit)
+			}
+		}
+
+		s.replaceLocked(slices.Clone(records), gen)
+		// Nil Names asks the backend for a full rewrite.
+		return Change{Generation: s.generation, all: s.snapshot}, nil
+	})
+}
+
+// fullestZone returns the zone holding the most of records, and how many.
+func (s *Store) fullestZone(records []Record) (string, int) {
+	counts := make(map[string]int)
+	var top string
+	for _, r := range records {
+		zone := s.zoneOf(strings.ToLower(r.Name))
+		if zone == "" {
+			continue
+		}
+		counts[zone]++
+		if counts[zone] > counts[top] {
+			top = zone
+		}
+	}
+	return top, counts[top]
+}
+
+// countNames returns the number of distinct names among records.
+// largestRRset returns the name and type ("name TYPE") holding the most of
+// records, and how many.
+func largestRRset(records []Record) (string, int) {
+	counts := make(map[string]int)
+	var top string
+	for _, r := range records {
+		set := strings.ToLower(r.Name) + " " + strings.ToUpper(r.Type)
+		counts[set]++
+		if counts[set] > counts[top] {
+			top = set
+		}
+	}
+	return top, counts[top]
+}
+
+func countNames(records []Record) int {
+	names := make(map[string]struct{}, len(records))
+	for _, r := range records {
+		names[strings.ToLower(r.Name)] = struct{}{}
+	}
+	return len(names)
+}
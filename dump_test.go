@@ -0,0 +1,220 @@
+// ABOUTME: Tests for the full configuration dump: round-trip into a fresh store, drift, rejection.
+// ABOUTME: Also checks that the admin endpoints never expose credentials.
+
+package dynupdate
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// newDumpHandler returns a handler with a fresh store and the given options.
+func newDumpHandler(t *testing.T, soa SOAConfig, opts ...StoreOption) *DynUpdate {
+	t.Helper()
+	s := newTestStore(t, filepath.Join(t.TempDir(), "records.json"), opts...)
+	return &DynUpdate{Zones: []string{"example.org."}, Store: s, SOA: soa}
+}
+
+func TestConfigDump_RoundTrip(t *testing.T) {
+	t.Parallel()
+	soa := SOAConfig{MName: "ns.example.net.", RName: "dns.example.net.", Refresh: 3600, MinTTL: 120}
+	opts := []StoreOption{
+		WithSyncPolicy(PolicyUpsertOnly),
+		WithMaxRecords(100),
+		WithQuotas(map[string]int{"team-a": 10}),
+		WithTenantPolicies(map[string]TenantPolicy{"team-a": {SyncPolicy: PolicySync, DefaultTTL: 600}}),
+	}
+
+	src := newDumpHandler(t, soa, opts...)
+	for _, r := range []Record{
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1", Owner: "team-a"},
+		{Name: "example.org.", Type: "NS", TTL: 3600, Value: "ns.example.net."},
+		{Name: "example.org.", Type: "MX", TTL: 300, Value: "mail.example.org.", Priority: 10},
+	} {
+		if err := src.Store.Upsert(t.Context(), r); err != nil {
+			t.Fatalf("Upsert() error: %v", err)
+		}
+	}
+
+	raw, err := json.Marshal(src.Dump())
+	if err != nil {
+		t.Fatalf("Marshal() error: %v", err)
+	}
+	var dump ConfigDump
+	if err := json.Unmarshal(raw, &dump); err != nil {
+		t.Fatalf("Unmarshal() error: %v", err)
+	}
+
+	dst := newDumpHandler(t, soa, opts...)
+	res, err := dst.Restore(t.Context(), dump)
+	if err != nil {
+		t.Fatalf("Restore() error: %v", err)
+	}
+	if res.Records != 3 || len(res.Drift) != 0 {
+		t.Errorf("Restore() = %+v, want 3 records and no drift", res)
+	}
+	if got, want := dst.Store.List(t.Context()), src.Store.List(t.Context()); !slices.EqualFunc(got, want, Record.equal) {
+		t.Errorf("restored records = %v, want %v", got, want)
+	}
+
+	srcSerial := src.soa("example.org.").(*dns.SOA).Serial
+	dstSerial := dst.soa("example.org.").(*dns.SOA).Serial
+	if dstSerial < srcSerial {
+		t.Errorf("restored SOA serial %d went backwards from %d", dstSerial, srcSerial)
+	}
+}
+
+func TestConfigDump_ReportsDrift(t *testing.T) {
+	t.Parallel()
+	src := newDumpHandler(t, SOAConfig{MName: "ns1"}, WithMaxRecords(10))
+	dst := newDumpHandler(t, SOAConfig{MName: "ns2"})
+
+	res, err := dst.Restore(t.Context(), src.Dump())
+	if err != nil {
+		t.Fatalf("Restore() error: %v", err)
+	}
+	if want := []string{"soa", "policy.max_records"}; !slices.Equal(res.Drift, want) {
+		t.Errorf("Drift = %v, want %v", res.Drift, want)
+	}
+	if dst.SOA.MName != "ns2" {
+		t.Errorf("running SOA was overwritten by the dump: %+v", dst.SOA)
+	}
+}
+
+func TestConfigDump_InvalidRejectedAtomically(t *testing.T) {
+	t.Parallel()
+	d := newDumpHandler(t, SOAConfig{})
+	existing := Record{Name: "keep.example.org.", Type: "A", TTL: 300, Value: "10.0.0.9"}
+	if err := d.Store.Upsert(t.Context(), existing); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	good := Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}
+
+	tests := []struct {
+		name string
+		dump ConfigDump
+	}{
+		{name: "unknown version", dump: ConfigDump{Version: 99, Records: []Record{good}}},
+		{name: "invalid record", dump: ConfigDump{Version: configDumpVersion, Records: []Record{good, {Name: "bad.example.org.", Type: "A", Value: "nope"}}}},
+		{name: "duplicate record", dump: ConfigDump{Version: configDumpVersion, Records: []Record{good, good}}},
+	}
+	for _, tt := range tests {
+		if _, err := d.Restore(t.Context(), tt.dump); !errors.Is(err, ErrInvalidDump) {
+			t.Errorf("%s: Restore() error = %v, want ErrInvalidDump", tt.name, err)
+		}
+	}
+	if got := d.Store.List(t.Context()); !slices.EqualFunc(got, []Record{existing}, Record.equal) {
+		t.Errorf("store = %v after rejected restores, want it unchanged", got)
+	}
+}
+
+func TestAPI_ConfigDump(t *testing.T) {
+	t.Parallel()
+	d := newDumpHandler(t, SOAConfig{})
+	if err := d.Store.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	auth := &Auth{Tokens: map[string]string{"admin-secret": "ops", "tenant-secret": "team-a"}, Ownership: true, Admins: []string{"ops"}}
+	h := NewAPIServer(d.Store, auth, ":0", nil, WithConfigDump(d)).handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/config", nil)
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("export status = %d, want %d; body = %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	body := rec.Body.Bytes()
+	if bytes.Contains(body, []byte("secret")) {
+		t.Errorf("export leaks credentials: %s", body)
+	}
+
+	req = httptest.NewRequest(http.MethodPut, "/api/v1/admin/config", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer tenant-secret")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("tenant import status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	req = httptest.NewRequest(http.MethodPut, "/api/v1/admin/config", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"records":1`) {
+		t.Errorf("admin import status = %d body = %s, want 200 with 1 record", rec.Code, rec.Body.String())
+	}
+}
+
+func TestAPI_ConfigDump_ExportOptions(t *testing.T) {
+	t.Parallel()
+	d := newDumpHandler(t, SOAConfig{})
+	if err := d.Store.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	h := NewAPIServer(d.Store, &Auth{Token: "test-token"}, ":0", nil, WithConfigDump(d)).handler()
+
+	export := func(query string) (int, map[string]json.RawMessage) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/config"+query, nil)
+		req.Header.Set("Authorization", "Bearer test-token")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			return rec.Code, nil
+		}
+		var dump struct {
+			Records []map[string]json.RawMessage `json:"records"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &dump); err != nil {
+			t.Fatalf("Unmarshal(%s) error: %v", rec.Body.String(), err)
+		}
+		if len(dump.Records) != 1 {
+			t.Fatalf("records = %d, want 1", len(dump.Records))
+		}
+		return rec.Code, dump.Records[0]
+	}
+
+	tests := []struct {
+		query   string
+		present []string
+		absent  []string
+	}{
+		{"", []string{"name", "ttl"}, []string{"priority", "weight", "port", "flag", "tag"}},
+		{"?fields=all", []string{"name", "priority", "weight", "port", "flag", "tag"}, []string{"owner", "expires_at", "ttl_window"}},
+		{"?naming=legacy", []string{"Name", "Type", "TTL", "Value"}, []string{"name", "ttl", "Priority"}},
+		{"?fields=all&naming=legacy", []string{"Name", "Priority", "Weight", "Port", "Flag", "Tag"}, []string{"priority", "Owner"}},
+	}
+	for _, tt := range tests {
+		_, rec := export(tt.query)
+		for _, f := range tt.present {
+			if _, ok := rec[f]; !ok {
+				t.Errorf("export%s: field %q missing from %v", tt.query, f, rec)
+			}
+		}
+		for _, f := range tt.absent {
+			if _, ok := rec[f]; ok {
+				t.Errorf("export%s: unexpected field %q in %v", tt.query, f, rec)
+			}
+		}
+	}
+
+	_, rec := export("?fields=all")
+	if string(rec["priority"]) != "0" || string(rec["tag"]) != `""` {
+		t.Errorf("zero fields = priority %s tag %s, want 0 and \"\"", rec["priority"], rec["tag"])
+	}
+	for _, q := range []string{"?fields=some", "?naming=camel"} {
+		if code, _ := export(q); code != http.StatusBadRequest {
+			t.Errorf("export%s status = %d, want %d", q, code, http.StatusBadRequest)
+		}
+	}
+}
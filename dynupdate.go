@@ -6,12 +6,15 @@ package dynupdate
 import (
 	"context"
 	"fmt"
+	"math/rand/v2"
+	"net/netip"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/coredns/coredns/plugin"
-	clog "github.com/coredns/coredns/plugin/pkg/log"
 	"github.com/coredns/coredns/plugin/pkg/fall"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
 	"github.com/coredns/coredns/request"
 	"github.com/miekg/dns"
 )
@@ -21,6 +24,27 @@ const (
 	maxCNAMEHops = 10
 )
 
+// dnssecTypes are the DNSSEC record types. The store never holds them; a
+// zone signed with a DNSSEC key synthesizes its DNSKEY and RRSIGs.
+var dnssecTypes = map[uint16]bool{
+	dns.TypeDS: true, dns.TypeDNSKEY: true, dns.TypeRRSIG: true,
+	dns.TypeNSEC: true, dns.TypeNSEC3: true, dns.TypeNSEC3PARAM: true,
+}
+
+// pluginAlias is an alternative Corefile directive name for the plugin. Both
+// names share the same setup; logs and errors always use pluginName.
+const pluginAlias = "dyndns"
+
+// Default SOA parameters used when the Corefile does not override them.
+const (
+	defaultSOAMName   = "ns1"
+	defaultSOARName   = "hostmaster"
+	defaultSOARefresh = 7200
+	defaultSOARetry   = 1800
+	defaultSOAExpire  = 86400
+	defaultSOAMinTTL  = 300
+)
+
 var log = clog.NewWithPlugin(pluginName)
 
 // DynUpdate implements plugin.Handler for dynamic DNS record management.
@@ -29,6 +53,64 @@ type DynUpdate struct {
 	Zones []string
 	Store *Store
 	Fall  fall.F
+	SOA   SOAConfig
+
+	// RoundRobin rotates multi-value answer sets on every query so clients
+	// that pick the first address spread load across all of them.
+	RoundRobin bool
+	rrCounter  atomic.Uint64
+
+	// Weighted answers A and AAAA queries with a single address, chosen on
+	// every query with probability proportional to the records' Weight.
+	Weighted bool
+
+	// WildcardNXDOMAIN answers NXDOMAIN instead of NODATA when a wildcard
+	// covers the name but holds no records of the queried type. RFC 4592
+	// calls for NODATA; this exists for clients that relied on the old
+	// behavior of other servers.
+	WildcardNXDOMAIN bool
+
+	// TTLJitter lowers each served RRset's TTL by a random amount of up to
+	// this fraction of it, so caches holding many records with the same TTL
+	// do not all expire them at once. Zero disables it.
+	TTLJitter float64
+
+	// MinServeTTL raises any served TTL below it to this many seconds, so a
+	// low stored TTL or a jittered one cannot be used to bust caches. Stored
+	// TTLs are unchanged. Zero disables it.
+	MinServeTTL uint32
+
+	// AnswerOrder sorts the records of each owner name in the answer
+	// section, for reproducible responses. OrderNone keeps store order.
+	AnswerOrder AnswerOrder
+
+	// DNSSEC, when set, signs answers in its zone for queries with the DO
+	// bit and serves its DNSKEY at the apex. Negative answers are not
+	// signed yet.
+	DNSSEC *DNSSECKey
+
+	// NegativeCache, when set, remembers names answered NXDOMAIN until the
+	// store next changes, skipping the lookup for repeated queries.
+	NegativeCache *negativeCache
+
+	// TransferTo lists the client prefixes allowed to AXFR the zones.
+	// Transfers are refused when empty.
+	TransferTo []netip.Prefix
+}
+
+// SOAConfig holds the parameters of the synthesized zone SOA record.
+// Zero-valued fields fall back to the defaults.
+type SOAConfig struct {
+	// MName and RName are qualified with the zone unless they end with a dot.
+	MName   string `json:"mname,omitempty"`
+	RName   string `json:"rname,omitempty"`
+	Refresh uint32 `json:"refresh,omitempty"`
+	Retry   uint32 `json:"retry,omitempty"`
+	Expire  uint32 `json:"expire,omitempty"`
+	MinTTL  uint32 `json:"minttl,omitempty"`
+	// Serial pins the SOA serial. When zero, the serial tracks the store
+	// generation so it only changes when records change.
+	Serial uint32 `json:"serial,omitempty"`
 }
 
 // Name returns the plugin name.
@@ -36,6 +118,13 @@ func (d *DynUpdate) Name() string { return pluginName }
 
 // ServeDNS handles DNS queries by looking up records in the store.
 func (d *DynUpdate) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	// Everything below reads the first question. A message without exactly
+	// one is malformed (RFC 9619): refuse it before touching the store and
+	// let the server write the FORMERR reply.
+	if len(r.Question) != 1 {
+		return dns.RcodeFormatError, nil
+	}
+
 	state := request.Request{W: w, Req: r}
 	qname := state.Name()
 	qtype := state.QType()
@@ -53,7 +142,49 @@ func (d *DynUpdate) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.M
 		responseCount.WithLabelValues(zone, dns.RcodeToString[rcode]).Inc()
 	}()
 
-	allRecords := d.Store.GetAll(qname)
+	if qtype == dns.TypeAXFR {
+		rcode, retErr = d.serveAXFR(ctx, w, r, zone)
+		return rcode, retErr
+	}
+
+	// The apex SOA is synthesized, never stored.
+	if qtype == dns.TypeSOA && qname == zone {
+		rcode, retErr = d.writeAnswer(w, r, []dns.RR{d.soa(zone)}, nil)
+		return rcode, retErr
+	}
+
+	// A signed zone publishes its key at the apex.
+	if qtype == dns.TypeDNSKEY && qname == zone && d.signs(zone) {
+		rcode, retErr = d.writeAnswer(w, r, []dns.RR{dns.Copy(d.DNSSEC.DNSKEY)}, nil)
+		return rcode, retErr
+	}
+
+	// An unsigned zone has no DNSSEC records at the apex. Answering NODATA
+	// with the SOA, never NXDOMAIN or a referral to the next plugin, lets
+	// validators prove the zone insecure. Below the apex these types follow
+	// the normal existence rules.
+	if qname == zone && dnssecTypes[qtype] {
+		rcode, retErr = d.writeNODATA(w, r, zone)
+		return rcode, retErr
+	}
+
+	managed := managedQType(qtype)
+	if !managed {
+		unsupportedQTypeCount.WithLabelValues(zone).Inc()
+	}
+
+	// Read the generation before the lookup, so a write racing with it
+	// leaves the cache entry already stale.
+	var gen uint64
+	if d.NegativeCache != nil {
+		gen = d.Store.Generation()
+		if d.NegativeCache.has(qname, gen) {
+			rcode, retErr = d.writeNXDOMAIN(w, r, zone)
+			return rcode, retErr
+		}
+	}
+
+	allRecords, wildcard := d.Store.Lookup(qname)
 
 	// No records for this name
 	if len(allRecords) == 0 {
@@ -61,14 +192,41 @@ func (d *DynUpdate) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.M
 			rcode, retErr = plugin.NextOrFailure(d.Name(), d.Next, ctx, w, r)
 			return rcode, retErr
 		}
+		// A name with records below it exists even without records of its
+		// own, and so does one whose records are waiting out the serve delay.
+		if d.Store.IsEmptyNonTerminal(qname) || d.Store.Pending(qname) {
+			rcode, retErr = d.writeNODATA(w, r, zone)
+			return rcode, retErr
+		}
+		if d.NegativeCache != nil {
+			d.NegativeCache.add(qname, gen)
+		}
 		rcode, retErr = d.writeNXDOMAIN(w, r, zone)
 		return rcode, retErr
 	}
 
+	// ANY returns every record the name holds, whatever its type.
+	if qtype == dns.TypeANY {
+		answers := recordsToRR(allRecords)
+		echoOwner(answers, state.QName())
+		rcode, retErr = d.writeAnswer(w, r, answers, nil)
+		return rcode, retErr
+	}
+
+	// No stored record can hold a type the plugin does not manage, so an
+	// existing name answers NODATA without filtering or chasing.
+	if !managed && !(wildcard && d.WildcardNXDOMAIN) {
+		rcode, retErr = d.writeNODATA(w, r, zone)
+		return rcode, retErr
+	}
+
 	// Filter by query type
 	typeRecords := filterByType(allRecords, qtype)
 	if len(typeRecords) > 0 {
-		rcode, retErr = d.writeAnswer(w, r, recordsToRR(typeRecords))
+		typeRecords = d.weigh(qtype, d.rotate(typeRecords))
+		answers := recordsToRR(typeRecords)
+		echoOwner(answers, state.QName())
+		rcode, retErr = d.writeAnswer(w, r, answers, d.glue(answers, zone))
 		return rcode, retErr
 	}
 
@@ -80,14 +238,20 @@ func (d *DynUpdate) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.M
 			// Build answer: CNAME + chain
 			rr, err := cnameRecords[0].ToRR()
 			if err == nil {
+				echoOwner([]dns.RR{rr}, state.QName())
 				answers := append([]dns.RR{rr}, chain...)
-				rcode, retErr = d.writeAnswer(w, r, answers)
+				rcode, retErr = d.writeAnswer(w, r, answers, nil)
 				return rcode, retErr
 			}
 		}
 	}
 
-	// Name exists but no matching type => NODATA
+	if wildcard && d.WildcardNXDOMAIN {
+		rcode, retErr = d.writeNXDOMAIN(w, r, zone)
+		return rcode, retErr
+	}
+
+	// Name exists (possibly through a wildcard) but no matching type => NODATA
 	rcode, retErr = d.writeNODATA(w, r, zone)
 	return rcode, retErr
 }
@@ -98,7 +262,7 @@ func (d *DynUpdate) chaseCNAME(target string, qtype uint16, depth int) []dns.RR
 		return nil
 	}
 
-	allRecords := d.Store.GetAll(target)
+	allRecords, _ := d.Store.Lookup(target)
 	if len(allRecords) == 0 {
 		return nil
 	}
@@ -106,6 +270,7 @@ func (d *DynUpdate) chaseCNAME(target string, qtype uint16, depth int) []dns.RR
 	// Check for the requested type at the target
 	typeRecords := filterByType(allRecords, qtype)
 	if len(typeRecords) > 0 {
+		typeRecords = d.weigh(qtype, d.rotate(typeRecords))
 		var rrs []dns.RR
 		for _, rec := range typeRecords {
 			rr, err := rec.ToRR()
@@ -130,6 +295,26 @@ func (d *DynUpdate) chaseCNAME(target string, qtype uint16, depth int) []dns.RR
 	return nil
 }
 
+// rotate returns records shifted left by a per-query offset when round-robin
+// is enabled. Each call claims its own counter value, so concurrent queries
+// never share an offset and the lead record cycles through the set.
+func (d *DynUpdate) rotate(records []Record) []Record {
+	if !d.RoundRobin || len(records) < 2 {
+		return records
+	}
+	n := uint64(len(records))
+	off := int((d.rrCounter.Add(1) - 1) % n)
+	out := make([]Record, 0, len(records))
+	out = append(out, records[off:]...)
+	return append(out, records[:off]...)
+}
+
+// managedQType reports whether qtype is one the plugin answers from its
+// own data: a type records can be stored with, the synthesized SOA, or ANY.
+func managedQType(qtype uint16) bool {
+	return qtype == dns.TypeSOA || qtype == dns.TypeANY || supportedTypes[dns.TypeToString[qtype]]
+}
+
 func filterByType(records []Record, qtype uint16) []Record {
 	typeName := dns.TypeToString[qtype]
 	var result []Record
@@ -141,6 +326,15 @@ func filterByType(records []Record, qtype uint16) []Record {
 	return result
 }
 
+// echoOwner sets the owner name of rrs to qname exactly as the client sent
+// it. Resolvers using 0x20 randomization compare the casing of the answer
+// with their query; stored and wildcard-expanded names would not match.
+func echoOwner(rrs []dns.RR, qname string) {
+	for _, rr := range rrs {
+		rr.Header().Name = qname
+	}
+}
+
 func recordsToRR(records []Record) []dns.RR {
 	rrs := make([]dns.RR, 0, len(records))
 	for _, rec := range records {
@@ -154,11 +348,80 @@ func recordsToRR(records []Record) []dns.RR {
 	return rrs
 }
 
-func (d *DynUpdate) writeAnswer(w dns.ResponseWriter, r *dns.Msg, answers []dns.RR) (int, error) {
+// glue returns the A/AAAA records held for the targets of MX, SRV and NS
+// answers, so resolvers can skip the follow-up lookup. Only in-zone targets
+// are considered, and each target is added once.
+func (d *DynUpdate) glue(answers []dns.RR, zone string) []dns.RR {
+	var extra []dns.RR
+	seen := make(map[string]bool)
+	for _, rr := range answers {
+		var target string
+		switch v := rr.(type) {
+		case *dns.MX:
+			target = v.Mx
+		case *dns.SRV:
+			target = v.Target
+		case *dns.NS:
+			target = v.Ns
+		default:
+			continue
+		}
+
+		target = strings.ToLower(target)
+		if seen[target] || !dns.IsSubDomain(zone, target) {
+			continue
+		}
+		seen[target] = true
+
+		recs, _ := d.Store.Lookup(target)
+		for _, rec := range recs {
+			if rec.Type != "A" && rec.Type != "AAAA" {
+				continue
+			}
+			if rr, err := rec.ToRR(); err == nil {
+				extra = append(extra, rr)
+			}
+		}
+	}
+	return extra
+}
+
+// writeAnswer sends answers with extra as additional data. The reply is
+// fitted to the client's buffer size, which its OPT record advertises:
+// names are compressed when the uncompressed message would not fit, and
+// records that still do not fit are dropped, additional data first. TC is
+// only set when answer records had to go; missing glue does not make an
+// answer incomplete (RFC 2181 section 9).
+func (d *DynUpdate) writeAnswer(w dns.ResponseWriter, r *dns.Msg, answers, extra []dns.RR) (int, error) {
+	state := request.Request{W: w, Req: r}
 	msg := new(dns.Msg)
 	msg.SetReply(r)
 	msg.Authoritative = true
 	msg.Answer = append(msg.Answer, answers...)
+	msg.Extra = append(msg.Extra, extra...)
+	d.AnswerOrder.sortAnswers(msg.Answer)
+	if d.TTLJitter > 0 {
+		d.jitterTTLs(msg.Answer)
+		d.jitterTTLs(msg.Extra)
+	}
+	if d.MinServeTTL > 0 {
+		d.floorTTLs(msg.Answer)
+		d.floorTTLs(msg.Extra)
+	}
+	// Signatures cover the final TTLs, so they are made last.
+	signed := len(answers)
+	if state.Do() && d.DNSSEC != nil {
+		now := time.Now()
+		msg.Answer = append(msg.Answer, d.DNSSEC.sign(msg.Answer, now)...)
+		msg.Extra = append(msg.Extra, d.DNSSEC.sign(msg.Extra, now)...)
+		signed = len(msg.Answer)
+	}
+
+	setOPT(state, msg)
+	msg = state.Scrub(msg)
+	if len(msg.Answer) == signed {
+		msg.Truncated = false
+	}
 
 	if err := w.WriteMsg(msg); err != nil {
 		return dns.RcodeServerFailure, fmt.Errorf("writing response: %w", err)
@@ -166,11 +429,58 @@ func (d *DynUpdate) writeAnswer(w dns.ResponseWriter, r *dns.Msg, answers []dns.
 	return dns.RcodeSuccess, nil
 }
 
+// jitterTTLs lowers the TTL of each RRset in rrs by a random amount of up to
+// TTLJitter of it. Records of one RRset keep a common TTL (RFC 2181 section
+// 5.2), and no TTL drops below 1.
+func (d *DynUpdate) jitterTTLs(rrs []dns.RR) {
+	type rrset struct {
+		name  string
+		rtype uint16
+	}
+	cut := make(map[rrset]uint32)
+	for _, rr := range rrs {
+		hdr := rr.Header()
+		key := rrset{strings.ToLower(hdr.Name), hdr.Rrtype}
+		c, ok := cut[key]
+		if !ok {
+			if span := uint32(float64(hdr.Ttl) * d.TTLJitter); span > 0 {
+				c = rand.Uint32N(span + 1)
+			}
+			cut[key] = c
+		}
+		hdr.Ttl = max(hdr.Ttl-min(c, hdr.Ttl), 1)
+	}
+}
+
+// floorTTLs raises every TTL in rrs below MinServeTTL to it.
+func (d *DynUpdate) floorTTLs(rrs []dns.RR) {
+	for _, rr := range rrs {
+		hdr := rr.Header()
+		hdr.Ttl = max(hdr.Ttl, d.MinServeTTL)
+	}
+}
+
+// signs reports whether zone is signed with the DNSSEC key.
+func (d *DynUpdate) signs(zone string) bool {
+	return d.DNSSEC != nil && d.DNSSEC.Zone() == zone
+}
+
+// setOPT answers an EDNS0 query with an OPT record advertising the
+// negotiated UDP buffer size, echoing the DO bit and the options CoreDNS
+// understands. A query without OPT gets none back (RFC 6891 section 7).
+func setOPT(state request.Request, msg *dns.Msg) {
+	size := state.Size()
+	if state.SizeAndDo(msg) {
+		msg.IsEdns0().SetUDPSize(uint16(size))
+	}
+}
+
 func (d *DynUpdate) writeNXDOMAIN(w dns.ResponseWriter, r *dns.Msg, zone string) (int, error) {
 	msg := new(dns.Msg)
 	msg.SetRcode(r, dns.RcodeNameError)
 	msg.Authoritative = true
-	msg.Ns = []dns.RR{d.soa(zone)}
+	msg.Ns = []dns.RR{d.negativeSOA(zone)}
+	setOPT(request.Request{W: w, Req: r}, msg)
 
 	if err := w.WriteMsg(msg); err != nil {
 		return dns.RcodeServerFailure, fmt.Errorf("writing NXDOMAIN: %w", err)
@@ -182,7 +492,8 @@ func (d *DynUpdate) writeNODATA(w dns.ResponseWriter, r *dns.Msg, zone string) (
 	msg := new(dns.Msg)
 	msg.SetReply(r)
 	msg.Authoritative = true
-	msg.Ns = []dns.RR{d.soa(zone)}
+	msg.Ns = []dns.RR{d.negativeSOA(zone)}
+	setOPT(request.Request{W: w, Req: r}, msg)
 
 	if err := w.WriteMsg(msg); err != nil {
 		return dns.RcodeServerFailure, fmt.Errorf("writing NODATA: %w", err)
@@ -191,6 +502,13 @@ func (d *DynUpdate) writeNODATA(w dns.ResponseWriter, r *dns.Msg, zone string) (
 }
 
 func (d *DynUpdate) soa(zone string) dns.RR {
+	cfg := d.SOA.withDefaults()
+
+	serial := cfg.Serial
+	if serial == 0 && d.Store != nil {
+		serial = uint32(d.Store.Generation())
+	}
+
 	return &dns.SOA{
 		Hdr: dns.RR_Header{
 			Name:   zone,
@@ -198,12 +516,54 @@ func (d *DynUpdate) soa(zone string) dns.RR {
 			Class:  dns.ClassINET,
 			Ttl:    300,
 		},
-		Ns:      "ns1." + zone,
-		Mbox:    "hostmaster." + zone,
-		Serial:  uint32(time.Now().Unix()),
-		Refresh: 7200,
-		Retry:   1800,
-		Expire:  86400,
-		Minttl:  300,
+		Ns:      qualifyName(cfg.MName, zone),
+		Mbox:    qualifyName(cfg.RName, zone),
+		Serial:  serial,
+		Refresh: cfg.Refresh,
+		Retry:   cfg.Retry,
+		Expire:  cfg.Expire,
+		Minttl:  cfg.MinTTL,
+	}
+}
+
+// negativeSOA returns the SOA for the authority section of NXDOMAIN and
+// NODATA answers. Resolvers cache a negative answer for the lesser of the
+// SOA's TTL and its MINIMUM field (RFC 2308 section 5), so both carry the
+// configured minttl.
+func (d *DynUpdate) negativeSOA(zone string) dns.RR {
+	soa := d.soa(zone)
+	soa.Header().Ttl = soa.(*dns.SOA).Minttl
+	return soa
+}
+
+// withDefaults returns a copy of c with unset fields replaced by the defaults.
+func (c SOAConfig) withDefaults() SOAConfig {
+	if c.MName == "" {
+		c.MName = defaultSOAMName
+	}
+	if c.RName == "" {
+		c.RName = defaultSOARName
+	}
+	if c.Refresh == 0 {
+		c.Refresh = defaultSOARefresh
+	}
+	if c.Retry == 0 {
+		c.Retry = defaultSOARetry
+	}
+	if c.Expire == 0 {
+		c.Expire = defaultSOAExpire
+	}
+	if c.MinTTL == 0 {
+		c.MinTTL = defaultSOAMinTTL
+	}
+	return c
+}
+
+// qualifyName returns name unchanged when it is already a FQDN, otherwise
+// it appends the zone.
+func qualifyName(name, zone string) string {
+	if dns.IsFqdn(name) {
+		return name
 	}
+	return name + "." + zone
 }
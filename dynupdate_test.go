@@ -5,13 +5,24 @@ package dynupdate
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/pkg/dnstest"
 	"github.com/coredns/coredns/plugin/pkg/fall"
 	"github.com/coredns/coredns/plugin/test"
 	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 )
 
 func newTestHandler(t *testing.T, records []Record) *DynUpdate {
@@ -26,7 +37,7 @@ func newTestHandler(t *testing.T, records []Record) *DynUpdate {
 	t.Cleanup(func() { s.Stop() })
 
 	for _, r := range records {
-		if err := s.Upsert(r); err != nil {
+		if err := s.Upsert(t.Context(), r); err != nil {
 			t.Fatalf("Upsert(%v) error: %v", r, err)
 		}
 	}
@@ -114,6 +125,39 @@ func TestServeDNS_MX_Record(t *testing.T) {
 	}
 }
 
+func TestServeDNS_HINFOAndRP(t *testing.T) {
+	t.Parallel()
+	d := newTestHandler(t, []Record{
+		{Name: "host.example.org.", Type: "HINFO", TTL: 300, Value: "amd64", OS: "Linux 6.1"},
+		{Name: "host.example.org.", Type: "RP", TTL: 300, Value: "admin.example.org.", TXTName: "contact.example.org."},
+	})
+
+	msg := queryDO(t, d, "host.example.org.", dns.TypeHINFO, false)
+	if len(msg.Answer) != 1 {
+		t.Fatalf("HINFO answer = %v, want 1 record", msg.Answer)
+	}
+	if hinfo, ok := msg.Answer[0].(*dns.HINFO); !ok || hinfo.Cpu != "amd64" || hinfo.Os != "Linux 6.1" {
+		t.Errorf("HINFO answer = %v, want amd64 and Linux 6.1", msg.Answer[0])
+	}
+
+	msg = queryDO(t, d, "host.example.org.", dns.TypeRP, false)
+	if len(msg.Answer) != 1 {
+		t.Fatalf("RP answer = %v, want 1 record", msg.Answer)
+	}
+	if rp, ok := msg.Answer[0].(*dns.RP); !ok || rp.Mbox != "admin.example.org." || rp.Txt != "contact.example.org." {
+		t.Errorf("RP answer = %v, want admin.example.org. and contact.example.org.", msg.Answer[0])
+	}
+
+	// Both survive a pack and unpack, as a real client receives them.
+	wire, err := msg.Pack()
+	if err != nil {
+		t.Fatalf("Pack() error: %v", err)
+	}
+	if err := new(dns.Msg).Unpack(wire); err != nil {
+		t.Errorf("Unpack() error: %v", err)
+	}
+}
+
 func TestServeDNS_NXDOMAIN(t *testing.T) {
 	t.Parallel()
 	d := newTestHandler(t, nil)
@@ -134,6 +178,135 @@ func TestServeDNS_NXDOMAIN(t *testing.T) {
 	}
 }
 
+func TestServeDNS_ServesStaleWhileBackendDown(t *testing.T) {
+	t.Parallel()
+	fb := &flakyBackend{Backend: NewFileBackend(filepath.Join(t.TempDir(), "records.json"))}
+	s, err := NewStoreWithBackend(fb, 0)
+	if err != nil {
+		t.Fatalf("NewStoreWithBackend() error: %v", err)
+	}
+	t.Cleanup(s.Stop)
+	d := &DynUpdate{Zones: []string{"example.org."}, Store: s}
+	h := NewAPIServer(s, &Auth{Token: "test-token"}, ":0", nil).handler()
+
+	if err := s.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+
+	fb.mu.Lock()
+	fb.failSaves = 100
+	fb.mu.Unlock()
+	err = s.Upsert(t.Context(), Record{Name: "b.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"})
+	if !errors.Is(err, ErrBackendUnavailable) {
+		t.Fatalf("Upsert() with failing backend error = %v, want ErrBackendUnavailable", err)
+	}
+	if !s.Degraded() {
+		t.Fatal("Degraded() = false after a failed write")
+	}
+
+	// Further writes are rejected before they reach memory.
+	err = s.Upsert(t.Context(), Record{Name: "c.example.org.", Type: "A", TTL: 300, Value: "10.0.0.3"})
+	if !errors.Is(err, ErrBackendUnavailable) {
+		t.Fatalf("Upsert() while degraded error = %v, want ErrBackendUnavailable", err)
+	}
+	if got := s.Get(t.Context(), "c.example.org.", "A"); len(got) != 0 {
+		t.Errorf("rejected record stored: %v", got)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/records",
+		strings.NewReader(`{"name":"d.example.org.","type":"A","ttl":300,"value":"10.0.0.4"}`))
+	req.Header.Set("Authorization", "Bearer test-token")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("POST while degraded: status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+
+	// Queries are still answered from memory.
+	m := new(dns.Msg)
+	m.SetQuestion("app.example.org.", dns.TypeA)
+	w := dnstest.NewRecorder(&test.ResponseWriter{})
+	code, err := d.ServeDNS(context.Background(), w, m)
+	if err != nil {
+		t.Fatalf("ServeDNS() error: %v", err)
+	}
+	if code != dns.RcodeSuccess || len(w.Msg.Answer) != 1 {
+		t.Fatalf("ServeDNS() while degraded: rcode %d, %d answers; want NOERROR with the cached record", code, len(w.Msg.Answer))
+	}
+
+	// Once the backend is back, the next write catches it up and succeeds.
+	fb.mu.Lock()
+	fb.failSaves = 0
+	fb.mu.Unlock()
+	if err := s.Upsert(t.Context(), Record{Name: "c.example.org.", Type: "A", TTL: 300, Value: "10.0.0.3"}); err != nil {
+		t.Fatalf("Upsert() after recovery error: %v", err)
+	}
+	if s.Degraded() {
+		t.Error("Degraded() = true after a successful write")
+	}
+}
+
+// Not parallel: it reads the global backend_degraded gauge.
+func TestStore_DegradedGaugePerBackend(t *testing.T) {
+	degraded := func(label string) (float64, bool) {
+		t.Helper()
+		mfs, err := prometheus.DefaultGatherer.Gather()
+		if err != nil {
+			t.Fatalf("Gather() error: %v", err)
+		}
+		for _, mf := range mfs {
+			if mf.GetName() != "coredns_dynupdate_backend_degraded" {
+				continue
+			}
+			for _, m := range mf.GetMetric() {
+				if m.GetLabel()[0].GetValue() == label {
+					return m.GetGauge().GetValue(), true
+				}
+			}
+		}
+		return 0, false
+	}
+
+	dir := t.TempDir()
+	down := &flakyBackend{Backend: NewFileBackend(filepath.Join(dir, "down.json"))}
+	sDown, err := NewStoreWithBackend(down, 0, WithFlushTimeout(0))
+	if err != nil {
+		t.Fatalf("NewStoreWithBackend() error: %v", err)
+	}
+	defer sDown.Stop()
+	up, err := NewStore(filepath.Join(dir, "up.json"), 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	defer up.Stop()
+
+	downLabel, upLabel := "file:"+filepath.Join(dir, "down.json"), "file:"+filepath.Join(dir, "up.json")
+	down.mu.Lock()
+	down.failSaves = 100
+	down.mu.Unlock()
+	if err := sDown.Upsert(t.Context(), Record{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); !errors.Is(err, ErrBackendUnavailable) {
+		t.Fatalf("Upsert() error = %v, want ErrBackendUnavailable", err)
+	}
+	// A successful write to the healthy store must not hide the outage.
+	if err := up.Upsert(t.Context(), Record{Name: "b.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	if v, ok := degraded(downLabel); !ok || v != 1 {
+		t.Errorf("backend_degraded{backend=%q} = %v (present %v), want 1", downLabel, v, ok)
+	}
+	if v, ok := degraded(upLabel); !ok || v != 0 {
+		t.Errorf("backend_degraded{backend=%q} = %v (present %v), want 0", upLabel, v, ok)
+	}
+
+	up.Stop()
+	if _, ok := degraded(upLabel); ok {
+		t.Errorf("backend_degraded{backend=%q} still reported after Stop", upLabel)
+	}
+	if _, ok := degraded(downLabel); !ok {
+		t.Errorf("backend_degraded{backend=%q} dropped when another store stopped", downLabel)
+	}
+}
+
 func TestServeDNS_NODATA(t *testing.T) {
 	t.Parallel()
 	d := newTestHandler(t, []Record{
@@ -160,6 +333,182 @@ func TestServeDNS_NODATA(t *testing.T) {
 	}
 }
 
+func TestServeDNS_ANY(t *testing.T) {
+	t.Parallel()
+	d := newTestHandler(t, []Record{
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"},
+		{Name: "app.example.org.", Type: "AAAA", TTL: 300, Value: "2001:db8::1"},
+		{Name: "app.example.org.", Type: "TXT", TTL: 300, Value: "hello"},
+		{Name: "other.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"},
+	})
+
+	req := new(dns.Msg)
+	req.SetQuestion("app.example.org.", dns.TypeANY)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+
+	code, err := d.ServeDNS(t.Context(), rec, req)
+	if err != nil {
+		t.Fatalf("ServeDNS() error: %v", err)
+	}
+	if code != dns.RcodeSuccess {
+		t.Errorf("rcode = %d, want %d", code, dns.RcodeSuccess)
+	}
+	if !rec.Msg.Authoritative {
+		t.Error("expected authoritative answer")
+	}
+	got := make(map[uint16]bool)
+	for _, rr := range rec.Msg.Answer {
+		if rr.Header().Name != "app.example.org." {
+			t.Errorf("answer %v for another name", rr)
+		}
+		got[rr.Header().Rrtype] = true
+	}
+	if len(rec.Msg.Answer) != 3 || !got[dns.TypeA] || !got[dns.TypeAAAA] || !got[dns.TypeTXT] {
+		t.Errorf("answer = %v, want the A, AAAA and TXT records", rec.Msg.Answer)
+	}
+}
+
+// TestServeDNS_UnsupportedQType is not parallel: unsupportedQTypeCount is a
+// global counter that other DNS tests may also increment.
+func TestServeDNS_UnsupportedQType(t *testing.T) {
+	d := newTestHandler(t, []Record{
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"},
+	})
+	unsupported := func() float64 {
+		t.Helper()
+		var m dto.Metric
+		if err := unsupportedQTypeCount.WithLabelValues("example.org.").Write(&m); err != nil {
+			t.Fatalf("reading counter: %v", err)
+		}
+		return m.GetCounter().GetValue()
+	}
+
+	tests := []struct {
+		name      string
+		qname     string
+		qtype     uint16
+		wantRcode int
+		counted   bool
+	}{
+		{"unknown type on existing name", "app.example.org.", 65, dns.RcodeSuccess, true},
+		{"unmanaged type on existing name", "app.example.org.", dns.TypeSSHFP, dns.RcodeSuccess, true},
+		{"unknown type on missing name", "missing.example.org.", 65, dns.RcodeNameError, true},
+		{"managed type", "app.example.org.", dns.TypeAAAA, dns.RcodeSuccess, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := unsupported()
+			req := new(dns.Msg)
+			req.SetQuestion(tt.qname, tt.qtype)
+			rec := dnstest.NewRecorder(&test.ResponseWriter{})
+			if _, err := d.ServeDNS(t.Context(), rec, req); err != nil {
+				t.Fatalf("ServeDNS() error: %v", err)
+			}
+			if rec.Msg.Rcode != tt.wantRcode {
+				t.Errorf("rcode = %d, want %d", rec.Msg.Rcode, tt.wantRcode)
+			}
+			if len(rec.Msg.Answer) != 0 {
+				t.Errorf("answer = %v, want none", rec.Msg.Answer)
+			}
+			if len(rec.Msg.Ns) == 0 {
+				t.Error("expected SOA in authority section")
+			}
+			want := before
+			if tt.counted {
+				want++
+			}
+			if got := unsupported(); got != want {
+				t.Errorf("unsupported qtype count = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestServeDNS_QuestionCount(t *testing.T) {
+	t.Parallel()
+	// No store: the guard must answer before any lookup.
+	d := &DynUpdate{Zones: []string{"example.org."}}
+
+	tests := []struct {
+		name     string
+		question []dns.Question
+	}{
+		{"no question", nil},
+		{"two questions", []dns.Question{
+			{Name: "a.example.org.", Qtype: dns.TypeA, Qclass: dns.ClassINET},
+			{Name: "b.example.org.", Qtype: dns.TypeA, Qclass: dns.ClassINET},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			req := new(dns.Msg)
+			req.Id = dns.Id()
+			req.Question = tt.question
+			rec := dnstest.NewRecorder(&test.ResponseWriter{})
+
+			code, err := d.ServeDNS(t.Context(), rec, req)
+			if err != nil {
+				t.Fatalf("ServeDNS() error: %v", err)
+			}
+			if code != dns.RcodeFormatError {
+				t.Errorf("rcode = %d, want %d (FORMERR)", code, dns.RcodeFormatError)
+			}
+			// The server writes the FORMERR reply for codes the plugin does not write.
+			if plugin.ClientWrite(code) || rec.Msg != nil {
+				t.Errorf("ServeDNS() wrote %v, want the reply left to the server", rec.Msg)
+			}
+		})
+	}
+}
+
+func TestServeDNS_EmptyNonTerminal(t *testing.T) {
+	t.Parallel()
+	d := newTestHandler(t, []Record{
+		{Name: "a.b.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"},
+		{Name: "*.svc.example.org.", Type: "A", TTL: 300, Value: "10.0.0.9"},
+		{Name: "x.y.svc.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"},
+		{Name: "old.gone.example.org.", Type: "A", TTL: 300, Value: "10.0.0.3", ExpiresAt: time.Now().Add(-time.Second)},
+	})
+
+	tests := []struct {
+		name       string
+		qname      string
+		wantRcode  int
+		wantAnswer bool
+	}{
+		{name: "empty non-terminal", qname: "b.example.org.", wantRcode: dns.RcodeSuccess},
+		{name: "absent name", qname: "c.example.org.", wantRcode: dns.RcodeNameError},
+		{name: "non-terminal is not covered by wildcard", qname: "y.svc.example.org.", wantRcode: dns.RcodeSuccess},
+		{name: "wildcard still matches siblings", qname: "z.svc.example.org.", wantRcode: dns.RcodeSuccess, wantAnswer: true},
+		{name: "only expired records below", qname: "gone.example.org.", wantRcode: dns.RcodeNameError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			req := new(dns.Msg)
+			req.SetQuestion(tt.qname, dns.TypeA)
+			rec := dnstest.NewRecorder(&test.ResponseWriter{})
+
+			code, err := d.ServeDNS(context.Background(), rec, req)
+			if err != nil {
+				t.Fatalf("ServeDNS() error: %v", err)
+			}
+			if code != tt.wantRcode {
+				t.Errorf("rcode = %d, want %d", code, tt.wantRcode)
+			}
+			if got := len(rec.Msg.Answer) > 0; got != tt.wantAnswer {
+				t.Errorf("got %d answers, want answer %v", len(rec.Msg.Answer), tt.wantAnswer)
+			}
+			if !tt.wantAnswer {
+				if len(rec.Msg.Ns) != 1 || rec.Msg.Ns[0].Header().Rrtype != dns.TypeSOA {
+					t.Errorf("authority = %v, want the zone SOA", rec.Msg.Ns)
+				}
+			}
+		})
+	}
+}
+
 func TestServeDNS_CNAME_Chasing_SingleHop(t *testing.T) {
 	t.Parallel()
 	d := newTestHandler(t, []Record{
@@ -189,6 +538,109 @@ func TestServeDNS_CNAME_Chasing_SingleHop(t *testing.T) {
 	}
 }
 
+func TestServeDNS_Disabled(t *testing.T) {
+	t.Parallel()
+	d := newTestHandler(t, []Record{
+		{Name: "off.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1", Disabled: true},
+		{Name: "mixed.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2", Disabled: true},
+		{Name: "mixed.example.org.", Type: "A", TTL: 300, Value: "10.0.0.3"},
+		{Name: "mixed.example.org.", Type: "TXT", TTL: 300, Value: "kept"},
+		{Name: "alias.example.org.", Type: "CNAME", TTL: 300, Value: "off.example.org."},
+		{Name: "gone.example.org.", Type: "CNAME", TTL: 300, Value: "mixed.example.org.", Disabled: true},
+	})
+
+	tests := []struct {
+		name      string
+		qname     string
+		qtype     uint16
+		wantRcode int
+		wantAns   []string
+	}{
+		{"only record disabled", "off.example.org.", dns.TypeA, dns.RcodeNameError, nil},
+		{"disabled value skipped", "mixed.example.org.", dns.TypeA, dns.RcodeSuccess, []string{"10.0.0.3"}},
+		{"chase stops at disabled target", "alias.example.org.", dns.TypeA, dns.RcodeSuccess, []string{"off.example.org."}},
+		{"disabled alias", "gone.example.org.", dns.TypeA, dns.RcodeNameError, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			req := new(dns.Msg)
+			req.SetQuestion(tt.qname, tt.qtype)
+			rec := dnstest.NewRecorder(&test.ResponseWriter{})
+
+			if _, err := d.ServeDNS(t.Context(), rec, req); err != nil {
+				t.Fatalf("ServeDNS() error: %v", err)
+			}
+			if rec.Msg.Rcode != tt.wantRcode {
+				t.Errorf("rcode = %d, want %d", rec.Msg.Rcode, tt.wantRcode)
+			}
+			var got []string
+			for _, rr := range rec.Msg.Answer {
+				switch rr := rr.(type) {
+				case *dns.A:
+					got = append(got, rr.A.String())
+				case *dns.CNAME:
+					got = append(got, rr.Target)
+				}
+			}
+			if !slices.Equal(got, tt.wantAns) {
+				t.Errorf("answers = %v, want %v", got, tt.wantAns)
+			}
+		})
+	}
+}
+
+func TestServeDNS_EchoesQueryNameCase(t *testing.T) {
+	t.Parallel()
+	d := newTestHandler(t, []Record{
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"},
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"},
+		{Name: "Alias.Example.Org.", Type: "CNAME", TTL: 300, Value: "App.Example.Org."},
+		{Name: "*.w.example.org.", Type: "TXT", TTL: 300, Value: "wild"},
+	})
+
+	tests := []struct {
+		name   string
+		qname  string
+		qtype  uint16
+		owners []string
+	}{
+		{"exact match", "APP.example.org.", dns.TypeA, []string{"APP.example.org.", "APP.example.org."}},
+		{"wildcard", "X.W.Example.ORG.", dns.TypeTXT, []string{"X.W.Example.ORG."}},
+		// The chased record keeps its stored name; only the queried owner is echoed.
+		{"CNAME chase", "aLiAs.example.org.", dns.TypeA, []string{"aLiAs.example.org.", "app.example.org.", "app.example.org."}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			req := new(dns.Msg)
+			req.SetQuestion(tt.qname, tt.qtype)
+			rec := dnstest.NewRecorder(&test.ResponseWriter{})
+			if _, err := d.ServeDNS(t.Context(), rec, req); err != nil {
+				t.Fatalf("ServeDNS() error: %v", err)
+			}
+			var owners []string
+			for _, rr := range rec.Msg.Answer {
+				owners = append(owners, rr.Header().Name)
+			}
+			if !slices.Equal(owners, tt.owners) {
+				t.Errorf("answer owners = %v, want %v", owners, tt.owners)
+			}
+		})
+	}
+
+	// The CNAME target keeps the casing it was stored with.
+	req := new(dns.Msg)
+	req.SetQuestion("alias.example.org.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := d.ServeDNS(t.Context(), rec, req); err != nil {
+		t.Fatalf("ServeDNS() error: %v", err)
+	}
+	if c, ok := rec.Msg.Answer[0].(*dns.CNAME); !ok || c.Target != "App.Example.Org." {
+		t.Errorf("first answer = %v, want a CNAME to App.Example.Org.", rec.Msg.Answer[0])
+	}
+}
+
 func TestServeDNS_CNAME_Chasing_MultiHop(t *testing.T) {
 	t.Parallel()
 	d := newTestHandler(t, []Record{
@@ -307,3 +759,754 @@ func TestDynUpdate_Name(t *testing.T) {
 		t.Errorf("Name() = %q, want %q", d.Name(), "dynupdate")
 	}
 }
+
+func TestSOA_ConfiguredValues(t *testing.T) {
+	t.Parallel()
+	d := newTestHandler(t, nil)
+	d.SOA = SOAConfig{
+		MName:   "ns.example.net.",
+		RName:   "dns-admin",
+		Refresh: 3600,
+		Retry:   600,
+		Expire:  604800,
+		MinTTL:  60,
+		Serial:  42,
+	}
+
+	soa := d.soa("example.org.").(*dns.SOA)
+	if soa.Ns != "ns.example.net." {
+		t.Errorf("Ns = %q, want %q", soa.Ns, "ns.example.net.")
+	}
+	if soa.Mbox != "dns-admin.example.org." {
+		t.Errorf("Mbox = %q, want %q", soa.Mbox, "dns-admin.example.org.")
+	}
+	if soa.Refresh != 3600 || soa.Retry != 600 || soa.Expire != 604800 || soa.Minttl != 60 {
+		t.Errorf("timers = %d/%d/%d/%d, want 3600/600/604800/60", soa.Refresh, soa.Retry, soa.Expire, soa.Minttl)
+	}
+	if soa.Serial != 42 {
+		t.Errorf("Serial = %d, want 42", soa.Serial)
+	}
+}
+
+func TestSOA_Defaults(t *testing.T) {
+	t.Parallel()
+	d := newTestHandler(t, nil)
+
+	soa := d.soa("example.org.").(*dns.SOA)
+	if soa.Ns != "ns1.example.org." {
+		t.Errorf("Ns = %q, want %q", soa.Ns, "ns1.example.org.")
+	}
+	if soa.Mbox != "hostmaster.example.org." {
+		t.Errorf("Mbox = %q, want %q", soa.Mbox, "hostmaster.example.org.")
+	}
+	if soa.Refresh != 7200 || soa.Retry != 1800 || soa.Expire != 86400 || soa.Minttl != 300 {
+		t.Errorf("timers = %d/%d/%d/%d, want 7200/1800/86400/300", soa.Refresh, soa.Retry, soa.Expire, soa.Minttl)
+	}
+}
+
+func TestSOA_SerialTracksGeneration(t *testing.T) {
+	t.Parallel()
+	d := newTestHandler(t, []Record{
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"},
+	})
+
+	first := d.soa("example.org.").(*dns.SOA).Serial
+	if again := d.soa("example.org.").(*dns.SOA).Serial; again != first {
+		t.Errorf("serial changed without mutation: %d -> %d", first, again)
+	}
+
+	if err := d.Store.Upsert(t.Context(), Record{Name: "db.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	if next := d.soa("example.org.").(*dns.SOA).Serial; next <= first {
+		t.Errorf("serial after mutation = %d, want > %d", next, first)
+	}
+}
+
+func TestServeDNS_SOA_Apex(t *testing.T) {
+	t.Parallel()
+	d := newTestHandler(t, nil)
+
+	req := new(dns.Msg)
+	req.SetQuestion("example.org.", dns.TypeSOA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+
+	code, err := d.ServeDNS(context.Background(), rec, req)
+	if err != nil {
+		t.Fatalf("ServeDNS() error: %v", err)
+	}
+	if code != dns.RcodeSuccess {
+		t.Errorf("rcode = %d, want %d", code, dns.RcodeSuccess)
+	}
+	if !rec.Msg.Authoritative {
+		t.Error("Authoritative = false, want true")
+	}
+	if len(rec.Msg.Answer) != 1 {
+		t.Fatalf("got %d answers, want 1", len(rec.Msg.Answer))
+	}
+	soa, ok := rec.Msg.Answer[0].(*dns.SOA)
+	if !ok {
+		t.Fatalf("answer is %T, want *dns.SOA", rec.Msg.Answer[0])
+	}
+	if soa.Hdr.Name != "example.org." {
+		t.Errorf("SOA owner = %q, want %q", soa.Hdr.Name, "example.org.")
+	}
+	if len(rec.Msg.Ns) != 0 {
+		t.Errorf("got %d authority records, want 0", len(rec.Msg.Ns))
+	}
+}
+
+func TestServeDNS_SOA_BelowApex_NODATA(t *testing.T) {
+	t.Parallel()
+	d := newTestHandler(t, []Record{
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"},
+	})
+
+	req := new(dns.Msg)
+	req.SetQuestion("app.example.org.", dns.TypeSOA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+
+	code, err := d.ServeDNS(context.Background(), rec, req)
+	if err != nil {
+		t.Fatalf("ServeDNS() error: %v", err)
+	}
+	if code != dns.RcodeSuccess {
+		t.Errorf("rcode = %d, want %d (NODATA)", code, dns.RcodeSuccess)
+	}
+	if len(rec.Msg.Answer) != 0 {
+		t.Errorf("got %d answers, want 0", len(rec.Msg.Answer))
+	}
+	if len(rec.Msg.Ns) != 1 {
+		t.Errorf("got %d authority records, want 1 (SOA)", len(rec.Msg.Ns))
+	}
+}
+
+func TestServeDNS_NegativeSOATTL(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		soa    SOAConfig
+		wantNS uint32
+	}{
+		{"default minttl", SOAConfig{}, defaultSOAMinTTL},
+		{"configured minttl", SOAConfig{MinTTL: 45}, 45},
+		{"minttl above the SOA TTL", SOAConfig{MinTTL: 3600}, 3600},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			d := newTestHandler(t, []Record{
+				{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"},
+			})
+			d.SOA = tt.soa
+
+			for _, q := range []struct {
+				qname string
+				rcode int
+			}{
+				{"app.example.org.", dns.RcodeSuccess},       // NODATA
+				{"missing.example.org.", dns.RcodeNameError}, // NXDOMAIN
+			} {
+				msg := queryDO(t, d, q.qname, dns.TypeAAAA, false)
+				if msg.Rcode != q.rcode || len(msg.Ns) != 1 {
+					t.Fatalf("%s: rcode %d, authority %v; want rcode %d with the SOA", q.qname, msg.Rcode, msg.Ns, q.rcode)
+				}
+				soa := msg.Ns[0].(*dns.SOA)
+				if soa.Hdr.Ttl != tt.wantNS || soa.Minttl != tt.wantNS {
+					t.Errorf("%s: SOA TTL %d, minttl %d, want both %d", q.qname, soa.Hdr.Ttl, soa.Minttl, tt.wantNS)
+				}
+			}
+		})
+	}
+}
+
+func TestServeDNS_Wildcard(t *testing.T) {
+	t.Parallel()
+	d := newTestHandler(t, []Record{
+		{Name: "*.apps.example.org.", Type: "A", TTL: 300, Value: "10.0.0.9"},
+		{Name: "exact.apps.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"},
+	})
+
+	tests := []struct {
+		qname     string
+		wantRcode int
+		wantValue string
+	}{
+		{qname: "foo.apps.example.org.", wantRcode: dns.RcodeSuccess, wantValue: "10.0.0.9"},
+		{qname: "exact.apps.example.org.", wantRcode: dns.RcodeSuccess, wantValue: "10.0.0.1"},
+		{qname: "foo.bar.apps.example.org.", wantRcode: dns.RcodeNameError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.qname, func(t *testing.T) {
+			req := new(dns.Msg)
+			req.SetQuestion(tt.qname, dns.TypeA)
+			rec := dnstest.NewRecorder(&test.ResponseWriter{})
+
+			code, err := d.ServeDNS(context.Background(), rec, req)
+			if err != nil {
+				t.Fatalf("ServeDNS() error: %v", err)
+			}
+			if code != tt.wantRcode {
+				t.Fatalf("rcode = %d, want %d", code, tt.wantRcode)
+			}
+			if tt.wantValue == "" {
+				if len(rec.Msg.Answer) != 0 {
+					t.Errorf("got %d answers, want 0", len(rec.Msg.Answer))
+				}
+				return
+			}
+			if len(rec.Msg.Answer) != 1 {
+				t.Fatalf("got %d answers, want 1", len(rec.Msg.Answer))
+			}
+			a := rec.Msg.Answer[0].(*dns.A)
+			if a.Hdr.Name != tt.qname {
+				t.Errorf("owner = %q, want %q", a.Hdr.Name, tt.qname)
+			}
+			if a.A.String() != tt.wantValue {
+				t.Errorf("A = %s, want %s", a.A, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestServeDNS_Wildcard_TypeAbsent(t *testing.T) {
+	t.Parallel()
+	records := []Record{
+		{Name: "*.apps.example.org.", Type: "A", TTL: 300, Value: "10.0.0.9"},
+	}
+
+	tests := []struct {
+		name             string
+		wildcardNXDOMAIN bool
+		qname            string
+		wantRcode        int
+	}{
+		{name: "covered name is NODATA", qname: "foo.apps.example.org.", wantRcode: dns.RcodeSuccess},
+		{name: "escape hatch answers NXDOMAIN", wildcardNXDOMAIN: true, qname: "foo.apps.example.org.", wantRcode: dns.RcodeNameError},
+		{name: "escape hatch leaves the wildcard owner alone", wildcardNXDOMAIN: true, qname: "*.apps.example.org.", wantRcode: dns.RcodeSuccess},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			d := newTestHandler(t, records)
+			d.WildcardNXDOMAIN = tt.wildcardNXDOMAIN
+
+			req := new(dns.Msg)
+			req.SetQuestion(tt.qname, dns.TypeAAAA)
+			rec := dnstest.NewRecorder(&test.ResponseWriter{})
+
+			code, err := d.ServeDNS(context.Background(), rec, req)
+			if err != nil {
+				t.Fatalf("ServeDNS() error: %v", err)
+			}
+			if code != tt.wantRcode {
+				t.Fatalf("rcode = %d, want %d", code, tt.wantRcode)
+			}
+			if len(rec.Msg.Answer) != 0 {
+				t.Errorf("got %d answers, want 0", len(rec.Msg.Answer))
+			}
+			if len(rec.Msg.Ns) == 0 {
+				t.Error("expected SOA in authority section")
+			}
+		})
+	}
+}
+
+func TestServeDNS_ExpiredRecord(t *testing.T) {
+	t.Parallel()
+	d := newTestHandler(t, []Record{
+		{Name: "ci.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1", ExpiresAt: time.Now().Add(-time.Second)},
+	})
+
+	req := new(dns.Msg)
+	req.SetQuestion("ci.example.org.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+
+	code, err := d.ServeDNS(context.Background(), rec, req)
+	if err != nil {
+		t.Fatalf("ServeDNS() error: %v", err)
+	}
+	if code != dns.RcodeNameError {
+		t.Errorf("rcode = %d, want NXDOMAIN before the sweep runs", code)
+	}
+}
+
+func TestServeDNS_Wildcard_CNAMETarget(t *testing.T) {
+	t.Parallel()
+	d := newTestHandler(t, []Record{
+		{Name: "www.example.org.", Type: "CNAME", TTL: 300, Value: "web.apps.example.org."},
+		{Name: "*.apps.example.org.", Type: "A", TTL: 300, Value: "10.0.0.9"},
+	})
+
+	req := new(dns.Msg)
+	req.SetQuestion("www.example.org.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+
+	if _, err := d.ServeDNS(context.Background(), rec, req); err != nil {
+		t.Fatalf("ServeDNS() error: %v", err)
+	}
+	if len(rec.Msg.Answer) != 2 {
+		t.Fatalf("got %d answers, want 2 (CNAME + A)", len(rec.Msg.Answer))
+	}
+	if got := rec.Msg.Answer[1].Header().Name; got != "web.apps.example.org." {
+		t.Errorf("A owner = %q, want web.apps.example.org.", got)
+	}
+}
+
+func TestServeDNS_RoundRobin(t *testing.T) {
+	t.Parallel()
+	d := newTestHandler(t, []Record{
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"},
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"},
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.3"},
+	})
+	d.RoundRobin = true
+
+	want := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.1", "10.0.0.2"}
+	for i, lead := range want {
+		req := new(dns.Msg)
+		req.SetQuestion("app.example.org.", dns.TypeA)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+
+		if _, err := d.ServeDNS(context.Background(), rec, req); err != nil {
+			t.Fatalf("ServeDNS() error: %v", err)
+		}
+		if len(rec.Msg.Answer) != 3 {
+			t.Fatalf("query %d: got %d answers, want 3", i, len(rec.Msg.Answer))
+		}
+		if got := rec.Msg.Answer[0].(*dns.A).A.String(); got != lead {
+			t.Errorf("query %d: lead = %s, want %s", i, got, lead)
+		}
+	}
+}
+
+func TestServeDNS_RoundRobin_Concurrent(t *testing.T) {
+	t.Parallel()
+	d := newTestHandler(t, []Record{
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"},
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"},
+	})
+	d.RoundRobin = true
+
+	const queries = 100
+	var (
+		mu    sync.Mutex
+		leads = make(map[string]int)
+		wg    sync.WaitGroup
+	)
+	for range queries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := new(dns.Msg)
+			req.SetQuestion("app.example.org.", dns.TypeA)
+			rec := dnstest.NewRecorder(&test.ResponseWriter{})
+			if _, err := d.ServeDNS(context.Background(), rec, req); err != nil {
+				t.Errorf("ServeDNS() error: %v", err)
+				return
+			}
+			mu.Lock()
+			leads[rec.Msg.Answer[0].(*dns.A).A.String()]++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	// Every query claims a distinct counter value, so leads split evenly.
+	if leads["10.0.0.1"] != queries/2 || leads["10.0.0.2"] != queries/2 {
+		t.Errorf("lead distribution = %v, want %d each", leads, queries/2)
+	}
+}
+
+func TestServeDNS_MX_Glue(t *testing.T) {
+	t.Parallel()
+	d := newTestHandler(t, []Record{
+		{Name: "example.org.", Type: "MX", TTL: 300, Value: "mail.example.org.", Priority: 10},
+		{Name: "example.org.", Type: "MX", TTL: 300, Value: "MAIL.example.org.", Priority: 20},
+		{Name: "example.org.", Type: "MX", TTL: 300, Value: "mx.other.net.", Priority: 30},
+		{Name: "mail.example.org.", Type: "A", TTL: 300, Value: "10.0.0.25"},
+		{Name: "mail.example.org.", Type: "TXT", TTL: 300, Value: "not glue"},
+	})
+
+	req := new(dns.Msg)
+	req.SetQuestion("example.org.", dns.TypeMX)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+
+	if _, err := d.ServeDNS(context.Background(), rec, req); err != nil {
+		t.Fatalf("ServeDNS() error: %v", err)
+	}
+	if len(rec.Msg.Answer) != 3 {
+		t.Fatalf("got %d answers, want 3", len(rec.Msg.Answer))
+	}
+	if _, ok := rec.Msg.Answer[0].(*dns.MX); !ok {
+		t.Fatalf("answer is %T, want *dns.MX", rec.Msg.Answer[0])
+	}
+	// One A record for the in-zone target (despite two MX pointing at it),
+	// nothing for the out-of-zone target.
+	if len(rec.Msg.Extra) != 1 {
+		t.Fatalf("got %d extra records, want 1", len(rec.Msg.Extra))
+	}
+	a, ok := rec.Msg.Extra[0].(*dns.A)
+	if !ok {
+		t.Fatalf("extra is %T, want *dns.A", rec.Msg.Extra[0])
+	}
+	if a.Hdr.Name != "mail.example.org." || a.A.String() != "10.0.0.25" {
+		t.Errorf("glue = %s %s, want mail.example.org. 10.0.0.25", a.Hdr.Name, a.A)
+	}
+}
+
+func TestServeDNS_SRV_NS_Glue(t *testing.T) {
+	t.Parallel()
+	d := newTestHandler(t, []Record{
+		{Name: "_sip._tcp.example.org.", Type: "SRV", TTL: 300, Value: "sip.example.org.", Priority: 10, Weight: 5, Port: 5060},
+		{Name: "sip.example.org.", Type: "AAAA", TTL: 300, Value: "2001:db8::5"},
+		{Name: "sub.example.org.", Type: "NS", TTL: 300, Value: "ns.sub.example.org."},
+		{Name: "ns.sub.example.org.", Type: "A", TTL: 300, Value: "10.0.0.53"},
+	})
+
+	tests := []struct {
+		qname string
+		qtype uint16
+		want  string
+	}{
+		{qname: "_sip._tcp.example.org.", qtype: dns.TypeSRV, want: "sip.example.org."},
+		{qname: "sub.example.org.", qtype: dns.TypeNS, want: "ns.sub.example.org."},
+	}
+	for _, tt := range tests {
+		req := new(dns.Msg)
+		req.SetQuestion(tt.qname, tt.qtype)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+
+		if _, err := d.ServeDNS(context.Background(), rec, req); err != nil {
+			t.Fatalf("ServeDNS() error: %v", err)
+		}
+		if len(rec.Msg.Extra) != 1 || rec.Msg.Extra[0].Header().Name != tt.want {
+			t.Errorf("%s: extra = %v, want glue for %s", tt.qname, rec.Msg.Extra, tt.want)
+		}
+	}
+}
+
+// largeMXZone returns n MX records at the apex, each with an A glue record.
+func largeMXZone(n int) []Record {
+	var recs []Record
+	for i := range n {
+		target := fmt.Sprintf("mx%02d.mail.example.org.", i)
+		recs = append(recs,
+			Record{Name: "example.org.", Type: "MX", TTL: 300, Value: target, Priority: uint16(10 + i)},
+			Record{Name: target, Type: "A", TTL: 300, Value: fmt.Sprintf("10.0.1.%d", i)},
+		)
+	}
+	return recs
+}
+
+func TestServeDNS_LargeAnswer_FitsBufferSize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		mx         int
+		tcp        bool
+		bufsize    uint16 // EDNS0 buffer size; 0 sends no OPT record
+		limit      int
+		wantTC     bool
+		wantAllAns bool
+		wantAllExt bool
+	}{
+		// Compression is what lets 60 MX records and their glue fit in 4096 bytes.
+		{name: "EDNS 4096 fits with compression", mx: 60, bufsize: 4096, limit: 4096, wantAllAns: true, wantAllExt: true},
+		{name: "plain UDP truncates answers", mx: 60, limit: dns.MinMsgSize, wantTC: true},
+		{name: "plain UDP drops glue only", mx: 20, limit: dns.MinMsgSize, wantAllAns: true},
+		{name: "TCP carries everything", mx: 60, tcp: true, limit: dns.MaxMsgSize, wantAllAns: true, wantAllExt: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			d := newTestHandler(t, largeMXZone(tt.mx))
+
+			req := new(dns.Msg)
+			req.SetQuestion("example.org.", dns.TypeMX)
+			if tt.bufsize > 0 {
+				req.SetEdns0(tt.bufsize, false)
+			}
+			rec := dnstest.NewRecorder(&test.ResponseWriter{TCP: tt.tcp})
+
+			if _, err := d.ServeDNS(context.Background(), rec, req); err != nil {
+				t.Fatalf("ServeDNS() error: %v", err)
+			}
+			msg := rec.Msg
+			packed, err := msg.Pack()
+			if err != nil {
+				t.Fatalf("Pack() error: %v", err)
+			}
+			if len(packed) > tt.limit {
+				t.Errorf("packed size %d exceeds %d", len(packed), tt.limit)
+			}
+			if msg.Truncated != tt.wantTC {
+				t.Errorf("TC = %v, want %v (answers %d/%d, extra %d)", msg.Truncated, tt.wantTC, len(msg.Answer), tt.mx, len(msg.Extra))
+			}
+			if (len(msg.Answer) == tt.mx) != tt.wantAllAns {
+				t.Errorf("answers = %d of %d, want all: %v", len(msg.Answer), tt.mx, tt.wantAllAns)
+			}
+			glue := 0
+			for _, rr := range msg.Extra {
+				if _, ok := rr.(*dns.A); ok {
+					glue++
+				}
+			}
+			if (glue == tt.mx) != tt.wantAllExt {
+				t.Errorf("glue = %d of %d, want all: %v", glue, tt.mx, tt.wantAllExt)
+			}
+			if !tt.tcp && len(msg.Answer) > 0 && !msg.Compress && len(packed) > 512 {
+				t.Error("large UDP answer sent without compression")
+			}
+		})
+	}
+}
+
+func TestServeDNS_EDNS0(t *testing.T) {
+	t.Parallel()
+	d := newTestHandler(t, append(largeMXZone(100),
+		Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"},
+	))
+
+	query := func(qname string, qtype uint16, opt bool) *dns.Msg {
+		t.Helper()
+		req := new(dns.Msg)
+		req.SetQuestion(qname, qtype)
+		if opt {
+			req.SetEdns0(1232, false)
+			o := req.IsEdns0()
+			o.Option = append(o.Option,
+				&dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: "0123456789abcdef"},
+				&dns.EDNS0_LOCAL{Code: dns.EDNS0LOCALSTART, Data: []byte{1}},
+			)
+		}
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := d.ServeDNS(t.Context(), rec, req); err != nil {
+			t.Fatalf("ServeDNS() error: %v", err)
+		}
+		return rec.Msg
+	}
+
+	// Answers, NODATA and NXDOMAIN all echo the OPT record.
+	for _, q := range []struct {
+		name  string
+		qtype uint16
+		rcode int
+	}{
+		{"app.example.org.", dns.TypeA, dns.RcodeSuccess},
+		{"app.example.org.", dns.TypeAAAA, dns.RcodeSuccess},
+		{"missing.example.org.", dns.TypeA, dns.RcodeNameError},
+	} {
+		msg := query(q.name, q.qtype, true)
+		if msg.Rcode != q.rcode {
+			t.Errorf("%s %s: rcode = %d, want %d", q.name, dns.TypeToString[q.qtype], msg.Rcode, q.rcode)
+		}
+		opt := msg.IsEdns0()
+		if opt == nil {
+			t.Errorf("%s %s: response has no OPT record", q.name, dns.TypeToString[q.qtype])
+			continue
+		}
+		if opt.UDPSize() != 1232 || opt.Do() {
+			t.Errorf("%s %s: OPT size %d, DO %v; want 1232 without DO", q.name, dns.TypeToString[q.qtype], opt.UDPSize(), opt.Do())
+		}
+		// The cookie is understood and echoed; the local option is not.
+		if len(opt.Option) != 1 || opt.Option[0].Option() != dns.EDNS0COOKIE {
+			t.Errorf("%s %s: OPT options = %v, want only the cookie", q.name, dns.TypeToString[q.qtype], opt.Option)
+		}
+	}
+
+	if msg := query("app.example.org.", dns.TypeA, false); msg.IsEdns0() != nil {
+		t.Error("response to a query without OPT carries one")
+	}
+
+	// A hundred MX records overflow the advertised 1232 bytes over UDP.
+	msg := query("example.org.", dns.TypeMX, true)
+	packed, err := msg.Pack()
+	if err != nil {
+		t.Fatalf("Pack() error: %v", err)
+	}
+	if len(packed) > 1232 {
+		t.Errorf("packed size %d exceeds the advertised 1232", len(packed))
+	}
+	if !msg.Truncated || len(msg.Answer) >= 100 {
+		t.Errorf("TC = %v with %d of 100 answers, want a truncated answer", msg.Truncated, len(msg.Answer))
+	}
+	if msg.IsEdns0() == nil {
+		t.Error("truncated response dropped its OPT record")
+	}
+}
+
+func TestServeDNS_DNSSECTypesUnsigned(t *testing.T) {
+	t.Parallel()
+	d := newTestHandler(t, []Record{
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"},
+	})
+
+	tests := []struct {
+		name      string
+		qname     string
+		qtype     uint16
+		wantRcode int
+	}{
+		{name: "DNSKEY at apex", qname: "example.org.", qtype: dns.TypeDNSKEY, wantRcode: dns.RcodeSuccess},
+		{name: "DS at apex", qname: "example.org.", qtype: dns.TypeDS, wantRcode: dns.RcodeSuccess},
+		{name: "NSEC at apex", qname: "example.org.", qtype: dns.TypeNSEC, wantRcode: dns.RcodeSuccess},
+		{name: "DS at existing name", qname: "app.example.org.", qtype: dns.TypeDS, wantRcode: dns.RcodeSuccess},
+		{name: "DNSKEY at absent name", qname: "nope.example.org.", qtype: dns.TypeDNSKEY, wantRcode: dns.RcodeNameError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			req := new(dns.Msg)
+			req.SetQuestion(tt.qname, tt.qtype)
+			rec := dnstest.NewRecorder(&test.ResponseWriter{})
+
+			code, err := d.ServeDNS(context.Background(), rec, req)
+			if err != nil {
+				t.Fatalf("ServeDNS() error: %v", err)
+			}
+			if code != tt.wantRcode {
+				t.Errorf("rcode = %d, want %d", code, tt.wantRcode)
+			}
+			if len(rec.Msg.Answer) != 0 {
+				t.Errorf("answer = %v, want none", rec.Msg.Answer)
+			}
+			if !rec.Msg.Authoritative {
+				t.Error("AA not set")
+			}
+			if len(rec.Msg.Ns) != 1 || rec.Msg.Ns[0].Header().Rrtype != dns.TypeSOA {
+				t.Errorf("authority = %v, want the zone SOA", rec.Msg.Ns)
+			}
+		})
+	}
+}
+
+func TestServeDNS_TTLJitter(t *testing.T) {
+	t.Parallel()
+	d := newTestHandler(t, []Record{
+		{Name: "app.example.org.", Type: "A", TTL: 1000, Value: "10.0.0.1"},
+		{Name: "app.example.org.", Type: "A", TTL: 1000, Value: "10.0.0.2"},
+	})
+	d.TTLJitter = 0.1
+
+	const queries = 200
+	seen := make(map[uint32]bool)
+	for range queries {
+		req := new(dns.Msg)
+		req.SetQuestion("app.example.org.", dns.TypeA)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := d.ServeDNS(t.Context(), rec, req); err != nil {
+			t.Fatalf("ServeDNS() error: %v", err)
+		}
+		if len(rec.Msg.Answer) != 2 {
+			t.Fatalf("got %d answers, want 2", len(rec.Msg.Answer))
+		}
+		ttl := rec.Msg.Answer[0].Header().Ttl
+		if ttl < 900 || ttl > 1000 {
+			t.Fatalf("TTL = %d, want within [900, 1000]", ttl)
+		}
+		if other := rec.Msg.Answer[1].Header().Ttl; other != ttl {
+			t.Fatalf("RRset TTLs = %d and %d, want them equal", ttl, other)
+		}
+		seen[ttl] = true
+	}
+	// 200 draws from 101 values leave roughly 87 distinct; 20 is far below
+	// anything a working jitter produces.
+	if len(seen) < 20 {
+		t.Errorf("saw %d distinct TTLs over %d queries, want them spread across the band", len(seen), queries)
+	}
+
+	// The stored TTL is untouched.
+	if got := d.Store.GetAll(t.Context(), "app.example.org."); got[0].TTL != 1000 {
+		t.Errorf("stored TTL = %d, want 1000", got[0].TTL)
+	}
+}
+
+func TestServeDNS_MinServeTTL(t *testing.T) {
+	t.Parallel()
+	d := newTestHandler(t, []Record{
+		{Name: "low.example.org.", Type: "A", TTL: 60, Value: "10.0.0.1"},
+		{Name: "high.example.org.", Type: "A", TTL: 600, Value: "10.0.0.2"},
+		{Name: "www.example.org.", Type: "CNAME", TTL: 60, Value: "high.example.org."},
+	})
+	d.MinServeTTL = 120
+
+	tests := []struct {
+		qname string
+		want  []uint32
+	}{
+		{"low.example.org.", []uint32{120}},
+		{"high.example.org.", []uint32{600}},
+		{"www.example.org.", []uint32{120, 600}},
+	}
+	for _, tt := range tests {
+		msg := queryDO(t, d, tt.qname, dns.TypeA, false)
+		if len(msg.Answer) != len(tt.want) {
+			t.Fatalf("%s answer = %v, want %d records", tt.qname, msg.Answer, len(tt.want))
+		}
+		for i, rr := range msg.Answer {
+			if rr.Header().Ttl != tt.want[i] {
+				t.Errorf("%s answer[%d] TTL = %d, want %d", tt.qname, i, rr.Header().Ttl, tt.want[i])
+			}
+		}
+	}
+
+	// The floor also holds under jitter, and the stored TTL is untouched.
+	d.TTLJitter = 0.5
+	for range 50 {
+		if ttl := queryDO(t, d, "low.example.org.", dns.TypeA, false).Answer[0].Header().Ttl; ttl != 120 {
+			t.Fatalf("jittered TTL = %d, want the 120 floor", ttl)
+		}
+	}
+	if got := d.Store.GetAll(t.Context(), "low.example.org."); got[0].TTL != 60 {
+		t.Errorf("stored TTL = %d, want 60", got[0].TTL)
+	}
+}
+
+func TestServeDNS_ServeDelay(t *testing.T) {
+	t.Parallel()
+	clock := &fakeClock{t: time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)}
+	s, err := NewStore(filepath.Join(t.TempDir(), "records.json"), 0, WithClock(clock.Now), WithServeDelay(30*time.Second))
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	t.Cleanup(s.Stop)
+	d := &DynUpdate{Zones: []string{"example.org."}, Store: s}
+
+	query := func() *dns.Msg {
+		t.Helper()
+		req := new(dns.Msg)
+		req.SetQuestion("new.example.org.", dns.TypeA)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := d.ServeDNS(t.Context(), rec, req); err != nil {
+			t.Fatalf("ServeDNS() error: %v", err)
+		}
+		return rec.Msg
+	}
+
+	r := Record{Name: "new.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}
+	if err := s.Upsert(t.Context(), r); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	if got := s.GetAll(t.Context(), r.Name); len(got) != 1 || !got[0].CreatedAt.Equal(clock.Now()) {
+		t.Fatalf("GetAll() = %+v, want one record created at %v", got, clock.Now())
+	}
+
+	clock.Advance(29 * time.Second)
+	if m := query(); m.Rcode != dns.RcodeSuccess || len(m.Answer) != 0 || len(m.Ns) != 1 {
+		t.Errorf("before the delay: rcode %d, %d answers, %d authority; want NODATA with SOA", m.Rcode, len(m.Answer), len(m.Ns))
+	}
+	// Updating the record does not restart the delay.
+	r.TTL = 600
+	if err := s.Upsert(t.Context(), r); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+
+	clock.Advance(time.Second)
+	if m := query(); len(m.Answer) != 1 || m.Answer[0].Header().Ttl != 600 {
+		t.Errorf("after the delay: answers %v, want the record with TTL 600", m.Answer)
+	}
+}
@@ -1,5 +1,5 @@
 // ABOUTME: gRPC server for DNS record management via protobuf.
-// ABOUTME: Implements DynUpdateService with TLS support and auth interceptor.
+// ABOUTME: Implements DynUpdateService and the gRPC health service with TLS support and auth interceptor.
 
 package dynupdate
 
@@ -7,29 +7,54 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"math"
 	"net"
+	"slices"
+	"strings"
 	"time"
 
 	pb "github.com/mauromedda/coredns-updater-plugin/proto"
+	"github.com/miekg/dns"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 // GRPCServer serves the gRPC management API.
 type GRPCServer struct {
-	store  *Store
-	auth   *Auth
-	listen string
-	tls    *tlsConfig
-	server *grpc.Server
+	store      *Store
+	auth       *Auth
+	listen     string
+	tls        *tlsConfig
+	reflection bool // register the server reflection service
+	server     *grpc.Server
+	stopCh     chan struct{} // closed by Stop to end Watch streams
+}
+
+// GRPCOption configures optional GRPCServer behaviour.
+type GRPCOption func(*GRPCServer)
+
+// WithReflection registers the gRPC server reflection service, so tools
+// such as grpcurl can discover the API. Reflection calls are authenticated
+// like any other and need the read scope.
+func WithReflection() GRPCOption {
+	return func(g *GRPCServer) {
+		g.reflection = true
+	}
 }
 
 // NewGRPCServer creates a gRPC server (not yet started).
-func NewGRPCServer(store *Store, auth *Auth, listen string, tls *tlsConfig) *GRPCServer {
-	return &GRPCServer{store: store, auth: auth, listen: listen, tls: tls}
+func NewGRPCServer(store *Store, auth *Auth, listen string, tls *tlsConfig, opts ...GRPCOption) *GRPCServer {
+	g := &GRPCServer{store: store, auth: auth, listen: listen, tls: tls, stopCh: make(chan struct{})}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
 }
 
 // Start begins serving the gRPC API in a background goroutine.
@@ -41,6 +66,7 @@ func (g *GRPCServer) Start() error {
 
 	opts := []grpc.ServerOption{
 		grpc.UnaryInterceptor(g.auth.UnaryInterceptor),
+		grpc.StreamInterceptor(g.auth.StreamInterceptor),
 	}
 
 	if g.tls != nil {
@@ -53,7 +79,7 @@ func (g *GRPCServer) Start() error {
 	}
 
 	g.server = grpc.NewServer(opts...)
-	pb.RegisterDynUpdateServiceServer(g.server, &grpcService{store: g.store})
+	g.register(g.server)
 
 	go func() {
 		if err := g.server.Serve(ln); err != nil {
@@ -64,11 +90,28 @@ func (g *GRPCServer) Start() error {
 	return nil
 }
 
+// register adds the services g serves to srv.
+func (g *GRPCServer) register(srv *grpc.Server) {
+	pb.RegisterDynUpdateServiceServer(srv, &grpcService{store: g.store, stop: g.stopCh})
+	healthpb.RegisterHealthServer(srv, newHealthServer(g.store, g.stopCh))
+	if g.reflection {
+		reflection.Register(srv)
+	}
+}
+
 // Stop gracefully shuts down the gRPC server with a timeout.
 func (g *GRPCServer) Stop() {
 	if g.server == nil {
 		return
 	}
+	select {
+	case <-g.stopCh:
+		return
+	default:
+	}
+	// Watch streams never finish on their own; end them so the graceful
+	// stop does not wait out its timeout.
+	close(g.stopCh)
 	done := make(chan struct{})
 	go func() {
 		g.server.GracefulStop()
@@ -85,14 +128,15 @@ func (g *GRPCServer) Stop() {
 type grpcService struct {
 	pb.UnimplementedDynUpdateServiceServer
 	store *Store
+	stop  <-chan struct{} // closed when the server stops; nil never fires
 }
 
-func (s *grpcService) List(_ context.Context, req *pb.ListRequest) (*pb.ListResponse, error) {
+func (s *grpcService) List(ctx context.Context, req *pb.ListRequest) (*pb.ListResponse, error) {
 	var records []Record
 	if req.Name != "" {
-		records = s.store.GetAll(req.Name)
+		records = s.store.GetAll(ctx, req.Name)
 	} else {
-		records = s.store.List()
+		records = s.store.List(ctx)
 	}
 
 	pbRecords := make([]*pb.Record, 0, len(records))
@@ -103,7 +147,67 @@ func (s *grpcService) List(_ context.Context, req *pb.ListRequest) (*pb.ListResp
 	return &pb.ListResponse{Records: pbRecords}, nil
 }
 
-func (s *grpcService) Upsert(_ context.Context, req *pb.UpsertRequest) (*pb.UpsertResponse, error) {
+// listStreamChunk is the number of records per ListStream message.
+const listStreamChunk = 100
+
+func (s *grpcService) ListStream(req *pb.ListRequest, stream pb.DynUpdateService_ListStreamServer) error {
+	if req.Name != "" {
+		// One name's records are few enough to fetch at once.
+		for chunk := range slices.Chunk(s.store.GetAll(stream.Context(), req.Name), listStreamChunk) {
+			if err := sendListChunk(stream, chunk); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// Each chunk is read from the store after the last key sent, so only
+	// one chunk is held at a time however many records match. Records
+	// written between chunks are included if they sort after the cursor.
+	var after *RecordKey
+	for {
+		chunk, more := s.store.ListPage(stream.Context(), after, listStreamChunk)
+		if len(chunk) > 0 {
+			if err := sendListChunk(stream, chunk); err != nil {
+				return err
+			}
+			last := chunk[len(chunk)-1].Key()
+			after = &last
+		}
+		if !more {
+			return nil
+		}
+	}
+}
+
+// sendListChunk sends records as one ListStream message.
+func sendListChunk(stream pb.DynUpdateService_ListStreamServer, records []Record) error {
+	pbRecords := make([]*pb.Record, 0, len(records))
+	for _, r := range records {
+		pbRecords = append(pbRecords, recordToProto(r))
+	}
+	return stream.Send(&pb.ListStreamResponse{Records: pbRecords})
+}
+
+func (s *grpcService) Get(ctx context.Context, req *pb.GetRequest) (*pb.GetResponse, error) {
+	if req.Name == "" || req.Type == "" {
+		return nil, status.Error(codes.InvalidArgument, "name and type are required")
+	}
+
+	records := s.store.Get(ctx, req.Name, req.Type)
+	if len(records) == 0 {
+		return nil, status.Errorf(codes.NotFound, "no %s records for %s", strings.ToUpper(req.Type), req.Name)
+	}
+	sortRecords(records)
+
+	pbRecords := make([]*pb.Record, 0, len(records))
+	for _, r := range records {
+		pbRecords = append(pbRecords, recordToProto(r))
+	}
+	return &pb.GetResponse{Records: pbRecords}, nil
+}
+
+func (s *grpcService) Upsert(ctx context.Context, req *pb.UpsertRequest) (*pb.UpsertResponse, error) {
 	if req.Record == nil {
 		return nil, status.Error(codes.InvalidArgument, "record is required")
 	}
@@ -112,56 +216,240 @@ func (s *grpcService) Upsert(_ context.Context, req *pb.UpsertRequest) (*pb.Upse
 	if err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "invalid field value: %v", err)
 	}
-	if err := rec.Validate(); err != nil {
+	s.store.ApplyDefaults(ctx, &rec)
+	if err := s.store.validateRecord(&rec); err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "validation failed: %v", err)
 	}
+	if req.Force {
+		ctx = ContextWithForce(ctx)
+	}
 
-	if err := s.store.Upsert(rec); err != nil {
-		if errors.Is(err, ErrPolicyDenied) {
+	if err := s.store.Upsert(ctx, rec); err != nil {
+		if errors.Is(err, ErrPolicyDenied) || errors.Is(err, ErrNotOwner) {
 			return nil, status.Errorf(codes.PermissionDenied, "upsert denied: %v", err)
 		}
-		return nil, status.Errorf(codes.Internal, "upsert failed: %v", err)
+		if errors.Is(err, ErrCNAMEConflict) {
+			return nil, status.Errorf(codes.FailedPrecondition, "upsert rejected: %v", err)
+		}
+		if limitReached(err) {
+			return nil, status.Errorf(codes.ResourceExhausted, "upsert denied: %v", err)
+		}
+		return nil, storeFailure("upsert", err)
 	}
 
 	return &pb.UpsertResponse{Record: recordToProto(rec)}, nil
 }
 
-func (s *grpcService) Delete(_ context.Context, req *pb.DeleteRequest) (*pb.DeleteResponse, error) {
+func (s *grpcService) Delete(ctx context.Context, req *pb.DeleteRequest) (*pb.DeleteResponse, error) {
 	if req.Name == "" {
 		return nil, status.Error(codes.InvalidArgument, "name is required")
 	}
+	if req.Force {
+		ctx = ContextWithForce(ctx)
+	}
 
 	if req.Type == "" && req.Value == "" {
-		if err := s.store.DeleteAll(req.Name); err != nil {
-			if errors.Is(err, ErrPolicyDenied) {
+		if err := s.store.DeleteAll(ctx, req.Name); err != nil {
+			if errors.Is(err, ErrPolicyDenied) || errors.Is(err, ErrNotOwner) {
 				return nil, status.Errorf(codes.PermissionDenied, "delete denied: %v", err)
 			}
-			return nil, status.Errorf(codes.Internal, "delete failed: %v", err)
+			if errors.Is(err, ErrProtected) {
+				return nil, status.Errorf(codes.FailedPrecondition, "delete refused: %v", err)
+			}
+			return nil, storeFailure("delete", err)
 		}
 	} else {
-		if err := s.store.Delete(req.Name, req.Type, req.Value); err != nil {
-			if errors.Is(err, ErrPolicyDenied) {
+		if err := s.store.Delete(ctx, req.Name, req.Type, req.Value); err != nil {
+			if errors.Is(err, ErrPolicyDenied) || errors.Is(err, ErrNotOwner) {
 				return nil, status.Errorf(codes.PermissionDenied, "delete denied: %v", err)
 			}
-			return nil, status.Errorf(codes.Internal, "delete failed: %v", err)
+			if errors.Is(err, ErrProtected) {
+				return nil, status.Errorf(codes.FailedPrecondition, "delete refused: %v", err)
+			}
+			return nil, storeFailure("delete", err)
 		}
 	}
 
 	return &pb.DeleteResponse{}, nil
 }
 
+func (s *grpcService) DeleteByType(ctx context.Context, req *pb.DeleteByTypeRequest) (*pb.DeleteByTypeResponse, error) {
+	if req.Name == "" || req.Type == "" {
+		return nil, status.Error(codes.InvalidArgument, "name and type are required")
+	}
+	if req.Force {
+		ctx = ContextWithForce(ctx)
+	}
+
+	if err := s.store.DeleteByType(ctx, req.Name, strings.ToUpper(req.Type)); err != nil {
+		if errors.Is(err, ErrPolicyDenied) || errors.Is(err, ErrNotOwner) {
+			return nil, status.Errorf(codes.PermissionDenied, "delete denied: %v", err)
+		}
+		if errors.Is(err, ErrProtected) {
+			return nil, status.Errorf(codes.FailedPrecondition, "delete refused: %v", err)
+		}
+		return nil, storeFailure("delete", err)
+	}
+	return &pb.DeleteByTypeResponse{}, nil
+}
+
+func (s *grpcService) DeleteBySuffix(ctx context.Context, req *pb.DeleteBySuffixRequest) (*pb.DeleteBySuffixResponse, error) {
+	if req.Suffix == "" {
+		return nil, status.Error(codes.InvalidArgument, "suffix is required")
+	}
+	if dns.Fqdn(req.Suffix) == "." {
+		return nil, status.Error(codes.InvalidArgument, "suffix must not be the root")
+	}
+	if req.Force {
+		ctx = ContextWithForce(ctx)
+	}
+
+	n, err := s.store.DeleteBySuffix(ctx, req.Suffix)
+	if err != nil {
+		if errors.Is(err, ErrPolicyDenied) || errors.Is(err, ErrNotOwner) {
+			return nil, status.Errorf(codes.PermissionDenied, "delete denied: %v", err)
+		}
+		if errors.Is(err, ErrProtected) {
+			return nil, status.Errorf(codes.FailedPrecondition, "delete refused: %v", err)
+		}
+		return nil, storeFailure("delete", err)
+	}
+	return &pb.DeleteBySuffixResponse{Deleted: uint32(n)}, nil
+}
+
+func (s *grpcService) Import(stream pb.DynUpdateService_ImportServer) error {
+	ctx := stream.Context()
+
+	var (
+		recs []Record
+		opts ImportOptions
+		size int
+	)
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		// The stream is held in memory until it ends; cap it like a REST
+		// batch body.
+		if size += proto.Size(req); size > maxBatchBytes {
+			return status.Errorf(codes.ResourceExhausted, "import exceeds %d bytes", maxBatchBytes)
+		}
+		if len(recs) == 0 {
+			switch req.OnDuplicate {
+			case pb.OnDuplicate_ON_DUPLICATE_OVERWRITE:
+				opts.OnDuplicate = DuplicateOverwrite
+			case pb.OnDuplicate_ON_DUPLICATE_SKIP:
+				opts.OnDuplicate = DuplicateSkip
+			case pb.OnDuplicate_ON_DUPLICATE_ERROR:
+				opts.OnDuplicate = DuplicateError
+			default:
+				return status.Errorf(codes.InvalidArgument, "unknown on_duplicate %v", req.OnDuplicate)
+			}
+		}
+		if req.Record == nil {
+			return status.Errorf(codes.InvalidArgument, "record %d: record is required", len(recs))
+		}
+		rec, err := protoToRecord(req.Record)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "record %d: invalid field value: %v", len(recs), err)
+		}
+		s.store.ApplyDefaults(ctx, &rec)
+		if err := s.store.validateRecord(&rec); err != nil {
+			return status.Errorf(codes.InvalidArgument, "record %d: validation failed: %v", len(recs), err)
+		}
+		recs = append(recs, rec)
+	}
+
+	res, err := s.store.Import(ctx, recs, opts)
+	if err != nil {
+		if errors.Is(err, ErrDuplicateRecord) {
+			return status.Errorf(codes.AlreadyExists, "import rejected: %v", err)
+		}
+		if errors.Is(err, ErrCNAMEConflict) {
+			return status.Errorf(codes.FailedPrecondition, "import rejected: %v", err)
+		}
+		if errors.Is(err, ErrPolicyDenied) || errors.Is(err, ErrNotOwner) {
+			return status.Errorf(codes.PermissionDenied, "import denied: %v", err)
+		}
+		if limitReached(err) {
+			return status.Errorf(codes.ResourceExhausted, "import denied: %v", err)
+		}
+		return storeFailure("import", err)
+	}
+
+	return stream.SendAndClose(&pb.ImportResponse{
+		Created: uint32(res.Created),
+		Updated: uint32(res.Updated),
+		Skipped: uint32(res.Skipped),
+	})
+}
+
+// Watch streams store changes visible to the caller until the client
+// disconnects or the server stops.
+func (s *grpcService) Watch(_ *pb.WatchRequest, stream pb.DynUpdateService_WatchServer) error {
+	ctx := stream.Context()
+	owner, scoped := scopedOwner(ctx)
+
+	events, unsubscribe := s.store.Subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.stop:
+			return nil
+		case ev := <-events:
+			if scoped && ev.Record.Owner != owner {
+				continue
+			}
+			if err := stream.Send(&pb.WatchEvent{Op: changeOpToProto(ev.Op), Record: recordToProto(ev.Record)}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func changeOpToProto(op ChangeOp) pb.ChangeOp {
+	switch op {
+	case OpAdded:
+		return pb.ChangeOp_CHANGE_OP_ADDED
+	case OpUpdated:
+		return pb.ChangeOp_CHANGE_OP_UPDATED
+	case OpDeleted:
+		return pb.ChangeOp_CHANGE_OP_DELETED
+	default:
+		return pb.ChangeOp_CHANGE_OP_UNSPECIFIED
+	}
+}
+
 func recordToProto(r Record) *pb.Record {
-	return &pb.Record{
-		Name:     r.Name,
-		Type:     r.Type,
-		Ttl:      r.TTL,
-		Value:    r.Value,
-		Priority: uint32(r.Priority),
-		Weight:   uint32(r.Weight),
-		Port:     uint32(r.Port),
-		Flag:     uint32(r.Flag),
-		Tag:      r.Tag,
+	p := &pb.Record{
+		Name:      r.Name,
+		Type:      r.Type,
+		Ttl:       r.TTL,
+		Value:     r.Value,
+		Priority:  uint32(r.Priority),
+		Weight:    uint32(r.Weight),
+		Port:      uint32(r.Port),
+		Flag:      uint32(r.Flag),
+		Tag:       r.Tag,
+		Os:        r.OS,
+		TxtName:   r.TXTName,
+		Owner:     r.Owner,
+		Comment:   r.Comment,
+		Labels:    r.Labels,
+		Disabled:  r.Disabled,
+		Protected: r.Protected,
 	}
+	if !r.ExpiresAt.IsZero() {
+		p.ExpiresAt = r.ExpiresAt.Unix()
+	}
+	return p
 }
 
 func protoToRecord(p *pb.Record) (Record, error) {
@@ -177,15 +465,34 @@ func protoToRecord(p *pb.Record) (Record, error) {
 	if p.Flag > math.MaxUint8 {
 		return Record{}, fmt.Errorf("flag %d exceeds max %d", p.Flag, math.MaxUint8)
 	}
-	return Record{
-		Name:     p.Name,
-		Type:     p.Type,
-		TTL:      p.Ttl,
-		Value:    p.Value,
-		Priority: uint16(p.Priority),
-		Weight:   uint16(p.Weight),
-		Port:     uint16(p.Port),
-		Flag:     uint8(p.Flag),
-		Tag:      p.Tag,
-	}, nil
+	r := Record{
+		Name:      p.Name,
+		Type:      p.Type,
+		TTL:       p.Ttl,
+		Value:     p.Value,
+		Priority:  uint16(p.Priority),
+		Weight:    uint16(p.Weight),
+		Port:      uint16(p.Port),
+		Flag:      uint8(p.Flag),
+		Tag:       p.Tag,
+		OS:        p.Os,
+		TXTName:   p.TxtName,
+		Comment:   p.Comment,
+		Labels:    p.Labels,
+		Disabled:  p.Disabled,
+		Protected: p.Protected,
+	}
+	if p.ExpiresAt != 0 {
+		r.ExpiresAt = time.Unix(p.ExpiresAt, 0).UTC()
+	}
+	return r, nil
+}
+
+// storeFailure converts a store error not covered by a more specific code:
+// Unavailable while the backend is failing, Internal otherwise.
+func storeFailure(op string, err error) error {
+	if errors.Is(err, ErrBackendUnavailable) {
+		return status.Errorf(codes.Unavailable, "%s failed: %v", op, err)
+	}
+	return status.Errorf(codes.Internal, "%s failed: %v", op, err)
 }
@@ -5,17 +5,24 @@ package dynupdate
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"net"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	pb "github.com/mauromedda/coredns-updater-plugin/proto"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
 	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
 )
 
 const bufSize = 1024 * 1024
@@ -32,7 +39,10 @@ func newTestGRPCClient(t *testing.T, token string, opts ...StoreOption) (pb.DynU
 	t.Cleanup(func() { store.Stop() })
 
 	auth := &Auth{Token: "grpc-secret"}
-	srv := grpc.NewServer(grpc.UnaryInterceptor(auth.UnaryInterceptor))
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(auth.UnaryInterceptor),
+		grpc.StreamInterceptor(auth.StreamInterceptor),
+	)
 	pb.RegisterDynUpdateServiceServer(srv, &grpcService{store: store})
 
 	lis := bufconn.Listen(bufSize)
@@ -102,11 +112,161 @@ func TestGRPC_UpsertAndList(t *testing.T) {
 	}
 }
 
+func TestGRPC_Upsert_IgnoresClientOwner(t *testing.T) {
+	t.Parallel()
+	client, store := newTestGRPCClient(t, "grpc-secret")
+
+	_, err := client.Upsert(authCtx("grpc-secret"), &pb.UpsertRequest{
+		Record: &pb.Record{Name: "app.example.org.", Type: "A", Ttl: 300, Value: "10.0.0.1", Owner: "team-a"},
+	})
+	if err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	if got := store.GetAll(t.Context(), "app.example.org."); len(got) != 1 || got[0].Owner != "" {
+		t.Errorf("GetAll() = %v, want one record without owner", got)
+	}
+}
+
+func TestGRPC_ListStream(t *testing.T) {
+	t.Parallel()
+	client, store := newTestGRPCClient(t, "grpc-secret")
+	const seeded = 350
+	recs := make([]Record, 0, seeded)
+	for i := range seeded {
+		recs = append(recs, Record{Name: fmt.Sprintf("host%03d.example.org.", i), Type: "A", TTL: 300, Value: fmt.Sprintf("10.0.%d.%d", i/256, i%256)})
+	}
+	if _, err := store.Import(t.Context(), recs, ImportOptions{}); err != nil {
+		t.Fatalf("Import() error: %v", err)
+	}
+
+	stream := func(req *pb.ListRequest) (names []string, messages int) {
+		t.Helper()
+		s, err := client.ListStream(authCtx("grpc-secret"), req)
+		if err != nil {
+			t.Fatalf("ListStream() error: %v", err)
+		}
+		for {
+			resp, err := s.Recv()
+			if err == io.EOF {
+				return names, messages
+			}
+			if err != nil {
+				t.Fatalf("Recv() error: %v", err)
+			}
+			messages++
+			if len(resp.Records) == 0 || len(resp.Records) > listStreamChunk {
+				t.Errorf("message %d holds %d records, want 1..%d", messages, len(resp.Records), listStreamChunk)
+			}
+			for _, r := range resp.Records {
+				names = append(names, r.Name)
+			}
+		}
+	}
+
+	names, messages := stream(&pb.ListRequest{})
+	if len(names) != seeded {
+		t.Fatalf("streamed %d records, want %d", len(names), seeded)
+	}
+	if want := (seeded + listStreamChunk - 1) / listStreamChunk; messages != want {
+		t.Errorf("got %d messages, want %d", messages, want)
+	}
+	if !slices.IsSorted(names) {
+		t.Error("records are not streamed in canonical order")
+	}
+
+	names, _ = stream(&pb.ListRequest{Name: "host042.example.org."})
+	if !slices.Equal(names, []string{"host042.example.org."}) {
+		t.Errorf("filtered stream = %v, want only host042.example.org.", names)
+	}
+
+	if _, messages = stream(&pb.ListRequest{Name: "absent.example.org."}); messages != 0 {
+		t.Errorf("got %d messages for an absent name, want 0", messages)
+	}
+}
+
+func TestGRPC_Annotations(t *testing.T) {
+	t.Parallel()
+	client, store := newTestGRPCClient(t, "grpc-secret")
+	ctx := authCtx("grpc-secret")
+
+	_, err := client.Upsert(ctx, &pb.UpsertRequest{Record: &pb.Record{
+		Name: "app.example.org.", Type: "A", Ttl: 300, Value: "10.0.0.1",
+		Comment: "INC-1234", Labels: map[string]string{"team": "netops"},
+	}})
+	if err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	if got := store.GetAll(t.Context(), "app.example.org."); len(got) != 1 || got[0].Comment != "INC-1234" || got[0].Labels["team"] != "netops" {
+		t.Errorf("stored = %+v, want the comment and labels kept", got)
+	}
+
+	resp, err := client.List(ctx, &pb.ListRequest{Name: "app.example.org."})
+	if err != nil {
+		t.Fatalf("List() error: %v", err)
+	}
+	if len(resp.Records) != 1 || resp.Records[0].Comment != "INC-1234" || resp.Records[0].Labels["team"] != "netops" {
+		t.Errorf("listed = %v, want the comment and labels returned", resp.Records)
+	}
+}
+
+func TestGRPC_HINFOAndRP(t *testing.T) {
+	t.Parallel()
+	client, _ := newTestGRPCClient(t, "grpc-secret")
+	ctx := authCtx("grpc-secret")
+
+	for _, rec := range []*pb.Record{
+		{Name: "host.example.org.", Type: "HINFO", Ttl: 300, Value: "amd64", Os: "Linux"},
+		{Name: "host.example.org.", Type: "RP", Ttl: 300, Value: "admin.example.org.", TxtName: "contact.example.org."},
+	} {
+		if _, err := client.Upsert(ctx, &pb.UpsertRequest{Record: rec}); err != nil {
+			t.Fatalf("Upsert(%s) error: %v", rec.Type, err)
+		}
+	}
+
+	resp, err := client.Get(ctx, &pb.GetRequest{Name: "host.example.org.", Type: "HINFO"})
+	if err != nil {
+		t.Fatalf("Get(HINFO) error: %v", err)
+	}
+	if len(resp.Records) != 1 || resp.Records[0].Value != "amd64" || resp.Records[0].Os != "Linux" {
+		t.Errorf("HINFO = %v, want amd64 and Linux", resp.Records)
+	}
+	resp, err = client.Get(ctx, &pb.GetRequest{Name: "host.example.org.", Type: "RP"})
+	if err != nil {
+		t.Fatalf("Get(RP) error: %v", err)
+	}
+	if len(resp.Records) != 1 || resp.Records[0].Value != "admin.example.org." || resp.Records[0].TxtName != "contact.example.org." {
+		t.Errorf("RP = %v, want admin.example.org. and contact.example.org.", resp.Records)
+	}
+}
+
+func TestGRPC_Disabled(t *testing.T) {
+	t.Parallel()
+	client, store := newTestGRPCClient(t, "grpc-secret")
+	ctx := authCtx("grpc-secret")
+
+	_, err := client.Upsert(ctx, &pb.UpsertRequest{Record: &pb.Record{
+		Name: "app.example.org.", Type: "A", Ttl: 300, Value: "10.0.0.1", Disabled: true,
+	}})
+	if err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	if got := store.GetAll(t.Context(), "app.example.org."); len(got) != 1 || !got[0].Disabled {
+		t.Errorf("stored = %+v, want the record kept disabled", got)
+	}
+	resp, err := client.List(ctx, &pb.ListRequest{Name: "app.example.org."})
+	if err != nil {
+		t.Fatalf("List() error: %v", err)
+	}
+	if len(resp.Records) != 1 || !resp.Records[0].Disabled {
+		t.Errorf("listed = %v, want the disabled record returned", resp.Records)
+	}
+}
+
 func TestGRPC_ListByName(t *testing.T) {
 	t.Parallel()
 	client, store := newTestGRPCClient(t, "grpc-secret")
-	_ = store.Upsert(Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
-	_ = store.Upsert(Record{Name: "other.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"})
+	_ = store.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
+	_ = store.Upsert(t.Context(), Record{Name: "other.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"})
 
 	resp, err := client.List(authCtx("grpc-secret"), &pb.ListRequest{Name: "app.example.org."})
 	if err != nil {
@@ -117,10 +277,55 @@ func TestGRPC_ListByName(t *testing.T) {
 	}
 }
 
+func TestGRPC_Get(t *testing.T) {
+	t.Parallel()
+	client, store := newTestGRPCClient(t, "grpc-secret")
+	for _, r := range []Record{
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"},
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"},
+		{Name: "app.example.org.", Type: "AAAA", TTL: 300, Value: "2001:db8::1"},
+	} {
+		if err := store.Upsert(t.Context(), r); err != nil {
+			t.Fatalf("Upsert() error: %v", err)
+		}
+	}
+	ctx := authCtx("grpc-secret")
+
+	resp, err := client.Get(ctx, &pb.GetRequest{Name: "app.example.org.", Type: "a"})
+	if err != nil {
+		t.Fatalf("Get() error: %v", err)
+	}
+	var values []string
+	for _, r := range resp.Records {
+		if r.Type != "A" {
+			t.Errorf("Get(A) returned a %s record", r.Type)
+		}
+		values = append(values, r.Value)
+	}
+	if want := []string{"10.0.0.1", "10.0.0.2"}; !slices.Equal(values, want) {
+		t.Errorf("Get(A) values = %v, want %v", values, want)
+	}
+
+	tests := []struct {
+		name string
+		req  *pb.GetRequest
+		code codes.Code
+	}{
+		{"wrong type", &pb.GetRequest{Name: "app.example.org.", Type: "TXT"}, codes.NotFound},
+		{"absent name", &pb.GetRequest{Name: "missing.example.org.", Type: "A"}, codes.NotFound},
+		{"missing type", &pb.GetRequest{Name: "app.example.org."}, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		if _, err := client.Get(ctx, tt.req); status.Code(err) != tt.code {
+			t.Errorf("%s: Get() code = %v, want %v", tt.name, status.Code(err), tt.code)
+		}
+	}
+}
+
 func TestGRPC_Delete(t *testing.T) {
 	t.Parallel()
 	client, store := newTestGRPCClient(t, "grpc-secret")
-	_ = store.Upsert(Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
+	_ = store.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
 	ctx := authCtx("grpc-secret")
 
 	_, err := client.Delete(ctx, &pb.DeleteRequest{
@@ -141,6 +346,106 @@ func TestGRPC_Delete(t *testing.T) {
 	}
 }
 
+func TestGRPC_DeleteByType(t *testing.T) {
+	t.Parallel()
+	client, store := newTestGRPCClient(t, "grpc-secret")
+	for _, r := range []Record{
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"},
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"},
+		{Name: "app.example.org.", Type: "AAAA", TTL: 300, Value: "2001:db8::1"},
+	} {
+		if err := store.Upsert(t.Context(), r); err != nil {
+			t.Fatalf("Upsert() error: %v", err)
+		}
+	}
+	ctx := authCtx("grpc-secret")
+
+	if _, err := client.DeleteByType(ctx, &pb.DeleteByTypeRequest{Name: "app.example.org.", Type: "a"}); err != nil {
+		t.Fatalf("DeleteByType() error: %v", err)
+	}
+	got := store.GetAll(t.Context(), "app.example.org.")
+	if len(got) != 1 || got[0].Type != "AAAA" {
+		t.Errorf("remaining records = %v, want only the AAAA record", got)
+	}
+
+	_, err := client.DeleteByType(ctx, &pb.DeleteByTypeRequest{Name: "app.example.org."})
+	if s, ok := status.FromError(err); !ok || s.Code() != codes.InvalidArgument {
+		t.Errorf("missing type: error = %v, want InvalidArgument", err)
+	}
+}
+
+func TestGRPC_DeleteByType_PolicyUpsertOnly(t *testing.T) {
+	t.Parallel()
+	client, store := newTestGRPCClient(t, "grpc-secret", WithSyncPolicy(PolicyUpsertOnly))
+	if err := store.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+
+	_, err := client.DeleteByType(authCtx("grpc-secret"), &pb.DeleteByTypeRequest{Name: "app.example.org.", Type: "A"})
+	if s, ok := status.FromError(err); !ok || s.Code() != codes.PermissionDenied {
+		t.Errorf("error = %v, want PermissionDenied", err)
+	}
+	if n := len(store.List(t.Context())); n != 1 {
+		t.Errorf("store holds %d records, want 1", n)
+	}
+}
+
+func TestGRPC_DeleteBySuffix(t *testing.T) {
+	t.Parallel()
+	client, store := newTestGRPCClient(t, "grpc-secret")
+	for _, r := range []Record{
+		{Name: "old.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"},
+		{Name: "a.old.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"},
+		{Name: "a.old.example.org.", Type: "TXT", TTL: 300, Value: "decommission me"},
+		{Name: "x.y.old.example.org.", Type: "AAAA", TTL: 300, Value: "2001:db8::1"},
+		{Name: "bold.example.org.", Type: "A", TTL: 300, Value: "10.0.0.3"},
+		{Name: "new.example.org.", Type: "A", TTL: 300, Value: "10.0.0.4"},
+	} {
+		if err := store.Upsert(t.Context(), r); err != nil {
+			t.Fatalf("Upsert() error: %v", err)
+		}
+	}
+	ctx := authCtx("grpc-secret")
+
+	resp, err := client.DeleteBySuffix(ctx, &pb.DeleteBySuffixRequest{Suffix: "OLD.example.org."})
+	if err != nil {
+		t.Fatalf("DeleteBySuffix() error: %v", err)
+	}
+	if resp.Deleted != 4 {
+		t.Errorf("deleted = %d, want 4", resp.Deleted)
+	}
+
+	var names []string
+	for _, r := range store.List(t.Context()) {
+		names = append(names, r.Name)
+	}
+	slices.Sort(names)
+	if want := []string{"bold.example.org.", "new.example.org."}; !slices.Equal(names, want) {
+		t.Errorf("remaining names = %v, want %v", names, want)
+	}
+
+	_, err = client.DeleteBySuffix(ctx, &pb.DeleteBySuffixRequest{Suffix: "."})
+	if s, ok := status.FromError(err); !ok || s.Code() != codes.InvalidArgument {
+		t.Errorf("root suffix: error = %v, want InvalidArgument", err)
+	}
+}
+
+func TestGRPC_DeleteBySuffix_PolicyUpsertOnly(t *testing.T) {
+	t.Parallel()
+	client, store := newTestGRPCClient(t, "grpc-secret", WithSyncPolicy(PolicyUpsertOnly))
+	if err := store.Upsert(t.Context(), Record{Name: "a.old.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+
+	_, err := client.DeleteBySuffix(authCtx("grpc-secret"), &pb.DeleteBySuffixRequest{Suffix: "old.example.org."})
+	if s, ok := status.FromError(err); !ok || s.Code() != codes.PermissionDenied {
+		t.Errorf("error = %v, want PermissionDenied", err)
+	}
+	if n := len(store.List(t.Context())); n != 1 {
+		t.Errorf("store holds %d records, want 1", n)
+	}
+}
+
 func TestGRPC_Unauthenticated(t *testing.T) {
 	t.Parallel()
 	client, _ := newTestGRPCClient(t, "grpc-secret")
@@ -252,6 +557,26 @@ func TestGRPC_Upsert_PolicyUpdateOnly_ReturnsPermissionDenied(t *testing.T) {
 	}
 }
 
+func TestGRPC_Upsert_QuotaExceeded_ReturnsResourceExhausted(t *testing.T) {
+	t.Parallel()
+	store, err := NewStore(filepath.Join(t.TempDir(), "records.json"), 0,
+		WithQuotas(map[string]int{"tenant-a": 0}))
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	t.Cleanup(func() { store.Stop() })
+	svc := &grpcService{store: store}
+
+	req := &pb.UpsertRequest{Record: &pb.Record{Name: "a.example.org.", Type: "A", Ttl: 300, Value: "10.0.0.1"}}
+	_, err = svc.Upsert(ContextWithOwner(t.Context(), Owner{Name: "tenant-a"}), req)
+	if s, ok := status.FromError(err); !ok || s.Code() != codes.ResourceExhausted {
+		t.Errorf("tenant-a: err = %v, want ResourceExhausted", err)
+	}
+	if _, err := svc.Upsert(ContextWithOwner(t.Context(), Owner{Name: "tenant-b"}), req); err != nil {
+		t.Errorf("tenant-b: unexpected error: %v", err)
+	}
+}
+
 func TestGRPC_Delete_PolicyUpsertOnly_ReturnsPermissionDenied(t *testing.T) {
 	t.Parallel()
 	client, store := newTestGRPCClient(t, "grpc-secret", WithSyncPolicy(PolicyUpsertOnly))
@@ -276,3 +601,295 @@ func TestGRPC_Delete_PolicyUpsertOnly_ReturnsPermissionDenied(t *testing.T) {
 		t.Errorf("code = %v, want PermissionDenied", s.Code())
 	}
 }
+
+func TestGRPC_Import_Stream(t *testing.T) {
+	t.Parallel()
+	client, store := newTestGRPCClient(t, "grpc-secret")
+	if err := store.Upsert(t.Context(), Record{Name: "h0000.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("seed Upsert() error: %v", err)
+	}
+	gen := store.Generation()
+
+	const n = 5000
+	stream, err := client.Import(authCtx("grpc-secret"))
+	if err != nil {
+		t.Fatalf("Import() error: %v", err)
+	}
+	for i := range n {
+		rec := &pb.Record{Name: fmt.Sprintf("h%04d.example.org.", i), Type: "A", Ttl: 300, Value: "10.0.0.1"}
+		if err := stream.Send(&pb.ImportRequest{Record: rec}); err != nil {
+			t.Fatalf("Send(%d) error: %v", i, err)
+		}
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatalf("CloseAndRecv() error: %v", err)
+	}
+	if resp.Created != n-1 || resp.Updated != 1 {
+		t.Errorf("created/updated = %d/%d, want %d/1", resp.Created, resp.Updated, n-1)
+	}
+
+	for i := range n {
+		name := fmt.Sprintf("h%04d.example.org.", i)
+		if recs, _ := store.Lookup(name); len(recs) != 1 {
+			t.Fatalf("%s: %d records after import, want 1", name, len(recs))
+		}
+	}
+	// The whole stream is applied as a single mutation.
+	if got := store.Generation(); got != gen+1 {
+		t.Errorf("generation = %d, want %d", got, gen+1)
+	}
+}
+
+func TestGRPC_Import_RejectsInvalidAtomically(t *testing.T) {
+	t.Parallel()
+	client, store := newTestGRPCClient(t, "grpc-secret")
+
+	stream, err := client.Import(authCtx("grpc-secret"))
+	if err != nil {
+		t.Fatalf("Import() error: %v", err)
+	}
+	records := []*pb.Record{
+		{Name: "ok.example.org.", Type: "A", Ttl: 300, Value: "10.0.0.1"},
+		{Name: "bad.example.org.", Type: "A", Ttl: 300, Value: "not-an-ip"},
+	}
+	for _, rec := range records {
+		// The server may abort the stream as soon as it sees the bad record.
+		if err := stream.Send(&pb.ImportRequest{Record: rec}); err != nil {
+			break
+		}
+	}
+	_, err = stream.CloseAndRecv()
+	if s, ok := status.FromError(err); !ok || s.Code() != codes.InvalidArgument {
+		t.Fatalf("CloseAndRecv() err = %v, want InvalidArgument", err)
+	}
+	if got := store.List(t.Context()); len(got) != 0 {
+		t.Errorf("store holds %d records after rejected import, want 0", len(got))
+	}
+}
+
+func TestGRPC_Import_TooLarge(t *testing.T) {
+	t.Parallel()
+	client, store := newTestGRPCClient(t, "grpc-secret")
+
+	stream, err := client.Import(authCtx("grpc-secret"))
+	if err != nil {
+		t.Fatalf("Import() error: %v", err)
+	}
+	value := strings.Repeat("x", 250)
+	for i, sent := 0, 0; sent <= maxBatchBytes; i++ {
+		req := &pb.ImportRequest{Record: &pb.Record{Name: fmt.Sprintf("h%06d.example.org.", i), Type: "TXT", Ttl: 300, Value: value}}
+		// The server may abort the stream as soon as the cap is passed.
+		if err := stream.Send(req); err != nil {
+			break
+		}
+		sent += proto.Size(req)
+	}
+	_, err = stream.CloseAndRecv()
+	if s, ok := status.FromError(err); !ok || s.Code() != codes.ResourceExhausted {
+		t.Fatalf("CloseAndRecv() err = %v, want ResourceExhausted", err)
+	}
+	if got := store.List(t.Context()); len(got) != 0 {
+		t.Errorf("store holds %d records after rejected import, want 0", len(got))
+	}
+}
+
+func TestGRPC_Import_Unauthenticated(t *testing.T) {
+	t.Parallel()
+	client, _ := newTestGRPCClient(t, "grpc-secret")
+
+	stream, err := client.Import(context.Background())
+	if err != nil {
+		t.Fatalf("Import() error: %v", err)
+	}
+	_, err = stream.CloseAndRecv()
+	if s, ok := status.FromError(err); !ok || s.Code() != codes.Unauthenticated {
+		t.Errorf("err = %v, want Unauthenticated", err)
+	}
+}
+
+func TestGRPC_Import_OnDuplicate(t *testing.T) {
+	t.Parallel()
+	client, store := newTestGRPCClient(t, "grpc-secret")
+	if err := store.Upsert(t.Context(), Record{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("seed Upsert() error: %v", err)
+	}
+
+	send := func(mode pb.OnDuplicate) (*pb.ImportResponse, error) {
+		t.Helper()
+		stream, err := client.Import(authCtx("grpc-secret"))
+		if err != nil {
+			t.Fatalf("Import() error: %v", err)
+		}
+		for i, value := range []string{"10.0.0.1", "10.0.0.2"} {
+			req := &pb.ImportRequest{Record: &pb.Record{Name: "a.example.org.", Type: "A", Ttl: 600, Value: value}}
+			if i == 0 {
+				req.OnDuplicate = mode
+			}
+			if err := stream.Send(req); err != nil {
+				break
+			}
+		}
+		return stream.CloseAndRecv()
+	}
+
+	_, err := send(pb.OnDuplicate_ON_DUPLICATE_ERROR)
+	if s, ok := status.FromError(err); !ok || s.Code() != codes.AlreadyExists {
+		t.Fatalf("error mode: err = %v, want AlreadyExists", err)
+	}
+
+	resp, err := send(pb.OnDuplicate_ON_DUPLICATE_SKIP)
+	if err != nil {
+		t.Fatalf("skip mode: error: %v", err)
+	}
+	if resp.Created != 1 || resp.Skipped != 1 || resp.Updated != 0 {
+		t.Errorf("skip mode: created/updated/skipped = %d/%d/%d, want 1/0/1", resp.Created, resp.Updated, resp.Skipped)
+	}
+	if got := store.Get(t.Context(), "a.example.org.", "A"); got[0].TTL != 300 && got[1].TTL != 300 {
+		t.Errorf("skip mode overwrote the existing record: %v", got)
+	}
+}
+
+func TestGRPC_Watch(t *testing.T) {
+	t.Parallel()
+	client, store := newTestGRPCClient(t, "grpc-secret")
+
+	ctx, cancel := context.WithCancel(authCtx("grpc-secret"))
+	defer cancel()
+	stream, err := client.Watch(ctx, &pb.WatchRequest{})
+	if err != nil {
+		t.Fatalf("Watch() error: %v", err)
+	}
+	// The server subscribes asynchronously; mutations before that are not streamed.
+	waitFor(t, store.watched)
+
+	rec := Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}
+	if err := store.Upsert(t.Context(), rec); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	rec.TTL = 600
+	if err := store.Upsert(t.Context(), rec); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	if err := store.Delete(t.Context(), rec.Name, rec.Type, rec.Value); err != nil {
+		t.Fatalf("Delete() error: %v", err)
+	}
+
+	want := []struct {
+		op  pb.ChangeOp
+		ttl uint32
+	}{
+		{pb.ChangeOp_CHANGE_OP_ADDED, 300},
+		{pb.ChangeOp_CHANGE_OP_UPDATED, 600},
+		{pb.ChangeOp_CHANGE_OP_DELETED, 600},
+	}
+	for i, w := range want {
+		ev, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Recv(%d) error: %v", i, err)
+		}
+		if ev.Op != w.op || ev.Record.GetName() != rec.Name || ev.Record.GetTtl() != w.ttl {
+			t.Errorf("event %d = %v %v, want %v with TTL %d", i, ev.Op, ev.Record, w.op, w.ttl)
+		}
+	}
+
+	// Disconnecting removes the subscription.
+	cancel()
+	waitFor(t, func() bool { return !store.watched() })
+}
+
+// waitFor polls cond until it holds or the test times out.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within 5s")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// newTestReflectionClient serves a GRPCServer's services over bufconn and
+// returns a reflection client for it.
+func newTestReflectionClient(t *testing.T, opts ...GRPCOption) reflectionpb.ServerReflectionClient {
+	t.Helper()
+	store, err := NewStore(filepath.Join(t.TempDir(), "records.json"), 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	t.Cleanup(store.Stop)
+
+	auth := &Auth{Token: "grpc-secret"}
+	g := NewGRPCServer(store, auth, "", nil, opts...)
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(auth.UnaryInterceptor),
+		grpc.StreamInterceptor(auth.StreamInterceptor),
+	)
+	g.register(srv)
+
+	lis := bufconn.Listen(bufSize)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough://bufnet",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+	)
+	if err != nil {
+		t.Fatalf("grpc.NewClient() error: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return reflectionpb.NewServerReflectionClient(conn)
+}
+
+// listServices asks the reflection service for the services it knows.
+func listServices(ctx context.Context, client reflectionpb.ServerReflectionClient) ([]string, error) {
+	stream, err := client.ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req := &reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}
+	if err := stream.Send(req); err != nil {
+		return nil, err
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, svc := range resp.GetListServicesResponse().GetService() {
+		names = append(names, svc.GetName())
+	}
+	return names, nil
+}
+
+func TestGRPC_Reflection(t *testing.T) {
+	t.Parallel()
+	client := newTestReflectionClient(t, WithReflection())
+
+	names, err := listServices(authCtx("grpc-secret"), client)
+	if err != nil {
+		t.Fatalf("ListServices error: %v", err)
+	}
+	if !slices.Contains(names, pb.DynUpdateService_ServiceDesc.ServiceName) {
+		t.Errorf("services = %v, want %s listed", names, pb.DynUpdateService_ServiceDesc.ServiceName)
+	}
+
+	if _, err := listServices(t.Context(), client); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("ListServices without token code = %v, want Unauthenticated", status.Code(err))
+	}
+}
+
+func TestGRPC_Reflection_DisabledByDefault(t *testing.T) {
+	t.Parallel()
+	client := newTestReflectionClient(t)
+
+	if _, err := listServices(authCtx("grpc-secret"), client); status.Code(err) != codes.Unimplemented {
+		t.Errorf("ListServices code = %v, want Unimplemented", status.Code(err))
+	}
+}
@@ -0,0 +1,55 @@
+// ABOUTME: Standard gRPC health service (grpc.health.v1) for the management server.
+// ABOUTME: Reports SERVING while the store is ready, NOT_SERVING while its backend fails or once it stops.
+
+package dynupdate
+
+import (
+	"strings"
+
+	pb "github.com/mauromedda/coredns-updater-plugin/proto"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// newHealthServer returns a health server tracking store until stop is
+// closed. The status is reported both server-wide (the empty service name)
+// and for DynUpdateService. It follows Store.Status: an instance whose
+// backend writes or polls fail, or whose last reload failed, reports
+// NOT_SERVING until the store recovers.
+func newHealthServer(store *Store, stop <-chan struct{}) *health.Server {
+	h := health.NewServer()
+	set := func(serving bool) {
+		st := healthpb.HealthCheckResponse_NOT_SERVING
+		if serving {
+			st = healthpb.HealthCheckResponse_SERVING
+		}
+		h.SetServingStatus("", st)
+		h.SetServingStatus(pb.DynUpdateService_ServiceDesc.ServiceName, st)
+	}
+	st, changed := store.status()
+	set(st.Ready)
+
+	go func() {
+		for {
+			select {
+			case <-changed:
+				st, changed = store.status()
+				set(st.Ready)
+			case <-store.stopCh:
+				set(false)
+				return
+			case <-stop:
+				// Shutdown reports NOT_SERVING and ignores later updates.
+				h.Shutdown()
+				return
+			}
+		}
+	}()
+	return h
+}
+
+// isHealthMethod reports whether method belongs to the gRPC health
+// service, which probes call without credentials.
+func isHealthMethod(method string) bool {
+	return strings.HasPrefix(method, "/"+healthpb.Health_ServiceDesc.ServiceName+"/")
+}
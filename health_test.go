@@ -0,0 +1,146 @@
+// ABOUTME: Tests for the gRPC health service over a bufconn connection.
+// ABOUTME: Covers unauthenticated checks, SERVING after load and NOT_SERVING during backend outages or once the store or server stops.
+
+package dynupdate
+
+import (
+	"context"
+	"math"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "github.com/mauromedda/coredns-updater-plugin/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestHealthClient serves the health and record services behind token
+// auth and returns clients for both, the store and the channel that stops
+// the health tracking.
+func newTestHealthClient(t *testing.T) (healthpb.HealthClient, pb.DynUpdateServiceClient, *Store, chan struct{}) {
+	t.Helper()
+	store, err := NewStore(filepath.Join(t.TempDir(), "records.json"), 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	t.Cleanup(store.Stop)
+
+	auth := &Auth{Token: "grpc-secret"}
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(auth.UnaryInterceptor),
+		grpc.StreamInterceptor(auth.StreamInterceptor),
+	)
+	stop := make(chan struct{})
+	pb.RegisterDynUpdateServiceServer(srv, &grpcService{store: store})
+	healthpb.RegisterHealthServer(srv, newHealthServer(store, stop))
+
+	lis := bufconn.Listen(bufSize)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough://bufnet",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+	)
+	if err != nil {
+		t.Fatalf("grpc.NewClient() error: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return healthpb.NewHealthClient(conn), pb.NewDynUpdateServiceClient(conn), store, stop
+}
+
+func checkHealth(t *testing.T, client healthpb.HealthClient, service string) healthpb.HealthCheckResponse_ServingStatus {
+	t.Helper()
+	// No credentials: the health service is exempt from authentication.
+	resp, err := client.Check(t.Context(), &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		t.Fatalf("Check(%q) error: %v", service, err)
+	}
+	return resp.Status
+}
+
+func TestHealth_ServingWithoutAuth(t *testing.T) {
+	t.Parallel()
+	client, records, _, _ := newTestHealthClient(t)
+
+	for _, service := range []string{"", pb.DynUpdateService_ServiceDesc.ServiceName} {
+		if got := checkHealth(t, client, service); got != healthpb.HealthCheckResponse_SERVING {
+			t.Errorf("Check(%q) = %v, want SERVING", service, got)
+		}
+	}
+
+	_, err := records.List(t.Context(), &pb.ListRequest{})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("List() without token code = %v, want Unauthenticated", status.Code(err))
+	}
+}
+
+func TestHealth_NotServingAfterStoreStop(t *testing.T) {
+	t.Parallel()
+	client, _, store, _ := newTestHealthClient(t)
+
+	store.Stop()
+	waitFor(t, func() bool {
+		return checkHealth(t, client, "") == healthpb.HealthCheckResponse_NOT_SERVING
+	})
+}
+
+func TestHealth_NotServingAfterServerStop(t *testing.T) {
+	t.Parallel()
+	client, _, _, stop := newTestHealthClient(t)
+
+	close(stop)
+	waitFor(t, func() bool {
+		return checkHealth(t, client, pb.DynUpdateService_ServiceDesc.ServiceName) == healthpb.HealthCheckResponse_NOT_SERVING
+	})
+}
+
+func TestHealth_FollowsBackendOutage(t *testing.T) {
+	t.Parallel()
+	fb := &flakyBackend{Backend: NewFileBackend(filepath.Join(t.TempDir(), "records.json"))}
+	store, err := NewStoreWithBackend(fb, 0, WithSweepInterval(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewStoreWithBackend() error: %v", err)
+	}
+	t.Cleanup(store.Stop)
+	h := newHealthServer(store, make(chan struct{}))
+
+	check := func() healthpb.HealthCheckResponse_ServingStatus {
+		resp, err := h.Check(t.Context(), &healthpb.HealthCheckRequest{Service: pb.DynUpdateService_ServiceDesc.ServiceName})
+		if err != nil {
+			t.Fatalf("Check() error: %v", err)
+		}
+		return resp.Status
+	}
+	if got := check(); got != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("Check() before outage = %v, want SERVING", got)
+	}
+
+	// Fail the write and every retry until the backend comes back.
+	fb.mu.Lock()
+	fb.failSaves = math.MaxInt
+	fb.mu.Unlock()
+	if err := store.Upsert(t.Context(), Record{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err == nil {
+		t.Fatal("Upsert() with failing backend: expected error")
+	}
+	waitFor(t, func() bool { return check() == healthpb.HealthCheckResponse_NOT_SERVING })
+
+	// Once the backend answers, the sweep's retry restores SERVING without
+	// another mutation.
+	fb.mu.Lock()
+	fb.failSaves = 0
+	fb.mu.Unlock()
+	waitFor(t, func() bool { return check() == healthpb.HealthCheckResponse_SERVING })
+	if store.Degraded() {
+		t.Error("Degraded() after recovery = true, want false")
+	}
+}
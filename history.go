@@ -0,0 +1,122 @@
+// ABOUTME: Bounded log of the records each generation added, updated or removed.
+// ABOUTME: Serves ChangedSince, deletions included, without scanning the whole store.
+
+package dynupdate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// ErrResyncRequired is returned by ChangedSince when the requested
+// generation is older than the change history the store keeps. The caller
+// must list every record again.
+var ErrResyncRequired = errors.New("generation older than the change history")
+
+// historyLimit bounds the entries the change history keeps. A client
+// further behind than that must resync with a full listing.
+const historyLimit = 100_000
+
+// historyEntry records that generation gen added, updated or removed the
+// record with key.
+type historyEntry struct {
+	gen   uint64
+	key   RecordKey
+	owner string // owner of the record when the entry was written
+}
+
+// history is the change log behind ChangedSince, in generation order.
+type history struct {
+	entries []historyEntry
+	floor   uint64 // oldest generation ChangedSince can answer for
+	limit   int
+}
+
+// add records that generation gen changed r.
+func (h *history) add(gen uint64, r Record) {
+	h.entries = append(h.entries, historyEntry{gen: gen, key: r.Key(), owner: r.Owner})
+	// Trim to the limit once twice over, so appends stay amortised O(1).
+	if h.limit > 0 && len(h.entries) >= 2*h.limit {
+		drop := len(h.entries) - h.limit
+		h.floor = h.entries[drop-1].gen
+		h.entries = slices.Clone(h.entries[drop:])
+	}
+}
+
+// reset forgets every entry. Only changes after gen can be reported.
+func (h *history) reset(gen uint64) {
+	h.entries = nil
+	h.floor = gen
+}
+
+// truncate drops the entries of generation gen and later, which were
+// rolled back.
+func (h *history) truncate(gen uint64) {
+	i := sort.Search(len(h.entries), func(i int) bool { return h.entries[i].gen >= gen })
+	h.entries = h.entries[:i]
+}
+
+// since returns the entries after generation gen, or false if entries
+// after gen have been dropped.
+func (h *history) since(gen uint64) ([]historyEntry, bool) {
+	if gen < h.floor {
+		return nil, false
+	}
+	i := sort.Search(len(h.entries), func(i int) bool { return h.entries[i].gen > gen })
+	return h.entries[i:], true
+}
+
+// Changes is what changed after a generation, as reported by ChangedSince.
+type Changes struct {
+	Records    []Record    // live records added or updated, in canonical order
+	Deleted    []RecordKey // records removed or expired, in canonical order
+	Generation uint64      // current generation, to pass as gen next time
+}
+
+// ChangedSince returns the records added, updated and removed after
+// generation gen, as far as they are visible to the owner in ctx. It costs
+// time proportional to the number of changes, not the size of the store.
+// If gen is older than the change history, which starts afresh on every
+// start, it fails with ErrResyncRequired.
+func (s *Store) ChangedSince(ctx context.Context, gen uint64) (Changes, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries, ok := s.history.since(gen)
+	if !ok {
+		return Changes{}, fmt.Errorf("generation %d: history starts at %d: %w", gen, s.history.floor, ErrResyncRequired)
+	}
+
+	owner, scoped := scopedOwner(ctx)
+	now := s.now()
+	out := Changes{Generation: s.generation}
+	seen := make(map[RecordKey]struct{}, len(entries))
+	// The latest entry for a key decides how it is reported.
+	for _, e := range slices.Backward(entries) {
+		if _, ok := seen[e.key]; ok {
+			continue
+		}
+		seen[e.key] = struct{}{}
+		if r, ok := s.findLocked(e.key); ok && !r.Expired(now) && (!scoped || r.Owner == owner) {
+			out.Records = append(out.Records, r)
+		} else if !scoped || e.owner == owner {
+			out.Deleted = append(out.Deleted, e.key)
+		}
+	}
+	sortRecords(out.Records)
+	slices.SortFunc(out.Deleted, RecordKey.Compare)
+	return out, nil
+}
+
+// findLocked returns the record with key. Caller must hold RLock.
+func (s *Store) findLocked(key RecordKey) (Record, bool) {
+	for _, r := range s.records[key.Name] {
+		if r.Key() == key {
+			return r, true
+		}
+	}
+	return Record{}, false
+}
@@ -0,0 +1,179 @@
+// ABOUTME: Tests for the change history behind ChangedSince.
+// ABOUTME: Covers adds, updates and deletions, rollbacks, owner scoping, trimming and restarts.
+
+package dynupdate
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// changedValues renders records as "name value" for comparison.
+func changedValues(recs []Record) []string {
+	var out []string
+	for _, r := range recs {
+		out = append(out, r.Name+" "+r.Value)
+	}
+	return out
+}
+
+func TestStore_ChangedSince(t *testing.T) {
+	t.Parallel()
+	fp := filepath.Join(t.TempDir(), "records.json")
+	s, err := NewStore(fp, 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+
+	upsert := func(r Record) {
+		t.Helper()
+		if err := s.Upsert(t.Context(), r); err != nil {
+			t.Fatalf("Upsert() error: %v", err)
+		}
+	}
+
+	upsert(Record{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
+	upsert(Record{Name: "b.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"})
+	upsert(Record{Name: "c.example.org.", Type: "A", TTL: 300, Value: "10.0.0.3"})
+	since := s.Generation()
+
+	upsert(Record{Name: "a.example.org.", Type: "A", TTL: 600, Value: "10.0.0.1"})
+	upsert(Record{Name: "d.example.org.", Type: "A", TTL: 300, Value: "10.0.0.4"})
+	if err := s.Delete(t.Context(), "b.example.org.", "A", "10.0.0.2"); err != nil {
+		t.Fatalf("Delete() error: %v", err)
+	}
+	// A record added and removed again is reported as removed.
+	upsert(Record{Name: "e.example.org.", Type: "A", TTL: 300, Value: "10.0.0.5"})
+	if err := s.Delete(t.Context(), "e.example.org.", "A", "10.0.0.5"); err != nil {
+		t.Fatalf("Delete() error: %v", err)
+	}
+	// A failed transaction changes nothing.
+	_ = s.Transaction(t.Context(), func(tx *Tx) error {
+		if err := tx.Upsert(Record{Name: "c.example.org.", Type: "A", TTL: 900, Value: "10.0.0.3"}); err != nil {
+			return err
+		}
+		return errors.New("abort")
+	})
+
+	got, err := s.ChangedSince(t.Context(), since)
+	if err != nil {
+		t.Fatalf("ChangedSince(%d) error: %v", since, err)
+	}
+	want := []string{"a.example.org. 10.0.0.1", "d.example.org. 10.0.0.4"}
+	if !slices.Equal(changedValues(got.Records), want) {
+		t.Errorf("ChangedSince(%d) = %v, want %v", since, changedValues(got.Records), want)
+	}
+	wantDeleted := []RecordKey{
+		{Name: "b.example.org.", Type: "A", Value: "10.0.0.2"},
+		{Name: "e.example.org.", Type: "A", Value: "10.0.0.5"},
+	}
+	if !slices.Equal(got.Deleted, wantDeleted) {
+		t.Errorf("ChangedSince(%d) deleted = %v, want %v", since, got.Deleted, wantDeleted)
+	}
+	if got.Generation != s.Generation() {
+		t.Errorf("ChangedSince() generation = %d, want %d", got.Generation, s.Generation())
+	}
+	gen := got.Generation
+	if got, err := s.ChangedSince(t.Context(), gen); err != nil || len(got.Records)+len(got.Deleted) != 0 {
+		t.Errorf("ChangedSince(current) = %+v, %v, want nothing", got, err)
+	}
+	s.Stop()
+
+	// The history starts afresh on a restart: older generations must resync.
+	s2, err := NewStore(fp, 0)
+	if err != nil {
+		t.Fatalf("NewStore() reopen error: %v", err)
+	}
+	defer s2.Stop()
+	if got, err := s2.ChangedSince(t.Context(), gen); err != nil || len(got.Records)+len(got.Deleted) != 0 {
+		t.Errorf("after restart ChangedSince(%d) = %+v, %v, want nothing", gen, got, err)
+	}
+	if _, err := s2.ChangedSince(t.Context(), since); !errors.Is(err, ErrResyncRequired) {
+		t.Errorf("after restart ChangedSince(%d) error = %v, want ErrResyncRequired", since, err)
+	}
+}
+
+func TestStore_ChangedSince_Trimmed(t *testing.T) {
+	t.Parallel()
+	s := newTestStore(t, filepath.Join(t.TempDir(), "records.json"))
+	s.mu.Lock()
+	s.history.limit = 2
+	s.mu.Unlock()
+
+	start := s.Generation()
+	for _, v := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"} {
+		if err := s.Upsert(t.Context(), Record{Name: "a.example.org.", Type: "A", TTL: 300, Value: v}); err != nil {
+			t.Fatalf("Upsert() error: %v", err)
+		}
+	}
+	if _, err := s.ChangedSince(t.Context(), start); !errors.Is(err, ErrResyncRequired) {
+		t.Errorf("ChangedSince(%d) past the history error = %v, want ErrResyncRequired", start, err)
+	}
+	got, err := s.ChangedSince(t.Context(), s.Generation()-1)
+	if err != nil {
+		t.Fatalf("ChangedSince() within the history error: %v", err)
+	}
+	if want := []string{"a.example.org. 10.0.0.4"}; !slices.Equal(changedValues(got.Records), want) {
+		t.Errorf("ChangedSince() = %v, want %v", changedValues(got.Records), want)
+	}
+}
+
+func TestStore_ChangedSince_Owner(t *testing.T) {
+	t.Parallel()
+	s := newTestStore(t, filepath.Join(t.TempDir(), "records.json"))
+	ctxA := ContextWithOwner(t.Context(), Owner{Name: "tenant-a"})
+	ctxB := ContextWithOwner(t.Context(), Owner{Name: "tenant-b"})
+
+	since := s.Generation()
+	if err := s.Upsert(ctxA, Record{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	if err := s.Upsert(ctxB, Record{Name: "b.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	if err := s.Delete(ctxB, "b.example.org.", "A", "10.0.0.2"); err != nil {
+		t.Fatalf("Delete() error: %v", err)
+	}
+
+	got, err := s.ChangedSince(ctxA, since)
+	if err != nil {
+		t.Fatalf("ChangedSince() error: %v", err)
+	}
+	if want := []string{"a.example.org. 10.0.0.1"}; !slices.Equal(changedValues(got.Records), want) || len(got.Deleted) != 0 {
+		t.Errorf("tenant-a sees %v, deleted %v, want only its own record", changedValues(got.Records), got.Deleted)
+	}
+	got, err = s.ChangedSince(ctxB, since)
+	if err != nil {
+		t.Fatalf("ChangedSince() error: %v", err)
+	}
+	if len(got.Records) != 0 || len(got.Deleted) != 1 || got.Deleted[0].Name != "b.example.org." {
+		t.Errorf("tenant-b sees %v, deleted %v, want only its own deletion", changedValues(got.Records), got.Deleted)
+	}
+}
+
+func TestStore_ChangedSince_FailedWrite(t *testing.T) {
+	t.Parallel()
+	fb := &flakyBackend{Backend: NewFileBackend(filepath.Join(t.TempDir(), "records.json"))}
+	s, err := NewStoreWithBackend(fb, 0)
+	if err != nil {
+		t.Fatalf("NewStoreWithBackend() error: %v", err)
+	}
+	t.Cleanup(s.Stop)
+
+	since := s.Generation()
+	fb.mu.Lock()
+	fb.failSaves = 1
+	fb.mu.Unlock()
+	if err := s.Upsert(t.Context(), Record{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err == nil {
+		t.Fatal("Upsert() with failing backend: expected error")
+	}
+	got, err := s.ChangedSince(t.Context(), since)
+	if err != nil {
+		t.Fatalf("ChangedSince() error: %v", err)
+	}
+	if len(got.Records)+len(got.Deleted) != 0 {
+		t.Errorf("rolled-back write reported: %v, deleted %v", changedValues(got.Records), got.Deleted)
+	}
+}
@@ -0,0 +1,215 @@
+// ABOUTME: Bulk import of records into the Store as a single atomic mutation.
+// ABOUTME: Handles duplicates via a DuplicatePolicy and loads BIND-style zone files.
+
+package dynupdate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// ErrDuplicateRecord is returned by an import with DuplicateError when a
+// record's name, type and value are already present.
+var ErrDuplicateRecord = errors.New("duplicate record")
+
+// ErrInvalidZone is returned by ImportZone when the zone file cannot be
+// parsed or contains a record that fails validation.
+var ErrInvalidZone = errors.New("invalid zone file")
+
+// DuplicatePolicy controls what an import does with a record whose name,
+// type and value match one already in the store or earlier in the import.
+type DuplicatePolicy uint8
+
+const (
+	// DuplicateOverwrite replaces the existing record (default zero-value).
+	DuplicateOverwrite DuplicatePolicy = iota
+	// DuplicateSkip keeps the existing record and ignores the imported one.
+	DuplicateSkip
+	// DuplicateError aborts the whole import.
+	DuplicateError
+)
+
+// ParseDuplicatePolicy parses "overwrite", "skip" or "error".
+func ParseDuplicatePolicy(s string) (DuplicatePolicy, error) {
+	switch strings.ToLower(s) {
+	case "overwrite", "":
+		return DuplicateOverwrite, nil
+	case "skip":
+		return DuplicateSkip, nil
+	case "error":
+		return DuplicateError, nil
+	default:
+		return 0, fmt.Errorf("unknown on_duplicate mode %q: valid values are skip, error, overwrite", s)
+	}
+}
+
+// String returns the canonical string representation of the policy.
+func (p DuplicatePolicy) String() string {
+	switch p {
+	case DuplicateOverwrite:
+		return "overwrite"
+	case DuplicateSkip:
+		return "skip"
+	case DuplicateError:
+		return "error"
+	default:
+		return fmt.Sprintf("DuplicatePolicy(%d)", p)
+	}
+}
+
+// ImportOptions configures an Import.
+type ImportOptions struct {
+	OnDuplicate DuplicatePolicy
+}
+
+// ImportOutcome is what an Import did with one input record.
+type ImportOutcome uint8
+
+const (
+	// OutcomeCreated means the record was new.
+	OutcomeCreated ImportOutcome = iota
+	// OutcomeUpdated means an existing record was overwritten.
+	OutcomeUpdated
+	// OutcomeSkipped means a duplicate was left untouched.
+	OutcomeSkipped
+)
+
+// ImportResult summarises an Import.
+type ImportResult struct {
+	Created int // new records
+	Updated int // duplicates overwritten
+	Skipped int // duplicates left untouched
+	// Outcomes holds one entry per input record, in input order.
+	Outcomes []ImportOutcome
+}
+
+// RecordError reports which record of a multi-record mutation was rejected.
+// The whole mutation is rolled back when it is returned.
+type RecordError struct {
+	Index int // position of the rejected record in the input
+	Err   error
+}
+
+func (e *RecordError) Error() string { return fmt.Sprintf("record %d: %v", e.Index, e.Err) }
+
+func (e *RecordError) Unwrap() error { return e.Err }
+
+// Import upserts every record in recs as one atomic change: either all of
+// them are applied and persisted in a single backend write, or, if any is
+// rejected (policy, ownership, limits, or a duplicate under DuplicateError),
+// none are and a *RecordError for the first rejected record is returned.
+func (s *Store) Import(ctx context.Context, recs []Record, opts ImportOptions) (ImportResult, error) {
+	var res ImportResult
+	if len(recs) == 0 {
+		return res, nil
+	}
+	res.Outcomes = make([]ImportOutcome, 0, len(recs))
+	err := s.commit(ctx, func() (Change, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		now := s.now()
+		undo := make(undoLog)
+		for i, r := range recs {
+			if s.duplicateLocked(ctx, r, now) {
+				switch opts.OnDuplicate {
+				case DuplicateSkip:
+					res.Skipped++
+					res.Outcomes = append(res.Outcomes, OutcomeSkipped)
+					continue
+				case DuplicateError:
+					s.rollbackLocked(undo)
+					res = ImportResult{}
+					return Change{}, &RecordError{Index: i, Err: fmt.Errorf("%s %s %s: %w", r.Name, r.Type, r.Value, ErrDuplicateRecord)}
+				}
+			}
+
+			undo.save(s, strings.ToLower(r.Name))
+			created, err := s.upsertLocked(ctx, r)
+			if err != nil {
+				s.rollbackLocked(undo)
+				res = ImportResult{}
+				return Change{}, &RecordError{Index: i, Err: err}
+			}
+			if created {
+				res.Created++
+				res.Outcomes = append(res.Outcomes, OutcomeCreated)
+			} else {
+				res.Updated++
+				res.Outcomes = append(res.Outcomes, OutcomeUpdated)
+			}
+		}
+		if len(undo) == 0 {
+			return Change{}, nil
+		}
+		return s.changeLocked(undo.keys()...), nil
+	})
+	return res, err
+}
+
+// duplicateLocked reports whether a live record with r's name, type and
+// value is visible to the caller in ctx. Records of other owners are not
+// reported, so the upsert itself rejects them with ErrNotOwner.
+// Caller must hold at least RLock.
+func (s *Store) duplicateLocked(ctx context.Context, r Record, now time.Time) bool {
+	owner, scoped := scopedOwner(ctx)
+	for _, existing := range s.records[strings.ToLower(r.Name)] {
+		if strings.EqualFold(existing.Type, r.Type) && existing.Value == r.Value &&
+			!existing.Expired(now) && (!scoped || existing.Owner == owner) {
+			return true
+		}
+	}
+	return false
+}
+
+// ZoneImportResult summarises an ImportZone.
+type ZoneImportResult struct {
+	Imported int `json:"imported"`
+	Skipped  int `json:"skipped"`
+	// Unsupported lists, in presentation format, every RR that was skipped
+	// because its type is not managed by this plugin.
+	Unsupported []string `json:"unsupported"`
+}
+
+// ImportZone reads a BIND-style zone file for zone from r and upserts every
+// record of a supported type as one atomic Import. RRs of other types (SOA,
+// SSHFP, ...) are skipped and listed in the result. Parse errors, invalid
+// records and names outside zone are reported as ErrInvalidZone before the
+// store is touched.
+func (s *Store) ImportZone(ctx context.Context, zone string, r io.Reader) (ZoneImportResult, error) {
+	origin := dns.Fqdn(zone)
+	res := ZoneImportResult{Unsupported: []string{}}
+
+	var recs []Record
+	zp := dns.NewZoneParser(r, origin, "")
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		if !dns.IsSubDomain(origin, rr.Header().Name) {
+			return ZoneImportResult{}, fmt.Errorf("%w: %s is outside zone %s", ErrInvalidZone, rr.Header().Name, origin)
+		}
+		rec, supported := recordFromRR(rr)
+		if !supported {
+			res.Skipped++
+			res.Unsupported = append(res.Unsupported, rr.String())
+			continue
+		}
+		if err := s.validateRecord(&rec); err != nil {
+			return ZoneImportResult{}, fmt.Errorf("%w: %s: %w", ErrInvalidZone, rr, err)
+		}
+		recs = append(recs, rec)
+	}
+	if err := zp.Err(); err != nil {
+		return ZoneImportResult{}, fmt.Errorf("%w: %w", ErrInvalidZone, err)
+	}
+
+	if _, err := s.Import(ctx, recs, ImportOptions{}); err != nil {
+		return ZoneImportResult{}, err
+	}
+	res.Imported = len(recs)
+	return res, nil
+}
@@ -0,0 +1,217 @@
+// ABOUTME: Tests for atomic bulk import and its duplicate-handling modes.
+// ABOUTME: Covers on_duplicate modes and loading BIND-style zone files.
+
+package dynupdate
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const testZoneFile = `$ORIGIN example.org.
+$TTL 300
+@       IN SOA   ns1 hostmaster 1 7200 3600 1209600 300
+@       IN MX    10 mail
+mail    IN A     10.0.0.25
+www     IN A     10.0.0.80
+        IN SSHFP 1 1 123456789abcdef67890123456789abcdef67890
+`
+
+func TestStore_Import_OnDuplicate(t *testing.T) {
+	t.Parallel()
+
+	input := []Record{
+		{Name: "a.example.org.", Type: "A", TTL: 600, Value: "10.0.0.1"}, // conflicts with the stored record
+		{Name: "b.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"},
+		{Name: "b.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"}, // exact duplicate within the input
+		{Name: "c.example.org.", Type: "A", TTL: 300, Value: "10.0.0.3"},
+	}
+
+	tests := []struct {
+		mode    DuplicatePolicy
+		want    ImportResult
+		wantErr error
+		wantTTL uint32 // TTL of a.example.org. afterwards
+		wantLen int    // records in the store afterwards
+	}{
+		{mode: DuplicateOverwrite, want: ImportResult{Created: 2, Updated: 2}, wantTTL: 600, wantLen: 3},
+		{mode: DuplicateSkip, want: ImportResult{Created: 2, Skipped: 2}, wantTTL: 300, wantLen: 3},
+		{mode: DuplicateError, wantErr: ErrDuplicateRecord, wantTTL: 300, wantLen: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode.String(), func(t *testing.T) {
+			t.Parallel()
+			s, err := NewStore(filepath.Join(t.TempDir(), "records.json"), 0)
+			if err != nil {
+				t.Fatalf("NewStore() error: %v", err)
+			}
+			defer s.Stop()
+			if err := s.Upsert(t.Context(), Record{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err != nil {
+				t.Fatalf("Upsert() error: %v", err)
+			}
+
+			got, err := s.Import(t.Context(), input, ImportOptions{OnDuplicate: tt.mode})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Import() error = %v, want %v", err, tt.wantErr)
+			}
+			if got.Created != tt.want.Created || got.Updated != tt.want.Updated || got.Skipped != tt.want.Skipped {
+				t.Errorf("Import() = %+v, want %+v", got, tt.want)
+			}
+			if recs := s.Get(t.Context(), "a.example.org.", "A"); len(recs) != 1 || recs[0].TTL != tt.wantTTL {
+				t.Errorf("a.example.org. = %v, want TTL %d", recs, tt.wantTTL)
+			}
+			if n := len(s.List(t.Context())); n != tt.wantLen {
+				t.Errorf("store holds %d records, want %d", n, tt.wantLen)
+			}
+		})
+	}
+}
+
+func TestStore_Import_Outcomes(t *testing.T) {
+	t.Parallel()
+	s, err := NewStore(filepath.Join(t.TempDir(), "records.json"), 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	defer s.Stop()
+	r := Record{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}
+	if err := s.Upsert(t.Context(), r); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+
+	in := []Record{
+		{Name: "b.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"},
+		r,
+		{Name: "c.example.org.", Type: "A", TTL: 300, Value: "10.0.0.3"},
+	}
+	res, err := s.Import(t.Context(), in, ImportOptions{OnDuplicate: DuplicateSkip})
+	if err != nil {
+		t.Fatalf("Import() error: %v", err)
+	}
+	want := []ImportOutcome{OutcomeCreated, OutcomeSkipped, OutcomeCreated}
+	if !slices.Equal(res.Outcomes, want) {
+		t.Errorf("Outcomes = %v, want %v", res.Outcomes, want)
+	}
+
+	_, err = s.Import(t.Context(), in, ImportOptions{OnDuplicate: DuplicateError})
+	var recErr *RecordError
+	if !errors.As(err, &recErr) || recErr.Index != 0 {
+		t.Errorf("Import() error = %v, want a RecordError for index 0", err)
+	}
+}
+
+func TestStore_Import_AllSkippedDoesNotPersist(t *testing.T) {
+	t.Parallel()
+	s, err := NewStore(filepath.Join(t.TempDir(), "records.json"), 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	defer s.Stop()
+	r := Record{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}
+	if err := s.Upsert(t.Context(), r); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	gen := s.Generation()
+
+	res, err := s.Import(t.Context(), []Record{r}, ImportOptions{OnDuplicate: DuplicateSkip})
+	if err != nil {
+		t.Fatalf("Import() error: %v", err)
+	}
+	if res.Skipped != 1 {
+		t.Errorf("Skipped = %d, want 1", res.Skipped)
+	}
+	if s.Generation() != gen {
+		t.Errorf("generation moved from %d to %d on a no-op import", gen, s.Generation())
+	}
+}
+
+func TestParseDuplicatePolicy(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		in      string
+		want    DuplicatePolicy
+		wantErr bool
+	}{
+		{in: "overwrite", want: DuplicateOverwrite},
+		{in: "", want: DuplicateOverwrite},
+		{in: "SKIP", want: DuplicateSkip},
+		{in: "error", want: DuplicateError},
+		{in: "merge", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseDuplicatePolicy(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseDuplicatePolicy(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got != tt.want {
+			t.Errorf("ParseDuplicatePolicy(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestStore_ImportZone(t *testing.T) {
+	t.Parallel()
+	s, err := NewStore(filepath.Join(t.TempDir(), "records.json"), 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	defer s.Stop()
+
+	res, err := s.ImportZone(t.Context(), "example.org", strings.NewReader(testZoneFile))
+	if err != nil {
+		t.Fatalf("ImportZone() error: %v", err)
+	}
+	if res.Imported != 3 || res.Skipped != 2 {
+		t.Errorf("imported/skipped = %d/%d, want 3/2", res.Imported, res.Skipped)
+	}
+	var types []string
+	for _, u := range res.Unsupported {
+		types = append(types, strings.Fields(u)[3])
+	}
+	if strings.Join(types, ",") != "SOA,SSHFP" {
+		t.Errorf("unsupported types = %v, want [SOA SSHFP]", types)
+	}
+
+	mx := s.Get(t.Context(), "example.org.", "MX")
+	if len(mx) != 1 || mx[0].Value != "mail.example.org." || mx[0].Priority != 10 || mx[0].TTL != 300 {
+		t.Errorf("MX = %+v, want mail.example.org. pref 10 TTL 300", mx)
+	}
+	if a := s.Get(t.Context(), "www.example.org.", "A"); len(a) != 1 || a[0].Value != "10.0.0.80" {
+		t.Errorf("www A = %+v, want 10.0.0.80", a)
+	}
+}
+
+func TestStore_ImportZone_Invalid(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		zone string
+	}{
+		{name: "syntax error", zone: "www 300 IN A not-an-ip\n"},
+		{name: "outside zone", zone: "www.example.com. 300 IN A 10.0.0.1\n"},
+		{name: "fails validation", zone: "www 10 IN A 10.0.0.1\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			s, err := NewStore(filepath.Join(t.TempDir(), "records.json"), 0)
+			if err != nil {
+				t.Fatalf("NewStore() error: %v", err)
+			}
+			defer s.Stop()
+
+			zone := "ok 300 IN A 10.0.0.2\n" + tt.zone
+			if _, err := s.ImportZone(t.Context(), "example.org.", strings.NewReader(zone)); !errors.Is(err, ErrInvalidZone) {
+				t.Fatalf("ImportZone() error = %v, want ErrInvalidZone", err)
+			}
+			if n := len(s.List(t.Context())); n != 0 {
+				t.Errorf("store holds %d records after a rejected import, want 0", n)
+			}
+		})
+	}
+}
@@ -148,7 +148,7 @@ func TestIntegration_ConcurrentAPIAndDNS(t *testing.T) {
 	}
 
 	// Seed an initial record
-	_ = store.Upsert(Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
+	_ = store.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
 
 	var wg sync.WaitGroup
 
@@ -1,5 +1,5 @@
 // ABOUTME: Prometheus metrics following the CoreDNS plugin convention.
-// ABOUTME: Tracks DNS requests, response rcodes, API requests, and store record counts.
+// ABOUTME: Tracks DNS requests, response rcodes, API requests, store record counts and update ages, dropped change events, backend health, persistence size and latency, and reloads.
 
 package dynupdate
 
@@ -23,6 +23,13 @@ var responseCount = promauto.NewCounterVec(prometheus.CounterOpts{
 	Help:      "Counter of DNS responses by rcode.",
 }, []string{"server", "rcode"})
 
+var unsupportedQTypeCount = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: plugin.Namespace,
+	Subsystem: "dynupdate",
+	Name:      "unsupported_qtype_count_total",
+	Help:      "Counter of DNS queries for record types the plugin does not manage.",
+}, []string{"server"})
+
 var apiRequestCount = promauto.NewCounterVec(prometheus.CounterOpts{
 	Namespace: plugin.Namespace,
 	Subsystem: "dynupdate",
@@ -30,9 +37,66 @@ var apiRequestCount = promauto.NewCounterVec(prometheus.CounterOpts{
 	Help:      "Counter of REST API requests.",
 }, []string{"method", "status"})
 
+var subscriberDroppedCount = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: plugin.Namespace,
+	Subsystem: "dynupdate",
+	Name:      "subscriber_dropped_events_total",
+	Help:      "Counter of change events dropped because a subscriber's buffer was full.",
+})
+
 var storeRecordGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
 	Namespace: plugin.Namespace,
 	Subsystem: "dynupdate",
 	Name:      "store_records",
 	Help:      "Current number of records in the store.",
 }, []string{"type"})
+
+var oldestUpdateAgeGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: plugin.Namespace,
+	Subsystem: "dynupdate",
+	Name:      "record_oldest_update_age_seconds",
+	Help:      "Seconds since the least recently updated record of each type was added or updated.",
+}, []string{"type"})
+
+var newestUpdateAgeGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: plugin.Namespace,
+	Subsystem: "dynupdate",
+	Name:      "record_newest_update_age_seconds",
+	Help:      "Seconds since the most recently updated record of each type was added or updated.",
+}, []string{"type"})
+
+var backendDegradedGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: plugin.Namespace,
+	Subsystem: "dynupdate",
+	Name:      "backend_degraded",
+	Help:      "1 while the backend is failing and records are served from memory only, 0 otherwise, per backend.",
+}, []string{"backend"})
+
+var storeBytesGauge = promauto.NewGauge(prometheus.GaugeOpts{
+	Namespace: plugin.Namespace,
+	Subsystem: "dynupdate",
+	Name:      "store_bytes",
+	Help:      "Size in bytes of the JSON store file as last written by the file backend.",
+})
+
+var persistDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+	Namespace: plugin.Namespace,
+	Subsystem: "dynupdate",
+	Name:      "persist_duration_seconds",
+	Help:      "Time taken by each backend write, successful or not.",
+	Buckets:   prometheus.DefBuckets,
+})
+
+var reloadCount = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: plugin.Namespace,
+	Subsystem: "dynupdate",
+	Name:      "reload_total",
+	Help:      "Counter of reloads of records changed in the backend by another writer, by result (success or error).",
+}, []string{"result"})
+
+var lastReloadTimestamp = promauto.NewGauge(prometheus.GaugeOpts{
+	Namespace: plugin.Namespace,
+	Subsystem: "dynupdate",
+	Name:      "last_reload_timestamp_seconds",
+	Help:      "Unix time of the last successful reload of records from the backend.",
+})
@@ -0,0 +1,74 @@
+// ABOUTME: Opt-in cache of names the handler answered NXDOMAIN for, to cheapen NXDOMAIN floods.
+// ABOUTME: Entries carry the store generation they were made at, so any write invalidates them.
+
+package dynupdate
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultNegativeCacheTTL bounds how long a cached NXDOMAIN is reused when
+// negative_cache gives no duration.
+const defaultNegativeCacheTTL = 10 * time.Second
+
+// negativeCache remembers names that do not exist. An entry only matches
+// while the store is still at the generation it was recorded at, so creating
+// a record anywhere (including a wildcard or a name below the cached one)
+// invalidates it. The TTL covers what changes without a write, such as a
+// serve_delay running out.
+type negativeCache struct {
+	mu      sync.Mutex
+	entries map[string]negativeEntry
+	size    int
+	ttl     time.Duration
+	now     func() time.Time
+}
+
+type negativeEntry struct {
+	generation uint64
+	expires    time.Time
+}
+
+// newNegativeCache returns a cache holding up to size names for ttl each.
+func newNegativeCache(size int, ttl time.Duration) *negativeCache {
+	return &negativeCache{
+		entries: make(map[string]negativeEntry, size),
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+	}
+}
+
+// has reports whether name was recorded as nonexistent at generation and
+// has not expired.
+func (c *negativeCache) has(name string, generation uint64) bool {
+	key := strings.ToLower(name)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return false
+	}
+	if e.generation != generation || !c.now().Before(e.expires) {
+		delete(c.entries, key)
+		return false
+	}
+	return true
+}
+
+// add records name as nonexistent at generation. When the cache is full an
+// arbitrary entry makes room.
+func (c *negativeCache) add(name string, generation uint64) {
+	key := strings.ToLower(name)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.size {
+		for k := range c.entries {
+			delete(c.entries, k)
+			break
+		}
+	}
+	c.entries[key] = negativeEntry{generation: generation, expires: c.now().Add(c.ttl)}
+}
@@ -0,0 +1,101 @@
+// ABOUTME: Tests for the negative-answer cache.
+// ABOUTME: Checks cached NXDOMAINs are dropped by any write, expire, and stay within the size bound.
+
+package dynupdate
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func TestServeDNS_NegativeCache_InvalidatedByCreate(t *testing.T) {
+	t.Parallel()
+	d := newTestHandler(t, []Record{
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"},
+	})
+	d.NegativeCache = newNegativeCache(100, time.Minute)
+
+	for range 2 {
+		if msg := queryDO(t, d, "new.example.org.", dns.TypeA, false); msg.Rcode != dns.RcodeNameError {
+			t.Fatalf("rcode = %d, want NXDOMAIN", msg.Rcode)
+		}
+	}
+	if !d.NegativeCache.has("NEW.example.org.", d.Store.Generation()) {
+		t.Fatal("NXDOMAIN for new.example.org. not cached")
+	}
+
+	if err := d.Store.Upsert(t.Context(), Record{Name: "new.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	msg := queryDO(t, d, "new.example.org.", dns.TypeA, false)
+	if msg.Rcode != dns.RcodeSuccess || len(msg.Answer) != 1 {
+		t.Errorf("after create: rcode %d, answer %v; want the new record", msg.Rcode, msg.Answer)
+	}
+}
+
+func TestServeDNS_NegativeCache_InvalidatedByOtherNames(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		add       Record
+		wantRcode int
+	}{
+		{"wildcard covering the name", Record{Name: "*.example.org.", Type: "A", TTL: 300, Value: "10.0.0.9"}, dns.RcodeSuccess},
+		{"record below the name", Record{Name: "x.gone.example.org.", Type: "A", TTL: 300, Value: "10.0.0.9"}, dns.RcodeSuccess},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			d := newTestHandler(t, nil)
+			d.NegativeCache = newNegativeCache(100, time.Minute)
+
+			if msg := queryDO(t, d, "gone.example.org.", dns.TypeA, false); msg.Rcode != dns.RcodeNameError {
+				t.Fatalf("rcode = %d, want NXDOMAIN", msg.Rcode)
+			}
+			if err := d.Store.Upsert(t.Context(), tt.add); err != nil {
+				t.Fatalf("Upsert() error: %v", err)
+			}
+			if msg := queryDO(t, d, "gone.example.org.", dns.TypeA, false); msg.Rcode != tt.wantRcode {
+				t.Errorf("rcode after adding %s = %d, want %d", tt.add.Name, msg.Rcode, tt.wantRcode)
+			}
+		})
+	}
+}
+
+func TestNegativeCache_Expiry(t *testing.T) {
+	t.Parallel()
+	c := newNegativeCache(10, time.Second)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	c.add("a.example.org.", 7)
+	if !c.has("a.example.org.", 7) {
+		t.Fatal("has() = false right after add")
+	}
+	if c.has("a.example.org.", 8) {
+		t.Error("has() = true at a later generation")
+	}
+
+	c.add("b.example.org.", 7)
+	now = now.Add(time.Second)
+	if c.has("b.example.org.", 7) {
+		t.Error("has() = true once the TTL has passed")
+	}
+}
+
+func TestNegativeCache_Size(t *testing.T) {
+	t.Parallel()
+	c := newNegativeCache(3, time.Minute)
+	for i := range 10 {
+		c.add(fmt.Sprintf("n%d.example.org.", i), 1)
+	}
+	if n := len(c.entries); n != 3 {
+		t.Errorf("cache holds %d entries, want 3", n)
+	}
+	if !c.has("n9.example.org.", 1) {
+		t.Error("latest entry missing")
+	}
+}
@@ -0,0 +1,197 @@
+// ABOUTME: Change notifications for Store mutations.
+// ABOUTME: Subscribers receive added, updated and deleted records once a mutation is applied.
+
+package dynupdate
+
+import (
+	"maps"
+	"sync"
+)
+
+// ChangeOp is the kind of change a ChangeEvent reports.
+type ChangeOp uint8
+
+const (
+	// OpAdded reports a record that did not exist before.
+	OpAdded ChangeOp = iota + 1
+	// OpUpdated reports a new version of an existing record.
+	OpUpdated
+	// OpDeleted reports a removed record, including expired records removed
+	// by the sweep.
+	OpDeleted
+)
+
+// String returns the name of the operation.
+func (op ChangeOp) String() string {
+	switch op {
+	case OpAdded:
+		return "added"
+	case OpUpdated:
+		return "updated"
+	case OpDeleted:
+		return "deleted"
+	default:
+		return "unknown"
+	}
+}
+
+// ChangeEvent describes one record changed by a store mutation. For
+// OpDeleted, Record is the record as it was before removal.
+type ChangeEvent struct {
+	Op     ChangeOp
+	Record Record
+}
+
+// subscriberBuffer is the number of events a subscriber may fall behind by.
+const subscriberBuffer = 1024
+
+// subscribers is the set of channels receiving change events.
+type subscribers struct {
+	mu    sync.Mutex
+	chans map[chan ChangeEvent]struct{}
+}
+
+// Subscribe returns a channel that receives an event for every record
+// changed by a mutation, a reload or a restore, in the order they are
+// applied, and a function that cancels the subscription and closes the
+// channel. Events of a failed or rolled-back mutation are never sent.
+//
+// Events are delivered without blocking: the channel buffers
+// subscriberBuffer events, and while it is full further events for this
+// subscriber are dropped and counted in the
+// coredns_dynupdate_subscriber_dropped_events_total metric. Mutations never
+// wait for a subscriber, so one that falls behind must re-read the store to
+// catch up.
+//
+// The unsubscribe function is safe to call more than once.
+func (s *Store) Subscribe() (<-chan ChangeEvent, func()) {
+	ch := make(chan ChangeEvent, subscriberBuffer)
+
+	s.subs.mu.Lock()
+	if s.subs.chans == nil {
+		s.subs.chans = make(map[chan ChangeEvent]struct{})
+	}
+	s.subs.chans[ch] = struct{}{}
+	s.subs.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			s.subs.mu.Lock()
+			delete(s.subs.chans, ch)
+			s.subs.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// watched reports whether anyone is subscribed, so mutations can skip
+// building events nobody reads.
+func (s *Store) watched() bool {
+	s.subs.mu.Lock()
+	defer s.subs.mu.Unlock()
+	return len(s.subs.chans) > 0
+}
+
+// emitLocked queues an event for the mutation being applied. The events are
+// sent by publishPending once the mutation succeeds. Caller must hold Lock
+// and persistMu.
+func (s *Store) emitLocked(op ChangeOp, r Record) {
+	if op != OpDeleted {
+		if s.touched == nil {
+			s.touched = make(map[RecordKey]struct{})
+		}
+		s.touched[r.Key()] = struct{}{}
+	} else {
+		s.removed = append(s.removed, r)
+	}
+	if s.audit != nil || s.watched() {
+		s.pending = append(s.pending, ChangeEvent{Op: op, Record: r})
+	}
+}
+
+// stampLocked sets ChangedAt to gen and UpdatedAt to the current time on
+// every record the mutation being applied added or updated, and adds them
+// and the records it removed to the change history. Caller must hold Lock
+// and persistMu.
+func (s *Store) stampLocked(gen uint64) {
+	now := s.now()
+	for key := range s.touched {
+		recs := s.records[key.Name]
+		for i := range recs {
+			if recs[i].Key() == key {
+				recs[i].ChangedAt = gen
+				recs[i].UpdatedAt = now
+				s.history.add(gen, recs[i])
+			}
+		}
+	}
+	for _, r := range s.removed {
+		s.history.add(gen, r)
+	}
+	s.touched = nil
+	s.removed = nil
+}
+
+// publishPending sends the queued events to every subscriber. A subscriber
+// whose buffer is full misses the event rather than holding up the store.
+// Caller must hold persistMu.
+func (s *Store) publishPending() {
+	events := s.pending
+	s.pending = nil
+	if len(events) == 0 {
+		return
+	}
+
+	s.subs.mu.Lock()
+	defer s.subs.mu.Unlock()
+	for ch := range s.subs.chans {
+		for _, ev := range events {
+			select {
+			case ch <- ev:
+			default:
+				subscriberDroppedCount.Inc()
+			}
+		}
+	}
+}
+
+// emitDiffLocked queues the events that turn the records in old into the
+// records currently held, for mutations that replace the whole set. The
+// diff is computed even without subscribers, since it also decides which
+// records get a new ChangedAt. Caller must hold Lock and persistMu.
+func (s *Store) emitDiffLocked(old map[string][]Record) {
+	before := make(map[RecordKey]Record)
+	for _, recs := range old {
+		for _, r := range recs {
+			before[r.Key()] = r
+		}
+	}
+	for _, recs := range s.records {
+		for _, r := range recs {
+			prev, ok := before[r.Key()]
+			switch {
+			case !ok:
+				s.emitLocked(OpAdded, r)
+			case !prev.equal(r):
+				s.emitLocked(OpUpdated, r)
+			}
+			delete(before, r.Key())
+		}
+	}
+	for _, r := range before {
+		s.emitLocked(OpDeleted, r)
+	}
+}
+
+// equal reports whether r and o hold the same data. ChangedAt is
+// bookkeeping, not data, and is ignored.
+func (r Record) equal(o Record) bool {
+	return r.Name == o.Name && r.Type == o.Type && r.TTL == o.TTL && r.Value == o.Value &&
+		r.Priority == o.Priority && r.Weight == o.Weight && r.Port == o.Port &&
+		r.Flag == o.Flag && r.Tag == o.Tag && r.OS == o.OS && r.TXTName == o.TXTName && r.Owner == o.Owner && r.Data == o.Data &&
+		r.ExpiresAt.Equal(o.ExpiresAt) && r.TTLWindow.OriginalTTL == o.TTLWindow.OriginalTTL &&
+		r.TTLWindow.RestoreAt.Equal(o.TTLWindow.RestoreAt) &&
+		r.Comment == o.Comment && maps.Equal(r.Labels, o.Labels) && r.Disabled == o.Disabled &&
+		r.Protected == o.Protected
+}
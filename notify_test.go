@@ -0,0 +1,149 @@
+// ABOUTME: Tests for Store change notifications.
+// ABOUTME: Covers events per mutation, unsubscribe, rollback silence, and dropping on a full buffer.
+
+package dynupdate
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// drain returns every event currently buffered in ch.
+func drain(ch <-chan ChangeEvent) []ChangeEvent {
+	var out []ChangeEvent
+	for {
+		select {
+		case ev := <-ch:
+			out = append(out, ev)
+		default:
+			return out
+		}
+	}
+}
+
+func TestStore_Subscribe_Events(t *testing.T) {
+	t.Parallel()
+	s := newTestStore(t, filepath.Join(t.TempDir(), "records.json"))
+	ctx := t.Context()
+
+	events, unsubscribe := s.Subscribe()
+	defer unsubscribe()
+
+	a := Record{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}
+	txt := Record{Name: "a.example.org.", Type: "TXT", TTL: 300, Value: "hello"}
+	b := Record{Name: "b.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"}
+	for _, r := range []Record{a, txt, b} {
+		if err := s.Upsert(ctx, r); err != nil {
+			t.Fatalf("Upsert() error: %v", err)
+		}
+	}
+	a.TTL = 600
+	_ = s.Upsert(ctx, a)
+	_ = s.DeleteByType(ctx, "a.example.org.", "TXT")
+	_ = s.Delete(ctx, "a.example.org.", "A", "10.0.0.1")
+	_ = s.DeleteAll(ctx, "b.example.org.")
+	// No-op mutations emit nothing.
+	_ = s.DeleteAll(ctx, "missing.example.org.")
+
+	want := []struct {
+		op    ChangeOp
+		name  string
+		rtype string
+	}{
+		{OpAdded, "a.example.org.", "A"},
+		{OpAdded, "a.example.org.", "TXT"},
+		{OpAdded, "b.example.org.", "A"},
+		{OpUpdated, "a.example.org.", "A"},
+		{OpDeleted, "a.example.org.", "TXT"},
+		{OpDeleted, "a.example.org.", "A"},
+		{OpDeleted, "b.example.org.", "A"},
+	}
+	got := drain(events)
+	if len(got) != len(want) {
+		t.Fatalf("got %d events %v, want %d", len(got), got, len(want))
+	}
+	for i, w := range want {
+		if got[i].Op != w.op || got[i].Record.Name != w.name || got[i].Record.Type != w.rtype {
+			t.Errorf("event %d = %v %s %s, want %v %s %s", i, got[i].Op, got[i].Record.Name, got[i].Record.Type, w.op, w.name, w.rtype)
+		}
+	}
+}
+
+func TestStore_Subscribe_Unsubscribe(t *testing.T) {
+	t.Parallel()
+	s := newTestStore(t, filepath.Join(t.TempDir(), "records.json"))
+
+	events, unsubscribe := s.Subscribe()
+	unsubscribe()
+	unsubscribe() // idempotent
+
+	if _, ok := <-events; ok {
+		t.Fatal("channel still open after unsubscribe")
+	}
+	if s.watched() {
+		t.Error("store still has subscribers after unsubscribe")
+	}
+	if err := s.Upsert(t.Context(), Record{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("Upsert() after unsubscribe error: %v", err)
+	}
+}
+
+func TestStore_Subscribe_RollbackEmitsNothing(t *testing.T) {
+	t.Parallel()
+	s := newTestStore(t, filepath.Join(t.TempDir(), "records.json"))
+	events, unsubscribe := s.Subscribe()
+	defer unsubscribe()
+
+	err := s.Transaction(t.Context(), func(tx *Tx) error {
+		if err := tx.Upsert(Record{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err != nil {
+			return err
+		}
+		return errors.New("abort")
+	})
+	if err == nil {
+		t.Fatal("Transaction() succeeded, want abort error")
+	}
+	if got := drain(events); len(got) != 0 {
+		t.Errorf("rolled-back transaction emitted %v", got)
+	}
+}
+
+func TestStore_Subscribe_FullBufferDrops(t *testing.T) {
+	t.Parallel()
+	s := newTestStore(t, filepath.Join(t.TempDir(), "records.json"))
+	slow, unsubscribeSlow := s.Subscribe()
+	defer unsubscribeSlow()
+
+	const extra = 10
+	done := make(chan error, 1)
+	go func() {
+		var err error
+		for i := range subscriberBuffer + extra {
+			r := Record{Name: "a.example.org.", Type: "A", TTL: uint32(MinTTL + i), Value: "10.0.0.1"}
+			if err = s.Upsert(t.Context(), r); err != nil {
+				break
+			}
+		}
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Upsert() error: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("mutations blocked on a subscriber that is not reading")
+	}
+
+	got := drain(slow)
+	if len(got) != subscriberBuffer {
+		t.Fatalf("buffered %d events, want %d", len(got), subscriberBuffer)
+	}
+	// The oldest events are kept; the overflow is dropped.
+	if got[0].Op != OpAdded || got[len(got)-1].Record.TTL != uint32(MinTTL+subscriberBuffer-1) {
+		t.Errorf("first/last events = %v/%v, want the first %d", got[0], got[len(got)-1], subscriberBuffer)
+	}
+}
@@ -0,0 +1,96 @@
+// ABOUTME: Deterministic ordering of the answer section: by type, by TTL or by value.
+// ABOUTME: Records are only reordered among those sharing an owner name, so CNAME chains stay in order.
+
+package dynupdate
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// AnswerOrder selects how writeAnswer orders the records of each owner name
+// in the answer section.
+type AnswerOrder uint8
+
+const (
+	// OrderNone keeps store order, rotated when round-robin is enabled
+	// (default zero-value).
+	OrderNone AnswerOrder = iota
+	// OrderType sorts by RR type code.
+	OrderType
+	// OrderTTL sorts by ascending TTL.
+	OrderTTL
+	// OrderValue sorts by the presentation form of the RDATA.
+	OrderValue
+)
+
+// ParseAnswerOrder parses "none", "type", "ttl" or "value".
+func ParseAnswerOrder(s string) (AnswerOrder, error) {
+	switch strings.ToLower(s) {
+	case "none", "":
+		return OrderNone, nil
+	case "type":
+		return OrderType, nil
+	case "ttl":
+		return OrderTTL, nil
+	case "value":
+		return OrderValue, nil
+	default:
+		return 0, fmt.Errorf("unknown answer_order %q: valid values are none, type, ttl, value", s)
+	}
+}
+
+// String returns the canonical string representation of the order.
+func (o AnswerOrder) String() string {
+	switch o {
+	case OrderType:
+		return "type"
+	case OrderTTL:
+		return "ttl"
+	case OrderValue:
+		return "value"
+	default:
+		return "none"
+	}
+}
+
+// sortAnswers orders each run of consecutive records sharing an owner name
+// by o, keeping the runs themselves in place so a CNAME stays ahead of the
+// records it points to. Ties keep their original order, then every key
+// falls back to the RDATA so the result does not depend on store order.
+func (o AnswerOrder) sortAnswers(rrs []dns.RR) {
+	if o == OrderNone {
+		return
+	}
+	for start := 0; start < len(rrs); {
+		end := start + 1
+		for end < len(rrs) && strings.EqualFold(rrs[end].Header().Name, rrs[start].Header().Name) {
+			end++
+		}
+		slices.SortStableFunc(rrs[start:end], o.compare)
+		start = end
+	}
+}
+
+func (o AnswerOrder) compare(a, b dns.RR) int {
+	var c int
+	switch o {
+	case OrderType:
+		c = cmp.Compare(a.Header().Rrtype, b.Header().Rrtype)
+	case OrderTTL:
+		c = cmp.Compare(a.Header().Ttl, b.Header().Ttl)
+	}
+	if c != 0 {
+		return c
+	}
+	return strings.Compare(rdata(a), rdata(b))
+}
+
+// rdata returns the presentation form of rr without its header.
+func rdata(rr dns.RR) string {
+	return strings.TrimPrefix(rr.String(), rr.Header().String())
+}
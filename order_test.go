@@ -0,0 +1,112 @@
+// ABOUTME: Tests for answer section ordering.
+// ABOUTME: Sorts mixed owner runs directly and checks ServeDNS applies the configured order.
+
+package dynupdate
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+)
+
+func mustRR(t *testing.T, s string) dns.RR {
+	t.Helper()
+	rr, err := dns.NewRR(s)
+	if err != nil {
+		t.Fatalf("dns.NewRR(%q) error: %v", s, err)
+	}
+	return rr
+}
+
+func TestAnswerOrder_SortAnswers(t *testing.T) {
+	t.Parallel()
+	// A CNAME followed by its target's records, which must stay behind it.
+	in := []string{
+		"alias.example.org. 300 IN CNAME app.example.org.",
+		"app.example.org. 60 IN TXT \"b\"",
+		"app.example.org. 300 IN A 10.0.0.2",
+		"APP.example.org. 120 IN A 10.0.0.1",
+		"app.example.org. 60 IN TXT \"a\"",
+	}
+	tests := []struct {
+		order AnswerOrder
+		want  []int // indexes into in
+	}{
+		{OrderNone, []int{0, 1, 2, 3, 4}},
+		{OrderType, []int{0, 3, 2, 4, 1}},
+		{OrderTTL, []int{0, 4, 1, 3, 2}},
+		{OrderValue, []int{0, 4, 1, 3, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.order.String(), func(t *testing.T) {
+			t.Parallel()
+			rrs := make([]dns.RR, len(in))
+			for i, s := range in {
+				rrs[i] = mustRR(t, s)
+			}
+			tt.order.sortAnswers(rrs)
+			var got, want []string
+			for i, rr := range rrs {
+				got = append(got, rr.String())
+				want = append(want, mustRR(t, in[tt.want[i]]).String())
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("sorted = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestServeDNS_AnswerOrder(t *testing.T) {
+	t.Parallel()
+	records := []Record{
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.3"},
+		{Name: "app.example.org.", Type: "A", TTL: 60, Value: "10.0.0.1"},
+		{Name: "app.example.org.", Type: "A", TTL: 120, Value: "10.0.0.2"},
+	}
+	tests := []struct {
+		order AnswerOrder
+		want  []string
+	}{
+		{OrderNone, []string{"10.0.0.3", "10.0.0.1", "10.0.0.2"}},
+		{OrderValue, []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}},
+		{OrderTTL, []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.order.String(), func(t *testing.T) {
+			t.Parallel()
+			d := newTestHandler(t, records)
+			d.AnswerOrder = tt.order
+			for range 3 {
+				req := new(dns.Msg)
+				req.SetQuestion("app.example.org.", dns.TypeA)
+				rec := dnstest.NewRecorder(&test.ResponseWriter{})
+				if _, err := d.ServeDNS(t.Context(), rec, req); err != nil {
+					t.Fatalf("ServeDNS() error: %v", err)
+				}
+				var got []string
+				for _, rr := range rec.Msg.Answer {
+					got = append(got, rr.(*dns.A).A.String())
+				}
+				if !slices.Equal(got, tt.want) {
+					t.Fatalf("answers = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseAnswerOrder(t *testing.T) {
+	t.Parallel()
+	for in, want := range map[string]AnswerOrder{"none": OrderNone, "TYPE": OrderType, "ttl": OrderTTL, "value": OrderValue} {
+		if got, err := ParseAnswerOrder(in); err != nil || got != want {
+			t.Errorf("ParseAnswerOrder(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseAnswerOrder("random"); err == nil {
+		t.Error("ParseAnswerOrder(\"random\") expected error")
+	}
+}
@@ -0,0 +1,73 @@
+// ABOUTME: Multi-tenant record ownership carried on the request context.
+// ABOUTME: Auth attaches the caller's Owner; the Store scopes reads and writes to it.
+
+package dynupdate
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotOwner is returned when a caller tries to modify a name that holds
+// records belonging to another owner.
+var ErrNotOwner = errors.New("record is owned by another tenant")
+
+// ErrQuotaExceeded is returned when creating a record would take an owner
+// past its configured quota.
+var ErrQuotaExceeded = errors.New("record quota exceeded")
+
+// Owner identifies the tenant a management request acts for.
+type Owner struct {
+	Name string
+	// Admin callers see and modify every tenant's records.
+	Admin bool
+}
+
+// TenantPolicy overrides store-wide settings for the owner it is keyed by.
+type TenantPolicy struct {
+	// SyncPolicy replaces the store's sync policy for this owner's mutations.
+	SyncPolicy SyncPolicy `json:"sync_policy"`
+	// DefaultTTL is used for records created without a TTL. Zero keeps the
+	// global DefaultTTL.
+	DefaultTTL uint32 `json:"default_ttl,omitempty"`
+}
+
+type ownerKey struct{}
+
+// ContextWithOwner returns a context that scopes Store operations to o.
+func ContextWithOwner(ctx context.Context, o Owner) context.Context {
+	return context.WithValue(ctx, ownerKey{}, o)
+}
+
+// OwnerFromContext returns the Owner attached to ctx, if any.
+func OwnerFromContext(ctx context.Context) (Owner, bool) {
+	o, ok := ctx.Value(ownerKey{}).(Owner)
+	return o, ok
+}
+
+// scopedOwner returns the owner name Store operations in ctx are restricted
+// to. ok is false when no owner is attached or the owner is an admin, in
+// which case every record is visible.
+func scopedOwner(ctx context.Context) (name string, ok bool) {
+	o, found := OwnerFromContext(ctx)
+	if !found || o.Admin {
+		return "", false
+	}
+	return o.Name, true
+}
+
+// filterOwned returns the records visible to the caller in ctx. The input
+// slice is returned unchanged for unscoped callers.
+func filterOwned(ctx context.Context, recs []Record) []Record {
+	owner, scoped := scopedOwner(ctx)
+	if !scoped {
+		return recs
+	}
+	var out []Record
+	for _, r := range recs {
+		if r.Owner == owner {
+			out = append(out, r)
+		}
+	}
+	return out
+}
@@ -0,0 +1,48 @@
+// ABOUTME: Protected records, which the delete operations refuse to remove unless forced.
+// ABOUTME: The force flag travels on the request context, like the caller's Owner.
+
+package dynupdate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrProtected is returned when a delete would remove a protected record
+// and the caller did not force it.
+var ErrProtected = errors.New("record is protected")
+
+type forceKey struct{}
+
+// ContextWithForce returns a context whose Store operations may delete
+// protected records and clear their Protected flag.
+func ContextWithForce(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forceKey{}, true)
+}
+
+// forced reports whether ctx was returned by ContextWithForce.
+func forced(ctx context.Context) bool {
+	f, _ := ctx.Value(forceKey{}).(bool)
+	return f
+}
+
+// refuseProtectedLocked returns ErrProtected if any record of name that is
+// visible to the caller in ctx and matched by match is protected, unless
+// ctx is forced. Delete operations call it before removing anything, so a
+// refused delete leaves the name untouched. Caller must hold Lock.
+func (s *Store) refuseProtectedLocked(ctx context.Context, name string, match func(Record) bool) error {
+	if forced(ctx) {
+		return nil
+	}
+	for _, r := range filterOwned(ctx, s.records[strings.ToLower(name)]) {
+		if r.Protected && match(r) {
+			return fmt.Errorf("cannot delete %s (type %s): %w", r.Name, r.Type, ErrProtected)
+		}
+	}
+	return nil
+}
+
+// anyRecord matches every record.
+func anyRecord(Record) bool { return true }
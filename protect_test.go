@@ -0,0 +1,175 @@
+// ABOUTME: Tests for protected records.
+// ABOUTME: Checks every delete path refuses them atomically unless forced, over the Store, REST and gRPC.
+
+package dynupdate
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pb "github.com/mauromedda/coredns-updater-plugin/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newProtectStore(t *testing.T) *Store {
+	t.Helper()
+	s := newTestStore(t, filepath.Join(t.TempDir(), "records.json"))
+	for _, r := range []Record{
+		{Name: "ns.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1", Protected: true},
+		{Name: "ns.example.org.", Type: "TXT", TTL: 300, Value: "hello"},
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"},
+	} {
+		if err := s.Upsert(t.Context(), r); err != nil {
+			t.Fatalf("Upsert(%s %s) error: %v", r.Name, r.Type, err)
+		}
+	}
+	return s
+}
+
+func TestStore_DeleteAll_Protected(t *testing.T) {
+	t.Parallel()
+	s := newProtectStore(t)
+
+	if err := s.DeleteAll(t.Context(), "ns.example.org."); !errors.Is(err, ErrProtected) {
+		t.Fatalf("DeleteAll(protected) error = %v, want ErrProtected", err)
+	}
+	// The refused delete removes nothing, not even the unprotected TXT.
+	if got := s.GetAll(t.Context(), "ns.example.org."); len(got) != 2 {
+		t.Errorf("records after refused DeleteAll = %+v, want both kept", got)
+	}
+
+	if err := s.DeleteAll(t.Context(), "app.example.org."); err != nil {
+		t.Fatalf("DeleteAll(unprotected) error: %v", err)
+	}
+	if got := s.GetAll(t.Context(), "app.example.org."); len(got) != 0 {
+		t.Errorf("records after DeleteAll = %+v, want none", got)
+	}
+
+	if err := s.DeleteAll(ContextWithForce(t.Context()), "ns.example.org."); err != nil {
+		t.Fatalf("forced DeleteAll error: %v", err)
+	}
+	if got := s.GetAll(t.Context(), "ns.example.org."); len(got) != 0 {
+		t.Errorf("records after forced DeleteAll = %+v, want none", got)
+	}
+}
+
+func TestStore_Delete_Protected(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		del     func(*Store) error
+		wantErr bool
+	}{
+		{"delete protected value", func(s *Store) error {
+			return s.Delete(t.Context(), "ns.example.org.", "A", "10.0.0.1")
+		}, true},
+		{"delete other value", func(s *Store) error {
+			return s.Delete(t.Context(), "ns.example.org.", "TXT", "hello")
+		}, false},
+		{"delete protected type", func(s *Store) error {
+			return s.DeleteByType(t.Context(), "ns.example.org.", "A")
+		}, true},
+		{"delete other type", func(s *Store) error {
+			return s.DeleteByType(t.Context(), "ns.example.org.", "TXT")
+		}, false},
+		{"delete suffix", func(s *Store) error {
+			_, err := s.DeleteBySuffix(t.Context(), "example.org.")
+			return err
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			s := newProtectStore(t)
+			err := tt.del(s)
+			if tt.wantErr != errors.Is(err, ErrProtected) {
+				t.Fatalf("error = %v, want ErrProtected %v", err, tt.wantErr)
+			}
+			if tt.wantErr && len(s.List(t.Context())) != 3 {
+				t.Errorf("List() holds %d records after refused delete, want 3", len(s.List(t.Context())))
+			}
+		})
+	}
+}
+
+func TestStore_Upsert_KeepsProtection(t *testing.T) {
+	t.Parallel()
+	s := newProtectStore(t)
+	rec := Record{Name: "ns.example.org.", Type: "A", TTL: 60, Value: "10.0.0.1"}
+
+	if err := s.Upsert(t.Context(), rec); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	if got := s.Get(t.Context(), "ns.example.org.", "A"); len(got) != 1 || !got[0].Protected || got[0].TTL != 60 {
+		t.Fatalf("after unforced update = %+v, want TTL 60 and still protected", got)
+	}
+
+	if err := s.Upsert(ContextWithForce(t.Context()), rec); err != nil {
+		t.Fatalf("forced Upsert() error: %v", err)
+	}
+	if got := s.Get(t.Context(), "ns.example.org.", "A"); len(got) != 1 || got[0].Protected {
+		t.Fatalf("after forced update = %+v, want unprotected", got)
+	}
+	if err := s.DeleteAll(t.Context(), "ns.example.org."); err != nil {
+		t.Errorf("DeleteAll() after unprotecting error: %v", err)
+	}
+}
+
+func TestAPI_DeleteProtected(t *testing.T) {
+	t.Parallel()
+	api, s := newTestAPIHandler(t)
+	if err := s.Upsert(t.Context(), Record{Name: "ns.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1", Protected: true}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	h := api.handler()
+
+	tests := []struct {
+		method, target, body string
+		want                 int
+	}{
+		{http.MethodDelete, "/api/v1/records/ns.example.org./A", "", http.StatusConflict},
+		{http.MethodDelete, "/api/v1/records/ns.example.org.", "", http.StatusConflict},
+		{http.MethodPut, "/api/v1/records/ns.example.org.", "[]", http.StatusConflict},
+		{http.MethodDelete, "/api/v1/records/ns.example.org.?force=maybe", "", http.StatusBadRequest},
+		{http.MethodDelete, "/api/v1/records/ns.example.org.?force=true", "", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+		req.Header.Set("Authorization", "Bearer test-token")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s %s: status = %d, want %d; body = %s", tt.method, tt.target, rec.Code, tt.want, rec.Body.String())
+		}
+	}
+	if n := len(s.List(t.Context())); n != 0 {
+		t.Errorf("List() holds %d records after forced delete, want 0", n)
+	}
+}
+
+func TestGRPC_DeleteProtected(t *testing.T) {
+	t.Parallel()
+	client, _ := newTestGRPCClient(t, "grpc-secret")
+	ctx := authCtx("grpc-secret")
+
+	resp, err := client.Upsert(ctx, &pb.UpsertRequest{Record: &pb.Record{Name: "ns.example.org.", Type: "A", Ttl: 300, Value: "10.0.0.1", Protected: true}})
+	if err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	if !resp.Record.Protected {
+		t.Errorf("Upsert() record = %+v, want protected", resp.Record)
+	}
+
+	_, err = client.DeleteByType(ctx, &pb.DeleteByTypeRequest{Name: "ns.example.org.", Type: "A"})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("DeleteByType() error = %v, want FailedPrecondition", err)
+	}
+	if _, err := client.DeleteByType(ctx, &pb.DeleteByTypeRequest{Name: "ns.example.org.", Type: "A", Force: true}); err != nil {
+		t.Fatalf("forced DeleteByType() error: %v", err)
+	}
+}
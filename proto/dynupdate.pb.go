@@ -1,5 +1,5 @@
 // ABOUTME: gRPC service definition for dynamic DNS record management.
-// ABOUTME: Defines List, Upsert, and Delete RPCs with Record message type.
+// ABOUTME: Defines List, Get, Upsert, Delete, DeleteByType, DeleteBySuffix, streaming ListStream, Import and Watch RPCs with Record message type.
 
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
@@ -24,6 +24,110 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// OnDuplicate selects how Import treats a record whose name, type and value
+// already exist.
+type OnDuplicate int32
+
+const (
+	OnDuplicate_ON_DUPLICATE_OVERWRITE OnDuplicate = 0
+	OnDuplicate_ON_DUPLICATE_SKIP      OnDuplicate = 1
+	OnDuplicate_ON_DUPLICATE_ERROR     OnDuplicate = 2
+)
+
+// Enum value maps for OnDuplicate.
+var (
+	OnDuplicate_name = map[int32]string{
+		0: "ON_DUPLICATE_OVERWRITE",
+		1: "ON_DUPLICATE_SKIP",
+		2: "ON_DUPLICATE_ERROR",
+	}
+	OnDuplicate_value = map[string]int32{
+		"ON_DUPLICATE_OVERWRITE": 0,
+		"ON_DUPLICATE_SKIP":      1,
+		"ON_DUPLICATE_ERROR":     2,
+	}
+)
+
+func (x OnDuplicate) Enum() *OnDuplicate {
+	p := new(OnDuplicate)
+	*p = x
+	return p
+}
+
+func (x OnDuplicate) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (OnDuplicate) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_dynupdate_proto_enumTypes[0].Descriptor()
+}
+
+func (OnDuplicate) Type() protoreflect.EnumType {
+	return &file_proto_dynupdate_proto_enumTypes[0]
+}
+
+func (x OnDuplicate) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use OnDuplicate.Descriptor instead.
+func (OnDuplicate) EnumDescriptor() ([]byte, []int) {
+	return file_proto_dynupdate_proto_rawDescGZIP(), []int{0}
+}
+
+// ChangeOp is the kind of change a WatchEvent reports.
+type ChangeOp int32
+
+const (
+	ChangeOp_CHANGE_OP_UNSPECIFIED ChangeOp = 0
+	ChangeOp_CHANGE_OP_ADDED       ChangeOp = 1
+	ChangeOp_CHANGE_OP_UPDATED     ChangeOp = 2
+	ChangeOp_CHANGE_OP_DELETED     ChangeOp = 3
+)
+
+// Enum value maps for ChangeOp.
+var (
+	ChangeOp_name = map[int32]string{
+		0: "CHANGE_OP_UNSPECIFIED",
+		1: "CHANGE_OP_ADDED",
+		2: "CHANGE_OP_UPDATED",
+		3: "CHANGE_OP_DELETED",
+	}
+	ChangeOp_value = map[string]int32{
+		"CHANGE_OP_UNSPECIFIED": 0,
+		"CHANGE_OP_ADDED":       1,
+		"CHANGE_OP_UPDATED":     2,
+		"CHANGE_OP_DELETED":     3,
+	}
+)
+
+func (x ChangeOp) Enum() *ChangeOp {
+	p := new(ChangeOp)
+	*p = x
+	return p
+}
+
+func (x ChangeOp) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ChangeOp) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_dynupdate_proto_enumTypes[1].Descriptor()
+}
+
+func (ChangeOp) Type() protoreflect.EnumType {
+	return &file_proto_dynupdate_proto_enumTypes[1]
+}
+
+func (x ChangeOp) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ChangeOp.Descriptor instead.
+func (ChangeOp) EnumDescriptor() ([]byte, []int) {
+	return file_proto_dynupdate_proto_rawDescGZIP(), []int{1}
+}
+
 type Record struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
@@ -35,6 +139,14 @@ type Record struct {
 	Port          uint32                 `protobuf:"varint,7,opt,name=port,proto3" json:"port,omitempty"`
 	Flag          uint32                 `protobuf:"varint,8,opt,name=flag,proto3" json:"flag,omitempty"`
 	Tag           string                 `protobuf:"bytes,9,opt,name=tag,proto3" json:"tag,omitempty"`
+	Owner         string                 `protobuf:"bytes,10,opt,name=owner,proto3" json:"owner,omitempty"`                                                                             // tenant label; set by the server from the caller, ignored on input
+	ExpiresAt     int64                  `protobuf:"varint,11,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`                                                   // Unix seconds after which the record is removed; 0 = never
+	Comment       string                 `protobuf:"bytes,12,opt,name=comment,proto3" json:"comment,omitempty"`                                                                         // operator note; never served in DNS
+	Labels        map[string]string      `protobuf:"bytes,13,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // operator annotations; never served in DNS
+	Disabled      bool                   `protobuf:"varint,14,opt,name=disabled,proto3" json:"disabled,omitempty"`                                                                      // kept and listed but not served in DNS
+	Protected     bool                   `protobuf:"varint,15,opt,name=protected,proto3" json:"protected,omitempty"`                                                                    // deletes fail unless forced; updates keep it unless forced
+	Os            string                 `protobuf:"bytes,16,opt,name=os,proto3" json:"os,omitempty"`                                                                                   // HINFO operating system; value holds the CPU
+	TxtName       string                 `protobuf:"bytes,17,opt,name=txt_name,json=txtName,proto3" json:"txt_name,omitempty"`                                                          // RP name of TXT records about the person; value holds the mailbox
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -132,6 +244,62 @@ func (x *Record) GetTag() string {
 	return ""
 }
 
+func (x *Record) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *Record) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+func (x *Record) GetComment() string {
+	if x != nil {
+		return x.Comment
+	}
+	return ""
+}
+
+func (x *Record) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *Record) GetDisabled() bool {
+	if x != nil {
+		return x.Disabled
+	}
+	return false
+}
+
+func (x *Record) GetProtected() bool {
+	if x != nil {
+		return x.Protected
+	}
+	return false
+}
+
+func (x *Record) GetOs() string {
+	if x != nil {
+		return x.Os
+	}
+	return ""
+}
+
+func (x *Record) GetTxtName() string {
+	if x != nil {
+		return x.TxtName
+	}
+	return ""
+}
+
 type ListRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
@@ -220,16 +388,158 @@ func (x *ListResponse) GetRecords() []*Record {
 	return nil
 }
 
+type ListStreamResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Records       []*Record              `protobuf:"bytes,1,rep,name=records,proto3" json:"records,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListStreamResponse) Reset() {
+	*x = ListStreamResponse{}
+	mi := &file_proto_dynupdate_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListStreamResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListStreamResponse) ProtoMessage() {}
+
+func (x *ListStreamResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dynupdate_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListStreamResponse.ProtoReflect.Descriptor instead.
+func (*ListStreamResponse) Descriptor() ([]byte, []int) {
+	return file_proto_dynupdate_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListStreamResponse) GetRecords() []*Record {
+	if x != nil {
+		return x.Records
+	}
+	return nil
+}
+
+type GetRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRequest) Reset() {
+	*x = GetRequest{}
+	mi := &file_proto_dynupdate_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRequest) ProtoMessage() {}
+
+func (x *GetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dynupdate_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRequest.ProtoReflect.Descriptor instead.
+func (*GetRequest) Descriptor() ([]byte, []int) {
+	return file_proto_dynupdate_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *GetRequest) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+type GetResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Records       []*Record              `protobuf:"bytes,1,rep,name=records,proto3" json:"records,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetResponse) Reset() {
+	*x = GetResponse{}
+	mi := &file_proto_dynupdate_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetResponse) ProtoMessage() {}
+
+func (x *GetResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dynupdate_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetResponse.ProtoReflect.Descriptor instead.
+func (*GetResponse) Descriptor() ([]byte, []int) {
+	return file_proto_dynupdate_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetResponse) GetRecords() []*Record {
+	if x != nil {
+		return x.Records
+	}
+	return nil
+}
+
+// force lets an update clear the record's protected flag.
 type UpsertRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Record        *Record                `protobuf:"bytes,1,opt,name=record,proto3" json:"record,omitempty"`
+	Force         bool                   `protobuf:"varint,2,opt,name=force,proto3" json:"force,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *UpsertRequest) Reset() {
 	*x = UpsertRequest{}
-	mi := &file_proto_dynupdate_proto_msgTypes[3]
+	mi := &file_proto_dynupdate_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -241,7 +551,7 @@ func (x *UpsertRequest) String() string {
 func (*UpsertRequest) ProtoMessage() {}
 
 func (x *UpsertRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_dynupdate_proto_msgTypes[3]
+	mi := &file_proto_dynupdate_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -254,7 +564,7 @@ func (x *UpsertRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UpsertRequest.ProtoReflect.Descriptor instead.
 func (*UpsertRequest) Descriptor() ([]byte, []int) {
-	return file_proto_dynupdate_proto_rawDescGZIP(), []int{3}
+	return file_proto_dynupdate_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *UpsertRequest) GetRecord() *Record {
@@ -264,6 +574,13 @@ func (x *UpsertRequest) GetRecord() *Record {
 	return nil
 }
 
+func (x *UpsertRequest) GetForce() bool {
+	if x != nil {
+		return x.Force
+	}
+	return false
+}
+
 type UpsertResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Record        *Record                `protobuf:"bytes,1,opt,name=record,proto3" json:"record,omitempty"`
@@ -273,7 +590,7 @@ type UpsertResponse struct {
 
 func (x *UpsertResponse) Reset() {
 	*x = UpsertResponse{}
-	mi := &file_proto_dynupdate_proto_msgTypes[4]
+	mi := &file_proto_dynupdate_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -285,7 +602,7 @@ func (x *UpsertResponse) String() string {
 func (*UpsertResponse) ProtoMessage() {}
 
 func (x *UpsertResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_dynupdate_proto_msgTypes[4]
+	mi := &file_proto_dynupdate_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -298,7 +615,7 @@ func (x *UpsertResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UpsertResponse.ProtoReflect.Descriptor instead.
 func (*UpsertResponse) Descriptor() ([]byte, []int) {
-	return file_proto_dynupdate_proto_rawDescGZIP(), []int{4}
+	return file_proto_dynupdate_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *UpsertResponse) GetRecord() *Record {
@@ -308,18 +625,20 @@ func (x *UpsertResponse) GetRecord() *Record {
 	return nil
 }
 
+// force deletes protected records, which are otherwise refused.
 type DeleteRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
 	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
 	Value         string                 `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	Force         bool                   `protobuf:"varint,4,opt,name=force,proto3" json:"force,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *DeleteRequest) Reset() {
 	*x = DeleteRequest{}
-	mi := &file_proto_dynupdate_proto_msgTypes[5]
+	mi := &file_proto_dynupdate_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -331,7 +650,7 @@ func (x *DeleteRequest) String() string {
 func (*DeleteRequest) ProtoMessage() {}
 
 func (x *DeleteRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_dynupdate_proto_msgTypes[5]
+	mi := &file_proto_dynupdate_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -344,7 +663,7 @@ func (x *DeleteRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeleteRequest.ProtoReflect.Descriptor instead.
 func (*DeleteRequest) Descriptor() ([]byte, []int) {
-	return file_proto_dynupdate_proto_rawDescGZIP(), []int{5}
+	return file_proto_dynupdate_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *DeleteRequest) GetName() string {
@@ -368,6 +687,13 @@ func (x *DeleteRequest) GetValue() string {
 	return ""
 }
 
+func (x *DeleteRequest) GetForce() bool {
+	if x != nil {
+		return x.Force
+	}
+	return false
+}
+
 type DeleteResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	unknownFields protoimpl.UnknownFields
@@ -376,7 +702,7 @@ type DeleteResponse struct {
 
 func (x *DeleteResponse) Reset() {
 	*x = DeleteResponse{}
-	mi := &file_proto_dynupdate_proto_msgTypes[6]
+	mi := &file_proto_dynupdate_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -388,7 +714,7 @@ func (x *DeleteResponse) String() string {
 func (*DeleteResponse) ProtoMessage() {}
 
 func (x *DeleteResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_dynupdate_proto_msgTypes[6]
+	mi := &file_proto_dynupdate_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -401,14 +727,409 @@ func (x *DeleteResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeleteResponse.ProtoReflect.Descriptor instead.
 func (*DeleteResponse) Descriptor() ([]byte, []int) {
-	return file_proto_dynupdate_proto_rawDescGZIP(), []int{6}
+	return file_proto_dynupdate_proto_rawDescGZIP(), []int{9}
+}
+
+type DeleteByTypeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Force         bool                   `protobuf:"varint,3,opt,name=force,proto3" json:"force,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteByTypeRequest) Reset() {
+	*x = DeleteByTypeRequest{}
+	mi := &file_proto_dynupdate_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteByTypeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteByTypeRequest) ProtoMessage() {}
+
+func (x *DeleteByTypeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dynupdate_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteByTypeRequest.ProtoReflect.Descriptor instead.
+func (*DeleteByTypeRequest) Descriptor() ([]byte, []int) {
+	return file_proto_dynupdate_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *DeleteByTypeRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *DeleteByTypeRequest) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *DeleteByTypeRequest) GetForce() bool {
+	if x != nil {
+		return x.Force
+	}
+	return false
+}
+
+type DeleteByTypeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteByTypeResponse) Reset() {
+	*x = DeleteByTypeResponse{}
+	mi := &file_proto_dynupdate_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteByTypeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteByTypeResponse) ProtoMessage() {}
+
+func (x *DeleteByTypeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dynupdate_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteByTypeResponse.ProtoReflect.Descriptor instead.
+func (*DeleteByTypeResponse) Descriptor() ([]byte, []int) {
+	return file_proto_dynupdate_proto_rawDescGZIP(), []int{11}
+}
+
+// suffix is a FQDN; it and every name below it are deleted.
+type DeleteBySuffixRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Suffix        string                 `protobuf:"bytes,1,opt,name=suffix,proto3" json:"suffix,omitempty"`
+	Force         bool                   `protobuf:"varint,2,opt,name=force,proto3" json:"force,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteBySuffixRequest) Reset() {
+	*x = DeleteBySuffixRequest{}
+	mi := &file_proto_dynupdate_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteBySuffixRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteBySuffixRequest) ProtoMessage() {}
+
+func (x *DeleteBySuffixRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dynupdate_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteBySuffixRequest.ProtoReflect.Descriptor instead.
+func (*DeleteBySuffixRequest) Descriptor() ([]byte, []int) {
+	return file_proto_dynupdate_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *DeleteBySuffixRequest) GetSuffix() string {
+	if x != nil {
+		return x.Suffix
+	}
+	return ""
+}
+
+func (x *DeleteBySuffixRequest) GetForce() bool {
+	if x != nil {
+		return x.Force
+	}
+	return false
+}
+
+type DeleteBySuffixResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Deleted       uint32                 `protobuf:"varint,1,opt,name=deleted,proto3" json:"deleted,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteBySuffixResponse) Reset() {
+	*x = DeleteBySuffixResponse{}
+	mi := &file_proto_dynupdate_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteBySuffixResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteBySuffixResponse) ProtoMessage() {}
+
+func (x *DeleteBySuffixResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dynupdate_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteBySuffixResponse.ProtoReflect.Descriptor instead.
+func (*DeleteBySuffixResponse) Descriptor() ([]byte, []int) {
+	return file_proto_dynupdate_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *DeleteBySuffixResponse) GetDeleted() uint32 {
+	if x != nil {
+		return x.Deleted
+	}
+	return 0
+}
+
+// on_duplicate is read from the first message of the stream.
+type ImportRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Record        *Record                `protobuf:"bytes,1,opt,name=record,proto3" json:"record,omitempty"`
+	OnDuplicate   OnDuplicate            `protobuf:"varint,2,opt,name=on_duplicate,json=onDuplicate,proto3,enum=dynupdate.v1.OnDuplicate" json:"on_duplicate,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportRequest) Reset() {
+	*x = ImportRequest{}
+	mi := &file_proto_dynupdate_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportRequest) ProtoMessage() {}
+
+func (x *ImportRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dynupdate_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportRequest.ProtoReflect.Descriptor instead.
+func (*ImportRequest) Descriptor() ([]byte, []int) {
+	return file_proto_dynupdate_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ImportRequest) GetRecord() *Record {
+	if x != nil {
+		return x.Record
+	}
+	return nil
+}
+
+func (x *ImportRequest) GetOnDuplicate() OnDuplicate {
+	if x != nil {
+		return x.OnDuplicate
+	}
+	return OnDuplicate_ON_DUPLICATE_OVERWRITE
+}
+
+type ImportResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Created       uint32                 `protobuf:"varint,1,opt,name=created,proto3" json:"created,omitempty"`
+	Updated       uint32                 `protobuf:"varint,2,opt,name=updated,proto3" json:"updated,omitempty"`
+	Skipped       uint32                 `protobuf:"varint,3,opt,name=skipped,proto3" json:"skipped,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportResponse) Reset() {
+	*x = ImportResponse{}
+	mi := &file_proto_dynupdate_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportResponse) ProtoMessage() {}
+
+func (x *ImportResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dynupdate_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportResponse.ProtoReflect.Descriptor instead.
+func (*ImportResponse) Descriptor() ([]byte, []int) {
+	return file_proto_dynupdate_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ImportResponse) GetCreated() uint32 {
+	if x != nil {
+		return x.Created
+	}
+	return 0
+}
+
+func (x *ImportResponse) GetUpdated() uint32 {
+	if x != nil {
+		return x.Updated
+	}
+	return 0
+}
+
+func (x *ImportResponse) GetSkipped() uint32 {
+	if x != nil {
+		return x.Skipped
+	}
+	return 0
+}
+
+type WatchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
+	mi := &file_proto_dynupdate_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRequest) ProtoMessage() {}
+
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dynupdate_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return file_proto_dynupdate_proto_rawDescGZIP(), []int{16}
+}
+
+// For CHANGE_OP_DELETED, record is the record as it was before removal.
+type WatchEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Op            ChangeOp               `protobuf:"varint,1,opt,name=op,proto3,enum=dynupdate.v1.ChangeOp" json:"op,omitempty"`
+	Record        *Record                `protobuf:"bytes,2,opt,name=record,proto3" json:"record,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchEvent) Reset() {
+	*x = WatchEvent{}
+	mi := &file_proto_dynupdate_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchEvent) ProtoMessage() {}
+
+func (x *WatchEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dynupdate_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchEvent.ProtoReflect.Descriptor instead.
+func (*WatchEvent) Descriptor() ([]byte, []int) {
+	return file_proto_dynupdate_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *WatchEvent) GetOp() ChangeOp {
+	if x != nil {
+		return x.Op
+	}
+	return ChangeOp_CHANGE_OP_UNSPECIFIED
+}
+
+func (x *WatchEvent) GetRecord() *Record {
+	if x != nil {
+		return x.Record
+	}
+	return nil
 }
 
 var File_proto_dynupdate_proto protoreflect.FileDescriptor
 
 const file_proto_dynupdate_proto_rawDesc = "" +
 	"\n" +
-	"\x15proto/dynupdate.proto\x12\fdynupdate.v1\"\xc6\x01\n" +
+	"\x15proto/dynupdate.proto\x12\fdynupdate.v1\"\xef\x03\n" +
 	"\x06Record\x12\x12\n" +
 	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
 	"\x04type\x18\x02 \x01(\tR\x04type\x12\x10\n" +
@@ -418,24 +1139,85 @@ const file_proto_dynupdate_proto_rawDesc = "" +
 	"\x06weight\x18\x06 \x01(\rR\x06weight\x12\x12\n" +
 	"\x04port\x18\a \x01(\rR\x04port\x12\x12\n" +
 	"\x04flag\x18\b \x01(\rR\x04flag\x12\x10\n" +
-	"\x03tag\x18\t \x01(\tR\x03tag\"!\n" +
+	"\x03tag\x18\t \x01(\tR\x03tag\x12\x14\n" +
+	"\x05owner\x18\n" +
+	" \x01(\tR\x05owner\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\v \x01(\x03R\texpiresAt\x12\x18\n" +
+	"\acomment\x18\f \x01(\tR\acomment\x128\n" +
+	"\x06labels\x18\r \x03(\v2 .dynupdate.v1.Record.LabelsEntryR\x06labels\x12\x1a\n" +
+	"\bdisabled\x18\x0e \x01(\bR\bdisabled\x12\x1c\n" +
+	"\tprotected\x18\x0f \x01(\bR\tprotected\x12\x0e\n" +
+	"\x02os\x18\x10 \x01(\tR\x02os\x12\x19\n" +
+	"\btxt_name\x18\x11 \x01(\tR\atxtName\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"!\n" +
 	"\vListRequest\x12\x12\n" +
 	"\x04name\x18\x01 \x01(\tR\x04name\">\n" +
 	"\fListResponse\x12.\n" +
-	"\arecords\x18\x01 \x03(\v2\x14.dynupdate.v1.RecordR\arecords\"=\n" +
+	"\arecords\x18\x01 \x03(\v2\x14.dynupdate.v1.RecordR\arecords\"D\n" +
+	"\x12ListStreamResponse\x12.\n" +
+	"\arecords\x18\x01 \x03(\v2\x14.dynupdate.v1.RecordR\arecords\"4\n" +
+	"\n" +
+	"GetRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\"=\n" +
+	"\vGetResponse\x12.\n" +
+	"\arecords\x18\x01 \x03(\v2\x14.dynupdate.v1.RecordR\arecords\"S\n" +
 	"\rUpsertRequest\x12,\n" +
-	"\x06record\x18\x01 \x01(\v2\x14.dynupdate.v1.RecordR\x06record\">\n" +
+	"\x06record\x18\x01 \x01(\v2\x14.dynupdate.v1.RecordR\x06record\x12\x14\n" +
+	"\x05force\x18\x02 \x01(\bR\x05force\">\n" +
 	"\x0eUpsertResponse\x12,\n" +
-	"\x06record\x18\x01 \x01(\v2\x14.dynupdate.v1.RecordR\x06record\"M\n" +
+	"\x06record\x18\x01 \x01(\v2\x14.dynupdate.v1.RecordR\x06record\"c\n" +
 	"\rDeleteRequest\x12\x12\n" +
 	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
 	"\x04type\x18\x02 \x01(\tR\x04type\x12\x14\n" +
-	"\x05value\x18\x03 \x01(\tR\x05value\"\x10\n" +
-	"\x0eDeleteResponse2\xdb\x01\n" +
+	"\x05value\x18\x03 \x01(\tR\x05value\x12\x14\n" +
+	"\x05force\x18\x04 \x01(\bR\x05force\"\x10\n" +
+	"\x0eDeleteResponse\"S\n" +
+	"\x13DeleteByTypeRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x14\n" +
+	"\x05force\x18\x03 \x01(\bR\x05force\"\x16\n" +
+	"\x14DeleteByTypeResponse\"E\n" +
+	"\x15DeleteBySuffixRequest\x12\x16\n" +
+	"\x06suffix\x18\x01 \x01(\tR\x06suffix\x12\x14\n" +
+	"\x05force\x18\x02 \x01(\bR\x05force\"2\n" +
+	"\x16DeleteBySuffixResponse\x12\x18\n" +
+	"\adeleted\x18\x01 \x01(\rR\adeleted\"{\n" +
+	"\rImportRequest\x12,\n" +
+	"\x06record\x18\x01 \x01(\v2\x14.dynupdate.v1.RecordR\x06record\x12<\n" +
+	"\fon_duplicate\x18\x02 \x01(\x0e2\x19.dynupdate.v1.OnDuplicateR\vonDuplicate\"^\n" +
+	"\x0eImportResponse\x12\x18\n" +
+	"\acreated\x18\x01 \x01(\rR\acreated\x12\x18\n" +
+	"\aupdated\x18\x02 \x01(\rR\aupdated\x12\x18\n" +
+	"\askipped\x18\x03 \x01(\rR\askipped\"\x0e\n" +
+	"\fWatchRequest\"b\n" +
+	"\n" +
+	"WatchEvent\x12&\n" +
+	"\x02op\x18\x01 \x01(\x0e2\x16.dynupdate.v1.ChangeOpR\x02op\x12,\n" +
+	"\x06record\x18\x02 \x01(\v2\x14.dynupdate.v1.RecordR\x06record*X\n" +
+	"\vOnDuplicate\x12\x1a\n" +
+	"\x16ON_DUPLICATE_OVERWRITE\x10\x00\x12\x15\n" +
+	"\x11ON_DUPLICATE_SKIP\x10\x01\x12\x16\n" +
+	"\x12ON_DUPLICATE_ERROR\x10\x02*h\n" +
+	"\bChangeOp\x12\x19\n" +
+	"\x15CHANGE_OP_UNSPECIFIED\x10\x00\x12\x13\n" +
+	"\x0fCHANGE_OP_ADDED\x10\x01\x12\x15\n" +
+	"\x11CHANGE_OP_UPDATED\x10\x02\x12\x15\n" +
+	"\x11CHANGE_OP_DELETED\x10\x032\xa0\x05\n" +
 	"\x10DynUpdateService\x12=\n" +
-	"\x04List\x12\x19.dynupdate.v1.ListRequest\x1a\x1a.dynupdate.v1.ListResponse\x12C\n" +
+	"\x04List\x12\x19.dynupdate.v1.ListRequest\x1a\x1a.dynupdate.v1.ListResponse\x12K\n" +
+	"\n" +
+	"ListStream\x12\x19.dynupdate.v1.ListRequest\x1a .dynupdate.v1.ListStreamResponse0\x01\x12:\n" +
+	"\x03Get\x12\x18.dynupdate.v1.GetRequest\x1a\x19.dynupdate.v1.GetResponse\x12C\n" +
 	"\x06Upsert\x12\x1b.dynupdate.v1.UpsertRequest\x1a\x1c.dynupdate.v1.UpsertResponse\x12C\n" +
-	"\x06Delete\x12\x1b.dynupdate.v1.DeleteRequest\x1a\x1c.dynupdate.v1.DeleteResponseB4Z2github.com/mauromedda/coredns-updater-plugin/protob\x06proto3"
+	"\x06Delete\x12\x1b.dynupdate.v1.DeleteRequest\x1a\x1c.dynupdate.v1.DeleteResponse\x12U\n" +
+	"\fDeleteByType\x12!.dynupdate.v1.DeleteByTypeRequest\x1a\".dynupdate.v1.DeleteByTypeResponse\x12[\n" +
+	"\x0eDeleteBySuffix\x12#.dynupdate.v1.DeleteBySuffixRequest\x1a$.dynupdate.v1.DeleteBySuffixResponse\x12E\n" +
+	"\x06Import\x12\x1b.dynupdate.v1.ImportRequest\x1a\x1c.dynupdate.v1.ImportResponse(\x01\x12?\n" +
+	"\x05Watch\x12\x1a.dynupdate.v1.WatchRequest\x1a\x18.dynupdate.v1.WatchEvent0\x01B4Z2github.com/mauromedda/coredns-updater-plugin/protob\x06proto3"
 
 var (
 	file_proto_dynupdate_proto_rawDescOnce sync.Once
@@ -449,31 +1231,65 @@ func file_proto_dynupdate_proto_rawDescGZIP() []byte {
 	return file_proto_dynupdate_proto_rawDescData
 }
 
-var file_proto_dynupdate_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_proto_dynupdate_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_proto_dynupdate_proto_msgTypes = make([]protoimpl.MessageInfo, 19)
 var file_proto_dynupdate_proto_goTypes = []any{
-	(*Record)(nil),         // 0: dynupdate.v1.Record
-	(*ListRequest)(nil),    // 1: dynupdate.v1.ListRequest
-	(*ListResponse)(nil),   // 2: dynupdate.v1.ListResponse
-	(*UpsertRequest)(nil),  // 3: dynupdate.v1.UpsertRequest
-	(*UpsertResponse)(nil), // 4: dynupdate.v1.UpsertResponse
-	(*DeleteRequest)(nil),  // 5: dynupdate.v1.DeleteRequest
-	(*DeleteResponse)(nil), // 6: dynupdate.v1.DeleteResponse
+	(OnDuplicate)(0),               // 0: dynupdate.v1.OnDuplicate
+	(ChangeOp)(0),                  // 1: dynupdate.v1.ChangeOp
+	(*Record)(nil),                 // 2: dynupdate.v1.Record
+	(*ListRequest)(nil),            // 3: dynupdate.v1.ListRequest
+	(*ListResponse)(nil),           // 4: dynupdate.v1.ListResponse
+	(*ListStreamResponse)(nil),     // 5: dynupdate.v1.ListStreamResponse
+	(*GetRequest)(nil),             // 6: dynupdate.v1.GetRequest
+	(*GetResponse)(nil),            // 7: dynupdate.v1.GetResponse
+	(*UpsertRequest)(nil),          // 8: dynupdate.v1.UpsertRequest
+	(*UpsertResponse)(nil),         // 9: dynupdate.v1.UpsertResponse
+	(*DeleteRequest)(nil),          // 10: dynupdate.v1.DeleteRequest
+	(*DeleteResponse)(nil),         // 11: dynupdate.v1.DeleteResponse
+	(*DeleteByTypeRequest)(nil),    // 12: dynupdate.v1.DeleteByTypeRequest
+	(*DeleteByTypeResponse)(nil),   // 13: dynupdate.v1.DeleteByTypeResponse
+	(*DeleteBySuffixRequest)(nil),  // 14: dynupdate.v1.DeleteBySuffixRequest
+	(*DeleteBySuffixResponse)(nil), // 15: dynupdate.v1.DeleteBySuffixResponse
+	(*ImportRequest)(nil),          // 16: dynupdate.v1.ImportRequest
+	(*ImportResponse)(nil),         // 17: dynupdate.v1.ImportResponse
+	(*WatchRequest)(nil),           // 18: dynupdate.v1.WatchRequest
+	(*WatchEvent)(nil),             // 19: dynupdate.v1.WatchEvent
+	nil,                            // 20: dynupdate.v1.Record.LabelsEntry
 }
 var file_proto_dynupdate_proto_depIdxs = []int32{
-	0, // 0: dynupdate.v1.ListResponse.records:type_name -> dynupdate.v1.Record
-	0, // 1: dynupdate.v1.UpsertRequest.record:type_name -> dynupdate.v1.Record
-	0, // 2: dynupdate.v1.UpsertResponse.record:type_name -> dynupdate.v1.Record
-	1, // 3: dynupdate.v1.DynUpdateService.List:input_type -> dynupdate.v1.ListRequest
-	3, // 4: dynupdate.v1.DynUpdateService.Upsert:input_type -> dynupdate.v1.UpsertRequest
-	5, // 5: dynupdate.v1.DynUpdateService.Delete:input_type -> dynupdate.v1.DeleteRequest
-	2, // 6: dynupdate.v1.DynUpdateService.List:output_type -> dynupdate.v1.ListResponse
-	4, // 7: dynupdate.v1.DynUpdateService.Upsert:output_type -> dynupdate.v1.UpsertResponse
-	6, // 8: dynupdate.v1.DynUpdateService.Delete:output_type -> dynupdate.v1.DeleteResponse
-	6, // [6:9] is the sub-list for method output_type
-	3, // [3:6] is the sub-list for method input_type
-	3, // [3:3] is the sub-list for extension type_name
-	3, // [3:3] is the sub-list for extension extendee
-	0, // [0:3] is the sub-list for field type_name
+	20, // 0: dynupdate.v1.Record.labels:type_name -> dynupdate.v1.Record.LabelsEntry
+	2,  // 1: dynupdate.v1.ListResponse.records:type_name -> dynupdate.v1.Record
+	2,  // 2: dynupdate.v1.ListStreamResponse.records:type_name -> dynupdate.v1.Record
+	2,  // 3: dynupdate.v1.GetResponse.records:type_name -> dynupdate.v1.Record
+	2,  // 4: dynupdate.v1.UpsertRequest.record:type_name -> dynupdate.v1.Record
+	2,  // 5: dynupdate.v1.UpsertResponse.record:type_name -> dynupdate.v1.Record
+	2,  // 6: dynupdate.v1.ImportRequest.record:type_name -> dynupdate.v1.Record
+	0,  // 7: dynupdate.v1.ImportRequest.on_duplicate:type_name -> dynupdate.v1.OnDuplicate
+	1,  // 8: dynupdate.v1.WatchEvent.op:type_name -> dynupdate.v1.ChangeOp
+	2,  // 9: dynupdate.v1.WatchEvent.record:type_name -> dynupdate.v1.Record
+	3,  // 10: dynupdate.v1.DynUpdateService.List:input_type -> dynupdate.v1.ListRequest
+	3,  // 11: dynupdate.v1.DynUpdateService.ListStream:input_type -> dynupdate.v1.ListRequest
+	6,  // 12: dynupdate.v1.DynUpdateService.Get:input_type -> dynupdate.v1.GetRequest
+	8,  // 13: dynupdate.v1.DynUpdateService.Upsert:input_type -> dynupdate.v1.UpsertRequest
+	10, // 14: dynupdate.v1.DynUpdateService.Delete:input_type -> dynupdate.v1.DeleteRequest
+	12, // 15: dynupdate.v1.DynUpdateService.DeleteByType:input_type -> dynupdate.v1.DeleteByTypeRequest
+	14, // 16: dynupdate.v1.DynUpdateService.DeleteBySuffix:input_type -> dynupdate.v1.DeleteBySuffixRequest
+	16, // 17: dynupdate.v1.DynUpdateService.Import:input_type -> dynupdate.v1.ImportRequest
+	18, // 18: dynupdate.v1.DynUpdateService.Watch:input_type -> dynupdate.v1.WatchRequest
+	4,  // 19: dynupdate.v1.DynUpdateService.List:output_type -> dynupdate.v1.ListResponse
+	5,  // 20: dynupdate.v1.DynUpdateService.ListStream:output_type -> dynupdate.v1.ListStreamResponse
+	7,  // 21: dynupdate.v1.DynUpdateService.Get:output_type -> dynupdate.v1.GetResponse
+	9,  // 22: dynupdate.v1.DynUpdateService.Upsert:output_type -> dynupdate.v1.UpsertResponse
+	11, // 23: dynupdate.v1.DynUpdateService.Delete:output_type -> dynupdate.v1.DeleteResponse
+	13, // 24: dynupdate.v1.DynUpdateService.DeleteByType:output_type -> dynupdate.v1.DeleteByTypeResponse
+	15, // 25: dynupdate.v1.DynUpdateService.DeleteBySuffix:output_type -> dynupdate.v1.DeleteBySuffixResponse
+	17, // 26: dynupdate.v1.DynUpdateService.Import:output_type -> dynupdate.v1.ImportResponse
+	19, // 27: dynupdate.v1.DynUpdateService.Watch:output_type -> dynupdate.v1.WatchEvent
+	19, // [19:28] is the sub-list for method output_type
+	10, // [10:19] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
 }
 
 func init() { file_proto_dynupdate_proto_init() }
@@ -486,13 +1302,14 @@ func file_proto_dynupdate_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_dynupdate_proto_rawDesc), len(file_proto_dynupdate_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   7,
+			NumEnums:      2,
+			NumMessages:   19,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_proto_dynupdate_proto_goTypes,
 		DependencyIndexes: file_proto_dynupdate_proto_depIdxs,
+		EnumInfos:         file_proto_dynupdate_proto_enumTypes,
 		MessageInfos:      file_proto_dynupdate_proto_msgTypes,
 	}.Build()
 	File_proto_dynupdate_proto = out.File
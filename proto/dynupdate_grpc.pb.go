@@ -1,5 +1,5 @@
 // ABOUTME: gRPC service definition for dynamic DNS record management.
-// ABOUTME: Defines List, Upsert, and Delete RPCs with Record message type.
+// ABOUTME: Defines List, Get, Upsert, Delete, DeleteByType, DeleteBySuffix, streaming ListStream, Import and Watch RPCs with Record message type.
 
 // Code generated by protoc-gen-go-grpc. DO NOT EDIT.
 // versions:
@@ -22,9 +22,15 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	DynUpdateService_List_FullMethodName   = "/dynupdate.v1.DynUpdateService/List"
-	DynUpdateService_Upsert_FullMethodName = "/dynupdate.v1.DynUpdateService/Upsert"
-	DynUpdateService_Delete_FullMethodName = "/dynupdate.v1.DynUpdateService/Delete"
+	DynUpdateService_List_FullMethodName           = "/dynupdate.v1.DynUpdateService/List"
+	DynUpdateService_ListStream_FullMethodName     = "/dynupdate.v1.DynUpdateService/ListStream"
+	DynUpdateService_Get_FullMethodName            = "/dynupdate.v1.DynUpdateService/Get"
+	DynUpdateService_Upsert_FullMethodName         = "/dynupdate.v1.DynUpdateService/Upsert"
+	DynUpdateService_Delete_FullMethodName         = "/dynupdate.v1.DynUpdateService/Delete"
+	DynUpdateService_DeleteByType_FullMethodName   = "/dynupdate.v1.DynUpdateService/DeleteByType"
+	DynUpdateService_DeleteBySuffix_FullMethodName = "/dynupdate.v1.DynUpdateService/DeleteBySuffix"
+	DynUpdateService_Import_FullMethodName         = "/dynupdate.v1.DynUpdateService/Import"
+	DynUpdateService_Watch_FullMethodName          = "/dynupdate.v1.DynUpdateService/Watch"
 )
 
 // DynUpdateServiceClient is the client API for DynUpdateService service.
@@ -32,8 +38,24 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type DynUpdateServiceClient interface {
 	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error)
+	// ListStream returns the same records as List in a stream of chunks, so
+	// large zones never need one huge message.
+	ListStream(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ListStreamResponse], error)
+	// Get returns the records of one name and type; NotFound when there are none.
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error)
 	Upsert(ctx context.Context, in *UpsertRequest, opts ...grpc.CallOption) (*UpsertResponse, error)
 	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error)
+	// DeleteByType removes every record of one type under a name.
+	DeleteByType(ctx context.Context, in *DeleteByTypeRequest, opts ...grpc.CallOption) (*DeleteByTypeResponse, error)
+	// DeleteBySuffix removes every record at or below a name in one atomic
+	// change and reports how many were removed.
+	DeleteBySuffix(ctx context.Context, in *DeleteBySuffixRequest, opts ...grpc.CallOption) (*DeleteBySuffixResponse, error)
+	// Import applies every streamed record as one atomic upsert once the
+	// client closes the stream.
+	Import(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[ImportRequest, ImportResponse], error)
+	// Watch streams an event for every record changed after the call, until
+	// the client cancels. Events are dropped if the client falls too far behind.
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchEvent], error)
 }
 
 type dynUpdateServiceClient struct {
@@ -54,6 +76,35 @@ func (c *dynUpdateServiceClient) List(ctx context.Context, in *ListRequest, opts
 	return out, nil
 }
 
+func (c *dynUpdateServiceClient) ListStream(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ListStreamResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &DynUpdateService_ServiceDesc.Streams[0], DynUpdateService_ListStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ListRequest, ListStreamResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DynUpdateService_ListStreamClient = grpc.ServerStreamingClient[ListStreamResponse]
+
+func (c *dynUpdateServiceClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetResponse)
+	err := c.cc.Invoke(ctx, DynUpdateService_Get_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *dynUpdateServiceClient) Upsert(ctx context.Context, in *UpsertRequest, opts ...grpc.CallOption) (*UpsertResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(UpsertResponse)
@@ -74,13 +125,81 @@ func (c *dynUpdateServiceClient) Delete(ctx context.Context, in *DeleteRequest,
 	return out, nil
 }
 
+func (c *dynUpdateServiceClient) DeleteByType(ctx context.Context, in *DeleteByTypeRequest, opts ...grpc.CallOption) (*DeleteByTypeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteByTypeResponse)
+	err := c.cc.Invoke(ctx, DynUpdateService_DeleteByType_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dynUpdateServiceClient) DeleteBySuffix(ctx context.Context, in *DeleteBySuffixRequest, opts ...grpc.CallOption) (*DeleteBySuffixResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteBySuffixResponse)
+	err := c.cc.Invoke(ctx, DynUpdateService_DeleteBySuffix_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dynUpdateServiceClient) Import(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[ImportRequest, ImportResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &DynUpdateService_ServiceDesc.Streams[1], DynUpdateService_Import_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ImportRequest, ImportResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DynUpdateService_ImportClient = grpc.ClientStreamingClient[ImportRequest, ImportResponse]
+
+func (c *dynUpdateServiceClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &DynUpdateService_ServiceDesc.Streams[2], DynUpdateService_Watch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchRequest, WatchEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DynUpdateService_WatchClient = grpc.ServerStreamingClient[WatchEvent]
+
 // DynUpdateServiceServer is the server API for DynUpdateService service.
 // All implementations must embed UnimplementedDynUpdateServiceServer
 // for forward compatibility.
 type DynUpdateServiceServer interface {
 	List(context.Context, *ListRequest) (*ListResponse, error)
+	// ListStream returns the same records as List in a stream of chunks, so
+	// large zones never need one huge message.
+	ListStream(*ListRequest, grpc.ServerStreamingServer[ListStreamResponse]) error
+	// Get returns the records of one name and type; NotFound when there are none.
+	Get(context.Context, *GetRequest) (*GetResponse, error)
 	Upsert(context.Context, *UpsertRequest) (*UpsertResponse, error)
 	Delete(context.Context, *DeleteRequest) (*DeleteResponse, error)
+	// DeleteByType removes every record of one type under a name.
+	DeleteByType(context.Context, *DeleteByTypeRequest) (*DeleteByTypeResponse, error)
+	// DeleteBySuffix removes every record at or below a name in one atomic
+	// change and reports how many were removed.
+	DeleteBySuffix(context.Context, *DeleteBySuffixRequest) (*DeleteBySuffixResponse, error)
+	// Import applies every streamed record as one atomic upsert once the
+	// client closes the stream.
+	Import(grpc.ClientStreamingServer[ImportRequest, ImportResponse]) error
+	// Watch streams an event for every record changed after the call, until
+	// the client cancels. Events are dropped if the client falls too far behind.
+	Watch(*WatchRequest, grpc.ServerStreamingServer[WatchEvent]) error
 	mustEmbedUnimplementedDynUpdateServiceServer()
 }
 
@@ -94,12 +213,30 @@ type UnimplementedDynUpdateServiceServer struct{}
 func (UnimplementedDynUpdateServiceServer) List(context.Context, *ListRequest) (*ListResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method List not implemented")
 }
+func (UnimplementedDynUpdateServiceServer) ListStream(*ListRequest, grpc.ServerStreamingServer[ListStreamResponse]) error {
+	return status.Error(codes.Unimplemented, "method ListStream not implemented")
+}
+func (UnimplementedDynUpdateServiceServer) Get(context.Context, *GetRequest) (*GetResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Get not implemented")
+}
 func (UnimplementedDynUpdateServiceServer) Upsert(context.Context, *UpsertRequest) (*UpsertResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method Upsert not implemented")
 }
 func (UnimplementedDynUpdateServiceServer) Delete(context.Context, *DeleteRequest) (*DeleteResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method Delete not implemented")
 }
+func (UnimplementedDynUpdateServiceServer) DeleteByType(context.Context, *DeleteByTypeRequest) (*DeleteByTypeResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method DeleteByType not implemented")
+}
+func (UnimplementedDynUpdateServiceServer) DeleteBySuffix(context.Context, *DeleteBySuffixRequest) (*DeleteBySuffixResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method DeleteBySuffix not implemented")
+}
+func (UnimplementedDynUpdateServiceServer) Import(grpc.ClientStreamingServer[ImportRequest, ImportResponse]) error {
+	return status.Error(codes.Unimplemented, "method Import not implemented")
+}
+func (UnimplementedDynUpdateServiceServer) Watch(*WatchRequest, grpc.ServerStreamingServer[WatchEvent]) error {
+	return status.Error(codes.Unimplemented, "method Watch not implemented")
+}
 func (UnimplementedDynUpdateServiceServer) mustEmbedUnimplementedDynUpdateServiceServer() {}
 func (UnimplementedDynUpdateServiceServer) testEmbeddedByValue()                          {}
 
@@ -139,6 +276,35 @@ func _DynUpdateService_List_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _DynUpdateService_ListStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DynUpdateServiceServer).ListStream(m, &grpc.GenericServerStream[ListRequest, ListStreamResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DynUpdateService_ListStreamServer = grpc.ServerStreamingServer[ListStreamResponse]
+
+func _DynUpdateService_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DynUpdateServiceServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DynUpdateService_Get_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DynUpdateServiceServer).Get(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _DynUpdateService_Upsert_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(UpsertRequest)
 	if err := dec(in); err != nil {
@@ -175,6 +341,60 @@ func _DynUpdateService_Delete_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _DynUpdateService_DeleteByType_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteByTypeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DynUpdateServiceServer).DeleteByType(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DynUpdateService_DeleteByType_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DynUpdateServiceServer).DeleteByType(ctx, req.(*DeleteByTypeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DynUpdateService_DeleteBySuffix_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteBySuffixRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DynUpdateServiceServer).DeleteBySuffix(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DynUpdateService_DeleteBySuffix_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DynUpdateServiceServer).DeleteBySuffix(ctx, req.(*DeleteBySuffixRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DynUpdateService_Import_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(DynUpdateServiceServer).Import(&grpc.GenericServerStream[ImportRequest, ImportResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DynUpdateService_ImportServer = grpc.ClientStreamingServer[ImportRequest, ImportResponse]
+
+func _DynUpdateService_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DynUpdateServiceServer).Watch(m, &grpc.GenericServerStream[WatchRequest, WatchEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DynUpdateService_WatchServer = grpc.ServerStreamingServer[WatchEvent]
+
 // DynUpdateService_ServiceDesc is the grpc.ServiceDesc for DynUpdateService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -186,6 +406,10 @@ var DynUpdateService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "List",
 			Handler:    _DynUpdateService_List_Handler,
 		},
+		{
+			MethodName: "Get",
+			Handler:    _DynUpdateService_Get_Handler,
+		},
 		{
 			MethodName: "Upsert",
 			Handler:    _DynUpdateService_Upsert_Handler,
@@ -194,7 +418,31 @@ var DynUpdateService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Delete",
 			Handler:    _DynUpdateService_Delete_Handler,
 		},
+		{
+			MethodName: "DeleteByType",
+			Handler:    _DynUpdateService_DeleteByType_Handler,
+		},
+		{
+			MethodName: "DeleteBySuffix",
+			Handler:    _DynUpdateService_DeleteBySuffix_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListStream",
+			Handler:       _DynUpdateService_ListStream_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Import",
+			Handler:       _DynUpdateService_Import_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Watch",
+			Handler:       _DynUpdateService_Watch_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/dynupdate.proto",
 }
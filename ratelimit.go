@@ -0,0 +1,137 @@
+// ABOUTME: Per-client token-bucket rate limiting and a cap on in-flight requests for the REST API.
+// ABOUTME: Buckets are keyed by client IP and pruned once idle long enough to have refilled.
+
+package dynupdate
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// minPruneInterval is the least time between sweeps of idle buckets.
+const minPruneInterval = time.Minute
+
+// rateLimiter hands out tokens from one bucket per client. Each bucket holds
+// up to burst tokens and refills at rate tokens per second.
+type rateLimiter struct {
+	rate  float64
+	burst float64
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	idle      time.Duration // a bucket untouched this long is full and can be dropped
+	lastPrune time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter returns a limiter allowing rate requests per second per
+// client, with bursts of up to burst requests.
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	refill := time.Duration(float64(burst) / rate * float64(time.Second))
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+		idle:    max(refill, minPruneInterval),
+	}
+}
+
+// allow takes a token from key's bucket. If the bucket is empty it returns
+// false and the time until the next token is available.
+func (l *rateLimiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.pruneLocked(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := (1 - b.tokens) / l.rate
+		return false, time.Duration(wait * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// pruneLocked drops buckets idle long enough to have refilled, which is
+// indistinguishable from having none. Caller must hold mu.
+func (l *rateLimiter) pruneLocked(now time.Time) {
+	if now.Sub(l.lastPrune) < l.idle {
+		return
+	}
+	l.lastPrune = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= l.idle {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// middleware rejects requests over the client's rate with 429 and a
+// Retry-After header in whole seconds.
+func (l *rateLimiter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ok, wait := l.allow(clientIP(r))
+		if !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeJSON(w, http.StatusTooManyRequests, apiErrorResponse{Error: "rate limit exceeded"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientIP returns the IP address of the peer that sent r. Forwarding
+// headers are ignored, since any client can set them.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// inflightLimiter caps the number of requests served at once, whoever
+// sends them.
+type inflightLimiter struct {
+	slots chan struct{}
+}
+
+// newInflightLimiter returns a limiter allowing n requests in flight.
+func newInflightLimiter(n int) *inflightLimiter {
+	return &inflightLimiter{slots: make(chan struct{}, n)}
+}
+
+// middleware rejects requests with 503 and Retry-After while every slot is
+// taken, rather than queueing them.
+func (l *inflightLimiter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case l.slots <- struct{}{}:
+		default:
+			w.Header().Set("Retry-After", "1")
+			writeJSON(w, http.StatusServiceUnavailable, apiErrorResponse{Error: "too many requests in flight"})
+			return
+		}
+		defer func() { <-l.slots }()
+		next.ServeHTTP(w, r)
+	})
+}
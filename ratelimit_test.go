@@ -0,0 +1,142 @@
+// ABOUTME: Tests for the REST API rate limiter.
+// ABOUTME: Covers burst exhaustion, refill, Retry-After, per-client buckets, idle pruning and the in-flight cap.
+
+package dynupdate
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_BurstAndRefill(t *testing.T) {
+	t.Parallel()
+	now := time.Unix(1_700_000_000, 0)
+	l := newRateLimiter(2, 3)
+	l.now = func() time.Time { return now }
+
+	for i := range 3 {
+		if ok, _ := l.allow("10.0.0.1"); !ok {
+			t.Fatalf("request %d within burst denied", i+1)
+		}
+	}
+	ok, wait := l.allow("10.0.0.1")
+	if ok {
+		t.Fatal("request beyond burst allowed")
+	}
+	if wait != 500*time.Millisecond {
+		t.Errorf("wait = %v, want 500ms at 2 rps", wait)
+	}
+	if ok, _ := l.allow("10.0.0.2"); !ok {
+		t.Error("other client denied; buckets must be per client")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if ok, _ := l.allow("10.0.0.1"); !ok {
+		t.Error("request after refill denied")
+	}
+	if ok, _ := l.allow("10.0.0.1"); ok {
+		t.Error("second request after a single token refilled allowed")
+	}
+}
+
+func TestRateLimiter_PrunesIdleBuckets(t *testing.T) {
+	t.Parallel()
+	now := time.Unix(1_700_000_000, 0)
+	l := newRateLimiter(10, 5)
+	l.now = func() time.Time { return now }
+
+	l.allow("10.0.0.1")
+	l.allow("10.0.0.2")
+	now = now.Add(l.idle)
+	l.allow("10.0.0.3")
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.buckets) != 1 {
+		t.Errorf("buckets = %d, want only the active client left", len(l.buckets))
+	}
+}
+
+func TestAPI_RateLimit(t *testing.T) {
+	t.Parallel()
+	_, store := newTestAPIHandler(t)
+	api := NewAPIServer(store, &Auth{Token: "test-token"}, ":0", nil, WithRateLimit(20, 2))
+	h := api.handler()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer test-token")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := range 2 {
+		if rec := get("/api/v1/records"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want %d", i+1, rec.Code, http.StatusOK)
+		}
+	}
+	rec := get("/api/v1/records")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("over limit: status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want %q", got, "1")
+	}
+	if rec := get("/api/v1/ready"); rec.Code != http.StatusOK {
+		t.Errorf("readiness: status = %d, want it exempt from the limit", rec.Code)
+	}
+
+	// At 20 rps a token is back after 50ms.
+	time.Sleep(100 * time.Millisecond)
+	if rec := get("/api/v1/records"); rec.Code != http.StatusOK {
+		t.Errorf("after refill: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestInflightLimiter_RejectsOverLimit(t *testing.T) {
+	t.Parallel()
+	const limit = 3
+	started := make(chan struct{})
+	release := make(chan struct{})
+	h := newInflightLimiter(limit).middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	done := make(chan int, limit)
+	for range limit {
+		go func() {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/records", nil))
+			done <- rec.Code
+		}()
+		<-started
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/records", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("request %d: status = %d, want %d", limit+1, rec.Code, http.StatusServiceUnavailable)
+	}
+	if got := rec.Header().Get("Retry-After"); got == "" {
+		t.Error("Retry-After header missing")
+	}
+
+	close(release)
+	for range limit {
+		if code := <-done; code != http.StatusOK {
+			t.Errorf("in-flight request status = %d, want %d", code, http.StatusOK)
+		}
+	}
+	// Slots are returned once requests finish.
+	go func() { <-started }()
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/records", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("after release: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
@@ -1,10 +1,177 @@
-// ABOUTME: Readiness reporting for the dynupdate plugin.
-// ABOUTME: Satisfies the ready.Readiness interface; returns true once store is loaded.
+// ABOUTME: Readiness reporting for the dynupdate plugin and its store.
+// ABOUTME: Satisfies the ready.Readiness interface and tracks backend and reload health for detailed status.
 
 package dynupdate
 
+import (
+	"context"
+	"sync"
+)
+
 // Ready reports whether the plugin is ready to serve DNS queries.
 // Once it returns true, CoreDNS will not check again.
 func (d *DynUpdate) Ready() bool {
 	return d.Store != nil && d.Store.Ready()
 }
+
+// StoreStatus is a detailed view of the store's readiness. Ready is true
+// when the records are loaded, the latest backend call succeeded and the
+// latest reload, if any, applied cleanly.
+type StoreStatus struct {
+	Ready            bool   `json:"ready"`
+	Loaded           bool   `json:"loaded"`
+	Records          int    `json:"records"`
+	Generation       uint64 `json:"generation"`
+	BackendConnected bool   `json:"backend_connected"`
+	BackendError     string `json:"backend_error,omitempty"`
+	LastReloadOK     bool   `json:"last_reload_ok"`
+	ReloadError      string `json:"reload_error,omitempty"`
+}
+
+// storeHealth holds the outcome of the latest backend calls.
+type storeHealth struct {
+	mu         sync.Mutex
+	backendErr error
+	reloadErr  error
+	changed    chan struct{} // closed and replaced whenever either error changes
+}
+
+// setBackendErr records the outcome of the latest backend write or poll.
+func (s *Store) setBackendErr(err error) {
+	s.health.update(func() bool {
+		if sameErr(s.health.backendErr, err) {
+			return false
+		}
+		s.health.backendErr = err
+		if err != nil {
+			backendDegradedGauge.WithLabelValues(s.backendID).Set(1)
+		} else {
+			backendDegradedGauge.WithLabelValues(s.backendID).Set(0)
+		}
+		return true
+	})
+}
+
+// degradedSeries counts the running stores under each backend_degraded
+// label. Across a CoreDNS reload the old and new store share a backend, so
+// the series is only dropped once the last of them stops.
+var degradedSeries = struct {
+	sync.Mutex
+	stores map[string]int
+}{stores: make(map[string]int)}
+
+// trackDegraded starts reporting backend_degraded for the store's backend,
+// at 0 unless another store already reports it.
+func (s *Store) trackDegraded() {
+	degradedSeries.Lock()
+	defer degradedSeries.Unlock()
+	if degradedSeries.stores[s.backendID] == 0 {
+		backendDegradedGauge.WithLabelValues(s.backendID).Set(0)
+	}
+	degradedSeries.stores[s.backendID]++
+}
+
+// untrackDegraded drops the store's backend_degraded series unless another
+// running store shares its backend.
+func (s *Store) untrackDegraded() {
+	degradedSeries.Lock()
+	defer degradedSeries.Unlock()
+	if degradedSeries.stores[s.backendID]--; degradedSeries.stores[s.backendID] > 0 {
+		return
+	}
+	delete(degradedSeries.stores, s.backendID)
+	backendDegradedGauge.DeleteLabelValues(s.backendID)
+}
+
+// backendErr returns the error from the latest backend write or poll.
+func (s *Store) backendErr() error {
+	s.health.mu.Lock()
+	defer s.health.mu.Unlock()
+	return s.health.backendErr
+}
+
+// Degraded reports whether the latest backend write or poll failed. A
+// degraded store keeps serving from memory but rejects mutations with
+// ErrBackendUnavailable until the backend answers again.
+func (s *Store) Degraded() bool {
+	return s.backendErr() != nil
+}
+
+// setReloadErr records the outcome of the latest reload.
+func (s *Store) setReloadErr(err error) {
+	s.health.update(func() bool {
+		if sameErr(s.health.reloadErr, err) {
+			return false
+		}
+		s.health.reloadErr = err
+		return true
+	})
+}
+
+// update runs set under the lock and wakes waiters if it reports a change.
+func (h *storeHealth) update(set func() bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if set() && h.changed != nil {
+		close(h.changed)
+		h.changed = nil
+	}
+}
+
+// sameErr reports whether a and b are both nil or carry the same message.
+func sameErr(a, b error) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Error() == b.Error()
+}
+
+// Status returns the store's detailed readiness.
+func (s *Store) Status() StoreStatus {
+	st, _ := s.status()
+	return st
+}
+
+// status returns the current status and a channel closed on the next
+// health change.
+func (s *Store) status() (StoreStatus, <-chan struct{}) {
+	s.mu.RLock()
+	st := StoreStatus{
+		Loaded:     s.Ready(),
+		Records:    s.countLocked(),
+		Generation: s.generation,
+	}
+	s.mu.RUnlock()
+
+	s.health.mu.Lock()
+	defer s.health.mu.Unlock()
+	st.BackendConnected = s.health.backendErr == nil
+	if !st.BackendConnected {
+		st.BackendError = s.health.backendErr.Error()
+	}
+	st.LastReloadOK = s.health.reloadErr == nil
+	if !st.LastReloadOK {
+		st.ReloadError = s.health.reloadErr.Error()
+	}
+	st.Ready = st.Loaded && st.BackendConnected && st.LastReloadOK
+	if s.health.changed == nil {
+		s.health.changed = make(chan struct{})
+	}
+	return st, s.health.changed
+}
+
+// WaitReady blocks until the store is ready or ctx is done, and returns
+// the status at that point.
+func (s *Store) WaitReady(ctx context.Context) StoreStatus {
+	for {
+		st, changed := s.status()
+		if st.Ready {
+			return st
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return s.Status()
+		}
+	}
+}
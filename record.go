@@ -1,12 +1,15 @@
 // ABOUTME: Record data model with per-type validation and dns.RR conversion.
-// ABOUTME: Supports A, AAAA, CNAME, TXT, MX, SRV, NS, PTR, CAA record types.
+// ABOUTME: Supports A, AAAA, CNAME, TXT, MX, SRV, NS, PTR, CAA, HINFO, RP record types.
 
 package dynupdate
 
 import (
+	"cmp"
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/miekg/dns"
 )
@@ -16,12 +19,25 @@ const (
 	MinTTL     = 60
 	MaxTTL     = 86400
 	txtChunk   = 255
+
+	// maxCharString is the longest DNS character-string, such as the CPU
+	// and OS of an HINFO record.
+	maxCharString = 255
+)
+
+// Limits on the operator annotations, which are never served in DNS.
+const (
+	maxCommentLen    = 1024
+	maxLabels        = 32
+	maxLabelKeyLen   = 63
+	maxLabelValueLen = 255
 )
 
 // supportedTypes enumerates DNS record types this plugin can manage.
 var supportedTypes = map[string]bool{
 	"A": true, "AAAA": true, "CNAME": true, "TXT": true,
 	"MX": true, "SRV": true, "NS": true, "PTR": true, "CAA": true,
+	"HINFO": true, "RP": true,
 }
 
 // validCAATags enumerates the allowed CAA tag values.
@@ -40,42 +56,334 @@ type Record struct {
 	Port     uint16 `json:"port,omitempty"`
 	Flag     uint8  `json:"flag,omitempty"`
 	Tag      string `json:"tag,omitempty"`
+	Owner    string `json:"owner,omitempty"`
+	// OS is the operating system of an HINFO record, whose Value is the CPU.
+	OS string `json:"os,omitempty"`
+	// TXTName is the name holding TXT records about the responsible person
+	// of an RP record, whose Value is their mailbox. Validate sets it to "."
+	// (none) when empty.
+	TXTName string `json:"txt_name,omitempty"`
+	// Data is an input-only alternative to the structured fields, holding
+	// the record data in zone-file presentation form: "PRIORITY WEIGHT PORT
+	// TARGET" for SRV, "PRIORITY TARGET" for MX. Validate expands it into
+	// Value and the numeric fields and clears it.
+	Data string `json:"data,omitempty"`
+	// ExpiresAt, when set, is the moment the record stops being served and
+	// becomes eligible for removal by the store.
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+	// TTLWindow, when set, holds the TTL to put back once a maintenance
+	// window lowering it ends. See Store.LowerTTL.
+	TTLWindow TTLWindow `json:"ttl_window,omitzero"`
+	// ChangedAt is the store generation at which the record was last added
+	// or updated. The store sets it; any value sent by a client is ignored.
+	ChangedAt uint64 `json:"changed_at,omitempty"`
+	// CreatedAt is when the store first added the record; updates keep it.
+	// The store sets it; any value sent by a client is ignored. Records
+	// stored before it existed have none.
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	// UpdatedAt is when the record was last added or updated, the wall-clock
+	// counterpart of ChangedAt. The store sets it like ChangedAt.
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
+	// Comment and Labels annotate the record for operators, e.g. with a
+	// ticket number. They are stored and returned by the APIs but never
+	// served in DNS answers.
+	Comment string            `json:"comment,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	// Disabled keeps the record stored and listed but stops it from being
+	// served in DNS, where it is treated as absent.
+	Disabled bool `json:"disabled,omitempty"`
+	// Protected records are refused by every delete unless the caller
+	// forces it, and updates keep the flag set unless forced.
+	Protected bool `json:"protected,omitempty"`
+}
+
+// TTLWindow is a pending TTL restoration.
+type TTLWindow struct {
+	OriginalTTL uint32    `json:"original_ttl"`
+	RestoreAt   time.Time `json:"restore_at"`
+}
+
+// Expired reports whether the record has an expiry at or before now.
+func (r Record) Expired(now time.Time) bool {
+	return !r.ExpiresAt.IsZero() && !now.Before(r.ExpiresAt)
+}
+
+// served reports whether DNS answers may use the record at now, given the
+// store's serve delay.
+func (r Record) served(now time.Time, delay time.Duration) bool {
+	return !r.Disabled && !r.Expired(now) && !r.pending(now, delay)
+}
+
+// pending reports whether the record was created less than delay before now
+// and so is not served yet.
+func (r Record) pending(now time.Time, delay time.Duration) bool {
+	return delay > 0 && !r.CreatedAt.IsZero() && now.Before(r.CreatedAt.Add(delay))
+}
+
+// RecordKey identifies a record in the canonical list ordering: name
+// (case-insensitive), then type, then value.
+type RecordKey struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// Key returns the record's canonical sort key.
+func (r Record) Key() RecordKey {
+	return RecordKey{Name: strings.ToLower(r.Name), Type: r.Type, Value: r.Value}
+}
+
+// Compare orders keys canonically, returning -1, 0 or +1.
+func (k RecordKey) Compare(o RecordKey) int {
+	if c := cmp.Compare(k.Name, o.Name); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(k.Type, o.Type); c != 0 {
+		return c
+	}
+	return cmp.Compare(k.Value, o.Value)
+}
+
+// Validation error codes, identifying the kind of problem with a field.
+const (
+	CodeRequired    = "required"
+	CodeInvalid     = "invalid"
+	CodeOutOfRange  = "out_of_range"
+	CodeUnsupported = "unsupported"
+	CodeConflict    = "conflict"
+	CodeNotAllowed  = "not_allowed"
+)
+
+// ValidationError is returned by Record.Validate. Field is the JSON name of
+// the offending field and Code one of the Code constants, so clients can
+// map the failure to a form field; Message is for humans.
+type ValidationError struct {
+	Field   string
+	Code    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// invalidField returns a ValidationError for field with a formatted message.
+func invalidField(field, code, format string, args ...any) *ValidationError {
+	return &ValidationError{Field: field, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// ValidateOption relaxes a check made by Record.Validate.
+type ValidateOption func(*validateConfig)
+
+type validateConfig struct {
+	allowRoot  bool
+	reversePTR bool
+}
+
+// AllowRoot permits records named "." (the DNS root), which Validate rejects
+// by default because they are almost always a mistake.
+func AllowRoot() ValidateOption {
+	return func(c *validateConfig) {
+		c.allowRoot = true
+	}
+}
+
+// ReversePTROnly rejects PTR records whose owner name is not in a reverse
+// zone (below in-addr.arpa. or ip6.arpa.).
+func ReversePTROnly() ValidateOption {
+	return func(c *validateConfig) {
+		c.reversePTR = true
+	}
+}
+
+// PTRCheck controls what the store does with a PTR record whose owner name
+// is not a reverse-DNS name.
+type PTRCheck uint8
+
+const (
+	// PTRCheckOff accepts PTR records at any name (default zero-value).
+	PTRCheckOff PTRCheck = iota
+	// PTRCheckWarn accepts them and logs a warning.
+	PTRCheckWarn
+	// PTRCheckReject fails validation.
+	PTRCheckReject
+)
+
+// ParsePTRCheck parses "off", "warn" or "reject".
+func ParsePTRCheck(s string) (PTRCheck, error) {
+	switch strings.ToLower(s) {
+	case "off":
+		return PTRCheckOff, nil
+	case "warn":
+		return PTRCheckWarn, nil
+	case "reject":
+		return PTRCheckReject, nil
+	default:
+		return 0, fmt.Errorf("unknown ptr_check mode %q: valid values are off, warn, reject", s)
+	}
+}
+
+// String returns the canonical string representation of the mode.
+func (c PTRCheck) String() string {
+	switch c {
+	case PTRCheckWarn:
+		return "warn"
+	case PTRCheckReject:
+		return "reject"
+	default:
+		return "off"
+	}
+}
+
+// reverseZones are the parents of every reverse-DNS name.
+var reverseZones = []string{"in-addr.arpa.", "ip6.arpa."}
+
+// IsReverseName reports whether name lies strictly below in-addr.arpa. or
+// ip6.arpa.
+func IsReverseName(name string) bool {
+	name = strings.ToLower(dns.Fqdn(name))
+	for _, zone := range reverseZones {
+		if name != zone && dns.IsSubDomain(zone, name) {
+			return true
+		}
+	}
+	return false
 }
 
 // Validate checks the record fields for correctness.
-// It normalises Type to uppercase and sets a default TTL when zero.
-func (r *Record) Validate() error {
+// It normalises Type to uppercase, expands Data and sets a default TTL when
+// zero.
+func (r *Record) Validate(opts ...ValidateOption) error {
+	var cfg validateConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
 	if r.Name == "" {
-		return fmt.Errorf("name must not be empty")
+		return invalidField("name", CodeRequired, "name must not be empty")
 	}
 	if !strings.HasSuffix(r.Name, ".") {
-		return fmt.Errorf("name %q must end with a trailing dot", r.Name)
+		return invalidField("name", CodeInvalid, "name %q must end with a trailing dot", r.Name)
 	}
 	if strings.Contains(r.Name, "..") {
-		return fmt.Errorf("name %q is invalid: consecutive dots", r.Name)
+		return invalidField("name", CodeInvalid, "name %q is invalid: consecutive dots", r.Name)
 	}
 	if _, ok := dns.IsDomainName(r.Name); !ok {
-		return fmt.Errorf("name %q is invalid: label or total length exceeded", r.Name)
+		return invalidField("name", CodeInvalid, "name %q is invalid: label or total length exceeded", r.Name)
+	}
+	if strings.Contains(strings.TrimPrefix(r.Name, "*."), "*") {
+		return invalidField("name", CodeInvalid, "name %q is invalid: wildcard is only allowed as the leftmost label", r.Name)
+	}
+	if r.Name == "." && !cfg.allowRoot {
+		return invalidField("name", CodeNotAllowed, "name %q is the DNS root; records there require allow_root", r.Name)
 	}
 
 	r.Type = strings.ToUpper(r.Type)
 	if r.Type == "" {
-		return fmt.Errorf("type must not be empty")
+		return invalidField("type", CodeRequired, "type must not be empty")
 	}
 	if !supportedTypes[r.Type] {
-		return fmt.Errorf("unsupported record type %q", r.Type)
+		return invalidField("type", CodeUnsupported, "unsupported record type %q", r.Type)
+	}
+	if r.Type == "PTR" && cfg.reversePTR && !IsReverseName(r.Name) {
+		return invalidField("name", CodeNotAllowed, "PTR name %q is not in a reverse zone (in-addr.arpa. or ip6.arpa.)", r.Name)
+	}
+
+	if r.Data != "" {
+		if err := r.expandData(); err != nil {
+			return err
+		}
 	}
 
 	if r.TTL == 0 {
 		r.TTL = DefaultTTL
 	}
 	if r.TTL < MinTTL || r.TTL > MaxTTL {
-		return fmt.Errorf("TTL %d out of range [%d, %d]", r.TTL, MinTTL, MaxTTL)
+		return invalidField("ttl", CodeOutOfRange, "TTL %d out of range [%d, %d]", r.TTL, MinTTL, MaxTTL)
+	}
+	if err := r.validateAnnotations(); err != nil {
+		return err
 	}
 
 	return r.validateValue()
 }
 
+// validateAnnotations bounds the size of Comment and Labels. Their content
+// is free-form.
+func (r *Record) validateAnnotations() error {
+	if len(r.Comment) > maxCommentLen {
+		return invalidField("comment", CodeOutOfRange, "comment is %d bytes, max %d", len(r.Comment), maxCommentLen)
+	}
+	if len(r.Labels) > maxLabels {
+		return invalidField("labels", CodeOutOfRange, "%d labels, max %d", len(r.Labels), maxLabels)
+	}
+	for k, v := range r.Labels {
+		if k == "" || len(k) > maxLabelKeyLen {
+			return invalidField("labels", CodeOutOfRange, "label key %q must be 1 to %d bytes", k, maxLabelKeyLen)
+		}
+		if len(v) > maxLabelValueLen {
+			return invalidField("labels", CodeOutOfRange, "label %q value is %d bytes, max %d", k, len(v), maxLabelValueLen)
+		}
+	}
+	return nil
+}
+
+// expandData parses Data into Value, Priority, Weight and Port. A structured
+// field that is also set must agree with Data.
+func (r *Record) expandData() error {
+	fields := strings.Fields(r.Data)
+	var nums []uint16
+	switch r.Type {
+	case "SRV":
+		if len(fields) != 4 {
+			return invalidField("data", CodeInvalid, "SRV data %q must be PRIORITY WEIGHT PORT TARGET", r.Data)
+		}
+	case "MX":
+		if len(fields) != 2 {
+			return invalidField("data", CodeInvalid, "MX data %q must be PRIORITY TARGET", r.Data)
+		}
+	default:
+		return invalidField("data", CodeUnsupported, "data is only supported for SRV and MX records, not %s", r.Type)
+	}
+	for _, f := range fields[:len(fields)-1] {
+		n, err := strconv.ParseUint(f, 10, 16)
+		if err != nil {
+			return invalidField("data", CodeOutOfRange, "%s data %q: %q is not a number between 0 and 65535", r.Type, r.Data, f)
+		}
+		nums = append(nums, uint16(n))
+	}
+	target := fields[len(fields)-1]
+
+	// Zero and empty mean "not given" for the structured fields.
+	if err := r.mergeData("priority", &r.Priority, nums[0]); err != nil {
+		return err
+	}
+	if r.Type == "SRV" {
+		if err := r.mergeData("weight", &r.Weight, nums[1]); err != nil {
+			return err
+		}
+		if err := r.mergeData("port", &r.Port, nums[2]); err != nil {
+			return err
+		}
+	}
+	if r.Value != "" && r.Value != target {
+		return invalidField("value", CodeConflict, "value %q conflicts with data %q", r.Value, r.Data)
+	}
+	r.Value = target
+	r.Data = ""
+	return nil
+}
+
+// mergeData sets the structured field dst, named name, to v from Data unless
+// it already holds a different value.
+func (r *Record) mergeData(name string, dst *uint16, v uint16) error {
+	if *dst != 0 && *dst != v {
+		return invalidField(name, CodeConflict, "%s %d conflicts with data %q", name, *dst, r.Data)
+	}
+	*dst = v
+	return nil
+}
+
 func (r *Record) validateValue() error {
 	switch r.Type {
 	case "A":
@@ -92,6 +400,10 @@ func (r *Record) validateValue() error {
 		return r.validateSRV()
 	case "CAA":
 		return r.validateCAA()
+	case "HINFO":
+		return r.validateHINFO()
+	case "RP":
+		return r.validateRP()
 	}
 	return nil
 }
@@ -99,7 +411,7 @@ func (r *Record) validateValue() error {
 func (r *Record) validateA() error {
 	ip := net.ParseIP(r.Value)
 	if ip == nil || ip.To4() == nil {
-		return fmt.Errorf("value %q is not a valid IPv4 address", r.Value)
+		return invalidField("value", CodeInvalid, "value %q is not a valid IPv4 address", r.Value)
 	}
 	return nil
 }
@@ -107,51 +419,80 @@ func (r *Record) validateA() error {
 func (r *Record) validateAAAA() error {
 	ip := net.ParseIP(r.Value)
 	if ip == nil || ip.To4() != nil {
-		return fmt.Errorf("value %q is not a valid IPv6 address", r.Value)
+		return invalidField("value", CodeInvalid, "value %q is not a valid IPv6 address", r.Value)
 	}
 	return nil
 }
 
 func (r *Record) validateFQDN() error {
 	if !dns.IsFqdn(r.Value) {
-		return fmt.Errorf("value %q must be a FQDN with trailing dot", r.Value)
+		return invalidField("value", CodeInvalid, "value %q must be a FQDN with trailing dot", r.Value)
 	}
 	return nil
 }
 
 func (r *Record) validateTXT() error {
 	if r.Value == "" {
-		return fmt.Errorf("TXT value must not be empty")
+		return invalidField("value", CodeRequired, "TXT value must not be empty")
 	}
 	return nil
 }
 
 func (r *Record) validateMX() error {
 	if !dns.IsFqdn(r.Value) {
-		return fmt.Errorf("MX value %q must be a FQDN with trailing dot", r.Value)
+		return invalidField("value", CodeInvalid, "MX value %q must be a FQDN with trailing dot", r.Value)
 	}
 	return nil
 }
 
 func (r *Record) validateSRV() error {
 	if !dns.IsFqdn(r.Value) {
-		return fmt.Errorf("SRV target %q must be a FQDN with trailing dot", r.Value)
+		return invalidField("value", CodeInvalid, "SRV target %q must be a FQDN with trailing dot", r.Value)
 	}
 	if r.Port == 0 {
-		return fmt.Errorf("SRV port must be non-zero")
+		return invalidField("port", CodeRequired, "SRV port must be non-zero")
 	}
 	return nil
 }
 
 func (r *Record) validateCAA() error {
 	if r.Value == "" {
-		return fmt.Errorf("CAA value must not be empty")
+		return invalidField("value", CodeRequired, "CAA value must not be empty")
 	}
 	if r.Tag == "" {
-		return fmt.Errorf("CAA tag must not be empty")
+		return invalidField("tag", CodeRequired, "CAA tag must not be empty")
 	}
 	if !validCAATags[r.Tag] {
-		return fmt.Errorf("CAA tag %q is invalid; must be one of: issue, issuewild, iodef", r.Tag)
+		return invalidField("tag", CodeInvalid, "CAA tag %q is invalid; must be one of: issue, issuewild, iodef", r.Tag)
+	}
+	return nil
+}
+
+func (r *Record) validateHINFO() error {
+	if r.Value == "" {
+		return invalidField("value", CodeRequired, "HINFO value (CPU) must not be empty")
+	}
+	if len(r.Value) > maxCharString {
+		return invalidField("value", CodeOutOfRange, "HINFO CPU is %d bytes, max %d", len(r.Value), maxCharString)
+	}
+	if r.OS == "" {
+		return invalidField("os", CodeRequired, "HINFO os must not be empty")
+	}
+	if len(r.OS) > maxCharString {
+		return invalidField("os", CodeOutOfRange, "HINFO os is %d bytes, max %d", len(r.OS), maxCharString)
+	}
+	return nil
+}
+
+func (r *Record) validateRP() error {
+	if !dns.IsFqdn(r.Value) {
+		return invalidField("value", CodeInvalid, "RP mailbox %q must be a FQDN with trailing dot", r.Value)
+	}
+	if r.TXTName == "" {
+		r.TXTName = "."
+	}
+	if !dns.IsFqdn(r.TXTName) {
+		return invalidField("txt_name", CodeInvalid, "RP txt_name %q must be a FQDN with trailing dot", r.TXTName)
 	}
 	return nil
 }
@@ -189,11 +530,50 @@ func (r Record) ToRR() (dns.RR, error) {
 		return &dns.PTR{Hdr: hdr, Ptr: r.Value}, nil
 	case "CAA":
 		return &dns.CAA{Hdr: hdr, Flag: r.Flag, Tag: r.Tag, Value: r.Value}, nil
+	case "HINFO":
+		return &dns.HINFO{Hdr: hdr, Cpu: r.Value, Os: r.OS}, nil
+	case "RP":
+		return &dns.RP{Hdr: hdr, Mbox: r.Value, Txt: r.TXTName}, nil
 	default:
 		return nil, fmt.Errorf("unsupported record type %q", r.Type)
 	}
 }
 
+// recordFromRR converts a miekg/dns RR into a Record. ok is false when the RR
+// type is not one this plugin manages.
+func recordFromRR(rr dns.RR) (rec Record, ok bool) {
+	hdr := rr.Header()
+	rec = Record{Name: hdr.Name, Type: dns.TypeToString[hdr.Rrtype], TTL: hdr.Ttl}
+
+	switch v := rr.(type) {
+	case *dns.A:
+		rec.Value = v.A.String()
+	case *dns.AAAA:
+		rec.Value = v.AAAA.String()
+	case *dns.CNAME:
+		rec.Value = v.Target
+	case *dns.TXT:
+		rec.Value = strings.Join(v.Txt, "")
+	case *dns.MX:
+		rec.Value, rec.Priority = v.Mx, v.Preference
+	case *dns.SRV:
+		rec.Value, rec.Priority, rec.Weight, rec.Port = v.Target, v.Priority, v.Weight, v.Port
+	case *dns.NS:
+		rec.Value = v.Ns
+	case *dns.PTR:
+		rec.Value = v.Ptr
+	case *dns.CAA:
+		rec.Value, rec.Flag, rec.Tag = v.Value, v.Flag, v.Tag
+	case *dns.HINFO:
+		rec.Value, rec.OS = v.Cpu, v.Os
+	case *dns.RP:
+		rec.Value, rec.TXTName = v.Mbox, v.Txt
+	default:
+		return Record{}, false
+	}
+	return rec, true
+}
+
 // splitTXT breaks a TXT value into 255-byte chunks as required by RFC 4408.
 func splitTXT(s string) []string {
 	if len(s) <= txtChunk {
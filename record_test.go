@@ -1,9 +1,12 @@
 // ABOUTME: Tests for the Record data model: validation per type and ToRR conversion.
-// ABOUTME: Covers A, AAAA, CNAME, TXT, MX, SRV, NS, PTR, CAA record types.
+// ABOUTME: Covers A, AAAA, CNAME, TXT, MX, SRV, NS, PTR, CAA, HINFO, RP record types.
 
 package dynupdate
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"strings"
 	"testing"
 
@@ -53,6 +56,18 @@ func TestRecord_Validate_ValidRecords(t *testing.T) {
 			name:   "valid CAA record",
 			record: Record{Name: "example.org.", Type: "CAA", TTL: 3600, Value: "letsencrypt.org", Flag: 0, Tag: "issue"},
 		},
+		{
+			name:   "valid HINFO record",
+			record: Record{Name: "host.example.org.", Type: "HINFO", TTL: 3600, Value: "amd64", OS: "Linux 6.1"},
+		},
+		{
+			name:   "valid RP record",
+			record: Record{Name: "host.example.org.", Type: "RP", TTL: 3600, Value: "admin.example.org.", TXTName: "contact.example.org."},
+		},
+		{
+			name:   "RP without txt_name",
+			record: Record{Name: "host.example.org.", Type: "RP", TTL: 3600, Value: "admin.example.org."},
+		},
 		{
 			name:   "type case insensitive",
 			record: Record{Name: "app.example.org.", Type: "a", TTL: 300, Value: "10.0.0.1"},
@@ -114,6 +129,11 @@ func TestRecord_Validate_InvalidRecords(t *testing.T) {
 			record:  Record{Name: strings.Repeat("a.", 127) + ".", Type: "A", TTL: 300, Value: "10.0.0.1"},
 			wantErr: "invalid",
 		},
+		{
+			name:    "wildcard not leftmost",
+			record:  Record{Name: "app.*.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"},
+			wantErr: "wildcard",
+		},
 		{
 			name:    "empty type",
 			record:  Record{Name: "app.example.org.", Type: "", TTL: 300, Value: "10.0.0.1"},
@@ -121,7 +141,7 @@ func TestRecord_Validate_InvalidRecords(t *testing.T) {
 		},
 		{
 			name:    "unsupported type",
-			record:  Record{Name: "app.example.org.", Type: "HINFO", TTL: 300, Value: "some"},
+			record:  Record{Name: "app.example.org.", Type: "SSHFP", TTL: 300, Value: "some"},
 			wantErr: "unsupported",
 		},
 		{
@@ -194,6 +214,31 @@ func TestRecord_Validate_InvalidRecords(t *testing.T) {
 			record:  Record{Name: "example.org.", Type: "CAA", TTL: 300, Value: "letsencrypt.org", Tag: "badtag"},
 			wantErr: "tag",
 		},
+		{
+			name:    "HINFO empty cpu",
+			record:  Record{Name: "host.example.org.", Type: "HINFO", TTL: 300, OS: "Linux"},
+			wantErr: "CPU",
+		},
+		{
+			name:    "HINFO empty os",
+			record:  Record{Name: "host.example.org.", Type: "HINFO", TTL: 300, Value: "amd64"},
+			wantErr: "os",
+		},
+		{
+			name:    "HINFO cpu too long",
+			record:  Record{Name: "host.example.org.", Type: "HINFO", TTL: 300, Value: strings.Repeat("x", 256), OS: "Linux"},
+			wantErr: "max 255",
+		},
+		{
+			name:    "RP mailbox not FQDN",
+			record:  Record{Name: "host.example.org.", Type: "RP", TTL: 300, Value: "admin.example.org"},
+			wantErr: "FQDN",
+		},
+		{
+			name:    "RP txt_name not FQDN",
+			record:  Record{Name: "host.example.org.", Type: "RP", TTL: 300, Value: "admin.example.org.", TXTName: "contact"},
+			wantErr: "txt_name",
+		},
 	}
 
 	for _, tt := range tests {
@@ -210,6 +255,128 @@ func TestRecord_Validate_InvalidRecords(t *testing.T) {
 	}
 }
 
+func TestRecord_Validate_ValidationError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		record Record
+		field  string
+		code   string
+	}{
+		{Record{Name: "app.example.org.", Type: "A", TTL: 10, Value: "10.0.0.1"}, "ttl", CodeOutOfRange},
+		{Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "not-an-ip"}, "value", CodeInvalid},
+		{Record{Name: "app.example.org", Type: "A", TTL: 300, Value: "10.0.0.1"}, "name", CodeInvalid},
+		{Record{Name: "app.example.org.", Type: "SPF", TTL: 300, Value: "v=spf1"}, "type", CodeUnsupported},
+		{Record{Name: "_sip._tcp.example.org.", Type: "SRV", TTL: 300, Value: "sip.example.org."}, "port", CodeRequired},
+		{Record{Name: "_sip._tcp.example.org.", Type: "SRV", TTL: 300, Data: "10 5 5060 sip.example.org.", Port: 5061}, "port", CodeConflict},
+		{Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1", Comment: strings.Repeat("x", maxCommentLen+1)}, "comment", CodeOutOfRange},
+		{Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1", Labels: map[string]string{"": "x"}}, "labels", CodeOutOfRange},
+		{Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1", Labels: map[string]string{"team": strings.Repeat("x", maxLabelValueLen+1)}}, "labels", CodeOutOfRange},
+	}
+	for _, tt := range tests {
+		err := tt.record.Validate()
+		var verr *ValidationError
+		if !errors.As(err, &verr) {
+			t.Errorf("Validate(%+v) error = %v, want a *ValidationError", tt.record, err)
+			continue
+		}
+		if verr.Field != tt.field || verr.Code != tt.code {
+			t.Errorf("Validate(%+v) field, code = %q, %q, want %q, %q", tt.record, verr.Field, verr.Code, tt.field, tt.code)
+		}
+	}
+}
+
+func TestRecord_Validate_Annotations(t *testing.T) {
+	t.Parallel()
+
+	r := Record{
+		Name:    "app.example.org.",
+		Type:    "A",
+		TTL:     300,
+		Value:   "10.0.0.1",
+		Comment: "INC-1234: failover target, ask #netops",
+		Labels:  map[string]string{"team": "netops", "ticket": "INC-1234", "note": ""},
+	}
+	if err := r.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v, want annotations accepted", err)
+	}
+
+	many := make(map[string]string, maxLabels+1)
+	for i := range maxLabels + 1 {
+		many[fmt.Sprintf("k%d", i)] = "v"
+	}
+	r.Labels = many
+	if err := r.Validate(); err == nil {
+		t.Errorf("Validate() with %d labels: expected error", len(many))
+	}
+
+	// Annotations never reach the wire.
+	r.Labels = nil
+	rr, err := r.ToRR()
+	if err != nil {
+		t.Fatalf("ToRR() error: %v", err)
+	}
+	if strings.Contains(rr.String(), "INC-1234") {
+		t.Errorf("ToRR() = %q, want no comment in the RR", rr)
+	}
+}
+
+func TestRecord_Validate_RootName(t *testing.T) {
+	t.Parallel()
+
+	r := Record{Name: ".", Type: "TXT", TTL: 300, Value: "hello"}
+	if err := r.Validate(); err == nil || !strings.Contains(err.Error(), "root") {
+		t.Errorf("Validate() error = %v, want root name rejected", err)
+	}
+	if err := r.Validate(AllowRoot()); err != nil {
+		t.Errorf("Validate(AllowRoot()) error = %v, want nil", err)
+	}
+	// AllowRoot only relaxes the root check.
+	bad := Record{Name: ".", Type: "A", TTL: 300, Value: "not-an-ip"}
+	if err := bad.Validate(AllowRoot()); err == nil {
+		t.Error("Validate(AllowRoot()) accepted an invalid A value")
+	}
+}
+
+func TestRecord_Validate_ReversePTR(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		owner   string
+		wantErr bool
+	}{
+		{"IPv4 reverse", "1.0.0.10.in-addr.arpa.", false},
+		{"IPv6 reverse", "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.", false},
+		{"mixed case", "1.0.0.10.IN-ADDR.ARPA.", false},
+		{"forward name", "host.example.org.", true},
+		{"reverse apex itself", "in-addr.arpa.", true},
+		{"lookalike", "10.in-addr.arpa.example.org.", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			r := Record{Name: tt.owner, Type: "PTR", TTL: 300, Value: "host.example.org."}
+			if err := r.Validate(); err != nil {
+				t.Fatalf("Validate() error = %v, want nil without ReversePTROnly", err)
+			}
+			err := r.Validate(ReversePTROnly())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate(ReversePTROnly()) error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var verr *ValidationError
+			if tt.wantErr && (!errors.As(err, &verr) || verr.Field != "name" || verr.Code != CodeNotAllowed) {
+				t.Errorf("error = %#v, want name/%s", err, CodeNotAllowed)
+			}
+		})
+	}
+
+	// Only PTR records are checked.
+	a := Record{Name: "host.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}
+	if err := a.Validate(ReversePTROnly()); err != nil {
+		t.Errorf("Validate(ReversePTROnly()) on an A record error = %v, want nil", err)
+	}
+}
+
 func TestRecord_Validate_DefaultTTL(t *testing.T) {
 	t.Parallel()
 	r := Record{Name: "app.example.org.", Type: "A", TTL: 0, Value: "10.0.0.1"}
@@ -221,6 +388,37 @@ func TestRecord_Validate_DefaultTTL(t *testing.T) {
 	}
 }
 
+func TestRecord_Validate_RPDefaultTXTName(t *testing.T) {
+	t.Parallel()
+	r := Record{Name: "host.example.org.", Type: "RP", TTL: 300, Value: "admin.example.org."}
+	if err := r.Validate(); err != nil {
+		t.Fatalf("Validate() unexpected error: %v", err)
+	}
+	if r.TXTName != "." {
+		t.Errorf("TXTName = %q, want \".\"", r.TXTName)
+	}
+}
+
+func TestRecord_HINFOAndRP_JSONRoundTrip(t *testing.T) {
+	t.Parallel()
+	for _, in := range []Record{
+		{Name: "host.example.org.", Type: "HINFO", TTL: 300, Value: "amd64", OS: "Linux"},
+		{Name: "host.example.org.", Type: "RP", TTL: 300, Value: "admin.example.org.", TXTName: "contact.example.org."},
+	} {
+		raw, err := json.Marshal(in)
+		if err != nil {
+			t.Fatalf("json.Marshal() error: %v", err)
+		}
+		var out Record
+		if err := json.Unmarshal(raw, &out); err != nil {
+			t.Fatalf("json.Unmarshal() error: %v", err)
+		}
+		if !out.equal(in) {
+			t.Errorf("%s round trip = %+v, want %+v (JSON %s)", in.Type, out, in, raw)
+		}
+	}
+}
+
 func TestRecord_Validate_NormalizesType(t *testing.T) {
 	t.Parallel()
 	r := Record{Name: "app.example.org.", Type: "aaaa", TTL: 300, Value: "2001:db8::1"}
@@ -232,6 +430,89 @@ func TestRecord_Validate_NormalizesType(t *testing.T) {
 	}
 }
 
+func TestRecord_Validate_Data(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		record  Record
+		wantRR  string
+		wantErr bool
+	}{
+		{
+			name:   "SRV combined form",
+			record: Record{Name: "_sip._tcp.example.org.", Type: "SRV", TTL: 300, Data: "10 60 5060 sip.example.org."},
+			wantRR: "_sip._tcp.example.org.\t300\tIN\tSRV\t10 60 5060 sip.example.org.",
+		},
+		{
+			name:   "SRV combined form agreeing with explicit fields",
+			record: Record{Name: "_sip._tcp.example.org.", Type: "srv", TTL: 300, Data: "10 60 5060 sip.example.org.", Value: "sip.example.org.", Port: 5060},
+			wantRR: "_sip._tcp.example.org.\t300\tIN\tSRV\t10 60 5060 sip.example.org.",
+		},
+		{
+			name:   "MX combined form",
+			record: Record{Name: "example.org.", Type: "MX", TTL: 300, Data: "20 mx2.example.org."},
+			wantRR: "example.org.\t300\tIN\tMX\t20 mx2.example.org.",
+		},
+		{
+			name:    "SRV port conflict",
+			record:  Record{Name: "_sip._tcp.example.org.", Type: "SRV", TTL: 300, Data: "10 60 5060 sip.example.org.", Port: 5061},
+			wantErr: true,
+		},
+		{
+			name:    "SRV target conflict",
+			record:  Record{Name: "_sip._tcp.example.org.", Type: "SRV", TTL: 300, Data: "10 60 5060 sip.example.org.", Value: "other.example.org."},
+			wantErr: true,
+		},
+		{
+			name:    "SRV missing field",
+			record:  Record{Name: "_sip._tcp.example.org.", Type: "SRV", TTL: 300, Data: "10 5060 sip.example.org."},
+			wantErr: true,
+		},
+		{
+			name:    "SRV port out of range",
+			record:  Record{Name: "_sip._tcp.example.org.", Type: "SRV", TTL: 300, Data: "10 60 70000 sip.example.org."},
+			wantErr: true,
+		},
+		{
+			name:    "MX target not FQDN",
+			record:  Record{Name: "example.org.", Type: "MX", TTL: 300, Data: "10 mx.example.org"},
+			wantErr: true,
+		},
+		{
+			name:    "data on A record",
+			record:  Record{Name: "app.example.org.", Type: "A", TTL: 300, Data: "10.0.0.1"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			r := tt.record
+			err := r.Validate()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Validate() = nil, want error; record = %+v", r)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate() unexpected error: %v", err)
+			}
+			if r.Data != "" {
+				t.Errorf("Data = %q, want it cleared", r.Data)
+			}
+			rr, err := r.ToRR()
+			if err != nil {
+				t.Fatalf("ToRR() error: %v", err)
+			}
+			if got := rr.String(); got != tt.wantRR {
+				t.Errorf("RR = %q, want %q", got, tt.wantRR)
+			}
+		})
+	}
+}
+
 func TestRecord_ToRR(t *testing.T) {
 	t.Parallel()
 
@@ -358,6 +639,28 @@ func TestRecord_ToRR(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:     "HINFO record",
+			record:   Record{Name: "host.example.org.", Type: "HINFO", TTL: 3600, Value: "amd64", OS: "Linux 6.1"},
+			wantType: dns.TypeHINFO,
+			check: func(t *testing.T, rr dns.RR) {
+				hinfo := rr.(*dns.HINFO)
+				if hinfo.Cpu != "amd64" || hinfo.Os != "Linux 6.1" {
+					t.Errorf("HINFO = %q %q, want amd64 and Linux 6.1", hinfo.Cpu, hinfo.Os)
+				}
+			},
+		},
+		{
+			name:     "RP record",
+			record:   Record{Name: "host.example.org.", Type: "RP", TTL: 3600, Value: "admin.example.org.", TXTName: "contact.example.org."},
+			wantType: dns.TypeRP,
+			check: func(t *testing.T, rr dns.RR) {
+				rp := rr.(*dns.RP)
+				if rp.Mbox != "admin.example.org." || rp.Txt != "contact.example.org." {
+					t.Errorf("RP = %s %s, want admin.example.org. contact.example.org.", rp.Mbox, rp.Txt)
+				}
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -4,16 +4,25 @@
 package dynupdate
 
 import (
+	"crypto/tls"
 	"fmt"
+	"math"
+	"net/netip"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
+	"github.com/miekg/dns"
 )
 
-func init() { plugin.Register(pluginName, setup) }
+func init() {
+	plugin.Register(pluginName, setup)
+	plugin.Register(pluginAlias, setup)
+}
 
 // pluginConfig holds parsed Corefile configuration.
 type pluginConfig struct {
@@ -21,30 +30,85 @@ type pluginConfig struct {
 	datafile string
 	reload   time.Duration
 
-	apiListen string
-	apiToken  string
-	apiTLS    *tlsConfig
+	flushTimeout *time.Duration // nil keeps the store default
+	serveDelay   time.Duration
+
+	backend     string // "file" (default), "redis" or "sqlite"
+	backendAddr string // redis address or sqlite path
+
+	apiListen   string
+	apiToken    string
+	apiTokens   map[string]string
+	apiTLS      *tlsConfig
+	apiTLSOpts  tlsConfig // tls_min_version and tls_ciphers, merged into apiTLS
+	apiH2C      bool
+	apiMetrics  bool
+	apiRPS      float64 // rate_limit requests per second; 0 disables
+	apiBurst    int
+	apiInflight int // max_inflight; 0 disables
+	apiTemplate int // max_template_records; 0 uses the default
 
-	grpcListen string
-	grpcToken  string
-	grpcTLS    *tlsConfig
+	grpcListen     string
+	grpcToken      string
+	grpcTokens     map[string]string
+	grpcTLS        *tlsConfig
+	grpcTLSOpts    tlsConfig
+	grpcReflection bool
 
-	apiAllowedCN  []string
-	apiNoAuth     bool
+	apiAllowedCN []string
+	apiNoAuth    bool
+	apiAccessLog bool
+	apiScopes    map[string][]Scope
 
 	grpcAllowedCN []string
 	grpcNoAuth    bool
+	grpcScopes    map[string][]Scope
+
+	maxRecords int
+	maxNames   int
+	maxPerZone int
+	maxPerSet  int
+	syncPolicy SyncPolicy
+	enableFall bool
+	fallArgs   []string
+	roundRobin bool
+	weighted   bool
+	allowRoot  bool
+	ptrCheck   PTRCheck
+	onConflict LoadConflictMode
+	transferTo []netip.Prefix
+
+	wildcardNXDOMAIN bool
+	ttlJitter        float64
+	minServeTTL      uint32
+	answerOrder      AnswerOrder
+	negCacheSize     int           // negative_cache; 0 disables
+	negCacheTTL      time.Duration // negative_cache duration; 0 uses the default
+	auditFile        string
+	dnssecKey        string // BIND key pair base name; empty leaves zones unsigned
 
-	maxRecords  int
-	syncPolicy  SyncPolicy
-	enableFall  bool
-	fallArgs    []string
+	ownership       bool
+	ownershipAdmins []string
+	quotas          map[string]int
+	tenants         map[string]*tenantConfig
+
+	soa SOAConfig
+}
+
+// tenantConfig holds one parsed tenant block. A nil policy inherits the
+// top-level sync_policy.
+type tenantConfig struct {
+	policy     *SyncPolicy
+	defaultTTL uint32
 }
 
 type tlsConfig struct {
 	cert string
 	key  string
 	ca   string
+
+	minVersion uint16   // tls_min_version; 0 keeps TLS 1.2
+	ciphers    []uint16 // tls_ciphers; nil keeps Go's defaults
 }
 
 func setup(c *caddy.Controller) error {
@@ -53,22 +117,91 @@ func setup(c *caddy.Controller) error {
 		return plugin.Error(pluginName, err)
 	}
 
-	var storeOpts []StoreOption
+	storeOpts := []StoreOption{WithZones(cfg.zones...)}
 	if cfg.maxRecords > 0 {
 		storeOpts = append(storeOpts, WithMaxRecords(cfg.maxRecords))
 	}
+	if cfg.maxNames > 0 {
+		storeOpts = append(storeOpts, WithMaxNames(cfg.maxNames))
+	}
+	if cfg.maxPerZone > 0 {
+		storeOpts = append(storeOpts, WithMaxRecordsPerZone(cfg.maxPerZone))
+	}
+	if cfg.maxPerSet > 0 {
+		storeOpts = append(storeOpts, WithMaxValuesPerRRset(cfg.maxPerSet))
+	}
 	if cfg.syncPolicy != PolicySync {
 		storeOpts = append(storeOpts, WithSyncPolicy(cfg.syncPolicy))
 	}
+	if cfg.allowRoot {
+		storeOpts = append(storeOpts, WithAllowRoot())
+	}
+	if cfg.ptrCheck != PTRCheckOff {
+		storeOpts = append(storeOpts, WithPTRCheck(cfg.ptrCheck))
+	}
+	if cfg.onConflict != ConflictKeepFirst {
+		storeOpts = append(storeOpts, WithLoadConflictMode(cfg.onConflict))
+	}
+	if len(cfg.quotas) > 0 {
+		storeOpts = append(storeOpts, WithQuotas(cfg.quotas))
+	}
+	if cfg.flushTimeout != nil {
+		storeOpts = append(storeOpts, WithFlushTimeout(*cfg.flushTimeout))
+	}
+	if cfg.serveDelay > 0 {
+		storeOpts = append(storeOpts, WithServeDelay(cfg.serveDelay))
+	}
+	if len(cfg.tenants) > 0 {
+		storeOpts = append(storeOpts, WithTenantPolicies(cfg.tenantPolicies()))
+	}
+
+	var dnssecKey *DNSSECKey
+	if cfg.dnssecKey != "" {
+		if dnssecKey, err = LoadDNSSECKey(cfg.dnssecKey); err != nil {
+			return plugin.Error(pluginName, err)
+		}
+		if !slices.Contains(cfg.zones, dnssecKey.Zone()) {
+			return plugin.Error(pluginName, fmt.Errorf("dnssec key is for %s, which is not a served zone", dnssecKey.Zone()))
+		}
+	}
 
-	store, err := NewStore(cfg.datafile, cfg.reload, storeOpts...)
+	var audit *AuditLog
+	if cfg.auditFile != "" {
+		if audit, err = OpenAuditLog(cfg.auditFile); err != nil {
+			return plugin.Error(pluginName, err)
+		}
+		storeOpts = append(storeOpts, WithAuditLog(audit))
+	}
+
+	store, err := newStore(cfg, storeOpts)
 	if err != nil {
+		if audit != nil {
+			audit.Close()
+		}
 		return plugin.Error(pluginName, fmt.Errorf("creating store: %w", err))
 	}
 
 	d := &DynUpdate{
-		Zones: cfg.zones,
-		Store: store,
+		Zones:      cfg.zones,
+		Store:      store,
+		SOA:        cfg.soa,
+		RoundRobin: cfg.roundRobin,
+		Weighted:   cfg.weighted,
+		TransferTo: cfg.transferTo,
+
+		WildcardNXDOMAIN: cfg.wildcardNXDOMAIN,
+		TTLJitter:        cfg.ttlJitter,
+		MinServeTTL:      cfg.minServeTTL,
+		AnswerOrder:      cfg.answerOrder,
+		DNSSEC:           dnssecKey,
+	}
+
+	if cfg.negCacheSize > 0 {
+		ttl := cfg.negCacheTTL
+		if ttl == 0 {
+			ttl = defaultNegativeCacheTTL
+		}
+		d.NegativeCache = newNegativeCache(cfg.negCacheSize, ttl)
 	}
 
 	if cfg.enableFall {
@@ -78,15 +211,54 @@ func setup(c *caddy.Controller) error {
 	// Start API server if configured
 	var apiSrv *APIServer
 	if cfg.apiListen != "" {
-		auth := &Auth{Token: cfg.apiToken, AllowedCN: cfg.apiAllowedCN, NoAuth: cfg.apiNoAuth}
-		apiSrv = NewAPIServer(store, auth, cfg.apiListen, cfg.apiTLS)
+		auth := &Auth{
+			Token:     cfg.apiToken,
+			Tokens:    cfg.apiTokens,
+			AllowedCN: cfg.apiAllowedCN,
+			NoAuth:    cfg.apiNoAuth,
+			Ownership: cfg.ownership,
+			Admins:    cfg.ownershipAdmins,
+			Scopes:    cfg.apiScopes,
+		}
+		apiOpts := []APIOption{WithConfigDump(d)}
+		if cfg.apiH2C {
+			apiOpts = append(apiOpts, WithH2C())
+		}
+		if cfg.apiMetrics {
+			apiOpts = append(apiOpts, WithMetrics())
+		}
+		if cfg.apiAccessLog {
+			apiOpts = append(apiOpts, WithAccessLog())
+		}
+		if cfg.apiRPS > 0 {
+			apiOpts = append(apiOpts, WithRateLimit(cfg.apiRPS, cfg.apiBurst))
+		}
+		if cfg.apiInflight > 0 {
+			apiOpts = append(apiOpts, WithMaxInflight(cfg.apiInflight))
+		}
+		if cfg.apiTemplate > 0 {
+			apiOpts = append(apiOpts, WithMaxTemplateRecords(cfg.apiTemplate))
+		}
+		apiSrv = NewAPIServer(store, auth, cfg.apiListen, cfg.apiTLS, apiOpts...)
 	}
 
 	// Start gRPC server if configured
 	var grpcSrv *GRPCServer
 	if cfg.grpcListen != "" {
-		auth := &Auth{Token: cfg.grpcToken, AllowedCN: cfg.grpcAllowedCN, NoAuth: cfg.grpcNoAuth}
-		grpcSrv = NewGRPCServer(store, auth, cfg.grpcListen, cfg.grpcTLS)
+		auth := &Auth{
+			Token:     cfg.grpcToken,
+			Tokens:    cfg.grpcTokens,
+			AllowedCN: cfg.grpcAllowedCN,
+			NoAuth:    cfg.grpcNoAuth,
+			Ownership: cfg.ownership,
+			Admins:    cfg.ownershipAdmins,
+			Scopes:    cfg.grpcScopes,
+		}
+		var grpcOpts []GRPCOption
+		if cfg.grpcReflection {
+			grpcOpts = append(grpcOpts, WithReflection())
+		}
+		grpcSrv = NewGRPCServer(store, auth, cfg.grpcListen, cfg.grpcTLS, grpcOpts...)
 	}
 
 	c.OnStartup(func() error {
@@ -106,13 +278,14 @@ func setup(c *caddy.Controller) error {
 	})
 
 	c.OnShutdown(func() error {
-		store.Stop()
+		// Stop accepting mutations before the store makes its final flush.
 		if apiSrv != nil {
 			apiSrv.Stop()
 		}
 		if grpcSrv != nil {
 			grpcSrv.Stop()
 		}
+		store.Stop()
 		return nil
 	})
 
@@ -127,7 +300,7 @@ func setup(c *caddy.Controller) error {
 func parseConfig(c *caddy.Controller) (*pluginConfig, error) {
 	cfg := &pluginConfig{}
 
-	c.Next() // skip "dynupdate"
+	c.Next() // skip the directive name ("dynupdate" or "dyndns")
 
 	// Parse zone arguments
 	cfg.zones = c.RemainingArgs()
@@ -136,10 +309,17 @@ func parseConfig(c *caddy.Controller) (*pluginConfig, error) {
 		copy(cfg.zones, c.ServerBlockKeys)
 	}
 
-	// Normalise zones to FQDN
+	// Normalise zones to FQDN, dropping repeats so each zone is matched and
+	// counted in metrics once.
 	var normalized []string
 	for _, z := range cfg.zones {
-		normalized = append(normalized, plugin.Host(z).NormalizeExact()...)
+		for _, n := range plugin.Host(z).NormalizeExact() {
+			if slices.Contains(normalized, n) {
+				log.Warningf("zone %s listed more than once; ignoring the duplicate", n)
+				continue
+			}
+			normalized = append(normalized, n)
+		}
 	}
 	cfg.zones = normalized
 
@@ -151,6 +331,12 @@ func parseConfig(c *caddy.Controller) (*pluginConfig, error) {
 			}
 			cfg.datafile = c.Val()
 
+		case "audit_file":
+			if !c.NextArg() {
+				return nil, fmt.Errorf("audit_file requires a path argument")
+			}
+			cfg.auditFile = c.Val()
+
 		case "reload":
 			if !c.NextArg() {
 				return nil, fmt.Errorf("reload requires a duration argument")
@@ -161,6 +347,37 @@ func parseConfig(c *caddy.Controller) (*pluginConfig, error) {
 			}
 			cfg.reload = d
 
+		case "flush_timeout":
+			if !c.NextArg() {
+				return nil, fmt.Errorf("flush_timeout requires a duration argument")
+			}
+			d, err := time.ParseDuration(c.Val())
+			if err != nil || d < 0 {
+				return nil, fmt.Errorf("invalid flush_timeout duration %q", c.Val())
+			}
+			cfg.flushTimeout = &d
+
+		case "serve_delay":
+			if !c.NextArg() {
+				return nil, fmt.Errorf("serve_delay requires a duration argument")
+			}
+			d, err := time.ParseDuration(c.Val())
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("invalid serve_delay duration %q", c.Val())
+			}
+			cfg.serveDelay = d
+
+		case "backend":
+			args := c.RemainingArgs()
+			switch {
+			case len(args) == 1 && args[0] == "file":
+			case len(args) == 2 && (args[0] == "redis" || args[0] == "sqlite"):
+				cfg.backendAddr = args[1]
+			default:
+				return nil, fmt.Errorf("backend requires: file | redis ADDR | sqlite PATH")
+			}
+			cfg.backend = args[0]
+
 		case "api":
 			if err := parseNestedBlock(c, func(key string, c *caddy.Controller) error {
 				return parseAPIDirective(key, c, cfg)
@@ -185,6 +402,36 @@ func parseConfig(c *caddy.Controller) (*pluginConfig, error) {
 			}
 			cfg.maxRecords = n
 
+		case "max_names":
+			if !c.NextArg() {
+				return nil, fmt.Errorf("max_names requires a numeric argument")
+			}
+			n, err := strconv.Atoi(c.Val())
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("max_names must be a non-negative integer: %q", c.Val())
+			}
+			cfg.maxNames = n
+
+		case "max_records_per_zone":
+			if !c.NextArg() {
+				return nil, fmt.Errorf("max_records_per_zone requires a numeric argument")
+			}
+			n, err := strconv.Atoi(c.Val())
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("max_records_per_zone must be a non-negative integer: %q", c.Val())
+			}
+			cfg.maxPerZone = n
+
+		case "max_values_per_rrset":
+			if !c.NextArg() {
+				return nil, fmt.Errorf("max_values_per_rrset requires a numeric argument")
+			}
+			n, err := strconv.Atoi(c.Val())
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("max_values_per_rrset must be a non-negative integer: %q", c.Val())
+			}
+			cfg.maxPerSet = n
+
 		case "sync_policy":
 			if !c.NextArg() {
 				return nil, fmt.Errorf("sync_policy requires an argument")
@@ -195,6 +442,165 @@ func parseConfig(c *caddy.Controller) (*pluginConfig, error) {
 			}
 			cfg.syncPolicy = p
 
+		case "soa":
+			if err := parseNestedBlock(c, func(key string, c *caddy.Controller) error {
+				return parseSOADirective(key, c, cfg)
+			}); err != nil {
+				return nil, err
+			}
+
+		case "dnssec":
+			if err := parseNestedBlock(c, func(key string, c *caddy.Controller) error {
+				return parseDNSSECDirective(key, c, cfg)
+			}); err != nil {
+				return nil, err
+			}
+			if cfg.dnssecKey == "" {
+				return nil, fmt.Errorf("dnssec block requires a key")
+			}
+
+		case "ownership":
+			cfg.ownership = true
+			cfg.ownershipAdmins = c.RemainingArgs()
+
+		case "quota":
+			args := c.RemainingArgs()
+			if len(args) != 2 {
+				return nil, fmt.Errorf("quota requires: IDENTITY N")
+			}
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("quota must be a non-negative integer: %q", args[1])
+			}
+			if cfg.quotas == nil {
+				cfg.quotas = make(map[string]int)
+			}
+			cfg.quotas[args[0]] = n
+
+		case "tenant":
+			if !c.NextArg() {
+				return nil, fmt.Errorf("tenant requires an identity")
+			}
+			name := c.Val()
+			if cfg.tenants == nil {
+				cfg.tenants = make(map[string]*tenantConfig)
+			}
+			if _, dup := cfg.tenants[name]; dup {
+				return nil, fmt.Errorf("duplicate tenant %q", name)
+			}
+			tc := &tenantConfig{}
+			cfg.tenants[name] = tc
+			if err := parseNestedBlock(c, func(key string, c *caddy.Controller) error {
+				return parseTenantDirective(key, c, tc)
+			}); err != nil {
+				return nil, err
+			}
+
+		case "transfer":
+			args := c.RemainingArgs()
+			if len(args) < 2 || args[0] != "to" {
+				return nil, fmt.Errorf("transfer requires: to ADDR [ADDR...]")
+			}
+			for _, a := range args[1:] {
+				prefixes, err := parseTransferTarget(a)
+				if err != nil {
+					return nil, err
+				}
+				cfg.transferTo = append(cfg.transferTo, prefixes...)
+			}
+
+		case "round_robin":
+			if c.NextArg() {
+				return nil, c.ArgErr()
+			}
+			cfg.roundRobin = true
+
+		case "weighted":
+			if c.NextArg() {
+				return nil, c.ArgErr()
+			}
+			cfg.weighted = true
+
+		case "wildcard_nxdomain":
+			if c.NextArg() {
+				return nil, c.ArgErr()
+			}
+			cfg.wildcardNXDOMAIN = true
+
+		case "ttl_jitter":
+			if !c.NextArg() {
+				return nil, fmt.Errorf("ttl_jitter requires a percentage argument")
+			}
+			p, err := parsePercent(c.Val())
+			if err != nil {
+				return nil, fmt.Errorf("invalid ttl_jitter: %w", err)
+			}
+			cfg.ttlJitter = p
+
+		case "min_serve_ttl":
+			if !c.NextArg() {
+				return nil, fmt.Errorf("min_serve_ttl requires a seconds argument")
+			}
+			v, err := strconv.ParseUint(c.Val(), 10, 32)
+			if err != nil || v < 1 || v > MaxTTL {
+				return nil, fmt.Errorf("min_serve_ttl must be between 1 and %d: %q", MaxTTL, c.Val())
+			}
+			cfg.minServeTTL = uint32(v)
+
+		case "negative_cache":
+			args := c.RemainingArgs()
+			if len(args) < 1 || len(args) > 2 {
+				return nil, fmt.Errorf("negative_cache requires a size and an optional duration")
+			}
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("negative_cache size must be a positive integer: %q", args[0])
+			}
+			cfg.negCacheSize = n
+			if len(args) == 2 {
+				d, err := time.ParseDuration(args[1])
+				if err != nil || d <= 0 {
+					return nil, fmt.Errorf("invalid negative_cache duration %q", args[1])
+				}
+				cfg.negCacheTTL = d
+			}
+
+		case "answer_order":
+			if !c.NextArg() {
+				return nil, fmt.Errorf("answer_order requires an argument: none, type, ttl or value")
+			}
+			o, err := ParseAnswerOrder(c.Val())
+			if err != nil {
+				return nil, err
+			}
+			cfg.answerOrder = o
+
+		case "allow_root":
+			if c.NextArg() {
+				return nil, c.ArgErr()
+			}
+			cfg.allowRoot = true
+
+		case "ptr_check":
+			if !c.NextArg() {
+				return nil, fmt.Errorf("ptr_check requires an argument")
+			}
+			check, err := ParsePTRCheck(c.Val())
+			if err != nil {
+				return nil, fmt.Errorf("invalid ptr_check: %w", err)
+			}
+			cfg.ptrCheck = check
+
+		case "on_load_conflict":
+			if !c.NextArg() {
+				return nil, fmt.Errorf("on_load_conflict requires an argument")
+			}
+			mode, err := ParseLoadConflictMode(c.Val())
+			if err != nil {
+				return nil, fmt.Errorf("invalid on_load_conflict: %w", err)
+			}
+			cfg.onConflict = mode
+
 		case "fallthrough":
 			cfg.enableFall = true
 			cfg.fallArgs = c.RemainingArgs()
@@ -204,20 +610,86 @@ func parseConfig(c *caddy.Controller) (*pluginConfig, error) {
 		}
 	}
 
-	if cfg.datafile == "" {
+	if cfg.datafile == "" && (cfg.backend == "" || cfg.backend == "file") {
 		return nil, fmt.Errorf("datafile is required")
 	}
 
-	if cfg.apiListen != "" && cfg.apiToken == "" && len(cfg.apiAllowedCN) == 0 && !cfg.apiNoAuth {
+	if cfg.apiListen != "" && cfg.apiToken == "" && len(cfg.apiTokens) == 0 && len(cfg.apiAllowedCN) == 0 && !cfg.apiNoAuth {
 		return nil, fmt.Errorf("api block requires token, allowed_cn, or explicit no_auth directive")
 	}
-	if cfg.grpcListen != "" && cfg.grpcToken == "" && len(cfg.grpcAllowedCN) == 0 && !cfg.grpcNoAuth {
+	if cfg.grpcListen != "" && cfg.grpcToken == "" && len(cfg.grpcTokens) == 0 && len(cfg.grpcAllowedCN) == 0 && !cfg.grpcNoAuth {
 		return nil, fmt.Errorf("grpc block requires token, allowed_cn, or explicit no_auth directive")
 	}
 
+	if err := checkScopes("api", cfg.apiScopes, cfg.apiTokens, cfg.apiAllowedCN, cfg.apiNoAuth); err != nil {
+		return nil, err
+	}
+	if err := checkScopes("grpc", cfg.grpcScopes, cfg.grpcTokens, cfg.grpcAllowedCN, cfg.grpcNoAuth); err != nil {
+		return nil, err
+	}
+
+	if err := mergeTLSOptions("api", cfg.apiTLS, cfg.apiTLSOpts); err != nil {
+		return nil, err
+	}
+	if err := mergeTLSOptions("grpc", cfg.grpcTLS, cfg.grpcTLSOpts); err != nil {
+		return nil, err
+	}
+
+	if len(cfg.quotas) > 0 && !cfg.ownership {
+		return nil, fmt.Errorf("quota requires ownership")
+	}
+	if len(cfg.tenants) > 0 && !cfg.ownership {
+		return nil, fmt.Errorf("tenant requires ownership")
+	}
+	// A fixed order would undo every rotation.
+	if cfg.roundRobin && cfg.answerOrder != OrderNone {
+		return nil, fmt.Errorf("answer_order %s cannot be combined with round_robin", cfg.answerOrder)
+	}
+
+	// Each weighted answer holds one address, leaving nothing to rotate.
+	if cfg.roundRobin && cfg.weighted {
+		return nil, fmt.Errorf("weighted cannot be combined with round_robin")
+	}
+
+	// Ownership needs every caller to carry an identity.
+	if cfg.ownership {
+		if cfg.apiListen != "" && (cfg.apiNoAuth || cfg.apiToken != "") {
+			return nil, fmt.Errorf("ownership requires named api tokens (token SECRET NAME) and no no_auth")
+		}
+		if cfg.grpcListen != "" && (cfg.grpcNoAuth || cfg.grpcToken != "") {
+			return nil, fmt.Errorf("ownership requires named grpc tokens (token SECRET NAME) and no no_auth")
+		}
+	}
+
 	return cfg, nil
 }
 
+// newStore creates the Store on top of the configured backend.
+func newStore(cfg *pluginConfig, opts []StoreOption) (*Store, error) {
+	var (
+		b   Backend
+		err error
+	)
+	switch cfg.backend {
+	case "redis":
+		b, err = NewRedisBackend(cfg.backendAddr)
+	case "sqlite":
+		b, err = NewSQLiteBackend(cfg.backendAddr)
+	default:
+		return NewStore(cfg.datafile, cfg.reload, opts...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := NewStoreWithBackend(b, cfg.reload, opts...)
+	if err != nil {
+		b.Close()
+		return nil, fmt.Errorf("initialising store from %s %s: %w", cfg.backend, cfg.backendAddr, err)
+	}
+	return s, nil
+}
+
 // parseNestedBlock manually handles Caddy v1 nested block parsing.
 // It consumes the opening `{`, iterates over directives, and stops at `}`.
 func parseNestedBlock(c *caddy.Controller, handler func(string, *caddy.Controller) error) error {
@@ -253,7 +725,15 @@ func parseAPIDirective(key string, c *caddy.Controller, cfg *pluginConfig) error
 		if !c.NextArg() {
 			return fmt.Errorf("api token requires a value")
 		}
-		cfg.apiToken = c.Val()
+		secret := c.Val()
+		if c.NextArg() {
+			if cfg.apiTokens == nil {
+				cfg.apiTokens = make(map[string]string)
+			}
+			cfg.apiTokens[secret] = c.Val()
+		} else {
+			cfg.apiToken = secret
+		}
 
 	case "tls":
 		args := c.RemainingArgs()
@@ -262,6 +742,9 @@ func parseAPIDirective(key string, c *caddy.Controller, cfg *pluginConfig) error
 		}
 		cfg.apiTLS = &tlsConfig{cert: args[0], key: args[1], ca: args[2]}
 
+	case "tls_min_version", "tls_ciphers":
+		return parseTLSOption("api", key, c, &cfg.apiTLSOpts)
+
 	case "allowed_cn":
 		cfg.apiAllowedCN = c.RemainingArgs()
 		if len(cfg.apiAllowedCN) == 0 {
@@ -271,6 +754,62 @@ func parseAPIDirective(key string, c *caddy.Controller, cfg *pluginConfig) error
 	case "no_auth":
 		cfg.apiNoAuth = true
 
+	case "scope":
+		if cfg.apiScopes == nil {
+			cfg.apiScopes = make(map[string][]Scope)
+		}
+		return parseScopeDirective(c, cfg.apiScopes)
+
+	case "h2c":
+		cfg.apiH2C = true
+
+	case "metrics":
+		if c.NextArg() {
+			return c.ArgErr()
+		}
+		cfg.apiMetrics = true
+
+	case "access_log":
+		if c.NextArg() {
+			return c.ArgErr()
+		}
+		cfg.apiAccessLog = true
+
+	case "rate_limit":
+		args := c.RemainingArgs()
+		if len(args) != 2 {
+			return fmt.Errorf("api rate_limit requires RPS and BURST arguments")
+		}
+		rps, err := strconv.ParseFloat(args[0], 64)
+		if err != nil || rps <= 0 || math.IsInf(rps, 0) || math.IsNaN(rps) {
+			return fmt.Errorf("api rate_limit RPS must be a positive number: %q", args[0])
+		}
+		burst, err := strconv.Atoi(args[1])
+		if err != nil || burst < 1 {
+			return fmt.Errorf("api rate_limit BURST must be a positive integer: %q", args[1])
+		}
+		cfg.apiRPS, cfg.apiBurst = rps, burst
+
+	case "max_inflight":
+		if !c.NextArg() {
+			return fmt.Errorf("api max_inflight requires a count argument")
+		}
+		n, err := strconv.Atoi(c.Val())
+		if err != nil || n < 1 {
+			return fmt.Errorf("api max_inflight must be a positive integer: %q", c.Val())
+		}
+		cfg.apiInflight = n
+
+	case "max_template_records":
+		if !c.NextArg() {
+			return fmt.Errorf("api max_template_records requires a count argument")
+		}
+		n, err := strconv.Atoi(c.Val())
+		if err != nil || n < 1 {
+			return fmt.Errorf("api max_template_records must be a positive integer: %q", c.Val())
+		}
+		cfg.apiTemplate = n
+
 	default:
 		return fmt.Errorf("unknown api directive %q", key)
 	}
@@ -289,7 +828,15 @@ func parseGRPCDirective(key string, c *caddy.Controller, cfg *pluginConfig) erro
 		if !c.NextArg() {
 			return fmt.Errorf("grpc token requires a value")
 		}
-		cfg.grpcToken = c.Val()
+		secret := c.Val()
+		if c.NextArg() {
+			if cfg.grpcTokens == nil {
+				cfg.grpcTokens = make(map[string]string)
+			}
+			cfg.grpcTokens[secret] = c.Val()
+		} else {
+			cfg.grpcToken = secret
+		}
 
 	case "tls":
 		args := c.RemainingArgs()
@@ -298,6 +845,9 @@ func parseGRPCDirective(key string, c *caddy.Controller, cfg *pluginConfig) erro
 		}
 		cfg.grpcTLS = &tlsConfig{cert: args[0], key: args[1], ca: args[2]}
 
+	case "tls_min_version", "tls_ciphers":
+		return parseTLSOption("grpc", key, c, &cfg.grpcTLSOpts)
+
 	case "allowed_cn":
 		cfg.grpcAllowedCN = c.RemainingArgs()
 		if len(cfg.grpcAllowedCN) == 0 {
@@ -307,8 +857,224 @@ func parseGRPCDirective(key string, c *caddy.Controller, cfg *pluginConfig) erro
 	case "no_auth":
 		cfg.grpcNoAuth = true
 
+	case "scope":
+		if cfg.grpcScopes == nil {
+			cfg.grpcScopes = make(map[string][]Scope)
+		}
+		return parseScopeDirective(c, cfg.grpcScopes)
+
+	case "reflection":
+		if c.NextArg() {
+			return c.ArgErr()
+		}
+		cfg.grpcReflection = true
+
 	default:
 		return fmt.Errorf("unknown grpc directive %q", key)
 	}
 	return nil
 }
+
+// parseScopeDirective parses "scope IDENTITY SCOPE..." into scopes.
+func parseScopeDirective(c *caddy.Controller, scopes map[string][]Scope) error {
+	args := c.RemainingArgs()
+	if len(args) < 2 {
+		return fmt.Errorf("scope requires an identity and at least one scope")
+	}
+	identity := args[0]
+	if _, dup := scopes[identity]; dup {
+		return fmt.Errorf("duplicate scope for %q", identity)
+	}
+	for _, arg := range args[1:] {
+		sc, err := ParseScope(arg)
+		if err != nil {
+			return fmt.Errorf("invalid scope for %q: %w", identity, err)
+		}
+		scopes[identity] = append(scopes[identity], sc)
+	}
+	return nil
+}
+
+// checkScopes verifies that every scoped identity in a management block is
+// a named token or an allowed CN of that block, so a typo cannot leave a
+// credential with full access.
+func checkScopes(block string, scopes map[string][]Scope, tokens map[string]string, allowedCN []string, noAuth bool) error {
+	if len(scopes) == 0 {
+		return nil
+	}
+	if noAuth {
+		return fmt.Errorf("%s scope cannot be combined with no_auth", block)
+	}
+	for identity := range scopes {
+		named := false
+		for _, name := range tokens {
+			if name == identity {
+				named = true
+				break
+			}
+		}
+		if !named && !slices.ContainsFunc(allowedCN, func(cn string) bool { return matchName(cn, identity) }) {
+			return fmt.Errorf("%s scope for %q: not a token name or allowed_cn", block, identity)
+		}
+	}
+	return nil
+}
+
+func parseTenantDirective(key string, c *caddy.Controller, tc *tenantConfig) error {
+	switch key {
+	case "sync_policy":
+		if !c.NextArg() {
+			return fmt.Errorf("tenant sync_policy requires a mode argument")
+		}
+		p, err := ParseSyncPolicy(c.Val())
+		if err != nil {
+			return fmt.Errorf("invalid tenant sync_policy: %w", err)
+		}
+		tc.policy = &p
+
+	case "default_ttl":
+		if !c.NextArg() {
+			return fmt.Errorf("tenant default_ttl requires a value")
+		}
+		v, err := strconv.ParseUint(c.Val(), 10, 32)
+		if err != nil || v < MinTTL || v > MaxTTL {
+			return fmt.Errorf("tenant default_ttl must be between %d and %d: %q", MinTTL, MaxTTL, c.Val())
+		}
+		tc.defaultTTL = uint32(v)
+
+	default:
+		return fmt.Errorf("unknown tenant directive %q", key)
+	}
+	return nil
+}
+
+// tenantPolicies resolves the parsed tenant blocks against the top-level
+// sync_policy, which may appear before or after them.
+func (cfg *pluginConfig) tenantPolicies() map[string]TenantPolicy {
+	out := make(map[string]TenantPolicy, len(cfg.tenants))
+	for name, tc := range cfg.tenants {
+		p := TenantPolicy{SyncPolicy: cfg.syncPolicy, DefaultTTL: tc.defaultTTL}
+		if tc.policy != nil {
+			p.SyncPolicy = *tc.policy
+		}
+		out[name] = p
+	}
+	return out
+}
+
+func parseDNSSECDirective(key string, c *caddy.Controller, cfg *pluginConfig) error {
+	switch key {
+	case "key":
+		args := c.RemainingArgs()
+		if len(args) != 1 {
+			return fmt.Errorf("dnssec key requires exactly one FILE argument")
+		}
+		if cfg.dnssecKey != "" {
+			return fmt.Errorf("dnssec supports a single key")
+		}
+		cfg.dnssecKey = args[0]
+	default:
+		return fmt.Errorf("unknown dnssec directive %q", key)
+	}
+	return nil
+}
+
+func parseSOADirective(key string, c *caddy.Controller, cfg *pluginConfig) error {
+	switch key {
+	case "mname":
+		if !c.NextArg() {
+			return fmt.Errorf("soa mname requires a name")
+		}
+		if _, ok := dns.IsDomainName(c.Val()); !ok {
+			return fmt.Errorf("soa mname %q is not a valid domain name", c.Val())
+		}
+		cfg.soa.MName = c.Val()
+
+	case "rname":
+		if !c.NextArg() {
+			return fmt.Errorf("soa rname requires a name")
+		}
+		if _, ok := dns.IsDomainName(c.Val()); !ok {
+			return fmt.Errorf("soa rname %q is not a valid domain name", c.Val())
+		}
+		cfg.soa.RName = c.Val()
+
+	case "refresh", "retry", "expire", "minttl", "serial":
+		if !c.NextArg() {
+			return fmt.Errorf("soa %s requires a numeric argument", key)
+		}
+		n, err := strconv.ParseUint(c.Val(), 10, 32)
+		if err != nil || n == 0 {
+			return fmt.Errorf("soa %s must be a positive 32-bit integer: %q", key, c.Val())
+		}
+		switch key {
+		case "refresh":
+			cfg.soa.Refresh = uint32(n)
+		case "retry":
+			cfg.soa.Retry = uint32(n)
+		case "expire":
+			cfg.soa.Expire = uint32(n)
+		case "minttl":
+			cfg.soa.MinTTL = uint32(n)
+		case "serial":
+			cfg.soa.Serial = uint32(n)
+		}
+
+	default:
+		return fmt.Errorf("unknown soa directive %q", key)
+	}
+	return nil
+}
+
+// parsePercent parses a percentage such as "10%" (the sign is optional) into
+// a fraction. It must be greater than 0 and less than 100.
+func parsePercent(s string) (float64, error) {
+	p, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	if err != nil || p <= 0 || p >= 100 {
+		return 0, fmt.Errorf("%q is not a percentage between 0 and 100 exclusive", s)
+	}
+	return p / 100, nil
+}
+
+// parseTLSOption parses a tls_min_version or tls_ciphers line of the named
+// block into opts.
+func parseTLSOption(block, key string, c *caddy.Controller, opts *tlsConfig) error {
+	args := c.RemainingArgs()
+	if key == "tls_min_version" {
+		if len(args) != 1 {
+			return fmt.Errorf("%s tls_min_version requires one version argument (1.2 or 1.3)", block)
+		}
+		v, err := parseTLSVersion(args[0])
+		if err != nil {
+			return fmt.Errorf("%s tls_min_version: %w", block, err)
+		}
+		opts.minVersion = v
+		return nil
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("%s tls_ciphers requires at least one cipher suite", block)
+	}
+	ids, err := parseCipherSuites(args)
+	if err != nil {
+		return fmt.Errorf("%s tls_ciphers: %w", block, err)
+	}
+	opts.ciphers = ids
+	return nil
+}
+
+// mergeTLSOptions copies the block's tls_min_version and tls_ciphers into
+// its tls settings, which they require.
+func mergeTLSOptions(block string, tc *tlsConfig, opts tlsConfig) error {
+	if opts.minVersion == 0 && opts.ciphers == nil {
+		return nil
+	}
+	if tc == nil {
+		return fmt.Errorf("%s tls_min_version and tls_ciphers require tls", block)
+	}
+	// TLS 1.3 suites are not configurable in Go.
+	if opts.minVersion == tls.VersionTLS13 && opts.ciphers != nil {
+		return fmt.Errorf("%s tls_ciphers has no effect with tls_min_version 1.3", block)
+	}
+	tc.minVersion, tc.ciphers = opts.minVersion, opts.ciphers
+	return nil
+}
@@ -4,8 +4,14 @@
 package dynupdate
 
 import (
+	"crypto/tls"
+	"os"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/coredns/caddy"
 )
 
@@ -23,6 +29,39 @@ func TestSetup_ValidMinimal(t *testing.T) {
 	}
 }
 
+func TestSetup_AliasDirective(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	input := `dyndns example.org. {
+		datafile ` + dir + `/records.json
+		sync_policy create-only
+	}`
+
+	c := caddy.NewTestController("dns", input)
+	cfg, err := parseConfig(c)
+	if err != nil {
+		t.Fatalf("parseConfig() error: %v", err)
+	}
+	if len(cfg.zones) != 1 || cfg.zones[0] != "example.org." {
+		t.Errorf("zones = %v, want [example.org.]", cfg.zones)
+	}
+	if cfg.syncPolicy != PolicyCreateOnly {
+		t.Errorf("syncPolicy = %v, want create-only", cfg.syncPolicy)
+	}
+
+	c = caddy.NewTestController("dns", input)
+	if err := setup(c); err != nil {
+		t.Fatalf("setup() error: %v", err)
+	}
+
+	registered := caddy.ListPlugins()["others"]
+	for _, name := range []string{pluginName, pluginAlias} {
+		if !slices.Contains(registered, "dns."+name) {
+			t.Errorf("directive %q not registered; plugins = %v", name, registered)
+		}
+	}
+}
+
 func TestSetup_ValidWithAPI(t *testing.T) {
 	t.Parallel()
 	dir := t.TempDir()
@@ -57,6 +96,24 @@ func TestSetup_MissingDatafile(t *testing.T) {
 	}
 }
 
+func TestSetup_DatafileDirUnwritable(t *testing.T) {
+	t.Parallel()
+	// The datafile's parent is a regular file, so no directory can be made.
+	parent := t.TempDir() + "/data"
+	if err := os.WriteFile(parent, nil, 0o644); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+	input := `dynupdate example.org. {
+		datafile ` + parent + `/records.json
+	}`
+
+	c := caddy.NewTestController("dns", input)
+	err := setup(c)
+	if err == nil || !strings.Contains(err.Error(), "data directory") {
+		t.Fatalf("setup() error = %v, want a data directory error", err)
+	}
+}
+
 func TestSetup_InvalidReloadDuration(t *testing.T) {
 	t.Parallel()
 	dir := t.TempDir()
@@ -273,3 +330,1193 @@ func TestSetup_FallthroughWithZones(t *testing.T) {
 		t.Fatalf("setup() error: %v", err)
 	}
 }
+
+func TestSetup_SOABlock(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	input := `dynupdate example.org. {
+		datafile ` + dir + `/records.json
+
+		soa {
+			mname   ns.example.net.
+			rname   dns-admin
+			refresh 3600
+			retry   600
+			expire  604800
+			minttl  60
+			serial  2024010101
+		}
+	}`
+
+	c := caddy.NewTestController("dns", input)
+	cfg, err := parseConfig(c)
+	if err != nil {
+		t.Fatalf("parseConfig() error: %v", err)
+	}
+
+	want := SOAConfig{
+		MName:   "ns.example.net.",
+		RName:   "dns-admin",
+		Refresh: 3600,
+		Retry:   600,
+		Expire:  604800,
+		MinTTL:  60,
+		Serial:  2024010101,
+	}
+	if cfg.soa != want {
+		t.Errorf("soa = %+v, want %+v", cfg.soa, want)
+	}
+}
+
+func TestSetup_SOABlockPartial(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	input := `dynupdate example.org. {
+		datafile ` + dir + `/records.json
+
+		soa {
+			minttl 120
+		}
+	}`
+
+	c := caddy.NewTestController("dns", input)
+	cfg, err := parseConfig(c)
+	if err != nil {
+		t.Fatalf("parseConfig() error: %v", err)
+	}
+	if cfg.soa.MinTTL != 120 {
+		t.Errorf("soa.MinTTL = %d, want 120", cfg.soa.MinTTL)
+	}
+	if cfg.soa.Serial != 0 {
+		t.Errorf("soa.Serial = %d, want 0 (generation-derived)", cfg.soa.Serial)
+	}
+}
+
+func TestSetup_SOAInvalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		soa  string
+	}{
+		{"non-numeric refresh", "refresh soon"},
+		{"zero retry", "retry 0"},
+		{"overflow expire", "expire 4294967296"},
+		{"missing serial", "serial"},
+		{"invalid mname", "mname bad..name"},
+		{"unknown directive", "owner me"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			input := `dynupdate example.org. {
+				datafile ` + dir + `/records.json
+				soa {
+					` + tt.soa + `
+				}
+			}`
+
+			c := caddy.NewTestController("dns", input)
+			if _, err := parseConfig(c); err == nil {
+				t.Errorf("parseConfig() expected error for soa %q", tt.soa)
+			}
+		})
+	}
+}
+
+func TestSetup_APIH2C(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	input := `dynupdate example.org. {
+		datafile ` + dir + `/records.json
+
+		api {
+			listen :18080
+			token  api-secret
+			h2c
+		}
+	}`
+
+	c := caddy.NewTestController("dns", input)
+	cfg, err := parseConfig(c)
+	if err != nil {
+		t.Fatalf("parseConfig() error: %v", err)
+	}
+	if !cfg.apiH2C {
+		t.Error("apiH2C = false, want true")
+	}
+}
+
+func TestSetup_APIMetrics(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		line    string
+		want    bool
+		wantErr bool
+	}{
+		{name: "default off", want: false},
+		{name: "enabled", line: "metrics", want: true},
+		{name: "extra argument", line: "metrics on", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := caddy.NewTestController("dns", `dynupdate example.org. {
+		datafile `+t.TempDir()+`/records.json
+		api {
+			listen :18080
+			token  api-secret
+			`+tt.line+`
+		}
+	}`)
+			cfg, err := parseConfig(c)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("parseConfig() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseConfig() error: %v", err)
+			}
+			if cfg.apiMetrics != tt.want {
+				t.Errorf("apiMetrics = %v, want %v", cfg.apiMetrics, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetup_APIAccessLog(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		line    string
+		want    bool
+		wantErr bool
+	}{
+		{name: "default off", want: false},
+		{name: "enabled", line: "access_log", want: true},
+		{name: "extra argument", line: "access_log stdout", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := caddy.NewTestController("dns", `dynupdate example.org. {
+		datafile `+t.TempDir()+`/records.json
+		api {
+			listen :18080
+			token  api-secret
+			`+tt.line+`
+		}
+	}`)
+			cfg, err := parseConfig(c)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("parseConfig() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseConfig() error: %v", err)
+			}
+			if cfg.apiAccessLog != tt.want {
+				t.Errorf("apiAccessLog = %v, want %v", cfg.apiAccessLog, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetup_RoundRobin(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	input := `dynupdate example.org. {
+		datafile ` + dir + `/records.json
+		round_robin
+	}`
+
+	c := caddy.NewTestController("dns", input)
+	cfg, err := parseConfig(c)
+	if err != nil {
+		t.Fatalf("parseConfig() error: %v", err)
+	}
+	if !cfg.roundRobin {
+		t.Error("roundRobin = false, want true")
+	}
+
+	c = caddy.NewTestController("dns", `dynupdate example.org. {
+		datafile `+dir+`/records.json
+		round_robin yes
+	}`)
+	if _, err := parseConfig(c); err == nil {
+		t.Error("parseConfig() with round_robin argument: expected error")
+	}
+}
+
+func TestSetup_Weighted(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		lines   string
+		wantErr bool
+	}{
+		{name: "enabled", lines: "weighted"},
+		{name: "argument", lines: "weighted yes", wantErr: true},
+		{name: "with round_robin", lines: "weighted\n\t\tround_robin", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := caddy.NewTestController("dns", `dynupdate example.org. {
+		datafile `+dir+`/records.json
+		`+tt.lines+`
+	}`)
+			cfg, err := parseConfig(c)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("parseConfig() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseConfig() error: %v", err)
+			}
+			if !cfg.weighted {
+				t.Error("weighted = false, want true")
+			}
+		})
+	}
+}
+
+func TestSetup_WildcardNXDOMAIN(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	c := caddy.NewTestController("dns", `dynupdate example.org. {
+		datafile `+dir+`/records.json
+		wildcard_nxdomain
+	}`)
+	cfg, err := parseConfig(c)
+	if err != nil {
+		t.Fatalf("parseConfig() error: %v", err)
+	}
+	if !cfg.wildcardNXDOMAIN {
+		t.Error("wildcardNXDOMAIN = false, want true")
+	}
+
+	c = caddy.NewTestController("dns", `dynupdate example.org. {
+		datafile `+dir+`/records.json
+		wildcard_nxdomain yes
+	}`)
+	if _, err := parseConfig(c); err == nil {
+		t.Error("parseConfig() with wildcard_nxdomain argument: expected error")
+	}
+}
+
+func TestSetup_PTRCheck(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		line    string
+		want    PTRCheck
+		wantErr bool
+	}{
+		{name: "default off", want: PTRCheckOff},
+		{name: "warn", line: "ptr_check warn", want: PTRCheckWarn},
+		{name: "reject", line: "ptr_check REJECT", want: PTRCheckReject},
+		{name: "missing argument", line: "ptr_check", wantErr: true},
+		{name: "unknown mode", line: "ptr_check strict", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := caddy.NewTestController("dns", `dynupdate example.org. {
+		datafile `+t.TempDir()+`/records.json
+		`+tt.line+`
+	}`)
+			cfg, err := parseConfig(c)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("parseConfig() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseConfig() error: %v", err)
+			}
+			if cfg.ptrCheck != tt.want {
+				t.Errorf("ptrCheck = %v, want %v", cfg.ptrCheck, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetup_TTLJitter(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		line    string
+		want    float64
+		wantErr bool
+	}{
+		{name: "default off", want: 0},
+		{name: "percent", line: "ttl_jitter 10%", want: 0.1},
+		{name: "without sign", line: "ttl_jitter 25", want: 0.25},
+		{name: "zero", line: "ttl_jitter 0%", wantErr: true},
+		{name: "hundred", line: "ttl_jitter 100%", wantErr: true},
+		{name: "not a number", line: "ttl_jitter abc", wantErr: true},
+		{name: "missing argument", line: "ttl_jitter", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := caddy.NewTestController("dns", `dynupdate example.org. {
+		datafile `+t.TempDir()+`/records.json
+		`+tt.line+`
+	}`)
+			cfg, err := parseConfig(c)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("parseConfig() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseConfig() error: %v", err)
+			}
+			if cfg.ttlJitter != tt.want {
+				t.Errorf("ttlJitter = %v, want %v", cfg.ttlJitter, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetup_MinServeTTL(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		line    string
+		want    uint32
+		wantErr bool
+	}{
+		{name: "default off", want: 0},
+		{name: "seconds", line: "min_serve_ttl 30", want: 30},
+		{name: "zero", line: "min_serve_ttl 0", wantErr: true},
+		{name: "above max", line: "min_serve_ttl 86401", wantErr: true},
+		{name: "duration", line: "min_serve_ttl 30s", wantErr: true},
+		{name: "missing argument", line: "min_serve_ttl", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := caddy.NewTestController("dns", `dynupdate example.org. {
+		datafile `+t.TempDir()+`/records.json
+		`+tt.line+`
+	}`)
+			cfg, err := parseConfig(c)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("parseConfig() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseConfig() error: %v", err)
+			}
+			if cfg.minServeTTL != tt.want {
+				t.Errorf("minServeTTL = %d, want %d", cfg.minServeTTL, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetup_AnswerOrder(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		lines   string
+		want    AnswerOrder
+		wantErr bool
+	}{
+		{name: "default none", want: OrderNone},
+		{name: "value", lines: "answer_order value", want: OrderValue},
+		{name: "ttl", lines: "answer_order TTL", want: OrderTTL},
+		{name: "missing argument", lines: "answer_order", wantErr: true},
+		{name: "unknown order", lines: "answer_order random", wantErr: true},
+		{name: "with round_robin", lines: "answer_order type\n\t\tround_robin", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := caddy.NewTestController("dns", `dynupdate example.org. {
+		datafile `+t.TempDir()+`/records.json
+		`+tt.lines+`
+	}`)
+			cfg, err := parseConfig(c)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("parseConfig() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseConfig() error: %v", err)
+			}
+			if cfg.answerOrder != tt.want {
+				t.Errorf("answerOrder = %v, want %v", cfg.answerOrder, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetup_NegativeCache(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		line     string
+		wantSize int
+		wantTTL  time.Duration
+		wantErr  bool
+	}{
+		{name: "default off"},
+		{name: "size", line: "negative_cache 1000", wantSize: 1000},
+		{name: "size and duration", line: "negative_cache 50 30s", wantSize: 50, wantTTL: 30 * time.Second},
+		{name: "missing size", line: "negative_cache", wantErr: true},
+		{name: "zero size", line: "negative_cache 0", wantErr: true},
+		{name: "invalid duration", line: "negative_cache 10 soon", wantErr: true},
+		{name: "extra argument", line: "negative_cache 10 5s 5s", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := caddy.NewTestController("dns", `dynupdate example.org. {
+		datafile `+t.TempDir()+`/records.json
+		`+tt.line+`
+	}`)
+			cfg, err := parseConfig(c)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("parseConfig() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseConfig() error: %v", err)
+			}
+			if cfg.negCacheSize != tt.wantSize || cfg.negCacheTTL != tt.wantTTL {
+				t.Errorf("negative_cache = %d %v, want %d %v", cfg.negCacheSize, cfg.negCacheTTL, tt.wantSize, tt.wantTTL)
+			}
+		})
+	}
+}
+
+func TestSetup_ServeDelay(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		line    string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "default off", want: 0},
+		{name: "duration", line: "serve_delay 5s", want: 5 * time.Second},
+		{name: "zero", line: "serve_delay 0s", wantErr: true},
+		{name: "invalid", line: "serve_delay soon", wantErr: true},
+		{name: "missing argument", line: "serve_delay", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := caddy.NewTestController("dns", `dynupdate example.org. {
+		datafile `+t.TempDir()+`/records.json
+		`+tt.line+`
+	}`)
+			cfg, err := parseConfig(c)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("parseConfig() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseConfig() error: %v", err)
+			}
+			if cfg.serveDelay != tt.want {
+				t.Errorf("serveDelay = %v, want %v", cfg.serveDelay, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetup_TLSOptions(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		block       string
+		wantVersion uint16
+		wantCiphers int
+		wantErr     bool
+	}{
+		{name: "api 1.3 only", block: "api {\n\t\t\tlisten :0\n\t\t\tno_auth\n\t\t\ttls_min_version 1.3\n\t\t\ttls cert.pem key.pem ca.pem\n\t\t}", wantVersion: tls.VersionTLS13},
+		{name: "grpc ciphers", block: "grpc {\n\t\t\tlisten :0\n\t\t\tno_auth\n\t\t\ttls cert.pem key.pem ca.pem\n\t\t\ttls_ciphers TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256\n\t\t}", wantCiphers: 2},
+		{name: "invalid version", block: "api {\n\t\t\tlisten :0\n\t\t\tno_auth\n\t\t\ttls cert.pem key.pem ca.pem\n\t\t\ttls_min_version 1.1\n\t\t}", wantErr: true},
+		{name: "unknown cipher", block: "grpc {\n\t\t\tlisten :0\n\t\t\tno_auth\n\t\t\ttls cert.pem key.pem ca.pem\n\t\t\ttls_ciphers TLS_FAKE\n\t\t}", wantErr: true},
+		{name: "ciphers with 1.3", block: "api {\n\t\t\tlisten :0\n\t\t\tno_auth\n\t\t\ttls cert.pem key.pem ca.pem\n\t\t\ttls_min_version 1.3\n\t\t\ttls_ciphers TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256\n\t\t}", wantErr: true},
+		{name: "without tls", block: "api {\n\t\t\tlisten :0\n\t\t\tno_auth\n\t\t\ttls_min_version 1.3\n\t\t}", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := caddy.NewTestController("dns", `dynupdate example.org. {
+		datafile `+t.TempDir()+`/records.json
+		`+tt.block+`
+	}`)
+			cfg, err := parseConfig(c)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("parseConfig() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseConfig() error: %v", err)
+			}
+			tc := cfg.apiTLS
+			if tc == nil {
+				tc = cfg.grpcTLS
+			}
+			if tc.minVersion != tt.wantVersion || len(tc.ciphers) != tt.wantCiphers {
+				t.Errorf("tls = %+v, want minVersion %#x and %d ciphers", tc, tt.wantVersion, tt.wantCiphers)
+			}
+		})
+	}
+}
+
+func TestSetup_AuditFile(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	c := caddy.NewTestController("dns", `dynupdate example.org. {
+		datafile `+dir+`/records.json
+		audit_file `+dir+`/audit.log
+	}`)
+	cfg, err := parseConfig(c)
+	if err != nil {
+		t.Fatalf("parseConfig() error: %v", err)
+	}
+	if want := dir + "/audit.log"; cfg.auditFile != want {
+		t.Errorf("auditFile = %q, want %q", cfg.auditFile, want)
+	}
+
+	c = caddy.NewTestController("dns", `dynupdate example.org. {
+		datafile `+dir+`/records.json
+		audit_file
+	}`)
+	if _, err := parseConfig(c); err == nil {
+		t.Error("parseConfig() expected error for audit_file without a path")
+	}
+}
+
+func TestSetup_OnLoadConflict(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	c := caddy.NewTestController("dns", `dynupdate example.org. {
+		datafile `+dir+`/records.json
+		on_load_conflict reject
+	}`)
+	cfg, err := parseConfig(c)
+	if err != nil {
+		t.Fatalf("parseConfig() error: %v", err)
+	}
+	if cfg.onConflict != ConflictReject {
+		t.Errorf("onConflict = %v, want %v", cfg.onConflict, ConflictReject)
+	}
+
+	for _, bad := range []string{"on_load_conflict", "on_load_conflict last"} {
+		c := caddy.NewTestController("dns", "dynupdate example.org. {\ndatafile "+dir+"/records.json\n"+bad+"\n}")
+		if _, err := parseConfig(c); err == nil {
+			t.Errorf("parseConfig(%q) expected error", bad)
+		}
+	}
+}
+
+func TestSetup_AllowRoot(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	input := `dynupdate example.org. {
+		datafile ` + dir + `/records.json
+		allow_root
+	}`
+
+	c := caddy.NewTestController("dns", input)
+	cfg, err := parseConfig(c)
+	if err != nil {
+		t.Fatalf("parseConfig() error: %v", err)
+	}
+	if !cfg.allowRoot {
+		t.Error("allowRoot = false, want true")
+	}
+
+	c = caddy.NewTestController("dns", `dynupdate example.org. {
+		datafile `+dir+`/records.json
+		allow_root yes
+	}`)
+	if _, err := parseConfig(c); err == nil {
+		t.Error("parseConfig() with allow_root argument: expected error")
+	}
+}
+
+func TestSetup_Ownership(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	input := `dynupdate example.org. {
+		datafile ` + dir + `/records.json
+		ownership root
+
+		api {
+			listen :18080
+			token  secret-a tenant-a
+			token  secret-root root
+		}
+	}`
+
+	c := caddy.NewTestController("dns", input)
+	cfg, err := parseConfig(c)
+	if err != nil {
+		t.Fatalf("parseConfig() error: %v", err)
+	}
+	if !cfg.ownership {
+		t.Error("ownership = false, want true")
+	}
+	if len(cfg.ownershipAdmins) != 1 || cfg.ownershipAdmins[0] != "root" {
+		t.Errorf("ownershipAdmins = %v, want [root]", cfg.ownershipAdmins)
+	}
+	if cfg.apiTokens["secret-a"] != "tenant-a" || cfg.apiTokens["secret-root"] != "root" {
+		t.Errorf("apiTokens = %v", cfg.apiTokens)
+	}
+}
+
+func TestSetup_OwnershipRequiresIdentity(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	tests := []struct {
+		name string
+		api  string
+	}{
+		{name: "unnamed token", api: "token secret"},
+		{name: "no_auth", api: "no_auth"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := `dynupdate example.org. {
+				datafile ` + dir + `/records.json
+				ownership
+				api {
+					listen :18080
+					` + tt.api + `
+				}
+			}`
+			c := caddy.NewTestController("dns", input)
+			if _, err := parseConfig(c); err == nil {
+				t.Error("parseConfig() expected error")
+			}
+		})
+	}
+}
+
+func TestSetup_Transfer(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	c := caddy.NewTestController("dns", `dynupdate example.org. {
+		datafile `+dir+`/records.json
+		transfer to 10.0.0.2 192.0.2.0/24 2001:db8::/32
+	}`)
+	cfg, err := parseConfig(c)
+	if err != nil {
+		t.Fatalf("parseConfig() error: %v", err)
+	}
+	want := []string{"10.0.0.2/32", "192.0.2.0/24", "2001:db8::/32"}
+	if len(cfg.transferTo) != len(want) {
+		t.Fatalf("transferTo = %v, want %v", cfg.transferTo, want)
+	}
+	for i, p := range cfg.transferTo {
+		if p.String() != want[i] {
+			t.Errorf("transferTo[%d] = %s, want %s", i, p, want[i])
+		}
+	}
+
+	for _, bad := range []string{"transfer", "transfer 10.0.0.1", "transfer to nope"} {
+		c := caddy.NewTestController("dns", `dynupdate example.org. {
+			datafile `+dir+`/records.json
+			`+bad+`
+		}`)
+		if _, err := parseConfig(c); err == nil {
+			t.Errorf("parseConfig(%q) expected error", bad)
+		}
+	}
+}
+
+func TestSetup_RedisBackend(t *testing.T) {
+	t.Parallel()
+	mr := miniredis.RunT(t)
+
+	c := caddy.NewTestController("dns", `dynupdate example.org. {
+		backend redis `+mr.Addr()+`
+	}`)
+	cfg, err := parseConfig(c)
+	if err != nil {
+		t.Fatalf("parseConfig() without datafile: %v", err)
+	}
+	if cfg.backend != "redis" || cfg.backendAddr != mr.Addr() {
+		t.Fatalf("backend = %q %q, want redis %q", cfg.backend, cfg.backendAddr, mr.Addr())
+	}
+
+	s, err := newStore(cfg, nil)
+	if err != nil {
+		t.Fatalf("newStore() error: %v", err)
+	}
+	defer s.Stop()
+	if err := s.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	if !mr.Exists(redisRecordsKey) {
+		t.Error("record was not written to redis")
+	}
+
+	for _, bad := range []string{"backend", "backend redis", "backend memcached host:1", "backend file extra"} {
+		c := caddy.NewTestController("dns", `dynupdate example.org. {
+			datafile /tmp/records.json
+			`+bad+`
+		}`)
+		if _, err := parseConfig(c); err == nil {
+			t.Errorf("parseConfig(%q) expected error", bad)
+		}
+	}
+
+	c = caddy.NewTestController("dns", `dynupdate example.org. {
+		backend file
+	}`)
+	if _, err := parseConfig(c); err == nil {
+		t.Error("parseConfig() with file backend and no datafile: expected error")
+	}
+}
+
+func TestSetup_Quota(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	c := caddy.NewTestController("dns", `dynupdate example.org. {
+		datafile `+dir+`/records.json
+		ownership
+		quota tenant-a 10
+		quota tenant-b 0
+	}`)
+	cfg, err := parseConfig(c)
+	if err != nil {
+		t.Fatalf("parseConfig() error: %v", err)
+	}
+	if cfg.quotas["tenant-a"] != 10 || cfg.quotas["tenant-b"] != 0 || len(cfg.quotas) != 2 {
+		t.Errorf("quotas = %v, want tenant-a=10 tenant-b=0", cfg.quotas)
+	}
+
+	for _, bad := range []string{"ownership\nquota tenant-a", "ownership\nquota tenant-a -1", "ownership\nquota tenant-a x", "quota tenant-a 5"} {
+		c := caddy.NewTestController("dns", "dynupdate example.org. {\ndatafile "+dir+"/records.json\n"+bad+"\n}")
+		if _, err := parseConfig(c); err == nil {
+			t.Errorf("parseConfig(%q) expected error", bad)
+		}
+	}
+}
+
+func TestSetup_SQLiteBackend(t *testing.T) {
+	t.Parallel()
+	path := t.TempDir() + "/records.db"
+
+	c := caddy.NewTestController("dns", `dynupdate example.org. {
+		backend sqlite `+path+`
+	}`)
+	cfg, err := parseConfig(c)
+	if err != nil {
+		t.Fatalf("parseConfig() without datafile: %v", err)
+	}
+	if cfg.backend != "sqlite" || cfg.backendAddr != path {
+		t.Fatalf("backend = %q %q, want sqlite %q", cfg.backend, cfg.backendAddr, path)
+	}
+
+	s, err := newStore(cfg, nil)
+	if err != nil {
+		t.Fatalf("newStore() error: %v", err)
+	}
+	defer s.Stop()
+	if err := s.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+
+	c = caddy.NewTestController("dns", `dynupdate example.org. {
+		backend sqlite
+	}`)
+	if _, err := parseConfig(c); err == nil {
+		t.Error("parseConfig() with sqlite backend and no path: expected error")
+	}
+}
+
+func TestSetup_Tenant(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	c := caddy.NewTestController("dns", `dynupdate example.org. {
+		datafile `+dir+`/records.json
+		ownership
+		tenant tenant-a {
+			sync_policy upsert-only
+			default_ttl 120
+		}
+		tenant tenant-b {
+			default_ttl 600
+		}
+		sync_policy create-only
+	}`)
+	cfg, err := parseConfig(c)
+	if err != nil {
+		t.Fatalf("parseConfig() error: %v", err)
+	}
+	got := cfg.tenantPolicies()
+	if want := (TenantPolicy{SyncPolicy: PolicyUpsertOnly, DefaultTTL: 120}); got["tenant-a"] != want {
+		t.Errorf("tenant-a = %+v, want %+v", got["tenant-a"], want)
+	}
+	// Tenants without their own sync_policy inherit the top-level one.
+	if want := (TenantPolicy{SyncPolicy: PolicyCreateOnly, DefaultTTL: 600}); got["tenant-b"] != want {
+		t.Errorf("tenant-b = %+v, want %+v", got["tenant-b"], want)
+	}
+
+	for _, bad := range []string{
+		"ownership\ntenant a {\nsync_policy nope\n}",
+		"ownership\ntenant a {\ndefault_ttl 5\n}",
+		"ownership\ntenant a {\ncolour blue\n}",
+		"ownership\ntenant a {\n}\ntenant a {\n}",
+		"tenant a {\ndefault_ttl 120\n}",
+	} {
+		c := caddy.NewTestController("dns", "dynupdate example.org. {\ndatafile "+dir+"/records.json\n"+bad+"\n}")
+		if _, err := parseConfig(c); err == nil {
+			t.Errorf("parseConfig(%q) expected error", bad)
+		}
+	}
+}
+
+func TestSetup_FlushTimeout(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	c := caddy.NewTestController("dns", `dynupdate example.org. {
+		datafile `+dir+`/records.json
+		flush_timeout 30s
+	}`)
+	cfg, err := parseConfig(c)
+	if err != nil {
+		t.Fatalf("parseConfig() error: %v", err)
+	}
+	if cfg.flushTimeout == nil || *cfg.flushTimeout != 30*time.Second {
+		t.Errorf("flushTimeout = %v, want 30s", cfg.flushTimeout)
+	}
+
+	for _, bad := range []string{"flush_timeout", "flush_timeout soon", "flush_timeout -1s"} {
+		c := caddy.NewTestController("dns", "dynupdate example.org. {\ndatafile "+dir+"/records.json\n"+bad+"\n}")
+		if _, err := parseConfig(c); err == nil {
+			t.Errorf("parseConfig(%q) expected error", bad)
+		}
+	}
+}
+
+func TestSetup_MaxNames(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	c := caddy.NewTestController("dns", `dynupdate example.org. {
+		datafile `+dir+`/records.json
+		max_names 500
+	}`)
+	cfg, err := parseConfig(c)
+	if err != nil {
+		t.Fatalf("parseConfig() error: %v", err)
+	}
+	if cfg.maxNames != 500 {
+		t.Errorf("maxNames = %d, want 500", cfg.maxNames)
+	}
+
+	for _, bad := range []string{"max_names", "max_names many", "max_names -1"} {
+		c := caddy.NewTestController("dns", "dynupdate example.org. {\ndatafile "+dir+"/records.json\n"+bad+"\n}")
+		if _, err := parseConfig(c); err == nil {
+			t.Errorf("parseConfig(%q) expected error", bad)
+		}
+	}
+}
+
+func TestSetup_MaxRecordsPerZone(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	c := caddy.NewTestController("dns", `dynupdate example.org. example.net. {
+		datafile `+dir+`/records.json
+		max_records_per_zone 100
+	}`)
+	cfg, err := parseConfig(c)
+	if err != nil {
+		t.Fatalf("parseConfig() error: %v", err)
+	}
+	if cfg.maxPerZone != 100 {
+		t.Errorf("maxPerZone = %d, want 100", cfg.maxPerZone)
+	}
+
+	for _, bad := range []string{"max_records_per_zone", "max_records_per_zone many", "max_records_per_zone -1"} {
+		c := caddy.NewTestController("dns", "dynupdate example.org. {\ndatafile "+dir+"/records.json\n"+bad+"\n}")
+		if _, err := parseConfig(c); err == nil {
+			t.Errorf("parseConfig(%q) expected error", bad)
+		}
+	}
+}
+
+func TestSetup_MaxValuesPerRRset(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	c := caddy.NewTestController("dns", `dynupdate example.org. {
+		datafile `+dir+`/records.json
+		max_values_per_rrset 16
+	}`)
+	cfg, err := parseConfig(c)
+	if err != nil {
+		t.Fatalf("parseConfig() error: %v", err)
+	}
+	if cfg.maxPerSet != 16 {
+		t.Errorf("maxPerSet = %d, want 16", cfg.maxPerSet)
+	}
+
+	for _, bad := range []string{"max_values_per_rrset", "max_values_per_rrset many", "max_values_per_rrset -1"} {
+		c := caddy.NewTestController("dns", "dynupdate example.org. {\ndatafile "+dir+"/records.json\n"+bad+"\n}")
+		if _, err := parseConfig(c); err == nil {
+			t.Errorf("parseConfig(%q) expected error", bad)
+		}
+	}
+}
+
+func TestSetup_Scopes(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	c := caddy.NewTestController("dns", `dynupdate example.org. {
+		datafile `+dir+`/records.json
+		api {
+			listen :18080
+			token secret-mon monitor
+			token secret-ci ci
+			scope monitor read
+			scope ci read write
+		}
+		grpc {
+			listen :18081
+			allowed_cn reader.example.org
+			scope reader.example.org READ
+		}
+	}`)
+	cfg, err := parseConfig(c)
+	if err != nil {
+		t.Fatalf("parseConfig() error: %v", err)
+	}
+	if got := cfg.apiScopes["monitor"]; !slices.Equal(got, []Scope{ScopeRead}) {
+		t.Errorf("api monitor scopes = %v, want [read]", got)
+	}
+	if got := cfg.apiScopes["ci"]; !slices.Equal(got, []Scope{ScopeRead, ScopeWrite}) {
+		t.Errorf("api ci scopes = %v, want [read write]", got)
+	}
+	if got := cfg.grpcScopes["reader.example.org"]; !slices.Equal(got, []Scope{ScopeRead}) {
+		t.Errorf("grpc reader scopes = %v, want [read]", got)
+	}
+
+	for _, bad := range []string{
+		"token s mon\nscope mon",
+		"token s mon\nscope mon everything",
+		"token s mon\nscope mon read\nscope mon write",
+		"token s mon\nscope typo read",
+		"no_auth\nscope mon read",
+	} {
+		c := caddy.NewTestController("dns", "dynupdate example.org. {\ndatafile "+dir+"/records.json\napi {\nlisten :18080\n"+bad+"\n}\n}")
+		if _, err := parseConfig(c); err == nil {
+			t.Errorf("parseConfig(%q) expected error", bad)
+		}
+	}
+}
+
+func TestSetup_APIRateLimit(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	c := caddy.NewTestController("dns", `dynupdate example.org. {
+		datafile `+dir+`/records.json
+		api {
+			listen :18080
+			token secret
+			rate_limit 5.5 20
+		}
+	}`)
+	cfg, err := parseConfig(c)
+	if err != nil {
+		t.Fatalf("parseConfig() error: %v", err)
+	}
+	if cfg.apiRPS != 5.5 || cfg.apiBurst != 20 {
+		t.Errorf("rate_limit = %v/%d, want 5.5/20", cfg.apiRPS, cfg.apiBurst)
+	}
+
+	for _, bad := range []string{"rate_limit", "rate_limit 10", "rate_limit 0 5", "rate_limit fast 5", "rate_limit 10 0", "rate_limit 10 1.5"} {
+		c := caddy.NewTestController("dns", "dynupdate example.org. {\ndatafile "+dir+"/records.json\napi {\nlisten :18080\ntoken secret\n"+bad+"\n}\n}")
+		if _, err := parseConfig(c); err == nil {
+			t.Errorf("parseConfig(%q) expected error", bad)
+		}
+	}
+}
+
+func TestSetup_APIMaxInflight(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	c := caddy.NewTestController("dns", `dynupdate example.org. {
+		datafile `+dir+`/records.json
+		api {
+			listen :18080
+			token secret
+			max_inflight 100
+		}
+	}`)
+	cfg, err := parseConfig(c)
+	if err != nil {
+		t.Fatalf("parseConfig() error: %v", err)
+	}
+	if cfg.apiInflight != 100 {
+		t.Errorf("max_inflight = %d, want 100", cfg.apiInflight)
+	}
+
+	for _, bad := range []string{"max_inflight", "max_inflight 0", "max_inflight -1", "max_inflight many"} {
+		c := caddy.NewTestController("dns", "dynupdate example.org. {\ndatafile "+dir+"/records.json\napi {\nlisten :18080\ntoken secret\n"+bad+"\n}\n}")
+		if _, err := parseConfig(c); err == nil {
+			t.Errorf("parseConfig(%q) expected error", bad)
+		}
+	}
+}
+
+func TestSetup_APIMaxTemplateRecords(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	c := caddy.NewTestController("dns", `dynupdate example.org. {
+		datafile `+dir+`/records.json
+		api {
+			listen :18080
+			token secret
+			max_template_records 5000
+		}
+	}`)
+	cfg, err := parseConfig(c)
+	if err != nil {
+		t.Fatalf("parseConfig() error: %v", err)
+	}
+	if cfg.apiTemplate != 5000 {
+		t.Errorf("max_template_records = %d, want 5000", cfg.apiTemplate)
+	}
+
+	for _, bad := range []string{"max_template_records", "max_template_records 0", "max_template_records many"} {
+		c := caddy.NewTestController("dns", "dynupdate example.org. {\ndatafile "+dir+"/records.json\napi {\nlisten :18080\ntoken secret\n"+bad+"\n}\n}")
+		if _, err := parseConfig(c); err == nil {
+			t.Errorf("parseConfig(%q) expected error", bad)
+		}
+	}
+}
+
+func TestSetup_GRPCReflection(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	for _, tt := range []struct {
+		block string
+		want  bool
+	}{
+		{"grpc {\nlisten :19090\ntoken secret\n}", false},
+		{"grpc {\nlisten :19090\ntoken secret\nreflection\n}", true},
+	} {
+		c := caddy.NewTestController("dns", "dynupdate example.org. {\ndatafile "+dir+"/records.json\n"+tt.block+"\n}")
+		cfg, err := parseConfig(c)
+		if err != nil {
+			t.Fatalf("parseConfig(%q) error: %v", tt.block, err)
+		}
+		if cfg.grpcReflection != tt.want {
+			t.Errorf("parseConfig(%q) reflection = %v, want %v", tt.block, cfg.grpcReflection, tt.want)
+		}
+	}
+
+	c := caddy.NewTestController("dns", "dynupdate example.org. {\ndatafile "+dir+"/records.json\ngrpc {\nlisten :19090\ntoken secret\nreflection on\n}\n}")
+	if _, err := parseConfig(c); err == nil {
+		t.Error("parseConfig(reflection on) expected error")
+	}
+}
+
+func TestSetup_DuplicateZones(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	c := caddy.NewTestController("dns", `dynupdate example.org. Example.ORG example.net. example.org {
+		datafile `+dir+`/records.json
+	}`)
+	cfg, err := parseConfig(c)
+	if err != nil {
+		t.Fatalf("parseConfig() error: %v", err)
+	}
+	want := []string{"example.org.", "example.net."}
+	if !slices.Equal(cfg.zones, want) {
+		t.Errorf("zones = %v, want %v", cfg.zones, want)
+	}
+
+	// Zones inherited from the server block are deduplicated too.
+	c = caddy.NewTestController("dns", `dynupdate {
+		datafile `+dir+`/records.json
+	}`)
+	c.ServerBlockKeys = []string{"example.org.", "example.org.:53"}
+	cfg, err = parseConfig(c)
+	if err != nil {
+		t.Fatalf("parseConfig() error: %v", err)
+	}
+	if !slices.Equal(cfg.zones, []string{"example.org."}) {
+		t.Errorf("inherited zones = %v, want [example.org.]", cfg.zones)
+	}
+}
+
+func TestSetup_DNSSEC(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	c := caddy.NewTestController("dns", `dynupdate example.org. {
+		datafile `+dir+`/records.json
+		dnssec {
+			key `+dir+`/Kexample.org.+013+12345
+		}
+	}`)
+	cfg, err := parseConfig(c)
+	if err != nil {
+		t.Fatalf("parseConfig() error: %v", err)
+	}
+	if want := dir + "/Kexample.org.+013+12345"; cfg.dnssecKey != want {
+		t.Errorf("dnssecKey = %q, want %q", cfg.dnssecKey, want)
+	}
+
+	for _, block := range []string{
+		"dnssec {\n}",
+		"dnssec {\nkey\n}",
+		"dnssec {\nkey a b\n}",
+		"dnssec {\nkey a\nkey b\n}",
+		"dnssec {\nnsec3\n}",
+	} {
+		c := caddy.NewTestController("dns", "dynupdate example.org. {\ndatafile "+dir+"/records.json\n"+block+"\n}")
+		if _, err := parseConfig(c); err == nil {
+			t.Errorf("parseConfig(%q) expected error", block)
+		}
+	}
+}
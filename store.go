@@ -1,22 +1,58 @@
-// ABOUTME: Thread-safe in-memory record store with atomic JSON persistence.
-// ABOUTME: Supports CRUD operations, auto-reload on external file changes, and concurrency safety.
+// ABOUTME: Thread-safe in-memory record store that writes mutations through to a Backend.
+// ABOUTME: Supports CRUD operations, auto-reload on external backend changes, and concurrency safety.
 
 package dynupdate
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/miekg/dns"
 )
 
+// defaultSweepInterval is how often expired records are removed and due TTL
+// windows are restored.
+const defaultSweepInterval = 10 * time.Second
+
+// defaultFlushTimeout bounds the final backend write made by Stop.
+const defaultFlushTimeout = 10 * time.Second
+
 // ErrPolicyDenied is returned when a mutation is rejected by the sync policy.
 var ErrPolicyDenied = errors.New("operation denied by sync policy")
 
+// ErrNameLimit is returned when a record would add a name beyond the
+// configured maximum number of distinct names.
+var ErrNameLimit = errors.New("name limit reached")
+
+// ErrZoneLimit is returned when a record would take its zone beyond the
+// configured maximum number of records per zone.
+var ErrZoneLimit = errors.New("zone record limit reached")
+
+// ErrRRsetLimit is returned when a record would give its name more values
+// of one type than the configured maximum.
+var ErrRRsetLimit = errors.New("rrset value limit reached")
+
+// limitReached reports whether err is a per-owner, per-zone, per-RRset or
+// name limit rejection, which callers surface as "too many" rather than a
+// failure.
+func limitReached(err error) bool {
+	return errors.Is(err, ErrQuotaExceeded) || errors.Is(err, ErrNameLimit) || errors.Is(err, ErrZoneLimit) ||
+		errors.Is(err, ErrRRsetLimit)
+}
+
+// ErrRecordNotFound is returned when a mutation targets a record that does not exist.
+var ErrRecordNotFound = errors.New("record not found")
+
+// ErrBackendUnavailable is returned for mutations while the backend is
+// failing. DNS queries and reads keep being answered from memory.
+var ErrBackendUnavailable = errors.New("backend unavailable")
+
 // SyncPolicy controls which mutation operations the store permits.
 type SyncPolicy uint8
 
@@ -64,25 +100,67 @@ func (p SyncPolicy) String() string {
 	}
 }
 
-// storeFile is the JSON envelope for persisted records.
-type storeFile struct {
-	Records []Record `json:"records"`
+// MarshalText encodes the policy by name, e.g. in configuration dumps.
+func (p SyncPolicy) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
 }
 
-// Store holds DNS records in memory with optional JSON file backing.
+// UnmarshalText parses a policy name accepted by ParseSyncPolicy.
+func (p *SyncPolicy) UnmarshalText(b []byte) error {
+	v, err := ParseSyncPolicy(string(b))
+	if err != nil {
+		return err
+	}
+	*p = v
+	return nil
+}
+
+// Store holds DNS records in memory and writes every mutation through to a
+// Backend. All reads are served from memory; the backend is only consulted
+// at startup and when it reports changes made by another writer.
 type Store struct {
 	mu         sync.RWMutex
-	records    map[string][]Record // key: lowercase FQDN
-	filePath   string
+	records    map[string][]Record            // key: lowercase FQDN
+	byValue    map[string]map[string]struct{} // value -> set of record keys holding it
+	tree       []treeName                     // every stored name, sorted so a name's descendants follow it
+	names      []string                       // every stored name in canonical order, for paging
+	zoneCounts map[string]int                 // zone -> number of records attributed to it
+	owners     map[string]int                 // owner -> number of records stamped with it
+	backend    Backend
 	reload     time.Duration
-	lastMod    time.Time
+	sweep      time.Duration // expired-record sweep interval; 0 disables
+	serveDelay time.Duration // new records are not served until this long after creation; 0 disables
+	now        func() time.Time
 	stopCh     chan struct{}
 	ready      bool
 	maxRecords int
+	maxNames   int            // distinct names (owner FQDNs); 0 means unlimited
+	zones      []string       // authoritative zones, for maxPerZone
+	maxPerZone int            // records under each zone; 0 means unlimited
+	maxPerSet  int            // values of one type at one name; 0 means unlimited
+	quotas     map[string]int // owner -> maximum records; owners without an entry are unlimited
+	tenants    map[string]TenantPolicy
 	syncPolicy SyncPolicy
-	persistMu  sync.Mutex // serializes file writes, independent of mu
+	allowRoot  bool       // accept records named "." in validateRecord
+	ptrCheck   PTRCheck   // handling of PTR records outside reverse zones in validateRecord
+	persistMu  sync.Mutex // serializes mutations with their backend writes and reloads; acquired before mu
 	generation uint64     // incremented on each mutation (under mu)
-	persisted  uint64     // generation of last successful persist (under persistMu, updated under mu)
+	persisted  uint64     // generation of last successful backend write (under persistMu)
+
+	flushTimeout time.Duration    // bound on the final write in Stop; 0 disables it
+	loadConflict LoadConflictMode // handling of CNAME conflicts in loaded record sets
+	audit        *AuditLog        // committed mutations are appended here; optional
+
+	subs    subscribers
+	pending []ChangeEvent          // events of the mutation being applied (under persistMu)
+	touched map[RecordKey]struct{} // records the mutation being applied added or updated (under persistMu)
+
+	undo    undoLog  // original record sets of the names the mutation being applied touched (under persistMu)
+	removed []Record // records the mutation being applied removed (under persistMu)
+	history history  // changes by generation, for ChangedSince (under mu)
+
+	health    storeHealth
+	backendID string // backendName(backend), the backend_degraded label
 }
 
 // StoreOption configures optional Store behaviour.
@@ -96,6 +174,129 @@ func WithMaxRecords(n int) StoreOption {
 	}
 }
 
+// WithMaxNames sets the maximum number of distinct names the store will
+// hold. Records added to an existing name do not count against it.
+// A value of 0 (default) means unlimited.
+func WithMaxNames(n int) StoreOption {
+	return func(s *Store) {
+		s.maxNames = n
+	}
+}
+
+// WithZones tells the store which zones it serves. Records are attributed
+// to the longest zone containing their name; WithMaxRecordsPerZone needs
+// it, and CNAMEs are refused at each zone's apex.
+func WithZones(zones ...string) StoreOption {
+	return func(s *Store) {
+		s.zones = zones
+	}
+}
+
+// WithMaxRecordsPerZone sets the maximum number of records each zone given
+// to WithZones may hold, so one busy zone cannot starve the others. Names
+// outside every zone are only subject to WithMaxRecords.
+// A value of 0 (default) means unlimited.
+func WithMaxRecordsPerZone(n int) StoreOption {
+	return func(s *Store) {
+		s.maxPerZone = n
+	}
+}
+
+// WithMaxValuesPerRRset sets the maximum number of records one name may
+// hold of each type, bounding answer sizes. A value of 0 (default) means
+// unlimited.
+func WithMaxValuesPerRRset(n int) StoreOption {
+	return func(s *Store) {
+		s.maxPerSet = n
+	}
+}
+
+// WithQuotas limits how many records each named owner may hold, independent
+// of WithMaxRecords. Owners not in the map are unlimited.
+func WithQuotas(q map[string]int) StoreOption {
+	return func(s *Store) {
+		s.quotas = q
+	}
+}
+
+// WithSweepInterval sets how often expired records are removed and the
+// removal persisted. Expired records are never served, so this only bounds
+// how long they linger in the backend. The same sweep restores TTLs whose
+// window has ended, so it also bounds how late a restoration can be.
+// A value of 0 disables sweeping.
+func WithSweepInterval(d time.Duration) StoreOption {
+	return func(s *Store) {
+		s.sweep = d
+	}
+}
+
+// WithFlushTimeout bounds the final backend write Stop makes when the backend
+// may not match memory, for example after a failed write. A value of 0
+// disables the final flush.
+func WithFlushTimeout(d time.Duration) StoreOption {
+	return func(s *Store) {
+		s.flushTimeout = d
+	}
+}
+
+// WithServeDelay holds back each newly created record from DNS answers
+// until d has passed since its creation, e.g. to let replicas reading the
+// same backend catch up. Updates to an existing record are served at once.
+func WithServeDelay(d time.Duration) StoreOption {
+	return func(s *Store) {
+		s.serveDelay = d
+	}
+}
+
+// WithAuditLog records every committed mutation in a, attributed to the
+// authenticated caller. Stop closes it.
+func WithAuditLog(a *AuditLog) StoreOption {
+	return func(s *Store) {
+		s.audit = a
+	}
+}
+
+// WithClock replaces time.Now as the store's source of the current time,
+// which decides record expiry and TTL window restoration. Intended for tests.
+func WithClock(now func() time.Time) StoreOption {
+	return func(s *Store) {
+		s.now = now
+	}
+}
+
+// WithTenantPolicies sets per-owner overrides, resolved from the Owner in
+// the request context at mutation time. Owners not in the map use the
+// store-wide settings.
+func WithTenantPolicies(p map[string]TenantPolicy) StoreOption {
+	return func(s *Store) {
+		s.tenants = p
+	}
+}
+
+// WithAllowRoot lets records be created at the DNS root ("."), which
+// validation otherwise rejects.
+func WithAllowRoot() StoreOption {
+	return func(s *Store) {
+		s.allowRoot = true
+	}
+}
+
+// WithPTRCheck sets how validation treats PTR records whose owner name is
+// not in a reverse zone.
+func WithPTRCheck(c PTRCheck) StoreOption {
+	return func(s *Store) {
+		s.ptrCheck = c
+	}
+}
+
+// WithLoadConflictMode sets what loads and reloads do with names whose
+// records cannot coexist (several CNAMEs, or a CNAME beside other types).
+func WithLoadConflictMode(m LoadConflictMode) StoreOption {
+	return func(s *Store) {
+		s.loadConflict = m
+	}
+}
+
 // WithSyncPolicy sets the mutation policy for the store.
 func WithSyncPolicy(p SyncPolicy) StoreOption {
 	return func(s *Store) {
@@ -103,30 +304,61 @@ func WithSyncPolicy(p SyncPolicy) StoreOption {
 	}
 }
 
-// NewStore creates a store backed by the given file path.
+// NewStore creates a store backed by the given JSON file path.
 // If the file exists, its records are loaded. If not, an empty file is created.
 // A reload duration of 0 disables auto-reload.
 func NewStore(filePath string, reload time.Duration, opts ...StoreOption) (*Store, error) {
+	s, err := NewStoreWithBackend(NewFileBackend(filePath), reload, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("initialising store from %s: %w", filePath, err)
+	}
+	return s, nil
+}
+
+// NewStoreWithBackend creates a store that loads its records from b and
+// writes every mutation back to it. A reload duration of 0 disables polling
+// the backend for changes made by other writers.
+func NewStoreWithBackend(b Backend, reload time.Duration, opts ...StoreOption) (*Store, error) {
 	s := &Store{
-		records:  make(map[string][]Record),
-		filePath: filePath,
-		reload:   reload,
-		stopCh:   make(chan struct{}),
+		records: make(map[string][]Record),
+		byValue: make(map[string]map[string]struct{}),
+		backend: b,
+		reload:  reload,
+		sweep:   defaultSweepInterval,
+		now:     time.Now,
+		stopCh:  make(chan struct{}),
+
+		flushTimeout: defaultFlushTimeout,
+		backendID:    backendName(b),
+
+		history: history{limit: historyLimit},
 	}
 
 	for _, opt := range opts {
 		opt(s)
 	}
 
-	if err := s.loadOrCreate(); err != nil {
-		return nil, fmt.Errorf("initialising store from %s: %w", filePath, err)
+	records, gen, err := b.Load(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	if records, err = s.resolveLoadConflicts(records); err != nil {
+		return nil, err
 	}
+	s.mu.Lock()
+	s.replaceLocked(records, gen)
+	s.mu.Unlock()
+	s.persisted = s.generation
 
+	s.trackDegraded()
 	s.ready = true
 
 	if reload > 0 {
 		go s.run()
 	}
+	if s.sweep > 0 {
+		go s.runSweep()
+	}
 	return s, nil
 }
 
@@ -135,68 +367,437 @@ func (s *Store) Ready() bool {
 	return s.ready
 }
 
-// Stop terminates the auto-reload goroutine.
+// Generation returns the current mutation generation. It increases on every
+// mutation and reload, and is persisted so it survives restarts.
+func (s *Store) Generation() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.generation
+}
+
+// Stop terminates the background goroutines, waits for any in-flight
+// mutation, flushes state that has not reached the backend, and closes the
+// backend. The latest in-memory state is persisted before Stop returns
+// unless the flush is disabled or fails, which is logged.
 func (s *Store) Stop() {
 	select {
 	case <-s.stopCh:
+		return
 	default:
-		close(s.stopCh)
+	}
+	close(s.stopCh)
+
+	if s.flushTimeout > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), s.flushTimeout)
+		if err := s.Flush(ctx); err != nil {
+			log.Errorf("final flush: %v", err)
+		}
+		cancel()
+	}
+	if err := s.backend.Close(); err != nil {
+		log.Errorf("closing backend: %v", err)
+	}
+	if s.audit != nil {
+		if err := s.audit.Close(); err != nil {
+			log.Errorf("closing audit log: %v", err)
+		}
+	}
+	s.untrackDegraded()
+}
+
+// Flush writes the full record set to the backend if a write has failed
+// since the last successful one, after waiting for in-flight mutations to
+// finish. Mutations are normally written through before they return, and
+// a failed one is rolled back, but its write may have reached part of the
+// backend.
+func (s *Store) Flush(ctx context.Context) error {
+	s.persistMu.Lock()
+	defer s.persistMu.Unlock()
+
+	if err := s.rewrite(ctx); err != nil {
+		return fmt.Errorf("flushing records: %w", err)
+	}
+	return nil
+}
+
+// rewrite writes the full record set to the backend if any mutation has
+// not been persisted yet. Caller must hold persistMu.
+func (s *Store) rewrite(ctx context.Context) error {
+	s.mu.RLock()
+	gen := s.generation
+	s.mu.RUnlock()
+	if gen <= s.persisted {
+		return nil
+	}
+	return s.saveAll(ctx, gen)
+}
+
+// saveAll writes the full record set to the backend as generation gen.
+// Caller must hold persistMu.
+func (s *Store) saveAll(ctx context.Context, gen uint64) error {
+	// Nil Names asks the backend for a full rewrite.
+	if err := s.save(ctx, Change{Generation: gen, all: s.snapshot}); err != nil {
+		s.setBackendErr(err)
+		return err
+	}
+	s.setBackendErr(nil)
+	s.persisted = gen
+	return nil
+}
+
+// save writes c to the backend, recording how long the write took.
+func (s *Store) save(ctx context.Context, c Change) error {
+	start := time.Now()
+	err := s.backend.Save(ctx, c)
+	persistDuration.Observe(time.Since(start).Seconds())
+	return err
+}
+
+// catchUp gets a degraded backend back in step before a new mutation is
+// applied. After a failed write the backend is rewritten in full from
+// memory; if that fails too, or the backend failed a reload poll and no
+// write is outstanding, the mutation is rejected with ErrBackendUnavailable
+// before it touches memory. Caller must hold persistMu.
+func (s *Store) catchUp(ctx context.Context) error {
+	if !s.Degraded() {
+		return nil
+	}
+	if err := s.rewrite(context.WithoutCancel(ctx)); err != nil {
+		return fmt.Errorf("%w: %w", ErrBackendUnavailable, err)
+	}
+	// Only the next successful poll clears a failed one.
+	if err := s.backendErr(); err != nil {
+		return fmt.Errorf("%w: %w", ErrBackendUnavailable, err)
+	}
+	return nil
+}
+
+// policyFor returns the sync policy that applies to the caller in ctx.
+func (s *Store) policyFor(ctx context.Context) SyncPolicy {
+	if o, ok := OwnerFromContext(ctx); ok {
+		if t, ok := s.tenants[o.Name]; ok {
+			return t.SyncPolicy
+		}
+	}
+	return s.syncPolicy
+}
+
+// validateRecord runs Record.Validate with the checks this store was
+// configured to relax.
+func (s *Store) validateRecord(r *Record) error {
+	var opts []ValidateOption
+	if s.allowRoot {
+		opts = append(opts, AllowRoot())
+	}
+	if s.ptrCheck == PTRCheckReject {
+		opts = append(opts, ReversePTROnly())
+	}
+	if err := r.Validate(opts...); err != nil {
+		return err
+	}
+	if s.ptrCheck == PTRCheckWarn && r.Type == "PTR" && !IsReverseName(r.Name) {
+		log.Warningf("PTR record %s is not in a reverse zone (in-addr.arpa. or ip6.arpa.)", r.Name)
+	}
+	return nil
+}
+
+// ApplyDefaults prepares a record received from a client of the caller in
+// ctx: the owner is always the caller's, whatever the client sent, and a
+// missing TTL is filled in from the caller's tenant policy. Call it before
+// Record.Validate, which applies the global default TTL.
+func (s *Store) ApplyDefaults(ctx context.Context, r *Record) {
+	r.Owner = ""
+	o, ok := OwnerFromContext(ctx)
+	if !ok {
+		return
+	}
+	r.Owner = o.Name
+	if r.TTL == 0 {
+		r.TTL = s.tenants[o.Name].DefaultTTL
 	}
 }
 
-// Get returns records matching the given FQDN and record type.
-func (s *Store) Get(name, qtype string) []Record {
+// Get returns records matching the given FQDN and record type that are
+// visible to the owner in ctx.
+func (s *Store) Get(ctx context.Context, name, qtype string) []Record {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	key := strings.ToLower(name)
+	now := s.now()
 	var result []Record
 	for _, r := range s.records[key] {
-		if strings.EqualFold(r.Type, qtype) {
+		if strings.EqualFold(r.Type, qtype) && !r.Expired(now) {
 			result = append(result, r)
 		}
 	}
-	return result
+	return filterOwned(ctx, result)
 }
 
-// GetAll returns all records for the given FQDN regardless of type.
-func (s *Store) GetAll(name string) []Record {
+// GetAll returns all records for the given FQDN regardless of type that are
+// visible to the owner in ctx.
+func (s *Store) GetAll(ctx context.Context, name string) []Record {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	key := strings.ToLower(name)
-	recs := s.records[key]
+	recs := liveRecords(s.records[key], s.now())
 	out := make([]Record, len(recs))
 	copy(out, recs)
-	return out
+	return filterOwned(ctx, out)
 }
 
-// List returns every record in the store as a flat slice.
-func (s *Store) List() []Record {
+// Lookup returns the records that answer queries for name. An exact match
+// always wins; otherwise a wildcard owner one label up (e.g. *.apps.example.org.
+// for foo.apps.example.org.) is used per RFC 4592, restricted to a single label.
+// Wildcard matches are returned with Name rewritten to the queried name and
+// wildcard set to true. Expired and disabled records are never returned.
+func (s *Store) Lookup(name string) (records []Record, wildcard bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	var all []Record
-	for _, recs := range s.records {
-		all = append(all, recs...)
+	now := s.now()
+	key := strings.ToLower(name)
+	if recs := servedRecords(s.records[key], now, s.serveDelay); len(recs) > 0 {
+		out := make([]Record, len(recs))
+		copy(out, recs)
+		return out, false
+	}
+
+	i := strings.IndexByte(key, '.')
+	if i < 0 || i == len(key)-1 {
+		return nil, false
+	}
+	// An empty non-terminal exists, so a wildcard does not cover it.
+	if s.hasDescendantLocked(key, now) {
+		return nil, false
+	}
+	recs := servedRecords(s.records["*."+key[i+1:]], now, s.serveDelay)
+	if len(recs) == 0 {
+		return nil, false
+	}
+	out := make([]Record, len(recs))
+	for j, r := range recs {
+		r.Name = name
+		out[j] = r
+	}
+	return out, true
+}
+
+// IsEmptyNonTerminal reports whether name has no records of its own but
+// exists in the tree because a name below it does, e.g. b.example.org. when
+// only a.b.example.org. is stored. Queries for such a name get NODATA rather
+// than NXDOMAIN (RFC 8020).
+func (s *Store) IsEmptyNonTerminal(name string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := s.now()
+	key := strings.ToLower(name)
+	if len(servedRecords(s.records[key], now, s.serveDelay)) > 0 {
+		return false
+	}
+	return s.hasDescendantLocked(key, now)
+}
+
+// Pending reports whether name holds a record that is not served yet
+// because it was created less than the serve delay ago. Such a name exists
+// for DNS, which answers it with NODATA rather than NXDOMAIN.
+func (s *Store) Pending(name string) bool {
+	if s.serveDelay <= 0 {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := s.now()
+	return slices.ContainsFunc(s.records[strings.ToLower(name)], func(r Record) bool {
+		return !r.Disabled && !r.Expired(now) && r.pending(now, s.serveDelay)
+	})
+}
+
+// hasDescendantLocked reports whether any name strictly below key holds a
+// served record. Only the names below key are visited, so a miss on a name
+// with nothing below it costs a binary search. Caller must hold at least RLock.
+func (s *Store) hasDescendantLocked(key string, now time.Time) bool {
+	prefix := treeKey(key)
+	i, _ := s.treeSearch(prefix)
+	for _, n := range s.tree[i:] {
+		if !strings.HasPrefix(n.rev, prefix) {
+			break
+		}
+		if n.rev != prefix && slices.ContainsFunc(s.records[n.key], func(r Record) bool { return r.served(now, s.serveDelay) }) {
+			return true
+		}
 	}
+	return false
+}
+
+// treeName is a stored name in the tree index.
+type treeName struct {
+	rev string // treeKey of key
+	key string // lowercase FQDN, the key in records
+}
+
+// treeKey returns key with its labels reversed and each one terminated by a
+// NUL byte, e.g. "org\x00example\x00www\x00" for www.example.org. Sorted
+// by these, the names below a name follow it in one run sharing its treeKey
+// as prefix.
+func treeKey(key string) string {
+	labels := dns.SplitDomainName(key)
+	var b strings.Builder
+	for i := len(labels) - 1; i >= 0; i-- {
+		b.WriteString(labels[i])
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+// treeSearch returns the position of rev in the tree index and whether it
+// is there. Caller must hold at least RLock.
+func (s *Store) treeSearch(rev string) (int, bool) {
+	return slices.BinarySearchFunc(s.tree, rev, func(n treeName, rev string) int {
+		return strings.Compare(n.rev, rev)
+	})
+}
+
+// List returns every record visible to the owner in ctx, in canonical order.
+func (s *Store) List(ctx context.Context) []Record {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	all := filterOwned(ctx, liveRecords(s.collectLocked(), s.now()))
+	sortRecords(all)
 	return all
 }
 
-// Upsert adds or updates a record. Matching is done on name+type+value.
-// The file is persisted atomically after the operation.
-func (s *Store) Upsert(r Record) error {
-	snapshot, gen, err := s.applyUpsert(r)
-	if err != nil {
-		return err
+// GetByValue returns every record whose value equals value, across all
+// names and types, in canonical order. It is served from the value index,
+// so the cost is proportional to the number of matching names. Only records
+// visible to the owner in ctx are returned.
+func (s *Store) GetByValue(ctx context.Context, value string) []Record {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := s.now()
+	var out []Record
+	for key := range s.byValue[value] {
+		for _, r := range s.records[key] {
+			if r.Value == value && !r.Expired(now) {
+				out = append(out, r)
+			}
+		}
+	}
+	out = filterOwned(ctx, out)
+	sortRecords(out)
+	return out
+}
+
+// ListPage returns up to limit records in canonical order that sort strictly
+// after the given key; a nil key starts from the beginning and limit <= 0
+// means no limit. Because the position is a key rather than an offset,
+// iteration stays stable when records are added or removed between pages.
+// more reports whether further records follow the returned page. A page is
+// read from the name index, so its cost does not grow with the store.
+func (s *Store) ListPage(ctx context.Context, after *RecordKey, limit int) (records []Record, more bool) {
+	if limit <= 0 {
+		return pageRecords(s.List(ctx), after, limit)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := s.now()
+	i := 0
+	if after != nil {
+		i, _ = slices.BinarySearch(s.names, after.Name)
+	}
+	for _, key := range s.names[i:] {
+		recs := slices.Clone(filterOwned(ctx, liveRecords(s.records[key], now)))
+		sortRecords(recs)
+		for _, r := range recs {
+			if after != nil && r.Key().Compare(*after) <= 0 {
+				continue
+			}
+			if len(records) == limit {
+				return records, true
+			}
+			records = append(records, r)
+		}
 	}
-	return s.persistSnapshot(snapshot, gen)
+	return records, false
+}
+
+// sortRecords orders records canonically by name, type and value.
+func sortRecords(recs []Record) {
+	slices.SortFunc(recs, func(a, b Record) int {
+		return a.Key().Compare(b.Key())
+	})
 }
 
-func (s *Store) applyUpsert(r Record) ([]Record, uint64, error) {
+// pageRecords slices an already sorted record list to the page following after.
+func pageRecords(sorted []Record, after *RecordKey, limit int) ([]Record, bool) {
+	if after != nil {
+		i, _ := slices.BinarySearchFunc(sorted, *after, func(r Record, k RecordKey) int {
+			return r.Key().Compare(k)
+		})
+		for i < len(sorted) && sorted[i].Key().Compare(*after) <= 0 {
+			i++
+		}
+		sorted = sorted[i:]
+	}
+	if limit > 0 && len(sorted) > limit {
+		return sorted[:limit], true
+	}
+	return sorted, false
+}
+
+// Upsert adds or updates a record. Matching is done on name+type+value.
+// When ctx carries a non-admin owner, the record is stamped with it and the
+// name must not hold records of any other owner (ErrNotOwner).
+// The change is written to the backend before Upsert returns.
+func (s *Store) Upsert(ctx context.Context, r Record) error {
+	return s.commit(ctx, func() (Change, error) {
+		return s.applyUpsert(ctx, r)
+	})
+}
+
+// Create adds r only if no live record with the same name, type and value
+// exists, and returns ErrDuplicateRecord otherwise. Unlike Upsert it never
+// modifies an existing record, so when several callers race to create the
+// same record exactly one succeeds. An expired record does not count as
+// existing and is replaced.
+func (s *Store) Create(ctx context.Context, r Record) error {
+	return s.commit(ctx, func() (Change, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if s.duplicateLocked(ctx, r, s.now()) {
+			return Change{}, fmt.Errorf("%s %s %s: %w", r.Name, r.Type, r.Value, ErrDuplicateRecord)
+		}
+		if _, err := s.upsertLocked(ctx, r); err != nil {
+			return Change{}, err
+		}
+		return s.changeLocked(strings.ToLower(r.Name)), nil
+	})
+}
+
+func (s *Store) applyUpsert(ctx context.Context, r Record) (Change, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if _, err := s.upsertLocked(ctx, r); err != nil {
+		return Change{}, err
+	}
+	return s.changeLocked(strings.ToLower(r.Name)), nil
+}
+
+// upsertLocked applies a single upsert to the record map without bumping the
+// generation. created reports whether r was a new record. The type is stored
+// uppercase whatever case it arrives in; the name keeps the casing it was
+// created with. Caller must hold Lock.
+func (s *Store) upsertLocked(ctx context.Context, r Record) (created bool, err error) {
+	r.Type = strings.ToUpper(r.Type)
 	key := strings.ToLower(r.Name)
 	recs := s.records[key]
 
@@ -209,50 +810,160 @@ func (s *Store) applyUpsert(r Record) ([]Record, uint64, error) {
 	}
 	found := idx >= 0
 
+	if owner, scoped := scopedOwner(ctx); scoped {
+		for _, existing := range recs {
+			if existing.Owner != owner {
+				return false, fmt.Errorf("cannot modify %s: %w", r.Name, ErrNotOwner)
+			}
+		}
+		r.Owner = owner
+	} else if _, admin := OwnerFromContext(ctx); found && (r.Owner == "" || admin) {
+		// Unscoped updates keep the existing owner unless one is given; an
+		// admin's update never takes the record over.
+		r.Owner = recs[idx].Owner
+	}
+	if found {
+		// Names match case-insensitively; the casing given at creation stays.
+		r.Name = recs[idx].Name
+		r.CreatedAt = recs[idx].CreatedAt
+		// Only a forced update can lift protection.
+		r.Protected = r.Protected || (recs[idx].Protected && !forced(ctx))
+	} else {
+		r.CreatedAt = s.now()
+	}
+
 	// Policy check before mutation
+	policy := s.policyFor(ctx)
 	switch {
-	case s.syncPolicy == PolicyCreateOnly && found:
-		return nil, 0, fmt.Errorf("cannot update record %s (type %s): %w", r.Name, r.Type, ErrPolicyDenied)
-	case s.syncPolicy == PolicyUpdateOnly && !found:
-		return nil, 0, fmt.Errorf("cannot create record %s (type %s): %w", r.Name, r.Type, ErrPolicyDenied)
+	case policy == PolicyCreateOnly && found:
+		return false, fmt.Errorf("cannot update record %s (type %s): %w", r.Name, r.Type, ErrPolicyDenied)
+	case policy == PolicyUpdateOnly && !found:
+		return false, fmt.Errorf("cannot create record %s (type %s): %w", r.Name, r.Type, ErrPolicyDenied)
 	}
 
+	s.keepLocked(key)
 	if found {
+		if recs[idx].Owner != r.Owner {
+			s.tallyLocked(key, recs[idx], -1)
+			s.tallyLocked(key, r, 1)
+		}
 		recs[idx] = r
 	} else {
+		if err := s.cnameConflictLocked(r, recs); err != nil {
+			return false, err
+		}
 		if s.maxRecords > 0 && s.countLocked() >= s.maxRecords {
-			return nil, 0, fmt.Errorf("record limit of %d reached", s.maxRecords)
+			return false, fmt.Errorf("record limit of %d reached", s.maxRecords)
+		}
+		if _, exists := s.records[key]; !exists && s.maxNames > 0 && len(s.records) >= s.maxNames {
+			return false, fmt.Errorf("cannot add %s: store holds %d names: %w", r.Name, s.maxNames, ErrNameLimit)
+		}
+		if s.maxPerSet > 0 && rrsetSize(recs, r.Type) >= s.maxPerSet {
+			return false, fmt.Errorf("cannot add %s (type %s): name holds %d values of that type: %w", r.Name, r.Type, s.maxPerSet, ErrRRsetLimit)
+		}
+		if s.maxPerZone > 0 {
+			if zone := s.zoneOf(key); zone != "" && s.zoneCountLocked(zone) >= s.maxPerZone {
+				return false, fmt.Errorf("cannot add %s: zone %s holds %d records: %w", r.Name, zone, s.maxPerZone, ErrZoneLimit)
+			}
+		}
+		if quota, ok := s.quotas[r.Owner]; ok && s.ownerCountLocked(r.Owner) >= quota {
+			return false, fmt.Errorf("owner %q holds %d records: %w", r.Owner, quota, ErrQuotaExceeded)
 		}
 		recs = append(recs, r)
+		s.indexLocked(key, r)
 	}
 	s.records[key] = recs
+	if found {
+		s.emitLocked(OpUpdated, r)
+	} else {
+		s.emitLocked(OpAdded, r)
+	}
 
-	s.generation++
-	return s.collectLocked(), s.generation, nil
+	return !found, nil
 }
 
-// Delete removes a specific record identified by name, type, and value.
-func (s *Store) Delete(name, qtype, value string) error {
-	snapshot, gen, err := s.applyDelete(name, qtype, value)
+// SetTTL changes the TTL of the record identified by name, type and value,
+// leaving every other field as it is, and returns the updated record. It is
+// an update, so PolicyCreateOnly denies it. Setting a TTL explicitly cancels
+// any pending TTL window on the record.
+func (s *Store) SetTTL(ctx context.Context, name, qtype, value string, ttl uint32) (Record, error) {
+	if ttl < MinTTL || ttl > MaxTTL {
+		return Record{}, fmt.Errorf("TTL %d out of range [%d, %d]", ttl, MinTTL, MaxTTL)
+	}
+
+	var updated Record
+	err := s.commit(ctx, func() (Change, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if s.policyFor(ctx) == PolicyCreateOnly {
+			return Change{}, fmt.Errorf("cannot update record %s (type %s): %w", name, qtype, ErrPolicyDenied)
+		}
+
+		key := strings.ToLower(name)
+		recs := s.records[key]
+		idx := slices.IndexFunc(recs, func(r Record) bool {
+			return strings.EqualFold(r.Type, qtype) && r.Value == value && !r.Expired(s.now())
+		})
+		if idx < 0 {
+			return Change{}, fmt.Errorf("%s %s %s: %w", name, qtype, value, ErrRecordNotFound)
+		}
+		if owner, scoped := scopedOwner(ctx); scoped && recs[idx].Owner != owner {
+			return Change{}, fmt.Errorf("cannot modify %s: %w", name, ErrNotOwner)
+		}
+
+		s.keepLocked(key)
+		recs[idx].TTL = ttl
+		recs[idx].TTLWindow = TTLWindow{}
+		updated = recs[idx]
+		s.emitLocked(OpUpdated, updated)
+		return s.changeLocked(key), nil
+	})
 	if err != nil {
-		return err
+		return Record{}, err
 	}
-	return s.persistSnapshot(snapshot, gen)
+	return updated, nil
+}
+
+// Delete removes a specific record identified by name, type, and value.
+// Records not visible to the owner in ctx are left untouched.
+func (s *Store) Delete(ctx context.Context, name, qtype, value string) error {
+	return s.commit(ctx, func() (Change, error) {
+		return s.applyDelete(ctx, name, qtype, value)
+	})
 }
 
-func (s *Store) applyDelete(name, qtype, value string) ([]Record, uint64, error) {
+func (s *Store) applyDelete(ctx context.Context, name, qtype, value string) (Change, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if s.syncPolicy != PolicySync {
-		return nil, 0, fmt.Errorf("delete denied: %w", ErrPolicyDenied)
+	if err := s.deleteLocked(ctx, name, qtype, value); err != nil {
+		return Change{}, err
 	}
+	return s.changeLocked(strings.ToLower(name)), nil
+}
 
+// deleteLocked removes one record without bumping the generation. Caller must hold Lock.
+func (s *Store) deleteLocked(ctx context.Context, name, qtype, value string) error {
+	if s.policyFor(ctx) != PolicySync {
+		return fmt.Errorf("delete denied: %w", ErrPolicyDenied)
+	}
+	if err := s.refuseProtectedLocked(ctx, name, func(r Record) bool {
+		return strings.EqualFold(r.Type, qtype) && r.Value == value
+	}); err != nil {
+		return err
+	}
+
+	owner, scoped := scopedOwner(ctx)
 	key := strings.ToLower(name)
 	recs := s.records[key]
+	s.keepLocked(key)
 	filtered := recs[:0]
+	var removed []Record
 	for _, r := range recs {
-		if strings.EqualFold(r.Type, qtype) && r.Value == value {
+		if strings.EqualFold(r.Type, qtype) && r.Value == value && (!scoped || r.Owner == owner) {
+			s.emitLocked(OpDeleted, r)
+			removed = append(removed, r)
 			continue
 		}
 		filtered = append(filtered, r)
@@ -263,35 +974,55 @@ func (s *Store) applyDelete(name, qtype, value string) ([]Record, uint64, error)
 	} else {
 		s.records[key] = filtered
 	}
-
-	s.generation++
-	return s.collectLocked(), s.generation, nil
+	for _, r := range removed {
+		s.unindexLocked(key, r)
+	}
+	return nil
 }
 
 // DeleteByType removes all records matching the given FQDN and record type
-// in a single atomic operation (one lock, one persist).
-func (s *Store) DeleteByType(name, qtype string) error {
-	snapshot, gen, err := s.applyDeleteByType(name, qtype)
-	if err != nil {
-		return err
-	}
-	return s.persistSnapshot(snapshot, gen)
+// in a single atomic operation (one lock, one persist). Records not visible
+// to the owner in ctx are left untouched.
+func (s *Store) DeleteByType(ctx context.Context, name, qtype string) error {
+	return s.commit(ctx, func() (Change, error) {
+		return s.applyDeleteByType(ctx, name, qtype)
+	})
 }
 
-func (s *Store) applyDeleteByType(name, qtype string) ([]Record, uint64, error) {
+func (s *Store) applyDeleteByType(ctx context.Context, name, qtype string) (Change, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if s.syncPolicy != PolicySync {
-		return nil, 0, fmt.Errorf("delete denied: %w", ErrPolicyDenied)
+	if err := s.deleteByTypeLocked(ctx, name, qtype); err != nil {
+		return Change{}, err
 	}
+	return s.changeLocked(strings.ToLower(name)), nil
+}
 
+// deleteByTypeLocked removes a name's records of one type without bumping
+// the generation. Caller must hold Lock.
+func (s *Store) deleteByTypeLocked(ctx context.Context, name, qtype string) error {
+	if s.policyFor(ctx) != PolicySync {
+		return fmt.Errorf("delete denied: %w", ErrPolicyDenied)
+	}
+	if err := s.refuseProtectedLocked(ctx, name, func(r Record) bool {
+		return strings.EqualFold(r.Type, qtype)
+	}); err != nil {
+		return err
+	}
+
+	owner, scoped := scopedOwner(ctx)
 	key := strings.ToLower(name)
 	recs := s.records[key]
+	s.keepLocked(key)
 	filtered := make([]Record, 0, len(recs))
+	var removed []Record
 	for _, r := range recs {
-		if !strings.EqualFold(r.Type, qtype) {
+		if !strings.EqualFold(r.Type, qtype) || (scoped && r.Owner != owner) {
 			filtered = append(filtered, r)
+		} else {
+			s.emitLocked(OpDeleted, r)
+			removed = append(removed, r)
 		}
 	}
 
@@ -300,86 +1031,257 @@ func (s *Store) applyDeleteByType(name, qtype string) ([]Record, uint64, error)
 	} else {
 		s.records[key] = filtered
 	}
-
-	s.generation++
-	return s.collectLocked(), s.generation, nil
+	for _, r := range removed {
+		s.unindexLocked(key, r)
+	}
+	return nil
 }
 
-// DeleteAll removes every record for the given FQDN.
-func (s *Store) DeleteAll(name string) error {
-	snapshot, gen, err := s.applyDeleteAll(name)
-	if err != nil {
-		return err
-	}
-	return s.persistSnapshot(snapshot, gen)
+// DeleteAll removes every record for the given FQDN that is visible to the
+// owner in ctx. Like the other deletes it fails with ErrProtected, removing
+// nothing, if any of those records is protected and ctx is not forced.
+func (s *Store) DeleteAll(ctx context.Context, name string) error {
+	return s.commit(ctx, func() (Change, error) {
+		return s.applyDeleteAll(ctx, name)
+	})
 }
 
-func (s *Store) applyDeleteAll(name string) ([]Record, uint64, error) {
+func (s *Store) applyDeleteAll(ctx context.Context, name string) (Change, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if s.syncPolicy != PolicySync {
-		return nil, 0, fmt.Errorf("delete denied: %w", ErrPolicyDenied)
+	if err := s.deleteAllLocked(ctx, name); err != nil {
+		return Change{}, err
 	}
+	return s.changeLocked(strings.ToLower(name)), nil
+}
 
-	key := strings.ToLower(name)
-	delete(s.records, key)
+// DeleteBySuffix removes every record at or below suffix that is visible to
+// the owner in ctx, as one atomic change, and returns how many it removed.
+// Like DeleteAll it needs the sync policy.
+func (s *Store) DeleteBySuffix(ctx context.Context, suffix string) (int, error) {
+	var n int
+	err := s.commit(ctx, func() (Change, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if s.policyFor(ctx) != PolicySync {
+			return Change{}, fmt.Errorf("delete denied: %w", ErrPolicyDenied)
+		}
 
-	s.generation++
-	return s.collectLocked(), s.generation, nil
+		target := strings.ToLower(dns.Fqdn(suffix))
+		// Check every name first so a protected record further down the
+		// tree does not leave the names before it half deleted.
+		for key := range s.records {
+			if !dns.IsSubDomain(target, key) {
+				continue
+			}
+			if err := s.refuseProtectedLocked(ctx, key, anyRecord); err != nil {
+				return Change{}, err
+			}
+		}
+		var keys []string
+		for key, recs := range s.records {
+			if !dns.IsSubDomain(target, key) {
+				continue
+			}
+			before := len(recs)
+			if err := s.deleteAllLocked(ctx, key); err != nil {
+				return Change{}, err
+			}
+			if removed := before - len(s.records[key]); removed > 0 {
+				n += removed
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) == 0 {
+			return Change{}, nil
+		}
+		return s.changeLocked(keys...), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// deleteAllLocked removes every record of a name visible to the owner in ctx
+// without bumping the generation. Caller must hold Lock.
+func (s *Store) deleteAllLocked(ctx context.Context, name string) error {
+	if s.policyFor(ctx) != PolicySync {
+		return fmt.Errorf("delete denied: %w", ErrPolicyDenied)
+	}
+	if err := s.refuseProtectedLocked(ctx, name, anyRecord); err != nil {
+		return err
+	}
+
+	key := strings.ToLower(name)
+	recs := s.records[key]
+	s.keepLocked(key)
+	removed := recs
+	if owner, scoped := scopedOwner(ctx); scoped {
+		var kept []Record
+		removed = nil
+		for _, r := range recs {
+			if r.Owner != owner {
+				kept = append(kept, r)
+			} else {
+				s.emitLocked(OpDeleted, r)
+				removed = append(removed, r)
+			}
+		}
+		if len(kept) > 0 {
+			s.records[key] = kept
+		} else {
+			delete(s.records, key)
+		}
+	} else {
+		delete(s.records, key)
+		for _, r := range recs {
+			s.emitLocked(OpDeleted, r)
+		}
+	}
+	for _, r := range removed {
+		s.unindexLocked(key, r)
+	}
+	return nil
 }
 
-// persistSnapshot writes the given records to the backing file atomically.
-// Serialized by persistMu; skips if a newer generation was already persisted.
+// commit runs apply and writes the resulting change to the backend. Holding
+// persistMu across both steps means changes reach the backend in generation
+// order and a reload can never interleave with a mutation.
 // Must NOT be called with s.mu held.
-func (s *Store) persistSnapshot(all []Record, gen uint64) error {
+func (s *Store) commit(ctx context.Context, apply func() (Change, error)) error {
 	s.persistMu.Lock()
 	defer s.persistMu.Unlock()
 
-	// A newer snapshot was already written; this one is stale.
-	// Safe without mu: persistMu serializes all callers, so s.persisted cannot change concurrently.
-	if gen > 0 && gen <= s.persisted {
+	if err := s.catchUp(ctx); err != nil {
+		return err
+	}
+	s.undo = make(undoLog)
+	defer func() { s.undo = nil }()
+	change, err := apply()
+	if err != nil {
+		s.pending = nil
+		s.touched = nil
+		s.removed = nil
+		return err
+	}
+	// A zero generation means apply found nothing to change.
+	if change.Generation == 0 {
+		s.pending = nil
+		s.touched = nil
+		s.removed = nil
 		return nil
 	}
+	// A previous write failed, so the backend may hold part of it: ask for a
+	// full rewrite instead of a delta.
+	if change.Generation > s.persisted+1 {
+		change.Names = nil
+	}
 
-	data := storeFile{Records: all}
-	raw, err := json.MarshalIndent(data, "", "  ")
-	if err != nil {
-		return fmt.Errorf("marshalling store: %w", err)
+	// Do not let a cancelled request leave the backend behind memory, which
+	// already holds the mutation.
+	if err := s.save(context.WithoutCancel(ctx), change); err != nil {
+		s.setBackendErr(err)
+		// Take the mutation back out of memory and drop its events, so the
+		// caller's error is the whole story. The generation stays bumped: the
+		// failed write may have reached part of the backend, and the next
+		// catchUp or Flush rewrites it in full.
+		s.mu.Lock()
+		s.rollbackLocked(s.undo)
+		s.history.truncate(change.Generation)
+		s.updateRecordGaugeLocked()
+		s.mu.Unlock()
+		s.pending = nil
+		return fmt.Errorf("%w: persisting records: %w", ErrBackendUnavailable, err)
 	}
+	s.setBackendErr(nil)
+	s.persisted = change.Generation
+	// The audit log and subscribers only hear of mutations that were
+	// persisted.
+	s.auditPending(ctx)
+	s.publishPending()
 
-	dir := filepath.Dir(s.filePath)
-	tmp, err := os.CreateTemp(dir, "dynupdate-*.json.tmp")
-	if err != nil {
-		return fmt.Errorf("creating temp file: %w", err)
+	s.mu.RLock()
+	s.updateRecordGaugeLocked()
+	s.mu.RUnlock()
+	return nil
+}
+
+// changeLocked bumps the generation and describes a mutation that touched
+// key. Caller must hold Lock.
+func (s *Store) changeLocked(keys ...string) Change {
+	s.generation++
+	s.stampLocked(s.generation)
+	names := make(map[string][]Record, len(keys))
+	for _, key := range keys {
+		names[key] = slices.Clone(s.records[key])
 	}
-	tmpName := tmp.Name()
+	return Change{
+		Generation: s.generation,
+		Names:      names,
+		all:        s.snapshot,
+	}
+}
 
-	if _, err := tmp.Write(raw); err != nil {
-		tmp.Close()
-		os.Remove(tmpName)
-		return fmt.Errorf("writing temp file: %w", err)
+// undoLog holds the original record sets of the names a multi-record
+// mutation has touched, so a failure part-way can be rolled back.
+type undoLog map[string][]Record
+
+// save remembers key's current record set the first time key is touched.
+// Caller must hold Lock.
+func (u undoLog) save(s *Store, key string) {
+	if _, ok := u[key]; !ok {
+		u[key] = slices.Clone(s.records[key])
 	}
-	if err := tmp.Close(); err != nil {
-		os.Remove(tmpName)
-		return fmt.Errorf("closing temp file: %w", err)
+}
+
+// keepLocked remembers key's record set before the mutation being applied
+// changes it, so commit can roll the mutation back if the backend write
+// fails. Outside commit it does nothing. Caller must hold Lock.
+func (s *Store) keepLocked(key string) {
+	if s.undo != nil {
+		s.undo.save(s, key)
 	}
+}
 
-	if err := os.Rename(tmpName, s.filePath); err != nil {
-		os.Remove(tmpName)
-		return fmt.Errorf("renaming temp to %s: %w", s.filePath, err)
+// keys returns the touched names.
+func (u undoLog) keys() []string {
+	keys := make([]string, 0, len(u))
+	for key := range u {
+		keys = append(keys, key)
 	}
+	return keys
+}
 
-	// Update metadata under mu to prevent self-triggered reload.
-	s.mu.Lock()
-	s.persisted = gen
-	if info, err := os.Stat(s.filePath); err == nil {
-		s.lastMod = info.ModTime()
+// rollbackLocked restores every name in u to its saved record set and fixes
+// up the value index. Caller must hold Lock.
+func (s *Store) rollbackLocked(u undoLog) {
+	for key, orig := range u {
+		cur := s.records[key]
+		if len(orig) == 0 {
+			delete(s.records, key)
+		} else {
+			s.records[key] = orig
+		}
+		for _, r := range cur {
+			s.unindexLocked(key, r)
+		}
+		for _, r := range orig {
+			s.indexLocked(key, r)
+		}
 	}
-	s.updateRecordGaugeLocked()
-	s.mu.Unlock()
+}
 
-	return nil
+// snapshot returns every record as a flat slice in canonical order, so
+// full rewrites of the backend are deterministic.
+func (s *Store) snapshot() []Record {
+	s.mu.RLock()
+	all := s.collectLocked()
+	s.mu.RUnlock()
+	sortRecords(all)
+	return all
 }
 
 // updateRecordGaugeLocked sets the storeRecordGauge per record type. Caller must hold at least RLock.
@@ -394,6 +1296,131 @@ func (s *Store) updateRecordGaugeLocked() {
 	for t, c := range counts {
 		storeRecordGauge.WithLabelValues(t).Set(c)
 	}
+	s.updateAgeGaugesLocked()
+}
+
+// updateAgeGaugesLocked sets, for each record type, how long ago the least
+// and the most recently updated record was updated. Records without
+// UpdatedAt are skipped. Ages grow between mutations, so the sweep also
+// calls it. Caller must hold at least RLock.
+func (s *Store) updateAgeGaugesLocked() {
+	type span struct{ oldest, newest time.Time }
+	spans := make(map[string]span)
+	for _, recs := range s.records {
+		for _, r := range recs {
+			if r.UpdatedAt.IsZero() {
+				continue
+			}
+			sp, ok := spans[r.Type]
+			if !ok || r.UpdatedAt.Before(sp.oldest) {
+				sp.oldest = r.UpdatedAt
+			}
+			if !ok || r.UpdatedAt.After(sp.newest) {
+				sp.newest = r.UpdatedAt
+			}
+			spans[r.Type] = sp
+		}
+	}
+	now := s.now()
+	oldestUpdateAgeGauge.Reset()
+	newestUpdateAgeGauge.Reset()
+	for t, sp := range spans {
+		oldestUpdateAgeGauge.WithLabelValues(t).Set(now.Sub(sp.oldest).Seconds())
+		newestUpdateAgeGauge.WithLabelValues(t).Set(now.Sub(sp.newest).Seconds())
+	}
+}
+
+// indexLocked accounts for r, just added under key: it adds key to the
+// value index entry for r's value, to the tree index if it is a new name,
+// and counts r against its zone and owner. Caller must hold Lock.
+func (s *Store) indexLocked(key string, r Record) {
+	s.addNameLocked(key)
+	s.tallyLocked(key, r, 1)
+	keys := s.byValue[r.Value]
+	if keys == nil {
+		keys = make(map[string]struct{})
+		s.byValue[r.Value] = keys
+	}
+	keys[key] = struct{}{}
+}
+
+// unindexLocked accounts for r, just removed from key: it removes key from
+// the index entry for r's value unless a record under key still carries
+// that value, and from the name indexes once the name holds no records.
+// Caller must hold Lock.
+func (s *Store) unindexLocked(key string, r Record) {
+	s.tallyLocked(key, r, -1)
+	if len(s.records[key]) == 0 {
+		s.removeNameLocked(key)
+	}
+	for _, o := range s.records[key] {
+		if o.Value == r.Value {
+			return
+		}
+	}
+	keys := s.byValue[r.Value]
+	delete(keys, key)
+	if len(keys) == 0 {
+		delete(s.byValue, r.Value)
+	}
+}
+
+// tallyLocked adds d to the record counts of the zone holding key and of
+// r's owner. Caller must hold Lock.
+func (s *Store) tallyLocked(key string, r Record, d int) {
+	if zone := s.zoneOf(key); zone != "" {
+		s.zoneCounts[zone] += d
+		if s.zoneCounts[zone] == 0 {
+			delete(s.zoneCounts, zone)
+		}
+	}
+	s.owners[r.Owner] += d
+	if s.owners[r.Owner] == 0 {
+		delete(s.owners, r.Owner)
+	}
+}
+
+// addNameLocked adds key to the tree and name indexes. Caller must hold Lock.
+func (s *Store) addNameLocked(key string) {
+	rev := treeKey(key)
+	if i, ok := s.treeSearch(rev); !ok {
+		s.tree = slices.Insert(s.tree, i, treeName{rev: rev, key: key})
+	}
+	if i, ok := slices.BinarySearch(s.names, key); !ok {
+		s.names = slices.Insert(s.names, i, key)
+	}
+}
+
+// removeNameLocked drops key from the tree and name indexes. Caller must
+// hold Lock.
+func (s *Store) removeNameLocked(key string) {
+	if i, ok := s.treeSearch(treeKey(key)); ok {
+		s.tree = slices.Delete(s.tree, i, i+1)
+	}
+	if i, ok := slices.BinarySearch(s.names, key); ok {
+		s.names = slices.Delete(s.names, i, i+1)
+	}
+}
+
+// rebuildIndexLocked recomputes the value, tree and name indexes and the
+// zone and owner counts from the record map. Caller must hold Lock.
+func (s *Store) rebuildIndexLocked() {
+	s.byValue = make(map[string]map[string]struct{})
+	s.zoneCounts = make(map[string]int)
+	s.owners = make(map[string]int)
+	s.tree = make([]treeName, 0, len(s.records))
+	s.names = make([]string, 0, len(s.records))
+	for key := range s.records {
+		s.tree = append(s.tree, treeName{rev: treeKey(key), key: key})
+		s.names = append(s.names, key)
+	}
+	slices.SortFunc(s.tree, func(a, b treeName) int { return strings.Compare(a.rev, b.rev) })
+	slices.Sort(s.names)
+	for key, recs := range s.records {
+		for _, r := range recs {
+			s.indexLocked(key, r)
+		}
+	}
 }
 
 // countLocked returns the total number of records. Caller must hold at least RLock.
@@ -405,6 +1432,34 @@ func (s *Store) countLocked() int {
 	return n
 }
 
+// zoneOf returns the longest configured zone containing the lowercase name
+// key, or "" if there is none.
+func (s *Store) zoneOf(key string) string {
+	return plugin.Zones(s.zones).Matches(key)
+}
+
+// zoneCountLocked returns the number of records attributed to zone. Caller must hold at least RLock.
+func (s *Store) zoneCountLocked(zone string) int {
+	return s.zoneCounts[zone]
+}
+
+// rrsetSize returns how many of recs, the records of one name, have type
+// qtype.
+func rrsetSize(recs []Record, qtype string) int {
+	n := 0
+	for _, r := range recs {
+		if strings.EqualFold(r.Type, qtype) {
+			n++
+		}
+	}
+	return n
+}
+
+// ownerCountLocked returns the number of records stamped with owner. Caller must hold at least RLock.
+func (s *Store) ownerCountLocked(owner string) int {
+	return s.owners[owner]
+}
+
 // collectLocked returns all records as a flat slice. Caller must hold at least RLock.
 func (s *Store) collectLocked() []Record {
 	var all []Record
@@ -414,42 +1469,69 @@ func (s *Store) collectLocked() []Record {
 	return all
 }
 
-// loadOrCreate loads records from file or creates an empty file.
-func (s *Store) loadOrCreate() error {
-	raw, err := os.ReadFile(s.filePath)
-	if os.IsNotExist(err) {
-		// Create empty file
-		s.records = make(map[string][]Record)
-		return s.persistSnapshot(nil, 0)
+// replaceLocked swaps in a freshly loaded record set. Caller must hold Lock.
+func (s *Store) replaceLocked(loaded []Record, gen uint64) {
+	records := make(map[string][]Record)
+	for _, r := range loaded {
+		// Backends may hold records written by hand or by older versions.
+		r.Type = strings.ToUpper(r.Type)
+		key := strings.ToLower(r.Name)
+		records[key] = append(records[key], r)
 	}
-	if err != nil {
-		return fmt.Errorf("reading %s: %w", s.filePath, err)
+	for key := range s.records {
+		s.keepLocked(key)
 	}
+	for key := range records {
+		s.keepLocked(key)
+	}
+	old := s.records
+	s.records = records
+	s.emitDiffLocked(old)
+	s.rebuildIndexLocked()
 
-	return s.loadFromBytes(raw)
+	// A load replaces the record set, so it counts as a mutation. Keep the
+	// persisted generation when it is ahead so serials survive restarts.
+	s.generation = max(s.generation+1, gen)
+	s.restampLocked(old)
+	s.updateRecordGaugeLocked()
 }
 
-func (s *Store) loadFromBytes(raw []byte) error {
-	var data storeFile
-	if err := json.Unmarshal(raw, &data); err != nil {
-		return fmt.Errorf("parsing JSON: %w", err)
+// restampLocked sets ChangedAt and UpdatedAt after the record set replaced
+// old. The initial load keeps the persisted values, giving the loaded
+// generation to records written without one. Later replacements stamp the
+// records that differ from old and keep the old values on the rest, whatever
+// the loaded copy says. Caller must hold Lock and persistMu.
+func (s *Store) restampLocked(old map[string][]Record) {
+	if !s.ready {
+		s.touched = nil
+		s.removed = nil
+		s.history.reset(s.generation)
+		for _, recs := range s.records {
+			for i := range recs {
+				if recs[i].ChangedAt == 0 {
+					recs[i].ChangedAt = s.generation
+				}
+			}
+		}
+		return
 	}
 
-	records := make(map[string][]Record)
-	for _, r := range data.Records {
-		key := strings.ToLower(r.Name)
-		records[key] = append(records[key], r)
+	prev := make(map[RecordKey]Record)
+	for _, recs := range old {
+		for _, r := range recs {
+			prev[r.Key()] = r
+		}
 	}
-	s.records = records
-
-	if info, err := os.Stat(s.filePath); err == nil {
-		s.lastMod = info.ModTime()
+	for _, recs := range s.records {
+		for i := range recs {
+			p := prev[recs[i].Key()]
+			recs[i].ChangedAt, recs[i].UpdatedAt = p.ChangedAt, p.UpdatedAt
+		}
 	}
-
-	return nil
+	s.stampLocked(s.generation)
 }
 
-// run is the auto-reload goroutine that checks file mtime periodically.
+// run is the auto-reload goroutine that polls the backend periodically.
 func (s *Store) run() {
 	ticker := time.NewTicker(s.reload)
 	defer ticker.Stop()
@@ -464,51 +1546,183 @@ func (s *Store) run() {
 	}
 }
 
+// runSweep is the goroutine that periodically removes expired records and
+// ends TTL windows that are due. It also rewrites the backend after a failed
+// write, so a degraded store recovers, and reports healthy again, without waiting for
+// the next mutation.
+func (s *Store) runSweep() {
+	ticker := time.NewTicker(s.sweep)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		case <-ticker.C:
+			if s.Degraded() {
+				if err := s.Flush(context.Background()); err != nil {
+					log.Errorf("retrying backend write: %v", err)
+				}
+			}
+			if err := s.sweepExpired(); err != nil {
+				log.Errorf("sweeping expired records: %v", err)
+			}
+			if err := s.restoreTTLs(); err != nil {
+				log.Errorf("restoring TTLs: %v", err)
+			}
+			s.mu.RLock()
+			s.updateAgeGaugesLocked()
+			s.mu.RUnlock()
+		}
+	}
+}
+
+// sweepExpired removes every expired record in one mutation and persists it.
+// The sync policy does not apply: the expiry was requested when the record
+// was written.
+func (s *Store) sweepExpired() error {
+	// Scan under the read lock first so an idle sweep never blocks queries.
+	s.mu.RLock()
+	due := s.hasExpiredLocked(s.now())
+	s.mu.RUnlock()
+	if !due {
+		return nil
+	}
+
+	_, err := s.purgeExpired(context.Background())
+	return err
+}
+
+// purgeExpired removes every expired record in one mutation, persists it
+// and returns the number removed.
+func (s *Store) purgeExpired(ctx context.Context) (int, error) {
+	var purged int
+	err := s.commit(ctx, func() (Change, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		now := s.now()
+		var keys []string
+		for key, recs := range s.records {
+			live := liveRecords(recs, now)
+			if len(live) == len(recs) {
+				continue
+			}
+			s.keepLocked(key)
+			for _, r := range recs {
+				if r.Expired(now) {
+					s.emitLocked(OpDeleted, r)
+				}
+			}
+			purged += len(recs) - len(live)
+			if len(live) == 0 {
+				delete(s.records, key)
+			} else {
+				s.records[key] = live
+			}
+			for _, r := range recs {
+				if r.Expired(now) {
+					s.unindexLocked(key, r)
+				}
+			}
+			keys = append(keys, key)
+		}
+		if len(keys) == 0 {
+			return Change{}, nil
+		}
+		return s.changeLocked(keys...), nil
+	})
+	return purged, err
+}
+
+// hasExpiredLocked reports whether any record is expired at now. Caller must hold at least RLock.
+func (s *Store) hasExpiredLocked(now time.Time) bool {
+	for _, recs := range s.records {
+		for _, r := range recs {
+			if r.Expired(now) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// liveRecords returns recs without the records expired at now. recs itself
+// is returned when nothing has expired.
+func liveRecords(recs []Record, now time.Time) []Record {
+	return keepRecords(recs, func(r Record) bool { return !r.Expired(now) })
+}
+
+// serves reports whether DNS answers may use r now.
+func (s *Store) serves(r Record) bool {
+	return r.served(s.now(), s.serveDelay)
+}
+
+// servedRecords returns the records of recs that DNS answers may use: live,
+// not disabled and past the serve delay.
+func servedRecords(recs []Record, now time.Time, delay time.Duration) []Record {
+	return keepRecords(recs, func(r Record) bool { return r.served(now, delay) })
+}
+
+// keepRecords returns the records of recs for which keep is true. recs is
+// returned as is when every record is kept, so the common case does not
+// allocate.
+func keepRecords(recs []Record, keep func(Record) bool) []Record {
+	i := slices.IndexFunc(recs, func(r Record) bool { return !keep(r) })
+	if i < 0 {
+		return recs
+	}
+	kept := slices.Clone(recs[:i])
+	for _, r := range recs[i+1:] {
+		if keep(r) {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
 func (s *Store) checkReload() {
-	// Skip if a persist is actively running to avoid overwriting in-flight mutations.
+	// Skip if a mutation is in flight; the next tick will pick up any change.
 	if !s.persistMu.TryLock() {
 		return
 	}
-	s.persistMu.Unlock()
+	defer s.persistMu.Unlock()
 
-	// Phase 1: check mtime under lock (fast path).
-	s.mu.RLock()
+	// The last write failed and may have reached part of the backend:
+	// rewrite it from memory before loading from it again.
 	if s.generation > s.persisted {
-		s.mu.RUnlock()
 		return
 	}
-	lastMod := s.lastMod
-	s.mu.RUnlock()
 
-	info, err := os.Stat(s.filePath)
+	ctx := context.Background()
+	changed, err := s.backend.Modified(ctx)
 	if err != nil {
+		s.setBackendErr(err)
+		log.Errorf("reload: %v", err)
 		return
 	}
-	if !info.ModTime().After(lastMod) {
+	s.setBackendErr(nil)
+	if !changed {
 		return
 	}
 
-	// Phase 2: read file outside any lock.
-	raw, err := os.ReadFile(s.filePath)
+	records, gen, err := s.backend.Load(ctx)
+	if err == nil {
+		records, err = s.resolveLoadConflicts(records)
+	}
 	if err != nil {
-		log.Errorf("reload %s: read error: %v", s.filePath, err)
+		s.setReloadErr(err)
+		reloadCount.WithLabelValues("error").Inc()
+		log.Errorf("reload: %v", err)
 		return
 	}
+	s.setReloadErr(nil)
+	reloadCount.WithLabelValues("success").Inc()
+	lastReloadTimestamp.Set(float64(s.now().Unix()))
 
-	// Phase 3: re-verify under write lock and swap.
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	// A mutation may have landed while we were reading; skip if so.
-	if s.generation > s.persisted {
-		return
-	}
-	// Re-check mtime: another reload or persist may have updated lastMod.
-	if !info.ModTime().After(s.lastMod) {
-		return
-	}
-
-	if err := s.loadFromBytes(raw); err != nil {
-		log.Errorf("reload %s: parse error: %v", s.filePath, err)
-	}
+	s.replaceLocked(records, gen)
+	s.persisted = s.generation
+	s.mu.Unlock()
+	s.publishPending()
 }
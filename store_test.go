@@ -4,17 +4,37 @@
 package dynupdate
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+	dto "github.com/prometheus/client_model/go"
 )
 
+// newTestStore returns a store backed by the file at fp, stopped when the
+// test ends.
+func newTestStore(t *testing.T, fp string, opts ...StoreOption) *Store {
+	t.Helper()
+	s, err := NewStore(fp, 0, opts...)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	t.Cleanup(s.Stop)
+	return s
+}
+
 func TestStore_NewAndReady(t *testing.T) {
 	t.Parallel()
 	dir := t.TempDir()
@@ -50,7 +70,7 @@ func TestStore_NewWithExistingFile(t *testing.T) {
 	}
 	defer s.Stop()
 
-	records := s.Get("app.example.org.", "A")
+	records := s.Get(t.Context(), "app.example.org.", "A")
 	if len(records) != 1 {
 		t.Fatalf("Get() returned %d records, want 1", len(records))
 	}
@@ -59,6 +79,113 @@ func TestStore_NewWithExistingFile(t *testing.T) {
 	}
 }
 
+func TestStore_New_CreatesDataDir(t *testing.T) {
+	t.Parallel()
+	fp := filepath.Join(t.TempDir(), "var", "lib", "dynupdate", "records.json")
+
+	s, err := NewStore(fp, 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	defer s.Stop()
+
+	if err := s.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	raw, err := os.ReadFile(fp)
+	if err != nil {
+		t.Fatalf("ReadFile() error: %v", err)
+	}
+	if !strings.Contains(string(raw), "10.0.0.1") {
+		t.Errorf("data file lacks the upserted record:\n%s", raw)
+	}
+}
+
+func TestStore_New_UnwritableDataDir(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		path func(t *testing.T) string
+	}{
+		{"read-only directory", func(t *testing.T) string {
+			if os.Geteuid() == 0 {
+				t.Skip("root can write to read-only directories")
+			}
+			dir := t.TempDir()
+			// An existing data file is readable, but could never be replaced.
+			fp := filepath.Join(dir, "records.json")
+			if err := os.WriteFile(fp, []byte(`{"records":[]}`), 0o644); err != nil {
+				t.Fatalf("WriteFile() error: %v", err)
+			}
+			if err := os.Chmod(dir, 0o555); err != nil {
+				t.Fatalf("Chmod() error: %v", err)
+			}
+			t.Cleanup(func() { os.Chmod(dir, 0o755) })
+			return fp
+		}},
+		{"parent is a file", func(t *testing.T) string {
+			parent := filepath.Join(t.TempDir(), "data")
+			if err := os.WriteFile(parent, nil, 0o644); err != nil {
+				t.Fatalf("WriteFile() error: %v", err)
+			}
+			return filepath.Join(parent, "records.json")
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if s, err := NewStore(tt.path(t), 0); err == nil {
+				s.Stop()
+				t.Fatal("NewStore() succeeded, want an error for the data directory")
+			}
+		})
+	}
+}
+
+func TestStore_LowercaseTypeNormalized(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "records.json")
+
+	data := storeFile{Records: []Record{
+		{Name: "app.example.org.", Type: "a", TTL: 300, Value: "10.0.0.1"},
+	}}
+	raw, _ := json.MarshalIndent(data, "", "  ")
+	if err := os.WriteFile(fp, raw, 0o644); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+
+	s, err := NewStore(fp, 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	defer s.Stop()
+	if err := s.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "txt", TTL: 300, Value: "hello"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+
+	d := &DynUpdate{Zones: []string{"example.org."}, Store: s}
+	req := new(dns.Msg)
+	req.SetQuestion("app.example.org.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := d.ServeDNS(t.Context(), rec, req); err != nil {
+		t.Fatalf("ServeDNS() error: %v", err)
+	}
+	if len(rec.Msg.Answer) != 1 {
+		t.Fatalf("got %d answers, want the loaded A record", len(rec.Msg.Answer))
+	}
+
+	exported := d.Dump().Records
+	if len(exported) != 2 {
+		t.Fatalf("exported %d records, want 2", len(exported))
+	}
+	for _, r := range exported {
+		if r.Type != strings.ToUpper(r.Type) {
+			t.Errorf("exported type = %q, want uppercase", r.Type)
+		}
+	}
+}
+
 func TestStore_Upsert_Insert(t *testing.T) {
 	t.Parallel()
 	dir := t.TempDir()
@@ -71,11 +198,11 @@ func TestStore_Upsert_Insert(t *testing.T) {
 	defer s.Stop()
 
 	r := Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}
-	if err := s.Upsert(r); err != nil {
+	if err := s.Upsert(t.Context(), r); err != nil {
 		t.Fatalf("Upsert() error: %v", err)
 	}
 
-	records := s.Get("app.example.org.", "A")
+	records := s.Get(t.Context(), "app.example.org.", "A")
 	if len(records) != 1 {
 		t.Fatalf("Get() returned %d records, want 1", len(records))
 	}
@@ -96,17 +223,17 @@ func TestStore_Upsert_Update(t *testing.T) {
 	defer s.Stop()
 
 	r := Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}
-	if err := s.Upsert(r); err != nil {
+	if err := s.Upsert(t.Context(), r); err != nil {
 		t.Fatalf("Upsert() error: %v", err)
 	}
 
 	// Upsert same name+type+value with different TTL => update
 	r2 := Record{Name: "app.example.org.", Type: "A", TTL: 600, Value: "10.0.0.1"}
-	if err := s.Upsert(r2); err != nil {
+	if err := s.Upsert(t.Context(), r2); err != nil {
 		t.Fatalf("Upsert() error: %v", err)
 	}
 
-	records := s.Get("app.example.org.", "A")
+	records := s.Get(t.Context(), "app.example.org.", "A")
 	if len(records) != 1 {
 		t.Fatalf("Get() returned %d records, want 1 (upsert should update, not duplicate)", len(records))
 	}
@@ -128,19 +255,114 @@ func TestStore_Upsert_MultipleValues(t *testing.T) {
 
 	r1 := Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}
 	r2 := Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"}
-	if err := s.Upsert(r1); err != nil {
+	if err := s.Upsert(t.Context(), r1); err != nil {
 		t.Fatalf("Upsert() error: %v", err)
 	}
-	if err := s.Upsert(r2); err != nil {
+	if err := s.Upsert(t.Context(), r2); err != nil {
 		t.Fatalf("Upsert() error: %v", err)
 	}
 
-	records := s.Get("app.example.org.", "A")
+	records := s.Get(t.Context(), "app.example.org.", "A")
 	if len(records) != 2 {
 		t.Fatalf("Get() returned %d records, want 2", len(records))
 	}
 }
 
+func TestStore_Annotations_Persisted(t *testing.T) {
+	t.Parallel()
+	fp := filepath.Join(t.TempDir(), "records.json")
+	s, err := NewStore(fp, 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	r := Record{
+		Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1",
+		Comment: "INC-1234", Labels: map[string]string{"team": "netops"},
+	}
+	if err := s.Upsert(t.Context(), r); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	s.Stop()
+
+	reopened, err := NewStore(fp, 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	defer reopened.Stop()
+	got := reopened.GetAll(t.Context(), "app.example.org.")
+	if len(got) != 1 || !got[0].equal(r) {
+		t.Errorf("after restart = %+v, want %+v", got, r)
+	}
+
+	// Changing only an annotation is an update.
+	r.Labels = map[string]string{"team": "sre"}
+	if err := reopened.Upsert(t.Context(), r); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	if got := reopened.GetAll(t.Context(), "app.example.org."); len(got) != 1 || got[0].Labels["team"] != "sre" {
+		t.Errorf("after relabel = %+v, want team=sre", got)
+	}
+}
+
+func TestStore_NameCasePreserved(t *testing.T) {
+	t.Parallel()
+	fp := filepath.Join(t.TempDir(), "records.json")
+	s, err := NewStore(fp, 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	ctx := t.Context()
+	const stored = "App.Example.Org."
+	if err := s.Upsert(ctx, Record{Name: stored, Type: "A", TTL: 300, Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+
+	check := func(s *Store, when string) {
+		t.Helper()
+		for name, got := range map[string][]Record{
+			"GetAll": s.GetAll(ctx, "app.example.org."),
+			"Get":    s.Get(ctx, "APP.EXAMPLE.ORG.", "A"),
+			"List":   s.List(ctx),
+		} {
+			if len(got) != 1 || got[0].Name != stored {
+				t.Errorf("%s: %s = %v, want one record named %s", when, name, got, stored)
+			}
+		}
+		if got, _ := s.Lookup("app.example.org."); len(got) != 1 || got[0].Name != stored {
+			t.Errorf("%s: Lookup = %v, want one record named %s", when, got, stored)
+		}
+	}
+	check(s, "after create")
+
+	// An update addressed in another casing changes the record, not its name.
+	if err := s.Upsert(ctx, Record{Name: "app.example.org.", Type: "A", TTL: 600, Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("Upsert(update) error: %v", err)
+	}
+	if got := s.GetAll(ctx, "app.example.org."); len(got) != 1 || got[0].TTL != 600 {
+		t.Fatalf("after update = %v, want TTL 600", got)
+	}
+	check(s, "after update")
+	s.Stop()
+
+	reopened, err := NewStore(fp, 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	defer reopened.Stop()
+	check(reopened, "after restart")
+
+	// Recreating the name after a delete takes the new casing.
+	if err := reopened.DeleteAll(ctx, "APP.example.org."); err != nil {
+		t.Fatalf("DeleteAll() error: %v", err)
+	}
+	if err := reopened.Upsert(ctx, Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	if got := reopened.GetAll(ctx, "App.Example.Org."); len(got) != 1 || got[0].Name != "app.example.org." {
+		t.Errorf("after recreate = %v, want the new casing", got)
+	}
+}
+
 func TestStore_GetAll(t *testing.T) {
 	t.Parallel()
 	dir := t.TempDir()
@@ -152,16 +374,103 @@ func TestStore_GetAll(t *testing.T) {
 	}
 	defer s.Stop()
 
-	_ = s.Upsert(Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
-	_ = s.Upsert(Record{Name: "app.example.org.", Type: "AAAA", TTL: 300, Value: "2001:db8::1"})
-	_ = s.Upsert(Record{Name: "other.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"})
+	_ = s.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
+	_ = s.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "AAAA", TTL: 300, Value: "2001:db8::1"})
+	_ = s.Upsert(t.Context(), Record{Name: "other.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"})
 
-	all := s.GetAll("app.example.org.")
+	all := s.GetAll(t.Context(), "app.example.org.")
 	if len(all) != 2 {
 		t.Errorf("GetAll() returned %d records, want 2", len(all))
 	}
 }
 
+func TestStore_Lookup_Wildcard(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "records.json")
+
+	s, err := NewStore(fp, 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	defer s.Stop()
+
+	_ = s.Upsert(t.Context(), Record{Name: "*.apps.example.org.", Type: "A", TTL: 300, Value: "10.0.0.9"})
+	_ = s.Upsert(t.Context(), Record{Name: "exact.apps.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
+
+	recs, wildcard := s.Lookup("foo.apps.example.org.")
+	if !wildcard || len(recs) != 1 {
+		t.Fatalf("Lookup(foo) = %v, wildcard=%v; want 1 wildcard record", recs, wildcard)
+	}
+	if recs[0].Name != "foo.apps.example.org." {
+		t.Errorf("synthesized Name = %q, want foo.apps.example.org.", recs[0].Name)
+	}
+
+	recs, wildcard = s.Lookup("exact.apps.example.org.")
+	if wildcard || len(recs) != 1 || recs[0].Value != "10.0.0.1" {
+		t.Errorf("Lookup(exact) = %v, wildcard=%v; want exact match", recs, wildcard)
+	}
+
+	if recs, _ := s.Lookup("foo.bar.apps.example.org."); len(recs) != 0 {
+		t.Errorf("Lookup(foo.bar) = %v, want no match", recs)
+	}
+
+	// The literal wildcard owner is what gets stored and persisted.
+	if got := s.GetAll(t.Context(), "*.apps.example.org."); len(got) != 1 {
+		t.Errorf("GetAll(*.apps) returned %d records, want 1", len(got))
+	}
+}
+
+func TestStore_EmptyNonTerminal_TracksWrites(t *testing.T) {
+	t.Parallel()
+	s, err := NewStore(filepath.Join(t.TempDir(), "records.json"), 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	defer s.Stop()
+	ctx := t.Context()
+
+	for _, r := range []Record{
+		{Name: "*.example.org.", Type: "A", TTL: 300, Value: "10.0.0.9"},
+		{Name: "a.b.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"},
+		{Name: "x.ab.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"},
+	} {
+		if err := s.Upsert(ctx, r); err != nil {
+			t.Fatalf("Upsert(%s) error: %v", r.Name, err)
+		}
+	}
+	for name, want := range map[string]bool{
+		"b.example.org.":    true,
+		"B.Example.Org.":    true,
+		"ab.example.org.":   true,
+		"example.org.":      true,
+		"a.b.example.org.":  false, // holds records itself
+		"b.b.example.org.":  false,
+		"zzz.example.org.":  false,
+		"a.ab.example.org.": false,
+	} {
+		if got := s.IsEmptyNonTerminal(name); got != want {
+			t.Errorf("IsEmptyNonTerminal(%s) = %v, want %v", name, got, want)
+		}
+	}
+	if _, wildcard := s.Lookup("b.example.org."); wildcard {
+		t.Error("Lookup(b.example.org.) matched the wildcard, want the empty non-terminal to block it")
+	}
+
+	if err := s.DeleteAll(ctx, "a.b.example.org."); err != nil {
+		t.Fatalf("DeleteAll() error: %v", err)
+	}
+	if s.IsEmptyNonTerminal("b.example.org.") {
+		t.Error("IsEmptyNonTerminal(b.example.org.) = true after deleting the name below it")
+	}
+	if _, wildcard := s.Lookup("b.example.org."); !wildcard {
+		t.Error("Lookup(b.example.org.) did not match the wildcard once the name below it was deleted")
+	}
+	if !s.IsEmptyNonTerminal("ab.example.org.") {
+		t.Error("IsEmptyNonTerminal(ab.example.org.) = false, want the remaining name below it to count")
+	}
+}
+
 func TestStore_List(t *testing.T) {
 	t.Parallel()
 	dir := t.TempDir()
@@ -173,15 +482,590 @@ func TestStore_List(t *testing.T) {
 	}
 	defer s.Stop()
 
-	_ = s.Upsert(Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
-	_ = s.Upsert(Record{Name: "other.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"})
+	_ = s.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
+	_ = s.Upsert(t.Context(), Record{Name: "other.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"})
 
-	all := s.List()
+	all := s.List(t.Context())
 	if len(all) != 2 {
 		t.Errorf("List() returned %d records, want 2", len(all))
 	}
 }
 
+func TestStore_GetByValue(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "records.json")
+
+	s, err := NewStore(fp, 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	defer s.Stop()
+
+	_ = s.Upsert(t.Context(), Record{Name: "web.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
+	_ = s.Upsert(t.Context(), Record{Name: "api.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
+	_ = s.Upsert(t.Context(), Record{Name: "api.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"})
+	_ = s.Upsert(t.Context(), Record{Name: "1.0.0.10.in-addr.arpa.", Type: "PTR", TTL: 300, Value: "web.example.org."})
+
+	got := s.GetByValue(t.Context(), "10.0.0.1")
+	if len(got) != 2 {
+		t.Fatalf("GetByValue() returned %d records, want 2", len(got))
+	}
+	if got[0].Name != "api.example.org." || got[1].Name != "web.example.org." {
+		t.Errorf("GetByValue() = %v, want api then web", got)
+	}
+
+	if got := s.GetByValue(t.Context(), "10.9.9.9"); len(got) != 0 {
+		t.Errorf("GetByValue(unknown) = %v, want empty", got)
+	}
+}
+
+func TestStore_ValueIndex_TracksMutations(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "records.json")
+
+	s, err := NewStore(fp, 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	defer s.Stop()
+
+	count := func(value string) int {
+		t.Helper()
+		return len(s.GetByValue(t.Context(), value))
+	}
+
+	_ = s.Upsert(t.Context(), Record{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
+	_ = s.Upsert(t.Context(), Record{Name: "b.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
+	_ = s.Upsert(t.Context(), Record{Name: "b.example.org.", Type: "TXT", TTL: 300, Value: "10.0.0.1"})
+	if got := count("10.0.0.1"); got != 3 {
+		t.Fatalf("after inserts: %d matches, want 3", got)
+	}
+
+	// Updating TTL keeps the record in place without duplicating it.
+	_ = s.Upsert(t.Context(), Record{Name: "a.example.org.", Type: "A", TTL: 600, Value: "10.0.0.1"})
+	if got := count("10.0.0.1"); got != 3 {
+		t.Errorf("after update: %d matches, want 3", got)
+	}
+
+	// Removing one of two records with the value under b keeps b indexed.
+	_ = s.Delete(t.Context(), "b.example.org.", "A", "10.0.0.1")
+	if got := count("10.0.0.1"); got != 2 {
+		t.Errorf("after Delete: %d matches, want 2", got)
+	}
+
+	_ = s.DeleteByType(t.Context(), "b.example.org.", "TXT")
+	if got := count("10.0.0.1"); got != 1 {
+		t.Errorf("after DeleteByType: %d matches, want 1", got)
+	}
+
+	_ = s.DeleteAll(t.Context(), "a.example.org.")
+	if got := count("10.0.0.1"); got != 0 {
+		t.Errorf("after DeleteAll: %d matches, want 0", got)
+	}
+
+	s.mu.RLock()
+	leftover := len(s.byValue)
+	s.mu.RUnlock()
+	if leftover != 0 {
+		t.Errorf("value index has %d entries after removing everything, want 0", leftover)
+	}
+}
+
+func TestStore_ValueIndex_RebuiltOnReload(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "records.json")
+
+	s, err := NewStore(fp, 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	defer s.Stop()
+	_ = s.Upsert(t.Context(), Record{Name: "old.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
+
+	s.mu.Lock()
+	s.replaceLocked([]Record{{Name: "new.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"}}, 0)
+	s.mu.Unlock()
+
+	if got := s.GetByValue(t.Context(), "10.0.0.1"); len(got) != 0 {
+		t.Errorf("GetByValue(old) = %v, want empty after reload", got)
+	}
+	if got := s.GetByValue(t.Context(), "10.0.0.2"); len(got) != 1 {
+		t.Errorf("GetByValue(new) returned %d records, want 1", len(got))
+	}
+}
+
+func BenchmarkStore_Lookup_Miss(b *testing.B) {
+	s, err := NewStore(filepath.Join(b.TempDir(), "records.json"), 0)
+	if err != nil {
+		b.Fatalf("NewStore() error: %v", err)
+	}
+	defer s.Stop()
+
+	// Populate directly: going through Upsert would persist on every insert.
+	s.mu.Lock()
+	for i := range 100_000 {
+		key := fmt.Sprintf("host%d.example.org.", i)
+		s.records[key] = []Record{{Name: key, Type: "A", TTL: 300, Value: "10.0.0.1"}}
+	}
+	s.rebuildIndexLocked()
+	s.mu.Unlock()
+
+	// A random-subdomain flood misses the exact match and the wildcard.
+	for b.Loop() {
+		if got, _ := s.Lookup("nx.example.org."); len(got) != 0 {
+			b.Fatalf("Lookup() returned %d records, want none", len(got))
+		}
+		if s.IsEmptyNonTerminal("nx.example.org.") {
+			b.Fatal("IsEmptyNonTerminal() = true, want false")
+		}
+	}
+}
+
+func BenchmarkStore_GetByValue(b *testing.B) {
+	s, err := NewStore(filepath.Join(b.TempDir(), "records.json"), 0)
+	if err != nil {
+		b.Fatalf("NewStore() error: %v", err)
+	}
+	defer s.Stop()
+
+	// Populate directly: going through Upsert would persist on every insert.
+	s.mu.Lock()
+	for i := range 100_000 {
+		key := fmt.Sprintf("host%d.example.org.", i)
+		value := fmt.Sprintf("10.%d.%d.%d", i>>16&0xff, i>>8&0xff, i&0xff)
+		s.records[key] = []Record{{Name: key, Type: "A", TTL: 300, Value: value}}
+	}
+	s.rebuildIndexLocked()
+	s.mu.Unlock()
+
+	for b.Loop() {
+		if got := s.GetByValue(b.Context(), "10.0.200.1"); len(got) != 1 {
+			b.Fatalf("GetByValue() returned %d records, want 1", len(got))
+		}
+	}
+}
+
+func TestStore_Ownership_Isolation(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "records.json")
+
+	s, err := NewStore(fp, 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	defer s.Stop()
+
+	ctxA := ContextWithOwner(t.Context(), Owner{Name: "tenant-a"})
+	ctxB := ContextWithOwner(t.Context(), Owner{Name: "tenant-b"})
+	admin := ContextWithOwner(t.Context(), Owner{Name: "root", Admin: true})
+
+	if err := s.Upsert(ctxA, Record{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("Upsert(A) error: %v", err)
+	}
+	if err := s.Upsert(ctxB, Record{Name: "b.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1", Owner: "tenant-a"}); err != nil {
+		t.Fatalf("Upsert(B) error: %v", err)
+	}
+
+	// The caller's identity wins over any owner in the payload.
+	if got := s.GetAll(admin, "b.example.org."); len(got) != 1 || got[0].Owner != "tenant-b" {
+		t.Fatalf("b.example.org. = %v, want one record owned by tenant-b", got)
+	}
+
+	if got := s.List(ctxA); len(got) != 1 || got[0].Name != "a.example.org." {
+		t.Errorf("List(A) = %v, want only a.example.org.", got)
+	}
+	if got := s.GetAll(ctxA, "b.example.org."); len(got) != 0 {
+		t.Errorf("GetAll(A, b) = %v, want empty", got)
+	}
+	if got := s.GetByValue(ctxA, "10.0.0.1"); len(got) != 1 {
+		t.Errorf("GetByValue(A) returned %d records, want 1", len(got))
+	}
+
+	// Tenant A can neither delete nor take over tenant B's name.
+	_ = s.DeleteAll(ctxA, "b.example.org.")
+	_ = s.DeleteByType(ctxA, "b.example.org.", "A")
+	_ = s.Delete(ctxA, "b.example.org.", "A", "10.0.0.1")
+	if got := s.GetAll(ctxB, "b.example.org."); len(got) != 1 {
+		t.Errorf("tenant B lost its record after tenant A deletes: %v", got)
+	}
+	err = s.Upsert(ctxA, Record{Name: "b.example.org.", Type: "A", TTL: 300, Value: "10.6.6.6"})
+	if !errors.Is(err, ErrNotOwner) {
+		t.Errorf("Upsert(A into b) error = %v, want ErrNotOwner", err)
+	}
+
+	if got := s.List(admin); len(got) != 2 {
+		t.Errorf("List(admin) returned %d records, want 2", len(got))
+	}
+	if err := s.DeleteAll(admin, "b.example.org."); err != nil {
+		t.Fatalf("DeleteAll(admin) error: %v", err)
+	}
+	if got := s.List(admin); len(got) != 1 {
+		t.Errorf("after admin delete: %d records, want 1", len(got))
+	}
+}
+
+func TestStore_Quota_PerOwner(t *testing.T) {
+	t.Parallel()
+	s, err := NewStore(filepath.Join(t.TempDir(), "records.json"), 0,
+		WithQuotas(map[string]int{"tenant-a": 2, "tenant-b": 5}))
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	defer s.Stop()
+
+	ctxA := ContextWithOwner(t.Context(), Owner{Name: "tenant-a"})
+	ctxB := ContextWithOwner(t.Context(), Owner{Name: "tenant-b"})
+	ctxC := ContextWithOwner(t.Context(), Owner{Name: "tenant-c"})
+
+	for i := range 2 {
+		r := Record{Name: fmt.Sprintf("a%d.example.org.", i), Type: "A", TTL: 300, Value: "10.0.0.1"}
+		if err := s.Upsert(ctxA, r); err != nil {
+			t.Fatalf("Upsert(A #%d) error: %v", i, err)
+		}
+	}
+	err = s.Upsert(ctxA, Record{Name: "a9.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Upsert(A over quota) error = %v, want ErrQuotaExceeded", err)
+	}
+
+	// Updating an existing record does not count against the quota.
+	if err := s.Upsert(ctxA, Record{Name: "a0.example.org.", Type: "A", TTL: 600, Value: "10.0.0.1"}); err != nil {
+		t.Errorf("Upsert(A update at quota) error: %v", err)
+	}
+
+	// Tenant A being full does not block anyone else.
+	if err := s.Upsert(ctxB, Record{Name: "b.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"}); err != nil {
+		t.Errorf("Upsert(B) error: %v", err)
+	}
+	if err := s.Upsert(ctxC, Record{Name: "c.example.org.", Type: "A", TTL: 300, Value: "10.0.0.3"}); err != nil {
+		t.Errorf("Upsert(C, no quota) error: %v", err)
+	}
+
+	// Freeing a record makes room again.
+	if err := s.DeleteAll(ctxA, "a1.example.org."); err != nil {
+		t.Fatalf("DeleteAll(A) error: %v", err)
+	}
+	if err := s.Upsert(ctxA, Record{Name: "a9.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err != nil {
+		t.Errorf("Upsert(A after delete) error: %v", err)
+	}
+}
+
+func TestStore_TenantPolicy(t *testing.T) {
+	t.Parallel()
+	s, err := NewStore(filepath.Join(t.TempDir(), "records.json"), 0,
+		WithTenantPolicies(map[string]TenantPolicy{
+			"tenant-a": {SyncPolicy: PolicyUpsertOnly, DefaultTTL: 120},
+			"tenant-b": {SyncPolicy: PolicySync},
+		}))
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	defer s.Stop()
+
+	ctxA := ContextWithOwner(t.Context(), Owner{Name: "tenant-a"})
+	ctxB := ContextWithOwner(t.Context(), Owner{Name: "tenant-b"})
+
+	for _, ctx := range []context.Context{ctxA, ctxB} {
+		o, _ := OwnerFromContext(ctx)
+		r := Record{Name: o.Name + ".example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}
+		if err := s.Upsert(ctx, r); err != nil {
+			t.Fatalf("Upsert(%s) error: %v", o.Name, err)
+		}
+	}
+
+	// The same delete is denied for the upsert-only tenant and allowed for the other.
+	err = s.DeleteAll(ctxA, "tenant-a.example.org.")
+	if !errors.Is(err, ErrPolicyDenied) {
+		t.Errorf("DeleteAll(A) error = %v, want ErrPolicyDenied", err)
+	}
+	if err := s.DeleteAll(ctxB, "tenant-b.example.org."); err != nil {
+		t.Errorf("DeleteAll(B) error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want uint32
+	}{
+		{name: "tenant default", ctx: ctxA, want: 120},
+		{name: "tenant without default", ctx: ctxB, want: DefaultTTL},
+		{name: "no owner", ctx: t.Context(), want: DefaultTTL},
+	}
+	for _, tt := range tests {
+		r := Record{Name: "x.example.org.", Type: "A", Value: "10.0.0.9"}
+		s.ApplyDefaults(tt.ctx, &r)
+		if err := r.Validate(); err != nil {
+			t.Fatalf("%s: Validate() error: %v", tt.name, err)
+		}
+		if r.TTL != tt.want {
+			t.Errorf("%s: TTL = %d, want %d", tt.name, r.TTL, tt.want)
+		}
+	}
+
+	r := Record{Name: "x.example.org.", Type: "A", TTL: 600, Value: "10.0.0.9"}
+	s.ApplyDefaults(ctxA, &r)
+	if r.TTL != 600 {
+		t.Errorf("explicit TTL overridden: got %d, want 600", r.TTL)
+	}
+}
+
+func TestStore_Import_AllOrNothing(t *testing.T) {
+	t.Parallel()
+	fp := filepath.Join(t.TempDir(), "records.json")
+	s, err := NewStore(fp, 0, WithMaxRecords(3))
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	defer s.Stop()
+
+	if err := s.Upsert(t.Context(), Record{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	gen := s.Generation()
+
+	// The third new record breaks max_records: nothing may be applied.
+	_, err = s.Import(t.Context(), []Record{
+		{Name: "a.example.org.", Type: "A", TTL: 600, Value: "10.0.0.1"},
+		{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"},
+		{Name: "b.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"},
+		{Name: "c.example.org.", Type: "A", TTL: 300, Value: "10.0.0.3"},
+	}, ImportOptions{})
+	if err == nil {
+		t.Fatal("Import() past max_records: expected error")
+	}
+	if got := s.GetAll(t.Context(), "a.example.org."); len(got) != 1 || got[0].TTL != 300 {
+		t.Errorf("a.example.org. = %v, want the original record only", got)
+	}
+	if got := s.GetAll(t.Context(), "b.example.org."); len(got) != 0 {
+		t.Errorf("b.example.org. = %v, want nothing", got)
+	}
+	if got := s.GetByValue(t.Context(), "10.0.0.2"); len(got) != 0 {
+		t.Errorf("value index still holds rolled-back records: %v", got)
+	}
+	if s.Generation() != gen {
+		t.Errorf("generation = %d after failed import, want %d", s.Generation(), gen)
+	}
+
+	res, err := s.Import(t.Context(), []Record{
+		{Name: "a.example.org.", Type: "A", TTL: 600, Value: "10.0.0.1"},
+		{Name: "b.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"},
+	}, ImportOptions{})
+	if err != nil {
+		t.Fatalf("Import() error: %v", err)
+	}
+	if res.Created != 1 || res.Updated != 1 {
+		t.Errorf("result = %+v, want 1 created, 1 updated", res)
+	}
+
+	// One persist for the whole import.
+	reloaded, err := NewStore(fp, 0)
+	if err != nil {
+		t.Fatalf("NewStore(reload) error: %v", err)
+	}
+	defer reloaded.Stop()
+	if got := reloaded.List(t.Context()); len(got) != 2 {
+		t.Errorf("persisted %d records, want 2", len(got))
+	}
+	if s.Generation() != gen+1 {
+		t.Errorf("generation = %d, want %d", s.Generation(), gen+1)
+	}
+}
+
+func TestStore_ExpiredRecords_NotServed(t *testing.T) {
+	t.Parallel()
+	s, err := NewStore(filepath.Join(t.TempDir(), "records.json"), 0, WithSweepInterval(0))
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	defer s.Stop()
+
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+	for _, r := range []Record{
+		{Name: "ci.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1", ExpiresAt: past},
+		{Name: "ci.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2", ExpiresAt: future},
+		{Name: "gone.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1", ExpiresAt: past},
+	} {
+		if err := s.Upsert(t.Context(), r); err != nil {
+			t.Fatalf("Upsert() error: %v", err)
+		}
+	}
+
+	if got := s.Get(t.Context(), "ci.example.org.", "A"); len(got) != 1 || got[0].Value != "10.0.0.2" {
+		t.Errorf("Get() = %v, want only the unexpired record", got)
+	}
+	if got := s.GetAll(t.Context(), "gone.example.org."); len(got) != 0 {
+		t.Errorf("GetAll(gone) = %v, want empty", got)
+	}
+	if got, _ := s.Lookup("gone.example.org."); len(got) != 0 {
+		t.Errorf("Lookup(gone) = %v, want empty", got)
+	}
+	if got := s.List(t.Context()); len(got) != 1 {
+		t.Errorf("List() returned %d records, want 1", len(got))
+	}
+	if got := s.GetByValue(t.Context(), "10.0.0.1"); len(got) != 0 {
+		t.Errorf("GetByValue() = %v, want empty", got)
+	}
+}
+
+func TestStore_SweepExpired_Persists(t *testing.T) {
+	t.Parallel()
+	fp := filepath.Join(t.TempDir(), "records.json")
+	s, err := NewStore(fp, 0, WithSweepInterval(0))
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	defer s.Stop()
+
+	_ = s.Upsert(t.Context(), Record{Name: "ci.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1", ExpiresAt: time.Now().Add(-time.Second)})
+	_ = s.Upsert(t.Context(), Record{Name: "keep.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"})
+	gen := s.Generation()
+
+	if err := s.sweepExpired(); err != nil {
+		t.Fatalf("sweepExpired() error: %v", err)
+	}
+	if s.Generation() != gen+1 {
+		t.Errorf("generation = %d, want %d", s.Generation(), gen+1)
+	}
+
+	raw, err := os.ReadFile(fp)
+	if err != nil {
+		t.Fatalf("ReadFile() error: %v", err)
+	}
+	var data storeFile
+	if err := json.Unmarshal(raw, &data); err != nil {
+		t.Fatalf("Unmarshal() error: %v", err)
+	}
+	if len(data.Records) != 1 || data.Records[0].Name != "keep.example.org." {
+		t.Errorf("persisted records = %v, want only keep.example.org.", data.Records)
+	}
+
+	// Nothing left to sweep: no new generation, no write.
+	if err := s.sweepExpired(); err != nil {
+		t.Fatalf("sweepExpired() error: %v", err)
+	}
+	if s.Generation() != gen+1 {
+		t.Errorf("idle sweep moved generation to %d", s.Generation())
+	}
+}
+
+func TestStore_Sweeper_RemovesExpired(t *testing.T) {
+	t.Parallel()
+	s, err := NewStore(filepath.Join(t.TempDir(), "records.json"), 0, WithSweepInterval(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	defer s.Stop()
+
+	_ = s.Upsert(t.Context(), Record{Name: "ci.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1", ExpiresAt: time.Now().Add(50 * time.Millisecond)})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		s.mu.RLock()
+		n := s.countLocked()
+		s.mu.RUnlock()
+		if n == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("expired record was not swept")
+}
+
+func TestStore_ListPage(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "records.json")
+
+	s, err := NewStore(fp, 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	defer s.Stop()
+
+	_ = s.Upsert(t.Context(), Record{Name: "c.example.org.", Type: "A", TTL: 300, Value: "10.0.0.3"})
+	_ = s.Upsert(t.Context(), Record{Name: "a.example.org.", Type: "AAAA", TTL: 300, Value: "2001:db8::1"})
+	_ = s.Upsert(t.Context(), Record{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
+	_ = s.Upsert(t.Context(), Record{Name: "b.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"})
+
+	page, more := s.ListPage(t.Context(), nil, 2)
+	if !more || len(page) != 2 {
+		t.Fatalf("first page = %d records, more=%v; want 2, true", len(page), more)
+	}
+	if page[0].Type != "A" || page[1].Type != "AAAA" {
+		t.Errorf("first page order = %s, %s; want A, AAAA", page[0].Type, page[1].Type)
+	}
+
+	after := page[1].Key()
+	page, more = s.ListPage(t.Context(), &after, 2)
+	if more || len(page) != 2 {
+		t.Fatalf("second page = %d records, more=%v; want 2, false", len(page), more)
+	}
+	if page[0].Name != "b.example.org." || page[1].Name != "c.example.org." {
+		t.Errorf("second page = %s, %s; want b, c", page[0].Name, page[1].Name)
+	}
+
+	// A cursor pointing at a deleted record still resumes after its position.
+	_ = s.Delete(t.Context(), "b.example.org.", "A", "10.0.0.2")
+	gone := RecordKey{Name: "b.example.org.", Type: "A", Value: "10.0.0.2"}
+	page, _ = s.ListPage(t.Context(), &gone, 0)
+	if len(page) != 1 || page[0].Name != "c.example.org." {
+		t.Errorf("page after deleted key = %v, want [c.example.org.]", page)
+	}
+}
+
+func TestStore_ListPage_SkipsHiddenRecords(t *testing.T) {
+	t.Parallel()
+	clock := &fakeClock{t: time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)}
+	s, err := NewStore(filepath.Join(t.TempDir(), "records.json"), 0, WithClock(clock.Now), WithSweepInterval(time.Hour))
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	defer s.Stop()
+
+	alice := ContextWithOwner(t.Context(), Owner{Name: "alice"})
+	bob := ContextWithOwner(t.Context(), Owner{Name: "bob"})
+	for i := range 10 {
+		ctx, r := alice, Record{Name: fmt.Sprintf("h%d.example.org.", i), Type: "A", TTL: 300, Value: "10.0.0.1"}
+		switch i % 3 {
+		case 1:
+			ctx = bob
+		case 2:
+			r.ExpiresAt = clock.Now().Add(time.Minute)
+		}
+		if err := s.Upsert(ctx, r); err != nil {
+			t.Fatalf("Upsert(%s) error: %v", r.Name, err)
+		}
+	}
+	clock.Advance(2 * time.Minute)
+
+	// Pages fill past the names alice cannot see or that expired.
+	var got []string
+	var after *RecordKey
+	for {
+		page, more := s.ListPage(alice, after, 2)
+		for _, r := range page {
+			got = append(got, r.Name)
+		}
+		if !more {
+			break
+		}
+		if len(page) != 2 {
+			t.Fatalf("page of %d records with more=true, want 2", len(page))
+		}
+		last := page[len(page)-1].Key()
+		after = &last
+	}
+	want := []string{"h0.example.org.", "h3.example.org.", "h6.example.org.", "h9.example.org."}
+	if !slices.Equal(got, want) {
+		t.Errorf("paged names = %v, want %v", got, want)
+	}
+}
+
 func TestStore_Delete(t *testing.T) {
 	t.Parallel()
 	dir := t.TempDir()
@@ -193,14 +1077,14 @@ func TestStore_Delete(t *testing.T) {
 	}
 	defer s.Stop()
 
-	_ = s.Upsert(Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
-	_ = s.Upsert(Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"})
+	_ = s.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
+	_ = s.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"})
 
-	if err := s.Delete("app.example.org.", "A", "10.0.0.1"); err != nil {
+	if err := s.Delete(t.Context(), "app.example.org.", "A", "10.0.0.1"); err != nil {
 		t.Fatalf("Delete() error: %v", err)
 	}
 
-	records := s.Get("app.example.org.", "A")
+	records := s.Get(t.Context(), "app.example.org.", "A")
 	if len(records) != 1 {
 		t.Fatalf("Get() returned %d records after delete, want 1", len(records))
 	}
@@ -220,14 +1104,14 @@ func TestStore_DeleteAll(t *testing.T) {
 	}
 	defer s.Stop()
 
-	_ = s.Upsert(Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
-	_ = s.Upsert(Record{Name: "app.example.org.", Type: "AAAA", TTL: 300, Value: "2001:db8::1"})
+	_ = s.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
+	_ = s.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "AAAA", TTL: 300, Value: "2001:db8::1"})
 
-	if err := s.DeleteAll("app.example.org."); err != nil {
+	if err := s.DeleteAll(t.Context(), "app.example.org."); err != nil {
 		t.Fatalf("DeleteAll() error: %v", err)
 	}
 
-	all := s.GetAll("app.example.org.")
+	all := s.GetAll(t.Context(), "app.example.org.")
 	if len(all) != 0 {
 		t.Errorf("GetAll() returned %d records after DeleteAll, want 0", len(all))
 	}
@@ -243,8 +1127,8 @@ func TestStore_JSONPersistence_RoundTrip(t *testing.T) {
 		t.Fatalf("NewStore() error: %v", err)
 	}
 
-	_ = s1.Upsert(Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
-	_ = s1.Upsert(Record{Name: "mail.example.org.", Type: "MX", TTL: 3600, Value: "mx1.example.org.", Priority: 10})
+	_ = s1.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
+	_ = s1.Upsert(t.Context(), Record{Name: "mail.example.org.", Type: "MX", TTL: 3600, Value: "mx1.example.org.", Priority: 10})
 	s1.Stop()
 
 	// Open a new store from the same file
@@ -254,11 +1138,11 @@ func TestStore_JSONPersistence_RoundTrip(t *testing.T) {
 	}
 	defer s2.Stop()
 
-	records := s2.Get("app.example.org.", "A")
+	records := s2.Get(t.Context(), "app.example.org.", "A")
 	if len(records) != 1 || records[0].Value != "10.0.0.1" {
 		t.Errorf("persistence round-trip failed for A record: got %v", records)
 	}
-	mxRecords := s2.Get("mail.example.org.", "MX")
+	mxRecords := s2.Get(t.Context(), "mail.example.org.", "MX")
 	if len(mxRecords) != 1 || mxRecords[0].Priority != 10 {
 		t.Errorf("persistence round-trip failed for MX record: got %v", mxRecords)
 	}
@@ -290,7 +1174,7 @@ func TestStore_AutoReload(t *testing.T) {
 	// Wait for reload cycle
 	time.Sleep(300 * time.Millisecond)
 
-	records := s.Get("external.example.org.", "A")
+	records := s.Get(t.Context(), "external.example.org.", "A")
 	if len(records) != 1 {
 		t.Fatalf("auto-reload: Get() returned %d records, want 1", len(records))
 	}
@@ -322,7 +1206,7 @@ func TestStore_ConcurrentAccess(t *testing.T) {
 				TTL:   300,
 				Value: "10.0.0." + string(rune('0'+i%10)),
 			}
-			_ = s.Upsert(r)
+			_ = s.Upsert(t.Context(), r)
 		}(i)
 	}
 	// Readers
@@ -330,9 +1214,9 @@ func TestStore_ConcurrentAccess(t *testing.T) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			_ = s.Get("app.example.org.", "A")
-			_ = s.GetAll("app.example.org.")
-			_ = s.List()
+			_ = s.Get(t.Context(), "app.example.org.", "A")
+			_ = s.GetAll(t.Context(), "app.example.org.")
+			_ = s.List(t.Context())
 		}()
 	}
 	wg.Wait()
@@ -349,7 +1233,7 @@ func TestStore_Get_EmptyStore(t *testing.T) {
 	}
 	defer s.Stop()
 
-	records := s.Get("nonexistent.example.org.", "A")
+	records := s.Get(t.Context(), "nonexistent.example.org.", "A")
 	if len(records) != 0 {
 		t.Errorf("Get() on empty store returned %d records, want 0", len(records))
 	}
@@ -378,7 +1262,7 @@ func TestStore_CheckReload_NoDataLoss(t *testing.T) {
 				TTL:   300,
 				Value: fmt.Sprintf("10.0.%d.%d", i/256, i%256),
 			}
-			if uErr := s.Upsert(r); uErr != nil {
+			if uErr := s.Upsert(t.Context(), r); uErr != nil {
 				t.Errorf("Upsert(%d) error: %v", i, uErr)
 			}
 		}(i)
@@ -389,7 +1273,7 @@ func TestStore_CheckReload_NoDataLoss(t *testing.T) {
 	time.Sleep(200 * time.Millisecond)
 
 	// All 100 records must still be present
-	all := s.List()
+	all := s.List(t.Context())
 	if len(all) != 100 {
 		t.Errorf("List() returned %d records after concurrent Upsert+reload, want 100", len(all))
 	}
@@ -400,91 +1284,366 @@ func TestStore_DeleteByType(t *testing.T) {
 	dir := t.TempDir()
 	fp := filepath.Join(dir, "records.json")
 
-	s, err := NewStore(fp, 0)
+	s, err := NewStore(fp, 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	defer s.Stop()
+
+	_ = s.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
+	_ = s.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"})
+	_ = s.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "AAAA", TTL: 300, Value: "2001:db8::1"})
+
+	if err := s.DeleteByType(t.Context(), "app.example.org.", "A"); err != nil {
+		t.Fatalf("DeleteByType() error: %v", err)
+	}
+
+	aRecords := s.Get(t.Context(), "app.example.org.", "A")
+	if len(aRecords) != 0 {
+		t.Errorf("A records = %d, want 0", len(aRecords))
+	}
+
+	aaaaRecords := s.Get(t.Context(), "app.example.org.", "AAAA")
+	if len(aaaaRecords) != 1 {
+		t.Errorf("AAAA records = %d, want 1", len(aaaaRecords))
+	}
+}
+
+func TestStore_Delete_NonExistent(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "records.json")
+
+	s, err := NewStore(fp, 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	defer s.Stop()
+
+	// Should not error when deleting non-existent records
+	if err := s.Delete(t.Context(), "nonexistent.example.org.", "A", "10.0.0.1"); err != nil {
+		t.Errorf("Delete() non-existent record returned error: %v", err)
+	}
+}
+
+func TestStore_MaxRecords_RejectsNew(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "records.json")
+
+	s, err := NewStore(fp, 0, WithMaxRecords(2))
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	defer s.Stop()
+
+	_ = s.Upsert(t.Context(), Record{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
+	_ = s.Upsert(t.Context(), Record{Name: "b.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"})
+
+	err = s.Upsert(t.Context(), Record{Name: "c.example.org.", Type: "A", TTL: 300, Value: "10.0.0.3"})
+	if err == nil {
+		t.Fatal("Upsert() expected error when limit reached")
+	}
+}
+
+func TestStore_MaxRecords_UpdatesAllowed(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "records.json")
+
+	s, err := NewStore(fp, 0, WithMaxRecords(2))
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	defer s.Stop()
+
+	_ = s.Upsert(t.Context(), Record{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
+	_ = s.Upsert(t.Context(), Record{Name: "b.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"})
+
+	// Update existing record (same name+type+value, different TTL) — should succeed
+	err = s.Upsert(t.Context(), Record{Name: "a.example.org.", Type: "A", TTL: 600, Value: "10.0.0.1"})
+	if err != nil {
+		t.Fatalf("Upsert(update) error: %v", err)
+	}
+
+	records := s.Get(t.Context(), "a.example.org.", "A")
+	if len(records) != 1 || records[0].TTL != 600 {
+		t.Errorf("update failed: got %v", records)
+	}
+}
+
+func TestStore_MaxNames(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "records.json")
+
+	s, err := NewStore(fp, 0, WithMaxNames(2))
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	defer s.Stop()
+	ctx := t.Context()
+
+	_ = s.Upsert(ctx, Record{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
+	_ = s.Upsert(ctx, Record{Name: "b.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"})
+
+	err = s.Upsert(ctx, Record{Name: "c.example.org.", Type: "A", TTL: 300, Value: "10.0.0.3"})
+	if !errors.Is(err, ErrNameLimit) {
+		t.Fatalf("Upsert(new name) error = %v, want ErrNameLimit", err)
+	}
+
+	// More values and types under an existing name do not add a name.
+	for _, r := range []Record{
+		{Name: "A.example.org.", Type: "A", TTL: 300, Value: "10.0.0.4"},
+		{Name: "a.example.org.", Type: "TXT", TTL: 300, Value: "hello"},
+	} {
+		if err := s.Upsert(ctx, r); err != nil {
+			t.Errorf("Upsert(%s %s) error: %v", r.Name, r.Type, err)
+		}
+	}
+
+	// Removing a name frees its slot.
+	if err := s.DeleteAll(ctx, "b.example.org."); err != nil {
+		t.Fatalf("DeleteAll() error: %v", err)
+	}
+	if err := s.Upsert(ctx, Record{Name: "c.example.org.", Type: "A", TTL: 300, Value: "10.0.0.3"}); err != nil {
+		t.Errorf("Upsert(new name after delete) error: %v", err)
+	}
+}
+
+func TestStore_MaxRecordsPerZone(t *testing.T) {
+	t.Parallel()
+	fp := filepath.Join(t.TempDir(), "records.json")
+
+	s, err := NewStore(fp, 0, WithZones("a.test.", "b.test.", "sub.a.test."), WithMaxRecordsPerZone(2))
 	if err != nil {
 		t.Fatalf("NewStore() error: %v", err)
 	}
 	defer s.Stop()
+	ctx := t.Context()
+
+	for _, r := range []Record{
+		{Name: "x.a.test.", Type: "A", TTL: 300, Value: "10.0.0.1"},
+		{Name: "y.A.test.", Type: "A", TTL: 300, Value: "10.0.0.2"},
+	} {
+		if err := s.Upsert(ctx, r); err != nil {
+			t.Fatalf("Upsert(%s) error: %v", r.Name, err)
+		}
+	}
 
-	_ = s.Upsert(Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
-	_ = s.Upsert(Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"})
-	_ = s.Upsert(Record{Name: "app.example.org.", Type: "AAAA", TTL: 300, Value: "2001:db8::1"})
+	err = s.Upsert(ctx, Record{Name: "z.a.test.", Type: "A", TTL: 300, Value: "10.0.0.3"})
+	if !errors.Is(err, ErrZoneLimit) {
+		t.Fatalf("Upsert(third record in a.test.) error = %v, want ErrZoneLimit", err)
+	}
 
-	if err := s.DeleteByType("app.example.org.", "A"); err != nil {
-		t.Fatalf("DeleteByType() error: %v", err)
+	// Updating an existing record in the full zone is still allowed.
+	if err := s.Upsert(ctx, Record{Name: "x.a.test.", Type: "A", TTL: 600, Value: "10.0.0.1"}); err != nil {
+		t.Errorf("Upsert(update in full zone) error: %v", err)
 	}
 
-	aRecords := s.Get("app.example.org.", "A")
-	if len(aRecords) != 0 {
-		t.Errorf("A records = %d, want 0", len(aRecords))
+	// Other zones, including a more specific child zone, have their own caps,
+	// and names outside every zone are not counted.
+	for _, r := range []Record{
+		{Name: "x.b.test.", Type: "A", TTL: 300, Value: "10.0.1.1"},
+		{Name: "y.b.test.", Type: "A", TTL: 300, Value: "10.0.1.2"},
+		{Name: "x.sub.a.test.", Type: "A", TTL: 300, Value: "10.0.2.1"},
+		{Name: "x.other.test.", Type: "A", TTL: 300, Value: "10.0.3.1"},
+		{Name: "y.other.test.", Type: "A", TTL: 300, Value: "10.0.3.2"},
+		{Name: "z.other.test.", Type: "A", TTL: 300, Value: "10.0.3.3"},
+	} {
+		if err := s.Upsert(ctx, r); err != nil {
+			t.Errorf("Upsert(%s) error: %v", r.Name, err)
+		}
 	}
 
-	aaaaRecords := s.Get("app.example.org.", "AAAA")
-	if len(aaaaRecords) != 1 {
-		t.Errorf("AAAA records = %d, want 1", len(aaaaRecords))
+	// Removing a record frees its zone's slot.
+	if err := s.Delete(ctx, "y.a.test.", "A", "10.0.0.2"); err != nil {
+		t.Fatalf("Delete() error: %v", err)
+	}
+	if err := s.Upsert(ctx, Record{Name: "z.a.test.", Type: "A", TTL: 300, Value: "10.0.0.3"}); err != nil {
+		t.Errorf("Upsert(after delete) error: %v", err)
 	}
 }
 
-func TestStore_Delete_NonExistent(t *testing.T) {
+// recountZones counts the records of every zone the slow way, to check the
+// counters the store keeps.
+func recountZones(s *Store) map[string]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	counts := make(map[string]int)
+	for key, recs := range s.records {
+		if zone := s.zoneOf(key); zone != "" {
+			counts[zone] += len(recs)
+		}
+	}
+	return counts
+}
+
+func TestStore_ZoneCountsFollowMutations(t *testing.T) {
 	t.Parallel()
-	dir := t.TempDir()
-	fp := filepath.Join(dir, "records.json")
+	clock := &fakeClock{t: time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)}
+	s := newTestStore(t, filepath.Join(t.TempDir(), "records.json"),
+		WithZones("a.test.", "sub.a.test."), WithMaxRecordsPerZone(100), WithClock(clock.Now))
+	ctx := t.Context()
+
+	check := func(step string) {
+		t.Helper()
+		s.mu.RLock()
+		got := maps.Clone(s.zoneCounts)
+		s.mu.RUnlock()
+		if want := recountZones(s); !maps.Equal(got, want) {
+			t.Errorf("after %s zone counts = %v, want %v", step, got, want)
+		}
+	}
 
-	s, err := NewStore(fp, 0)
-	if err != nil {
-		t.Fatalf("NewStore() error: %v", err)
+	for _, r := range []Record{
+		{Name: "x.a.test.", Type: "A", TTL: 300, Value: "10.0.0.1"},
+		{Name: "x.a.test.", Type: "A", TTL: 300, Value: "10.0.0.2"},
+		{Name: "x.a.test.", Type: "TXT", TTL: 300, Value: "hello"},
+		{Name: "y.sub.a.test.", Type: "A", TTL: 300, Value: "10.0.0.1"},
+		{Name: "z.a.test.", Type: "A", TTL: 300, Value: "10.0.0.3", ExpiresAt: clock.Now().Add(time.Minute)},
+		{Name: "x.other.test.", Type: "A", TTL: 300, Value: "10.0.0.4"},
+	} {
+		if err := s.Upsert(ctx, r); err != nil {
+			t.Fatalf("Upsert(%s) error: %v", r.Name, err)
+		}
 	}
-	defer s.Stop()
+	check("upserts")
 
-	// Should not error when deleting non-existent records
-	if err := s.Delete("nonexistent.example.org.", "A", "10.0.0.1"); err != nil {
-		t.Errorf("Delete() non-existent record returned error: %v", err)
+	if err := s.Delete(ctx, "x.a.test.", "A", "10.0.0.2"); err != nil {
+		t.Fatalf("Delete() error: %v", err)
+	}
+	check("delete")
+	if err := s.DeleteByType(ctx, "x.a.test.", "A"); err != nil {
+		t.Fatalf("DeleteByType() error: %v", err)
+	}
+	check("delete by type")
+	_ = s.Transaction(ctx, func(tx *Tx) error {
+		if err := tx.DeleteAll("y.sub.a.test."); err != nil {
+			return err
+		}
+		return errors.New("abort")
+	})
+	check("aborted transaction")
+	clock.Advance(2 * time.Minute)
+	if _, err := s.purgeExpired(ctx); err != nil {
+		t.Fatalf("purgeExpired() error: %v", err)
+	}
+	check("purge")
+	if err := s.DeleteAll(ctx, "y.sub.a.test."); err != nil {
+		t.Fatalf("DeleteAll() error: %v", err)
 	}
+	check("delete all")
+	if err := s.Restore(ctx, []Record{{Name: "w.a.test.", Type: "A", TTL: 300, Value: "10.0.0.9"}}, 0); err != nil {
+		t.Fatalf("Restore() error: %v", err)
+	}
+	check("restore")
 }
 
-func TestStore_MaxRecords_RejectsNew(t *testing.T) {
+func TestStore_OwnerCountsFollowMutations(t *testing.T) {
 	t.Parallel()
-	dir := t.TempDir()
-	fp := filepath.Join(dir, "records.json")
+	s := newTestStore(t, filepath.Join(t.TempDir(), "records.json"), WithQuotas(map[string]int{"tenant-a": 2}))
+	ctxA := ContextWithOwner(t.Context(), Owner{Name: "tenant-a"})
+	ctxB := ContextWithOwner(t.Context(), Owner{Name: "tenant-b"})
+
+	check := func(step string) {
+		t.Helper()
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		want := make(map[string]int)
+		for _, recs := range s.records {
+			for _, r := range recs {
+				want[r.Owner]++
+			}
+		}
+		if !maps.Equal(s.owners, want) {
+			t.Errorf("after %s owner counts = %v, want %v", step, s.owners, want)
+		}
+	}
 
-	s, err := NewStore(fp, 0, WithMaxRecords(2))
-	if err != nil {
-		t.Fatalf("NewStore() error: %v", err)
+	for _, r := range []Record{
+		{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"},
+		{Name: "a.example.org.", Type: "TXT", TTL: 300, Value: "hello"},
+	} {
+		if err := s.Upsert(ctxA, r); err != nil {
+			t.Fatalf("Upsert(%s %s) error: %v", r.Name, r.Type, err)
+		}
+	}
+	if err := s.Upsert(ctxB, Record{Name: "b.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	check("upserts")
+	if err := s.Upsert(ctxA, Record{Name: "c.example.org.", Type: "A", TTL: 300, Value: "10.0.0.3"}); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Upsert(over quota) error = %v, want ErrQuotaExceeded", err)
 	}
-	defer s.Stop()
 
-	_ = s.Upsert(Record{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
-	_ = s.Upsert(Record{Name: "b.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"})
+	// An unscoped update handing a record to another owner moves its count.
+	if err := s.Upsert(t.Context(), Record{Name: "a.example.org.", Type: "TXT", TTL: 300, Value: "hello", Owner: "tenant-b"}); err != nil {
+		t.Fatalf("Upsert(owner change) error: %v", err)
+	}
+	check("owner change")
+	if err := s.DeleteByType(ctxB, "b.example.org.", "A"); err != nil {
+		t.Fatalf("DeleteByType() error: %v", err)
+	}
+	check("delete by type")
+	_ = s.Transaction(t.Context(), func(tx *Tx) error {
+		if err := tx.DeleteAll("a.example.org."); err != nil {
+			return err
+		}
+		return errors.New("abort")
+	})
+	check("aborted transaction")
 
-	err = s.Upsert(Record{Name: "c.example.org.", Type: "A", TTL: 300, Value: "10.0.0.3"})
-	if err == nil {
-		t.Fatal("Upsert() expected error when limit reached")
+	// The freed slot can be used again.
+	if err := s.Upsert(ctxA, Record{Name: "c.example.org.", Type: "A", TTL: 300, Value: "10.0.0.3"}); err != nil {
+		t.Errorf("Upsert(after owner change) error: %v", err)
 	}
+	check("quota reuse")
 }
 
-func TestStore_MaxRecords_UpdatesAllowed(t *testing.T) {
+func TestStore_MaxValuesPerRRset(t *testing.T) {
 	t.Parallel()
-	dir := t.TempDir()
-	fp := filepath.Join(dir, "records.json")
+	fp := filepath.Join(t.TempDir(), "records.json")
 
-	s, err := NewStore(fp, 0, WithMaxRecords(2))
+	s, err := NewStore(fp, 0, WithMaxValuesPerRRset(3))
 	if err != nil {
 		t.Fatalf("NewStore() error: %v", err)
 	}
 	defer s.Stop()
+	ctx := t.Context()
 
-	_ = s.Upsert(Record{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"})
-	_ = s.Upsert(Record{Name: "b.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"})
+	for i := range 3 {
+		r := Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: fmt.Sprintf("10.0.0.%d", i+1)}
+		if err := s.Upsert(ctx, r); err != nil {
+			t.Fatalf("Upsert(value %d) error: %v", i+1, err)
+		}
+	}
 
-	// Update existing record (same name+type+value, different TTL) — should succeed
-	err = s.Upsert(Record{Name: "a.example.org.", Type: "A", TTL: 600, Value: "10.0.0.1"})
-	if err != nil {
-		t.Fatalf("Upsert(update) error: %v", err)
+	err = s.Upsert(ctx, Record{Name: "APP.example.org.", Type: "a", TTL: 300, Value: "10.0.0.4"})
+	if !errors.Is(err, ErrRRsetLimit) {
+		t.Fatalf("Upsert(fourth A value) error = %v, want ErrRRsetLimit", err)
 	}
 
-	records := s.Get("a.example.org.", "A")
-	if len(records) != 1 || records[0].TTL != 600 {
-		t.Errorf("update failed: got %v", records)
+	// Updating a value already in the full RRset is still allowed, and other
+	// types at the same name and the same type at other names have their own
+	// limits.
+	for _, r := range []Record{
+		{Name: "app.example.org.", Type: "A", TTL: 600, Value: "10.0.0.1"},
+		{Name: "app.example.org.", Type: "AAAA", TTL: 300, Value: "2001:db8::1"},
+		{Name: "app.example.org.", Type: "TXT", TTL: 300, Value: "hello"},
+		{Name: "web.example.org.", Type: "A", TTL: 300, Value: "10.0.0.4"},
+	} {
+		if err := s.Upsert(ctx, r); err != nil {
+			t.Errorf("Upsert(%s %s %s) error: %v", r.Name, r.Type, r.Value, err)
+		}
+	}
+
+	// Removing a value frees its slot.
+	if err := s.Delete(ctx, "app.example.org.", "A", "10.0.0.2"); err != nil {
+		t.Fatalf("Delete() error: %v", err)
+	}
+	if err := s.Upsert(ctx, Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.4"}); err != nil {
+		t.Errorf("Upsert(after delete) error: %v", err)
 	}
 }
 
@@ -500,13 +1659,13 @@ func TestStore_MaxRecords_ZeroUnlimited(t *testing.T) {
 	defer s.Stop()
 
 	for i := range 100 {
-		err := s.Upsert(Record{Name: fmt.Sprintf("host-%d.example.org.", i), Type: "A", TTL: 300, Value: fmt.Sprintf("10.0.%d.%d", i/256, i%256)})
+		err := s.Upsert(t.Context(), Record{Name: fmt.Sprintf("host-%d.example.org.", i), Type: "A", TTL: 300, Value: fmt.Sprintf("10.0.%d.%d", i/256, i%256)})
 		if err != nil {
 			t.Fatalf("Upsert(%d) error: %v", i, err)
 		}
 	}
-	if len(s.List()) != 100 {
-		t.Errorf("List() = %d, want 100", len(s.List()))
+	if len(s.List(t.Context())) != 100 {
+		t.Errorf("List() = %d, want 100", len(s.List(t.Context())))
 	}
 }
 
@@ -681,7 +1840,7 @@ func TestStore_Upsert_SyncPolicy(t *testing.T) {
 				s.mu.Unlock()
 			}
 
-			err = s.Upsert(tt.upsert)
+			err = s.Upsert(t.Context(), tt.upsert)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Upsert() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -728,7 +1887,7 @@ func TestStore_Delete_SyncPolicy(t *testing.T) {
 			}
 			s.mu.Unlock()
 
-			err = s.Delete("a.example.org.", "A", "10.0.0.1")
+			err = s.Delete(t.Context(), "a.example.org.", "A", "10.0.0.1")
 			if (err != nil) != pp.wantErr {
 				t.Errorf("Delete() error = %v, wantErr %v", err, pp.wantErr)
 			}
@@ -739,6 +1898,42 @@ func TestStore_Delete_SyncPolicy(t *testing.T) {
 	}
 }
 
+func TestStore_SetTTL(t *testing.T) {
+	t.Parallel()
+	s, err := NewStore(filepath.Join(t.TempDir(), "records.json"), 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	defer s.Stop()
+	ctx := t.Context()
+	_ = s.Upsert(ctx, Record{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1", Owner: "tenant-a"})
+	if _, err := s.LowerTTL(ctx, "a.example.org.", false, 60, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("LowerTTL() error: %v", err)
+	}
+	gen := s.Generation()
+
+	got, err := s.SetTTL(ctx, "A.example.org.", "a", "10.0.0.1", 900)
+	if err != nil {
+		t.Fatalf("SetTTL() error: %v", err)
+	}
+	if got.TTL != 900 || !got.TTLWindow.RestoreAt.IsZero() {
+		t.Errorf("SetTTL() = %+v, want TTL 900 and no pending window", got)
+	}
+	if s.Generation() != gen+1 {
+		t.Errorf("generation = %d, want %d", s.Generation(), gen+1)
+	}
+
+	if _, err := s.SetTTL(ctx, "a.example.org.", "A", "10.0.0.2", 900); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("SetTTL(missing) error = %v, want ErrRecordNotFound", err)
+	}
+	if _, err := s.SetTTL(ctx, "a.example.org.", "A", "10.0.0.1", 5); err == nil {
+		t.Error("SetTTL(ttl 5) succeeded, want range error")
+	}
+	if _, err := s.SetTTL(ContextWithOwner(ctx, Owner{Name: "tenant-b"}), "a.example.org.", "A", "10.0.0.1", 600); !errors.Is(err, ErrNotOwner) {
+		t.Errorf("SetTTL(other owner) error = %v, want ErrNotOwner", err)
+	}
+}
+
 func TestStore_DeleteByType_SyncPolicy(t *testing.T) {
 	t.Parallel()
 
@@ -771,7 +1966,7 @@ func TestStore_DeleteByType_SyncPolicy(t *testing.T) {
 			}
 			s.mu.Unlock()
 
-			err = s.DeleteByType("a.example.org.", "A")
+			err = s.DeleteByType(t.Context(), "a.example.org.", "A")
 			if (err != nil) != pp.wantErr {
 				t.Errorf("DeleteByType() error = %v, wantErr %v", err, pp.wantErr)
 			}
@@ -814,7 +2009,7 @@ func TestStore_DeleteAll_SyncPolicy(t *testing.T) {
 			}
 			s.mu.Unlock()
 
-			err = s.DeleteAll("a.example.org.")
+			err = s.DeleteAll(t.Context(), "a.example.org.")
 			if (err != nil) != pp.wantErr {
 				t.Errorf("DeleteAll() error = %v, wantErr %v", err, pp.wantErr)
 			}
@@ -838,19 +2033,19 @@ func TestStore_Upsert_PolicyCreateOnly_DoesNotCorrupt(t *testing.T) {
 
 	// Create initial record
 	orig := Record{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}
-	if err := s.Upsert(orig); err != nil {
+	if err := s.Upsert(t.Context(), orig); err != nil {
 		t.Fatalf("Upsert(create) error: %v", err)
 	}
 
 	// Attempt update (should be rejected by policy)
 	updated := Record{Name: "a.example.org.", Type: "A", TTL: 600, Value: "10.0.0.1"}
-	err = s.Upsert(updated)
+	err = s.Upsert(t.Context(), updated)
 	if !errors.Is(err, ErrPolicyDenied) {
 		t.Fatalf("Upsert(update) error = %v, want ErrPolicyDenied", err)
 	}
 
 	// Verify in-memory state was NOT corrupted by the rejected update
-	records := s.Get("a.example.org.", "A")
+	records := s.Get(t.Context(), "a.example.org.", "A")
 	if len(records) != 1 {
 		t.Fatalf("Get() returned %d records, want 1", len(records))
 	}
@@ -880,8 +2075,407 @@ func TestStore_LoadFromTestdata(t *testing.T) {
 	}
 	defer s.Stop()
 
-	all := s.List()
+	all := s.List(t.Context())
 	if len(all) != 9 {
 		t.Errorf("List() returned %d records, want 9", len(all))
 	}
 }
+
+func TestStore_Generation_SurvivesRestart(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "records.json")
+
+	s, err := NewStore(fp, 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	for i := 1; i <= 3; i++ {
+		r := Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: fmt.Sprintf("10.0.0.%d", i)}
+		if err := s.Upsert(t.Context(), r); err != nil {
+			t.Fatalf("Upsert() error: %v", err)
+		}
+	}
+	gen := s.Generation()
+	s.Stop()
+
+	s2, err := NewStore(fp, 0)
+	if err != nil {
+		t.Fatalf("NewStore() reopen error: %v", err)
+	}
+	defer s2.Stop()
+
+	if got := s2.Generation(); got != gen {
+		t.Errorf("Generation() after restart = %d, want %d", got, gen)
+	}
+}
+
+func TestStore_ChangedAt(t *testing.T) {
+	t.Parallel()
+	fp := filepath.Join(t.TempDir(), "records.json")
+	s, err := NewStore(fp, 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	defer s.Stop()
+
+	changedAt := func(name string) uint64 {
+		t.Helper()
+		recs := s.GetAll(t.Context(), name)
+		if len(recs) != 1 {
+			t.Fatalf("GetAll(%s) = %v, want one record", name, recs)
+		}
+		return recs[0].ChangedAt
+	}
+
+	a := Record{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}
+	b := Record{Name: "b.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"}
+	for _, r := range []Record{a, b} {
+		if err := s.Upsert(t.Context(), r); err != nil {
+			t.Fatalf("Upsert() error: %v", err)
+		}
+	}
+	aGen, bGen := changedAt("a.example.org."), changedAt("b.example.org.")
+	if aGen == 0 || bGen != s.Generation() || aGen >= bGen {
+		t.Fatalf("ChangedAt a = %d, b = %d at generation %d, want 0 < a < b = generation", aGen, bGen, s.Generation())
+	}
+
+	a.TTL = 600
+	a.ChangedAt = 1 // clients cannot set it
+	if err := s.Upsert(t.Context(), a); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	if got := changedAt("a.example.org."); got != s.Generation() || got <= aGen {
+		t.Errorf("updated record ChangedAt = %d, want the new generation %d", got, s.Generation())
+	}
+	if got := changedAt("b.example.org."); got != bGen {
+		t.Errorf("unrelated record ChangedAt = %d, want it fixed at %d", got, bGen)
+	}
+
+	raw, err := os.ReadFile(fp)
+	if err != nil {
+		t.Fatalf("ReadFile() error: %v", err)
+	}
+	if !strings.Contains(string(raw), fmt.Sprintf(`"changed_at": %d`, bGen)) {
+		t.Errorf("data file does not persist changed_at %d:\n%s", bGen, raw)
+	}
+
+	// A reload stamps only the records that differ, whatever the loaded
+	// copies carry.
+	s.mu.Lock()
+	s.replaceLocked([]Record{
+		{Name: "a.example.org.", Type: "A", TTL: 900, Value: "10.0.0.1", ChangedAt: 1},
+		{Name: "b.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2", ChangedAt: 1},
+	}, 0)
+	s.mu.Unlock()
+	if got := changedAt("a.example.org."); got != s.Generation() {
+		t.Errorf("reloaded changed record ChangedAt = %d, want %d", got, s.Generation())
+	}
+	if got := changedAt("b.example.org."); got != bGen {
+		t.Errorf("reloaded unchanged record ChangedAt = %d, want it kept at %d", got, bGen)
+	}
+}
+
+// flakyBackend wraps a Backend and fails the next failSaves calls to Save.
+type flakyBackend struct {
+	Backend
+	mu        sync.Mutex
+	failSaves int
+}
+
+func (f *flakyBackend) Save(ctx context.Context, c Change) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failSaves > 0 {
+		f.failSaves--
+		return errors.New("backend unavailable")
+	}
+	return f.Backend.Save(ctx, c)
+}
+
+func (f *flakyBackend) String() string {
+	return backendName(f.Backend)
+}
+
+func TestStore_Stop_PersistsLastMutation(t *testing.T) {
+	t.Parallel()
+	fp := filepath.Join(t.TempDir(), "records.json")
+	s, err := NewStore(fp, 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+
+	r := Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}
+	if err := s.Upsert(t.Context(), r); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	s.Stop()
+
+	reopened, err := NewStore(fp, 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	defer reopened.Stop()
+	if got := reopened.Get(t.Context(), r.Name, r.Type); len(got) != 1 || !got[0].equal(r) {
+		t.Errorf("after restart = %v, want %v", got, r)
+	}
+}
+
+func TestStore_FailedWrite_RollsBack(t *testing.T) {
+	t.Parallel()
+	fp := filepath.Join(t.TempDir(), "records.json")
+	fb := &flakyBackend{Backend: NewFileBackend(fp)}
+	s, err := NewStoreWithBackend(fb, 0)
+	if err != nil {
+		t.Fatalf("NewStoreWithBackend() error: %v", err)
+	}
+	events, unsubscribe := s.Subscribe()
+	defer unsubscribe()
+
+	if err := s.Upsert(t.Context(), Record{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	drain(events)
+
+	// The writes for these mutations fail, so none of them may stay in
+	// memory or reach subscribers.
+	fb.mu.Lock()
+	fb.failSaves = 3
+	fb.mu.Unlock()
+	if err := s.Upsert(t.Context(), Record{Name: "b.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"}); !errors.Is(err, ErrBackendUnavailable) {
+		t.Fatalf("Upsert() with failing backend error = %v, want ErrBackendUnavailable", err)
+	}
+	if got := s.Get(t.Context(), "b.example.org.", "A"); len(got) != 0 {
+		t.Errorf("failed create still served: %v", got)
+	}
+	if err := s.Delete(t.Context(), "a.example.org.", "A", "10.0.0.1"); err == nil {
+		t.Fatal("Delete() with failing backend: expected error")
+	}
+	if _, err := s.SetTTL(t.Context(), "a.example.org.", "A", "10.0.0.1", 60); err == nil {
+		t.Fatal("SetTTL() with failing backend: expected error")
+	}
+	got := s.Get(t.Context(), "a.example.org.", "A")
+	if len(got) != 1 || got[0].TTL != 300 {
+		t.Errorf("a.example.org. after failed delete and TTL change = %v, want the original record", got)
+	}
+	if keys := s.byValue["10.0.0.2"]; len(keys) != 0 {
+		t.Errorf("value index still holds the failed create: %v", keys)
+	}
+	if got := drain(events); len(got) != 0 {
+		t.Errorf("failed writes emitted %v", got)
+	}
+
+	// The backend recovers: the next mutation is persisted alone.
+	if err := s.Upsert(t.Context(), Record{Name: "c.example.org.", Type: "A", TTL: 300, Value: "10.0.0.3"}); err != nil {
+		t.Fatalf("Upsert() after recovery error: %v", err)
+	}
+	s.Stop()
+
+	reopened, err := NewStore(fp, 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	defer reopened.Stop()
+	if n := len(reopened.List(t.Context())); n != 2 {
+		t.Errorf("after restart the store holds %d records, want 2", n)
+	}
+}
+
+func TestStore_Stop_FlushDisabled(t *testing.T) {
+	t.Parallel()
+	fp := filepath.Join(t.TempDir(), "records.json")
+	fb := &flakyBackend{Backend: NewFileBackend(fp), failSaves: 1}
+	s, err := NewStoreWithBackend(fb, 0, WithFlushTimeout(0))
+	if err != nil {
+		t.Fatalf("NewStoreWithBackend() error: %v", err)
+	}
+	if err := s.Upsert(t.Context(), Record{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err == nil {
+		t.Fatal("Upsert() with failing backend: expected error")
+	}
+	s.Stop()
+
+	reopened, err := NewStore(fp, 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	defer reopened.Stop()
+	if n := len(reopened.List(t.Context())); n != 0 {
+		t.Errorf("store holds %d records, want the unpersisted mutation dropped with flush disabled", n)
+	}
+}
+
+func TestStore_CreatedAtPersisted(t *testing.T) {
+	t.Parallel()
+	fp := filepath.Join(t.TempDir(), "records.json")
+	created := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	clock := &fakeClock{t: created}
+	s, err := NewStore(fp, 0, WithClock(clock.Now))
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	// A client-supplied creation time is ignored.
+	r := Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1", CreatedAt: created.Add(-time.Hour)}
+	if err := s.Upsert(t.Context(), r); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	clock.Advance(time.Minute)
+	r.TTL = 600
+	if err := s.Upsert(t.Context(), r); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	s.Stop()
+
+	reloaded, err := NewStore(fp, 0)
+	if err != nil {
+		t.Fatalf("NewStore() reload error: %v", err)
+	}
+	defer reloaded.Stop()
+	got := reloaded.GetAll(t.Context(), "app.example.org.")
+	if len(got) != 1 || !got[0].CreatedAt.Equal(created) {
+		t.Errorf("reloaded = %+v, want CreatedAt %v kept across the update and reload", got, created)
+	}
+}
+
+// ageGauges reads the oldest and newest update age gauges for qtype.
+func ageGauges(t *testing.T, qtype string) (oldest, newest float64) {
+	t.Helper()
+	var m dto.Metric
+	if err := oldestUpdateAgeGauge.WithLabelValues(qtype).Write(&m); err != nil {
+		t.Fatalf("reading oldest age gauge: %v", err)
+	}
+	oldest = m.GetGauge().GetValue()
+	if err := newestUpdateAgeGauge.WithLabelValues(qtype).Write(&m); err != nil {
+		t.Fatalf("reading newest age gauge: %v", err)
+	}
+	return oldest, m.GetGauge().GetValue()
+}
+
+// TestStore_UpdateAgeGauges is not parallel: the age gauges are global and
+// every other store resets them.
+func TestStore_UpdateAgeGauges(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)}
+	s := newTTLWindowStore(t, filepath.Join(t.TempDir(), "records.json"), clock)
+	upsert := func(r Record) {
+		t.Helper()
+		if err := s.Upsert(t.Context(), r); err != nil {
+			t.Fatalf("Upsert() error: %v", err)
+		}
+	}
+	first := Record{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}
+	upsert(first)
+	clock.Advance(time.Hour)
+	upsert(Record{Name: "b.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"})
+
+	if oldest, newest := ageGauges(t, "A"); oldest != 3600 || newest != 0 {
+		t.Errorf("after two adds: oldest %v, newest %v; want 3600 and 0", oldest, newest)
+	}
+
+	// Ages grow until the next update, which resets the newest.
+	clock.Advance(10 * time.Minute)
+	first.TTL = 600
+	upsert(first)
+	if oldest, newest := ageGauges(t, "A"); oldest != 600 || newest != 0 {
+		t.Errorf("after updating the oldest: oldest %v, newest %v; want 600 and 0", oldest, newest)
+	}
+	got := s.GetAll(t.Context(), first.Name)
+	if len(got) != 1 || !got[0].UpdatedAt.Equal(clock.Now()) {
+		t.Errorf("GetAll() = %+v, want UpdatedAt %v", got, clock.Now())
+	}
+}
+
+// TestStore_PersistMetrics is not parallel: the store size gauge and the
+// persist histogram are global.
+func TestStore_PersistMetrics(t *testing.T) {
+	readHist := func() uint64 {
+		t.Helper()
+		var m dto.Metric
+		if err := persistDuration.Write(&m); err != nil {
+			t.Fatalf("reading persist histogram: %v", err)
+		}
+		return m.GetHistogram().GetSampleCount()
+	}
+	before := readHist()
+
+	s, err := NewStore(filepath.Join(t.TempDir(), "records.json"), 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	defer s.Stop()
+	if err := s.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+
+	var m dto.Metric
+	if err := storeBytesGauge.Write(&m); err != nil {
+		t.Fatalf("reading store bytes gauge: %v", err)
+	}
+	if v := m.GetGauge().GetValue(); v <= 0 {
+		t.Errorf("store_bytes = %v after an upsert, want > 0", v)
+	}
+	if after := readHist(); after <= before {
+		t.Errorf("persist_duration_seconds sample count = %d, want more than %d", after, before)
+	}
+}
+
+// TestStore_ReloadMetrics is not parallel: the reload counter and timestamp
+// are global.
+func TestStore_ReloadMetrics(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "records.json")
+	s, err := NewStore(fp, 0)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	defer s.Stop()
+
+	reloads := func(result string) float64 {
+		t.Helper()
+		var m dto.Metric
+		if err := reloadCount.WithLabelValues(result).Write(&m); err != nil {
+			t.Fatalf("reading reload counter: %v", err)
+		}
+		return m.GetCounter().GetValue()
+	}
+	// writeExternal replaces the file as another writer would, with an mtime
+	// past the store's own write.
+	mtime := time.Now()
+	writeExternal := func(raw string) {
+		t.Helper()
+		if err := os.WriteFile(fp, []byte(raw), 0o644); err != nil {
+			t.Fatalf("WriteFile() error: %v", err)
+		}
+		mtime = mtime.Add(time.Minute)
+		if err := os.Chtimes(fp, mtime, mtime); err != nil {
+			t.Fatalf("Chtimes() error: %v", err)
+		}
+	}
+	okBefore, errBefore := reloads("success"), reloads("error")
+
+	writeExternal(`{"records": [`)
+	s.checkReload()
+	if got := reloads("error"); got != errBefore+1 {
+		t.Errorf("error reloads = %v after a corrupt file, want %v", got, errBefore+1)
+	}
+	if got := reloads("success"); got != okBefore {
+		t.Errorf("successful reloads = %v after a corrupt file, want %v", got, okBefore)
+	}
+
+	writeExternal(`{"records": [{"name": "ext.example.org.", "type": "A", "ttl": 300, "value": "10.0.0.9"}]}`)
+	s.checkReload()
+	if got := reloads("success"); got != okBefore+1 {
+		t.Errorf("successful reloads = %v after a good file, want %v", got, okBefore+1)
+	}
+	if got := reloads("error"); got != errBefore+1 {
+		t.Errorf("error reloads = %v after a good file, want %v", got, errBefore+1)
+	}
+	if len(s.Get(t.Context(), "ext.example.org.", "A")) != 1 {
+		t.Error("good file not loaded")
+	}
+
+	var m dto.Metric
+	if err := lastReloadTimestamp.Write(&m); err != nil {
+		t.Fatalf("reading reload timestamp: %v", err)
+	}
+	if ts := m.GetGauge().GetValue(); time.Since(time.Unix(int64(ts), 0)) > time.Minute {
+		t.Errorf("last reload timestamp = %v, want about now", ts)
+	}
+}
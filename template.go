@@ -0,0 +1,119 @@
+// ABOUTME: Record templates: a name and value holding numeric ranges such as {0..9}.
+// ABOUTME: ExpandTemplate turns one template into the records it describes, bounded by a count limit.
+
+package dynupdate
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"strconv"
+)
+
+// defaultMaxTemplateRecords caps the records one template may expand to
+// when no max_template_records is configured.
+const defaultMaxTemplateRecords = 1000
+
+// templateRange matches a range: {START..END}, both non-negative integers.
+var templateRange = regexp.MustCompile(`\{(\d+)\.\.(\d+)\}`)
+
+// numRange is one {START..END} range. A START written with a leading zero
+// sets the width every value is zero-padded to, so {00..10} yields 00..10.
+type numRange struct {
+	start, end uint64
+	width      int
+}
+
+// count returns the number of values in the range.
+func (r numRange) count() uint64 { return r.end - r.start + 1 }
+
+// value returns the i-th value of the range, padded to its width.
+func (r numRange) value(i uint64) string {
+	return fmt.Sprintf("%0*d", r.width, r.start+i)
+}
+
+// ExpandTemplate returns the records described by tmpl, whose Name and
+// Value may hold ranges written {START..END}. Every range in a template
+// must have the same number of values; record i takes the i-th value of
+// each, so "pod-{0..9}" with "10.0.0.{10..19}" pairs pod-0 with 10.0.0.10.
+// A template without ranges yields tmpl itself. It fails if the template
+// would expand to more than limit records.
+func ExpandTemplate(tmpl Record, limit int) ([]Record, error) {
+	nameRanges, err := parseRanges(tmpl.Name)
+	if err != nil {
+		return nil, err
+	}
+	valueRanges, err := parseRanges(tmpl.Value)
+	if err != nil {
+		return nil, err
+	}
+	all := append(nameRanges, valueRanges...)
+	if len(all) == 0 {
+		return []Record{tmpl}, nil
+	}
+
+	n := all[0].count()
+	for _, r := range all[1:] {
+		if r.count() != n {
+			return nil, fmt.Errorf("template ranges differ in length: %d and %d values", n, r.count())
+		}
+	}
+	// A range spanning every uint64 wraps its count to zero.
+	if n == 0 || n > uint64(limit) {
+		return nil, fmt.Errorf("template expands to more than %d records", limit)
+	}
+
+	recs := make([]Record, n)
+	for i := range n {
+		rec := tmpl
+		rec.Name = expandRanges(tmpl.Name, nameRanges, i)
+		rec.Value = expandRanges(tmpl.Value, valueRanges, i)
+		rec.Labels = maps.Clone(tmpl.Labels)
+		recs[i] = rec
+	}
+	return recs, nil
+}
+
+// parseRanges returns the ranges in s, in order.
+func parseRanges(s string) ([]numRange, error) {
+	var ranges []numRange
+	for _, m := range templateRange.FindAllStringSubmatch(s, -1) {
+		r, err := parseRange(m[1], m[2])
+		if err != nil {
+			return nil, fmt.Errorf("template range %s: %w", m[0], err)
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+// expandRanges replaces each range in s, in order, with the i-th value of
+// the matching entry of ranges, as returned by parseRanges(s).
+func expandRanges(s string, ranges []numRange, i uint64) string {
+	k := 0
+	return templateRange.ReplaceAllStringFunc(s, func(string) string {
+		v := ranges[k].value(i)
+		k++
+		return v
+	})
+}
+
+// parseRange parses the bounds of one range.
+func parseRange(start, end string) (numRange, error) {
+	lo, err := strconv.ParseUint(start, 10, 64)
+	if err != nil {
+		return numRange{}, err
+	}
+	hi, err := strconv.ParseUint(end, 10, 64)
+	if err != nil {
+		return numRange{}, err
+	}
+	if hi < lo {
+		return numRange{}, fmt.Errorf("end %d is below start %d", hi, lo)
+	}
+	r := numRange{start: lo, end: hi}
+	if len(start) > 1 && start[0] == '0' {
+		r.width = len(start)
+	}
+	return r, nil
+}
@@ -0,0 +1,169 @@
+// ABOUTME: Tests for record templates and the records:template endpoint.
+// ABOUTME: Covers range expansion, padding and limits, and that expanded records answer DNS queries.
+
+package dynupdate
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestExpandTemplate(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name       string
+		tmpl       Record
+		limit      int
+		wantNames  []string
+		wantValues []string
+		wantErr    bool
+	}{
+		{
+			name:       "name and value in lockstep",
+			tmpl:       Record{Name: "pod-{0..2}.svc.example.org.", Type: "A", Value: "10.0.0.{10..12}"},
+			limit:      10,
+			wantNames:  []string{"pod-0.svc.example.org.", "pod-1.svc.example.org.", "pod-2.svc.example.org."},
+			wantValues: []string{"10.0.0.10", "10.0.0.11", "10.0.0.12"},
+		},
+		{
+			name:       "zero padded",
+			tmpl:       Record{Name: "node{08..10}.example.org.", Type: "TXT", Value: "rack {1..3}"},
+			limit:      10,
+			wantNames:  []string{"node08.example.org.", "node09.example.org.", "node10.example.org."},
+			wantValues: []string{"rack 1", "rack 2", "rack 3"},
+		},
+		{
+			name:       "name only",
+			tmpl:       Record{Name: "h{1..2}.example.org.", Type: "A", Value: "10.0.0.1"},
+			limit:      10,
+			wantNames:  []string{"h1.example.org.", "h2.example.org."},
+			wantValues: []string{"10.0.0.1", "10.0.0.1"},
+		},
+		{
+			name:       "no range",
+			tmpl:       Record{Name: "app.example.org.", Type: "A", Value: "10.0.0.1"},
+			limit:      1,
+			wantNames:  []string{"app.example.org."},
+			wantValues: []string{"10.0.0.1"},
+		},
+		{
+			name:    "lengths differ",
+			tmpl:    Record{Name: "pod-{0..9}.example.org.", Type: "A", Value: "10.0.0.{0..8}"},
+			limit:   100,
+			wantErr: true,
+		},
+		{
+			name:    "descending",
+			tmpl:    Record{Name: "pod-{9..0}.example.org.", Type: "A", Value: "10.0.0.1"},
+			limit:   100,
+			wantErr: true,
+		},
+		{
+			name:    "over limit",
+			tmpl:    Record{Name: "pod-{0..10}.example.org.", Type: "A", Value: "10.0.0.1"},
+			limit:   10,
+			wantErr: true,
+		},
+		{
+			name:    "full uint64 span",
+			tmpl:    Record{Name: "pod-{0..18446744073709551615}.example.org.", Type: "A", Value: "10.0.0.1"},
+			limit:   10,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			recs, err := ExpandTemplate(tt.tmpl, tt.limit)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ExpandTemplate() = %+v, want error", recs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExpandTemplate() error: %v", err)
+			}
+			if len(recs) != len(tt.wantNames) {
+				t.Fatalf("ExpandTemplate() returned %d records, want %d", len(recs), len(tt.wantNames))
+			}
+			for i, r := range recs {
+				if r.Name != tt.wantNames[i] || r.Value != tt.wantValues[i] || r.Type != tt.tmpl.Type {
+					t.Errorf("record %d = %s %s %s, want %s %s %s", i, r.Name, r.Type, r.Value, tt.wantNames[i], tt.tmpl.Type, tt.wantValues[i])
+				}
+			}
+		})
+	}
+}
+
+func TestExpandTemplate_LabelsNotShared(t *testing.T) {
+	t.Parallel()
+	recs, err := ExpandTemplate(Record{Name: "h{1..2}.example.org.", Type: "A", Value: "10.0.0.1", Labels: map[string]string{"team": "a"}}, 10)
+	if err != nil {
+		t.Fatalf("ExpandTemplate() error: %v", err)
+	}
+	recs[0].Labels["team"] = "b"
+	if recs[1].Labels["team"] != "a" {
+		t.Error("expanded records share one Labels map")
+	}
+}
+
+func TestAPI_Template(t *testing.T) {
+	t.Parallel()
+	api, store := newTestAPIHandler(t)
+	WithMaxTemplateRecords(10)(api)
+	h := api.handler()
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/records:template", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test-token")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := post(`{"name": "pod-{0..9}.svc.example.org.", "type": "A", "ttl": 300, "value": "10.0.0.{0..9}"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body = %s", rec.Code, rec.Body.String())
+	}
+	var resp apiBatchResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if len(resp.Results) != 10 {
+		t.Fatalf("results = %d, want 10", len(resp.Results))
+	}
+	for _, res := range resp.Results {
+		if res.Status != http.StatusCreated {
+			t.Errorf("result %d status = %d, want 201", res.Index, res.Status)
+		}
+	}
+
+	d := &DynUpdate{Zones: []string{"example.org."}, Store: store}
+	for i := range 10 {
+		qname := fmt.Sprintf("pod-%d.svc.example.org.", i)
+		msg := queryDO(t, d, qname, dns.TypeA, false)
+		if len(msg.Answer) != 1 || msg.Answer[0].(*dns.A).A.String() != fmt.Sprintf("10.0.0.%d", i) {
+			t.Errorf("%s answer = %v, want 10.0.0.%d", qname, msg.Answer, i)
+		}
+	}
+
+	// Too many records, mismatched ranges and invalid expansions change nothing.
+	for _, body := range []string{
+		`{"name": "big-{0..10}.example.org.", "type": "A", "value": "10.0.1.1"}`,
+		`{"name": "bad-{0..2}.example.org.", "type": "A", "value": "10.0.1.{0..1}"}`,
+		`{"name": "bad-{0..2}.example.org.", "type": "A", "value": "10.0.1.{254..256}"}`,
+	} {
+		if rec := post(body); rec.Code != http.StatusBadRequest {
+			t.Errorf("POST %s: status = %d, want 400; body = %s", body, rec.Code, rec.Body.String())
+		}
+	}
+	if n := len(store.List(t.Context())); n != 10 {
+		t.Errorf("List() holds %d records, want the 10 from the first template", n)
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"crypto/x509"
 	"fmt"
 	"os"
+	"slices"
+	"strings"
 )
 
 // buildTLSConfig creates a *tls.Config from the plugin's tlsConfig.
@@ -21,6 +23,10 @@ func buildTLSConfig(cfg *tlsConfig) (*tls.Config, error) {
 	tlsCfg := &tls.Config{
 		Certificates: []tls.Certificate{cert},
 		MinVersion:   tls.VersionTLS12,
+		CipherSuites: cfg.ciphers,
+	}
+	if cfg.minVersion != 0 {
+		tlsCfg.MinVersion = cfg.minVersion
 	}
 
 	if cfg.ca != "" {
@@ -38,3 +44,38 @@ func buildTLSConfig(cfg *tlsConfig) (*tls.Config, error) {
 
 	return tlsCfg, nil
 }
+
+// parseTLSVersion parses a tls_min_version argument. Versions below 1.2 are
+// not accepted.
+func parseTLSVersion(s string) (uint16, error) {
+	switch s {
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS version %q: valid values are 1.2, 1.3", s)
+	}
+}
+
+// parseCipherSuites maps tls_ciphers names, as listed by tls.CipherSuites
+// (e.g. TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256), to their IDs. Suites Go
+// considers insecure are rejected, and so are TLS 1.3 suites, which Go does
+// not let a server configure.
+func parseCipherSuites(names []string) ([]uint16, error) {
+	known := make(map[string]uint16)
+	for _, cs := range tls.CipherSuites() {
+		if slices.Contains(cs.SupportedVersions, tls.VersionTLS12) {
+			known[cs.Name] = cs.ID
+		}
+	}
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[strings.ToUpper(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown or insecure cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
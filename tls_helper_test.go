@@ -12,8 +12,10 @@ import (
 	"crypto/x509/pkix"
 	"encoding/pem"
 	"math/big"
+	"net"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 	"time"
 )
@@ -210,3 +212,61 @@ func TestBuildTLSConfig_InvalidCA(t *testing.T) {
 		t.Fatal("buildTLSConfig() expected error for invalid CA")
 	}
 }
+
+func TestBuildTLSConfig_MinVersion13(t *testing.T) {
+	t.Parallel()
+	certs := generateTestCerts(t)
+
+	tlsCfg, err := buildTLSConfig(&tlsConfig{cert: certs.ServerCert, key: certs.ServerKey, minVersion: ctls.VersionTLS13})
+	if err != nil {
+		t.Fatalf("buildTLSConfig() error: %v", err)
+	}
+	if tlsCfg.MinVersion != ctls.VersionTLS13 {
+		t.Errorf("MinVersion = %v, want TLS 1.3", tlsCfg.MinVersion)
+	}
+
+	// A client capped at TLS 1.2 cannot complete a handshake.
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	defer clientConn.Close()
+	go func() { _ = ctls.Server(serverConn, tlsCfg).Handshake() }()
+	client := ctls.Client(clientConn, &ctls.Config{InsecureSkipVerify: true, MaxVersion: ctls.VersionTLS12})
+	if err := client.Handshake(); err == nil {
+		t.Error("TLS 1.2 handshake succeeded against a TLS 1.3 minimum")
+	}
+}
+
+func TestBuildTLSConfig_CipherSuites(t *testing.T) {
+	t.Parallel()
+	certs := generateTestCerts(t)
+
+	ids, err := parseCipherSuites([]string{"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384", "tls_ecdhe_rsa_with_aes_128_gcm_sha256"})
+	if err != nil {
+		t.Fatalf("parseCipherSuites() error: %v", err)
+	}
+	tlsCfg, err := buildTLSConfig(&tlsConfig{cert: certs.ServerCert, key: certs.ServerKey, ciphers: ids})
+	if err != nil {
+		t.Fatalf("buildTLSConfig() error: %v", err)
+	}
+	want := []uint16{ctls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384, ctls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}
+	if !slices.Equal(tlsCfg.CipherSuites, want) {
+		t.Errorf("CipherSuites = %v, want %v", tlsCfg.CipherSuites, want)
+	}
+	if tlsCfg.MinVersion != ctls.VersionTLS12 {
+		t.Errorf("MinVersion = %v, want the TLS 1.2 default", tlsCfg.MinVersion)
+	}
+}
+
+func TestParseTLSOptions_Invalid(t *testing.T) {
+	t.Parallel()
+	for _, v := range []string{"1.1", "1.0", "tls1.3", ""} {
+		if _, err := parseTLSVersion(v); err == nil {
+			t.Errorf("parseTLSVersion(%q) expected error", v)
+		}
+	}
+	for _, name := range []string{"TLS_FAKE_SUITE", "TLS_AES_128_GCM_SHA256", "TLS_RSA_WITH_RC4_128_SHA"} {
+		if _, err := parseCipherSuites([]string{name}); err == nil {
+			t.Errorf("parseCipherSuites(%q) expected error", name)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// ABOUTME: AXFR zone transfer support for secondary servers.
+// ABOUTME: Streams SOA, every stored record in the zone, then SOA again, to allowed clients only.
+
+package dynupdate
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/miekg/dns"
+)
+
+// axfrChunk is the number of RRs sent per transfer message.
+const axfrChunk = 100
+
+// serveAXFR answers an AXFR for zone. Transfers are refused unless the client
+// address is listed in TransferTo and the query arrived over TCP.
+func (d *DynUpdate) serveAXFR(ctx context.Context, w dns.ResponseWriter, r *dns.Msg, zone string) (int, error) {
+	if !d.transferAllowed(w.RemoteAddr()) {
+		return dns.RcodeRefused, nil
+	}
+
+	soa := d.soa(zone)
+	rrs := append([]dns.RR{soa}, d.zoneRRs(ctx, zone)...)
+	rrs = append(rrs, soa)
+
+	ch := make(chan *dns.Envelope)
+	errCh := make(chan error, 1)
+	tr := new(dns.Transfer)
+	go func() { errCh <- tr.Out(w, r, ch) }()
+
+	// Out stops reading envelopes once a write fails, for example when the
+	// client hangs up, so stop sending as soon as it returns.
+	var err error
+send:
+	for len(rrs) > 0 {
+		n := min(axfrChunk, len(rrs))
+		select {
+		case ch <- &dns.Envelope{RR: rrs[:n]}:
+			rrs = rrs[n:]
+		case err = <-errCh:
+			break send
+		}
+	}
+	close(ch)
+	if len(rrs) == 0 {
+		err = <-errCh
+	}
+
+	if err != nil {
+		return dns.RcodeServerFailure, fmt.Errorf("writing AXFR: %w", err)
+	}
+	w.Hijack()
+	return dns.RcodeSuccess, nil
+}
+
+// zoneRRs returns every served record in zone as an RR, in list order.
+func (d *DynUpdate) zoneRRs(ctx context.Context, zone string) []dns.RR {
+	var rrs []dns.RR
+	for _, rec := range d.Store.List(ctx) {
+		// Names delegated to a more specific configured zone belong to that zone.
+		if !d.Store.serves(rec) || plugin.Zones(d.Zones).Matches(rec.Name) != zone {
+			continue
+		}
+		rr, err := rec.ToRR()
+		if err != nil {
+			log.Errorf("converting record to RR: %v", err)
+			continue
+		}
+		rrs = append(rrs, rr)
+	}
+	return rrs
+}
+
+// transferAllowed reports whether addr may request a zone transfer. AXFR is
+// only served over TCP.
+func (d *DynUpdate) transferAllowed(addr net.Addr) bool {
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	ip, ok := netip.AddrFromSlice(tcp.IP)
+	if !ok {
+		return false
+	}
+	ip = ip.Unmap()
+	for _, p := range d.TransferTo {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTransferTarget parses a `transfer to` argument: an IP address, a CIDR
+// prefix, or "*" for any client.
+func parseTransferTarget(s string) ([]netip.Prefix, error) {
+	if s == "*" {
+		return []netip.Prefix{netip.MustParsePrefix("0.0.0.0/0"), netip.MustParsePrefix("::/0")}, nil
+	}
+	if p, err := netip.ParsePrefix(s); err == nil {
+		return []netip.Prefix{p.Masked()}, nil
+	}
+	ip, err := netip.ParseAddr(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transfer address %q", s)
+	}
+	ip = ip.Unmap()
+	return []netip.Prefix{netip.PrefixFrom(ip, ip.BitLen())}, nil
+}
@@ -0,0 +1,168 @@
+// ABOUTME: Tests for AXFR zone transfers: SOA framing, record coverage, and client ACLs.
+// ABOUTME: Uses a multi-message recorder around the CoreDNS test.ResponseWriter.
+
+package dynupdate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+)
+
+// xfrRecorder captures every message written during a transfer.
+type xfrRecorder struct {
+	test.ResponseWriter
+	msgs []*dns.Msg
+}
+
+func (x *xfrRecorder) WriteMsg(m *dns.Msg) error {
+	x.msgs = append(x.msgs, m)
+	return nil
+}
+
+func (x *xfrRecorder) answers() []dns.RR {
+	var rrs []dns.RR
+	for _, m := range x.msgs {
+		rrs = append(rrs, m.Answer...)
+	}
+	return rrs
+}
+
+func TestServeDNS_AXFR(t *testing.T) {
+	t.Parallel()
+	stored := []Record{
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"},
+		{Name: "app.example.org.", Type: "AAAA", TTL: 300, Value: "2001:db8::1"},
+		{Name: "example.org.", Type: "MX", TTL: 300, Value: "mail.example.org.", Priority: 10},
+		{Name: "*.apps.example.org.", Type: "A", TTL: 300, Value: "10.0.0.9"},
+	}
+	for i := range 150 {
+		stored = append(stored, Record{Name: fmt.Sprintf("h%03d.bulk.example.org.", i), Type: "TXT", TTL: 300, Value: "bulk"})
+	}
+	d := newTestHandler(t, stored)
+	d.TransferTo = []netip.Prefix{netip.MustParsePrefix("10.240.0.0/16")}
+
+	req := new(dns.Msg)
+	req.SetAxfr("example.org.")
+	rec := &xfrRecorder{ResponseWriter: test.ResponseWriter{TCP: true}}
+
+	code, err := d.ServeDNS(context.Background(), rec, req)
+	if err != nil {
+		t.Fatalf("ServeDNS() error: %v", err)
+	}
+	if code != dns.RcodeSuccess {
+		t.Fatalf("rcode = %d, want %d", code, dns.RcodeSuccess)
+	}
+	if len(rec.msgs) < 2 {
+		t.Errorf("got %d messages, want the transfer split across several", len(rec.msgs))
+	}
+
+	rrs := rec.answers()
+	if len(rrs) != len(stored)+2 {
+		t.Fatalf("got %d RRs, want %d (records + 2 SOA)", len(rrs), len(stored)+2)
+	}
+	if _, ok := rrs[0].(*dns.SOA); !ok {
+		t.Errorf("first RR is %T, want *dns.SOA", rrs[0])
+	}
+	if _, ok := rrs[len(rrs)-1].(*dns.SOA); !ok {
+		t.Errorf("last RR is %T, want *dns.SOA", rrs[len(rrs)-1])
+	}
+
+	seen := make(map[string]bool)
+	for _, rr := range rrs[1 : len(rrs)-1] {
+		seen[rr.String()] = true
+	}
+	for _, r := range stored {
+		rr, err := r.ToRR()
+		if err != nil {
+			t.Fatalf("ToRR() error: %v", err)
+		}
+		if !seen[rr.String()] {
+			t.Errorf("transfer missing %s", rr)
+		}
+	}
+}
+
+// failingXfrWriter fails every write after the first, as a connection the
+// client closed mid-transfer does.
+type failingXfrWriter struct {
+	test.ResponseWriter
+	writes int
+}
+
+func (f *failingXfrWriter) WriteMsg(*dns.Msg) error {
+	f.writes++
+	if f.writes > 1 {
+		return errors.New("connection reset by peer")
+	}
+	return nil
+}
+
+func TestServeDNS_AXFR_WriteFails(t *testing.T) {
+	t.Parallel()
+	var stored []Record
+	for i := range 5 * axfrChunk {
+		stored = append(stored, Record{Name: fmt.Sprintf("h%03d.example.org.", i), Type: "TXT", TTL: 300, Value: "bulk"})
+	}
+	d := newTestHandler(t, stored)
+	d.TransferTo = []netip.Prefix{netip.MustParsePrefix("10.240.0.0/16")}
+
+	req := new(dns.Msg)
+	req.SetAxfr("example.org.")
+	done := make(chan error, 1)
+	go func() {
+		_, err := d.ServeDNS(context.Background(), &failingXfrWriter{ResponseWriter: test.ResponseWriter{TCP: true}}, req)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("ServeDNS() error = nil, want the write failure")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ServeDNS() did not return after the transfer write failed")
+	}
+}
+
+func TestServeDNS_AXFR_Refused(t *testing.T) {
+	t.Parallel()
+	d := newTestHandler(t, []Record{
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"},
+	})
+
+	tests := []struct {
+		name  string
+		allow []netip.Prefix
+		tcp   bool
+	}{
+		{name: "transfer not configured", tcp: true},
+		{name: "client not allowed", allow: []netip.Prefix{netip.MustParsePrefix("192.0.2.0/24")}, tcp: true},
+		{name: "over UDP", allow: []netip.Prefix{netip.MustParsePrefix("10.240.0.1/32")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d.TransferTo = tt.allow
+			req := new(dns.Msg)
+			req.SetAxfr("example.org.")
+			rec := &xfrRecorder{ResponseWriter: test.ResponseWriter{TCP: tt.tcp}}
+
+			code, err := d.ServeDNS(context.Background(), rec, req)
+			if err != nil {
+				t.Fatalf("ServeDNS() error: %v", err)
+			}
+			if code != dns.RcodeRefused {
+				t.Errorf("rcode = %d, want %d", code, dns.RcodeRefused)
+			}
+			if len(rec.msgs) != 0 {
+				t.Errorf("wrote %d messages, want none", len(rec.msgs))
+			}
+		})
+	}
+}
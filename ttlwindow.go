@@ -0,0 +1,133 @@
+// ABOUTME: Maintenance windows that lower record TTLs now and restore them later.
+// ABOUTME: Pending restorations live on the records themselves, so every backend persists them.
+
+package dynupdate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// LowerTTL sets the TTL of every record under name to ttl and schedules the
+// original TTL to be restored at restoreAt. With suffix set, every name at or
+// below name is included. Records already at or below ttl are left alone
+// unless a window is already pending on them, in which case its restoration
+// time moves to restoreAt and the TTL it restores to is kept. Records not
+// visible to the owner in ctx are skipped. It returns the number of records
+// changed.
+//
+// Restoration happens in the store's periodic sweep. An explicit upsert of a
+// record during its window replaces it and cancels the restoration.
+func (s *Store) LowerTTL(ctx context.Context, name string, suffix bool, ttl uint32, restoreAt time.Time) (int, error) {
+	var n int
+	err := s.commit(ctx, func() (Change, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if s.policyFor(ctx) == PolicyCreateOnly {
+			return Change{}, fmt.Errorf("cannot lower TTLs: %w", ErrPolicyDenied)
+		}
+
+		owner, scoped := scopedOwner(ctx)
+		target := strings.ToLower(dns.Fqdn(name))
+		var keys []string
+		for key, recs := range s.records {
+			if key != target && !(suffix && dns.IsSubDomain(target, key)) {
+				continue
+			}
+			touched := false
+			for i, r := range recs {
+				if scoped && r.Owner != owner {
+					continue
+				}
+				if r.TTLWindow.RestoreAt.IsZero() {
+					if r.TTL <= ttl {
+						continue
+					}
+					r.TTLWindow.OriginalTTL = r.TTL
+				}
+				s.keepLocked(key)
+				r.TTL = min(r.TTL, ttl)
+				r.TTLWindow.RestoreAt = restoreAt
+				recs[i] = r
+				s.emitLocked(OpUpdated, r)
+				touched = true
+				n++
+			}
+			if touched {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) == 0 {
+			return Change{}, nil
+		}
+		return s.changeLocked(keys...), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// restoreTTLs puts back the original TTL of every record whose window has
+// ended, in one mutation. Like expiry, the restoration was requested when the
+// window was opened, so the sync policy does not apply.
+func (s *Store) restoreTTLs() error {
+	s.mu.RLock()
+	due := s.hasRestoreDueLocked(s.now())
+	s.mu.RUnlock()
+	if !due {
+		return nil
+	}
+
+	return s.commit(context.Background(), func() (Change, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		now := s.now()
+		var keys []string
+		for key, recs := range s.records {
+			touched := false
+			for i, r := range recs {
+				if !r.TTLWindow.due(now) {
+					continue
+				}
+				s.keepLocked(key)
+				r.TTL = r.TTLWindow.OriginalTTL
+				r.TTLWindow = TTLWindow{}
+				recs[i] = r
+				s.emitLocked(OpUpdated, r)
+				touched = true
+			}
+			if touched {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) == 0 {
+			return Change{}, nil
+		}
+		return s.changeLocked(keys...), nil
+	})
+}
+
+// hasRestoreDueLocked reports whether any TTL window has ended at now.
+// Caller must hold at least RLock.
+func (s *Store) hasRestoreDueLocked(now time.Time) bool {
+	for _, recs := range s.records {
+		for _, r := range recs {
+			if r.TTLWindow.due(now) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// due reports whether the window is pending and ends at or before now.
+func (w TTLWindow) due(now time.Time) bool {
+	return !w.RestoreAt.IsZero() && !now.Before(w.RestoreAt)
+}
@@ -0,0 +1,157 @@
+// ABOUTME: Tests for TTL maintenance windows: immediate lowering, timed restoration, persistence.
+// ABOUTME: Drives restoration with a fake clock instead of waiting on the sweep ticker.
+
+package dynupdate
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a settable time source for WithClock.
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+func newTTLWindowStore(t *testing.T, fp string, clock *fakeClock, opts ...StoreOption) *Store {
+	t.Helper()
+	opts = append(opts, WithClock(clock.Now), WithSweepInterval(0))
+	return newTestStore(t, fp, opts...)
+}
+
+func ttlOf(t *testing.T, s *Store, name, qtype string) uint32 {
+	t.Helper()
+	recs := s.Get(t.Context(), name, qtype)
+	if len(recs) != 1 {
+		t.Fatalf("Get(%s, %s) returned %d records, want 1", name, qtype, len(recs))
+	}
+	return recs[0].TTL
+}
+
+func TestStore_LowerTTL_RestoresAfterWindow(t *testing.T) {
+	t.Parallel()
+	clock := &fakeClock{t: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}
+	s := newTTLWindowStore(t, filepath.Join(t.TempDir(), "records.json"), clock)
+
+	for _, r := range []Record{
+		{Name: "app.example.org.", Type: "A", TTL: 3600, Value: "10.0.0.1"},
+		{Name: "db.app.example.org.", Type: "A", TTL: 1800, Value: "10.0.0.2"},
+		{Name: "short.app.example.org.", Type: "A", TTL: 60, Value: "10.0.0.3"},
+		{Name: "other.example.org.", Type: "A", TTL: 3600, Value: "10.0.0.4"},
+	} {
+		if err := s.Upsert(t.Context(), r); err != nil {
+			t.Fatalf("Upsert() error: %v", err)
+		}
+	}
+
+	restoreAt := clock.Now().Add(2 * time.Hour)
+	n, err := s.LowerTTL(t.Context(), "app.example.org.", true, 120, restoreAt)
+	if err != nil {
+		t.Fatalf("LowerTTL() error: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("LowerTTL() changed %d records, want 2", n)
+	}
+	want := map[string]uint32{"app.example.org.": 120, "db.app.example.org.": 120, "short.app.example.org.": 60, "other.example.org.": 3600}
+	for name, ttl := range want {
+		if got := ttlOf(t, s, name, "A"); got != ttl {
+			t.Errorf("%s TTL during window = %d, want %d", name, got, ttl)
+		}
+	}
+
+	clock.Advance(time.Hour)
+	if err := s.restoreTTLs(); err != nil {
+		t.Fatalf("restoreTTLs() error: %v", err)
+	}
+	if got := ttlOf(t, s, "app.example.org.", "A"); got != 120 {
+		t.Errorf("TTL restored before the window ended: %d", got)
+	}
+
+	clock.Advance(time.Hour)
+	if err := s.restoreTTLs(); err != nil {
+		t.Fatalf("restoreTTLs() error: %v", err)
+	}
+	want = map[string]uint32{"app.example.org.": 3600, "db.app.example.org.": 1800, "short.app.example.org.": 60, "other.example.org.": 3600}
+	for name, ttl := range want {
+		recs := s.Get(t.Context(), name, "A")
+		if recs[0].TTL != ttl || !recs[0].TTLWindow.RestoreAt.IsZero() {
+			t.Errorf("%s after window = TTL %d window %+v, want TTL %d and no window", name, recs[0].TTL, recs[0].TTLWindow, ttl)
+		}
+	}
+}
+
+func TestStore_LowerTTL_SurvivesRestart(t *testing.T) {
+	t.Parallel()
+	clock := &fakeClock{t: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}
+	fp := filepath.Join(t.TempDir(), "records.json")
+
+	s := newTTLWindowStore(t, fp, clock)
+	if err := s.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 3600, Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	if _, err := s.LowerTTL(t.Context(), "app.example.org.", false, 60, clock.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("LowerTTL() error: %v", err)
+	}
+	s.Stop()
+
+	s2 := newTTLWindowStore(t, fp, clock)
+	if got := ttlOf(t, s2, "app.example.org.", "A"); got != 60 {
+		t.Fatalf("TTL after restart = %d, want the lowered 60", got)
+	}
+	clock.Advance(time.Hour)
+	if err := s2.restoreTTLs(); err != nil {
+		t.Fatalf("restoreTTLs() error: %v", err)
+	}
+	if got := ttlOf(t, s2, "app.example.org.", "A"); got != 3600 {
+		t.Errorf("TTL after window = %d, want 3600 restored from the persisted window", got)
+	}
+}
+
+func TestStore_LowerTTL_ExtendsPendingWindow(t *testing.T) {
+	t.Parallel()
+	clock := &fakeClock{t: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}
+	s := newTTLWindowStore(t, filepath.Join(t.TempDir(), "records.json"), clock)
+	if err := s.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 3600, Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+
+	if _, err := s.LowerTTL(t.Context(), "app.example.org.", false, 300, clock.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("LowerTTL() error: %v", err)
+	}
+	later := clock.Now().Add(3 * time.Hour)
+	if _, err := s.LowerTTL(t.Context(), "app.example.org.", false, 60, later); err != nil {
+		t.Fatalf("second LowerTTL() error: %v", err)
+	}
+
+	r := s.Get(t.Context(), "app.example.org.", "A")[0]
+	if r.TTL != 60 || r.TTLWindow.OriginalTTL != 3600 || !r.TTLWindow.RestoreAt.Equal(later) {
+		t.Errorf("record = TTL %d window %+v, want TTL 60 restoring 3600 at %v", r.TTL, r.TTLWindow, later)
+	}
+}
+
+func TestStore_LowerTTL_CreateOnlyDenied(t *testing.T) {
+	t.Parallel()
+	clock := &fakeClock{t: time.Now()}
+	s := newTTLWindowStore(t, filepath.Join(t.TempDir(), "records.json"), clock, WithSyncPolicy(PolicyCreateOnly))
+
+	_, err := s.LowerTTL(t.Context(), "app.example.org.", false, 60, clock.Now().Add(time.Hour))
+	if !errors.Is(err, ErrPolicyDenied) {
+		t.Errorf("LowerTTL() error = %v, want ErrPolicyDenied", err)
+	}
+}
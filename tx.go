@@ -0,0 +1,77 @@
+// ABOUTME: Atomic multi-operation transactions over the Store.
+// ABOUTME: Batches upserts and deletes under one lock and one backend write, rolling back on error.
+
+package dynupdate
+
+import (
+	"context"
+	"strings"
+)
+
+// Tx is the handle passed to a Store.Transaction callback. Its operations
+// apply immediately to the store's records but are neither visible to
+// readers nor persisted until the callback returns nil.
+type Tx struct {
+	s    *Store
+	ctx  context.Context
+	undo undoLog
+}
+
+// Transaction runs fn with exclusive access to the store and applies every
+// operation it performs as one atomic change: readers see either none or
+// all of them, and the backend is written once. If fn returns an error, the
+// records are restored to their state before the transaction and the error
+// is returned. Ownership and sync policy are enforced per operation, using
+// the owner in ctx.
+//
+// fn must not call Store methods, which would deadlock; use the Tx instead.
+func (s *Store) Transaction(ctx context.Context, fn func(tx *Tx) error) error {
+	return s.commit(ctx, func() (Change, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		tx := &Tx{s: s, ctx: ctx, undo: make(undoLog)}
+		if err := fn(tx); err != nil {
+			s.rollbackLocked(tx.undo)
+			return Change{}, err
+		}
+		if len(tx.undo) == 0 {
+			return Change{}, nil
+		}
+		return s.changeLocked(tx.undo.keys()...), nil
+	})
+}
+
+// Upsert adds or updates a record, as Store.Upsert.
+func (tx *Tx) Upsert(r Record) error {
+	tx.undo.save(tx.s, strings.ToLower(r.Name))
+	_, err := tx.s.upsertLocked(tx.ctx, r)
+	return err
+}
+
+// Delete removes the record identified by name, type and value, as Store.Delete.
+func (tx *Tx) Delete(name, qtype, value string) error {
+	tx.undo.save(tx.s, strings.ToLower(name))
+	return tx.s.deleteLocked(tx.ctx, name, qtype, value)
+}
+
+// DeleteByType removes a name's records of one type, as Store.DeleteByType.
+func (tx *Tx) DeleteByType(name, qtype string) error {
+	tx.undo.save(tx.s, strings.ToLower(name))
+	return tx.s.deleteByTypeLocked(tx.ctx, name, qtype)
+}
+
+// DeleteAll removes every record of a name, as Store.DeleteAll.
+func (tx *Tx) DeleteAll(name string) error {
+	tx.undo.save(tx.s, strings.ToLower(name))
+	return tx.s.deleteAllLocked(tx.ctx, name)
+}
+
+// GetAll returns the records of a name as the transaction currently sees
+// them, including its own uncommitted changes.
+func (tx *Tx) GetAll(name string) []Record {
+	recs := liveRecords(tx.s.records[strings.ToLower(name)], tx.s.now())
+	out := make([]Record, len(recs))
+	copy(out, recs)
+	return filterOwned(tx.ctx, out)
+}
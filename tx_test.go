@@ -0,0 +1,166 @@
+// ABOUTME: Tests for Store.Transaction: single-write commits, rollback, and reader atomicity.
+// ABOUTME: Failed transactions must leave both memory and the backend unchanged.
+
+package dynupdate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestStore_Transaction_Commit(t *testing.T) {
+	t.Parallel()
+	fp := filepath.Join(t.TempDir(), "records.json")
+	s := newTestStore(t, fp)
+	if err := s.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	gen := s.Generation()
+
+	err := s.Transaction(t.Context(), func(tx *Tx) error {
+		if err := tx.DeleteAll("app.example.org."); err != nil {
+			return err
+		}
+		if len(tx.GetAll("app.example.org.")) != 0 {
+			t.Error("transaction does not see its own delete")
+		}
+		if err := tx.Upsert(Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"}); err != nil {
+			return err
+		}
+		return tx.Upsert(Record{Name: "db.example.org.", Type: "A", TTL: 300, Value: "10.0.0.3"})
+	})
+	if err != nil {
+		t.Fatalf("Transaction() error: %v", err)
+	}
+	if got := s.Generation(); got != gen+1 {
+		t.Errorf("generation moved from %d to %d, want one mutation", gen, got)
+	}
+
+	reloaded := newTestStore(t, fp)
+	want := []Record{
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"},
+		{Name: "db.example.org.", Type: "A", TTL: 300, Value: "10.0.0.3"},
+	}
+	if got := reloaded.List(t.Context()); !slices.EqualFunc(got, want, Record.equal) {
+		t.Errorf("persisted records = %v, want %v", got, want)
+	}
+}
+
+func TestStore_Transaction_Rollback(t *testing.T) {
+	t.Parallel()
+	errAbort := errors.New("abort")
+	existing := Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"}
+
+	tests := []struct {
+		name    string
+		opts    []StoreOption
+		fn      func(tx *Tx) error
+		wantErr error
+	}{
+		{
+			name: "callback error",
+			fn: func(tx *Tx) error {
+				if err := tx.DeleteAll("app.example.org."); err != nil {
+					return err
+				}
+				if err := tx.Upsert(Record{Name: "new.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"}); err != nil {
+					return err
+				}
+				return errAbort
+			},
+			wantErr: errAbort,
+		},
+		{
+			name: "operation denied part-way",
+			opts: []StoreOption{WithSyncPolicy(PolicyUpsertOnly)},
+			fn: func(tx *Tx) error {
+				if err := tx.Upsert(Record{Name: "app.example.org.", Type: "A", TTL: 900, Value: "10.0.0.1"}); err != nil {
+					return err
+				}
+				return tx.Delete("app.example.org.", "A", "10.0.0.1")
+			},
+			wantErr: ErrPolicyDenied,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			fp := filepath.Join(t.TempDir(), "records.json")
+			s := newTestStore(t, fp, tt.opts...)
+			if err := s.Upsert(t.Context(), existing); err != nil {
+				t.Fatalf("Upsert() error: %v", err)
+			}
+			gen := s.Generation()
+			before, err := os.ReadFile(fp)
+			if err != nil {
+				t.Fatalf("ReadFile() error: %v", err)
+			}
+
+			if err := s.Transaction(t.Context(), tt.fn); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Transaction() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := s.List(t.Context()); !slices.EqualFunc(got, []Record{existing}, Record.equal) {
+				t.Errorf("records = %v, want %v", got, []Record{existing})
+			}
+			if got := s.GetByValue(t.Context(), "10.0.0.2"); len(got) != 0 {
+				t.Errorf("value index still holds rolled-back record: %v", got)
+			}
+			if s.Generation() != gen {
+				t.Errorf("generation moved on a failed transaction")
+			}
+			after, err := os.ReadFile(fp)
+			if err != nil {
+				t.Fatalf("ReadFile() error: %v", err)
+			}
+			if string(after) != string(before) {
+				t.Errorf("backend was written by a failed transaction")
+			}
+		})
+	}
+}
+
+func TestStore_Transaction_ReadersSeeAllOrNothing(t *testing.T) {
+	t.Parallel()
+	s := newTestStore(t, filepath.Join(t.TempDir(), "records.json"), WithSweepInterval(0))
+	if err := s.Upsert(t.Context(), Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.0"}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if got := s.Get(t.Context(), "app.example.org.", "A"); len(got) != 1 {
+				t.Errorf("reader saw %d records mid-replace, want exactly 1", len(got))
+				return
+			}
+		}
+	}()
+
+	for i := 1; i <= 200; i++ {
+		value := fmt.Sprintf("10.0.0.%d", i%10)
+		err := s.Transaction(t.Context(), func(tx *Tx) error {
+			if err := tx.DeleteAll("app.example.org."); err != nil {
+				return err
+			}
+			return tx.Upsert(Record{Name: "app.example.org.", Type: "A", TTL: 300, Value: value})
+		})
+		if err != nil {
+			t.Fatalf("Transaction() error: %v", err)
+		}
+	}
+	close(done)
+	wg.Wait()
+}
@@ -0,0 +1,152 @@
+// ABOUTME: Consistency check between the in-memory records and the backend, with optional repair.
+// ABOUTME: Reports records missing on either side or differing, and can rewrite or reload to resolve them.
+
+package dynupdate
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// RepairSource names the side a repair treats as correct.
+type RepairSource string
+
+const (
+	// RepairNone only reports discrepancies.
+	RepairNone RepairSource = ""
+	// RepairFromMemory rewrites the backend from the in-memory records.
+	RepairFromMemory RepairSource = "memory"
+	// RepairFromBackend replaces the in-memory records with the backend's.
+	RepairFromBackend RepairSource = "backend"
+)
+
+// ParseRepairSource parses "memory" or "backend".
+func ParseRepairSource(s string) (RepairSource, error) {
+	switch src := RepairSource(s); src {
+	case RepairFromMemory, RepairFromBackend:
+		return src, nil
+	}
+	return "", fmt.Errorf("unknown repair source %q: valid values are memory, backend", s)
+}
+
+// Discrepancy kinds.
+const (
+	DiscrepancyMissingInBackend = "missing_in_backend"
+	DiscrepancyMissingInMemory  = "missing_in_memory"
+	DiscrepancyDiffers          = "differs"
+)
+
+// Discrepancy is one record that memory and the backend disagree on.
+// Memory and Backend hold each side's copy, nil where it is missing.
+type Discrepancy struct {
+	Kind    string  `json:"kind"`
+	Memory  *Record `json:"memory,omitempty"`
+	Backend *Record `json:"backend,omitempty"`
+}
+
+// VerifyReport is the outcome of Store.Verify. The generations are
+// informational: a store counts its initial load as a mutation, so they may
+// differ while the records agree.
+type VerifyReport struct {
+	Consistent        bool          `json:"consistent"`
+	MemoryGeneration  uint64        `json:"memory_generation"`
+	BackendGeneration uint64        `json:"backend_generation"`
+	Discrepancies     []Discrepancy `json:"discrepancies"`
+	Repaired          RepairSource  `json:"repaired,omitempty"`
+}
+
+// Verify loads the backend's records and compares them with memory. If they
+// disagree and repair is set, the side it names overwrites the other: a
+// memory repair rewrites the backend in full, a backend repair replaces the
+// in-memory records and notifies subscribers as a reload would. The report
+// describes the state found before any repair.
+//
+// Verify holds persistMu, so no mutation or reload runs during the check.
+// Loading counts as a reload for backends that track external writes, so a
+// change made by another writer shows up here as a discrepancy rather than
+// being picked up by the next reload.
+func (s *Store) Verify(ctx context.Context, repair RepairSource) (VerifyReport, error) {
+	switch repair {
+	case RepairNone, RepairFromMemory, RepairFromBackend:
+	default:
+		return VerifyReport{}, fmt.Errorf("unknown repair source %q", repair)
+	}
+
+	s.persistMu.Lock()
+	defer s.persistMu.Unlock()
+
+	loaded, gen, err := s.backend.Load(ctx)
+	if err != nil {
+		return VerifyReport{}, fmt.Errorf("%w: loading records: %w", ErrBackendUnavailable, err)
+	}
+
+	s.mu.RLock()
+	report := VerifyReport{
+		MemoryGeneration:  s.generation,
+		BackendGeneration: gen,
+		Discrepancies:     diffRecords(s.collectLocked(), loaded),
+	}
+	s.mu.RUnlock()
+	report.Consistent = len(report.Discrepancies) == 0
+	if report.Consistent || repair == RepairNone {
+		return report, nil
+	}
+
+	switch repair {
+	case RepairFromMemory:
+		if err := s.saveAll(ctx, report.MemoryGeneration); err != nil {
+			return report, fmt.Errorf("%w: rewriting backend: %w", ErrBackendUnavailable, err)
+		}
+	case RepairFromBackend:
+		if loaded, err = s.resolveLoadConflicts(loaded); err != nil {
+			return report, err
+		}
+		s.mu.Lock()
+		s.replaceLocked(loaded, gen)
+		s.persisted = s.generation
+		s.mu.Unlock()
+		s.publishPending()
+	}
+	report.Repaired = repair
+	return report, nil
+}
+
+// diffRecords returns the records that differ between memory and backend,
+// in canonical key order.
+func diffRecords(memory, backend []Record) []Discrepancy {
+	stored := make(map[RecordKey]Record, len(backend))
+	for _, r := range backend {
+		// Backends may hold types written in lower case; loads normalize them.
+		r.Type = strings.ToUpper(r.Type)
+		stored[r.Key()] = r
+	}
+
+	out := []Discrepancy{}
+	for _, r := range memory {
+		b, ok := stored[r.Key()]
+		delete(stored, r.Key())
+		switch {
+		case !ok:
+			out = append(out, Discrepancy{Kind: DiscrepancyMissingInBackend, Memory: &r})
+		case !b.equal(r):
+			out = append(out, Discrepancy{Kind: DiscrepancyDiffers, Memory: &r, Backend: &b})
+		}
+	}
+	for _, b := range stored {
+		out = append(out, Discrepancy{Kind: DiscrepancyMissingInMemory, Backend: &b})
+	}
+	slices.SortFunc(out, func(a, b Discrepancy) int {
+		return a.key().Compare(b.key())
+	})
+	return out
+}
+
+// key returns the key of whichever side is present.
+func (d Discrepancy) key() RecordKey {
+	if d.Memory != nil {
+		return d.Memory.Key()
+	}
+	return d.Backend.Key()
+}
@@ -0,0 +1,185 @@
+// ABOUTME: Tests for the memory/backend consistency check and its repairs.
+// ABOUTME: Injects divergence by rewriting the data file behind the store's back.
+
+package dynupdate
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// writeDataFile replaces the store's data file with recs, as another writer
+// or a lost write would.
+func writeDataFile(t *testing.T, fp string, recs ...Record) {
+	t.Helper()
+	raw, err := json.Marshal(storeFile{Generation: 1, Records: recs})
+	if err != nil {
+		t.Fatalf("json.Marshal() error: %v", err)
+	}
+	if err := os.WriteFile(fp, raw, 0o644); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+}
+
+// newDivergedStore returns a store holding a and b whose data file holds a
+// with another TTL and c instead.
+func newDivergedStore(t *testing.T) (*Store, string) {
+	t.Helper()
+	fp := filepath.Join(t.TempDir(), "records.json")
+	s := newTestStore(t, fp)
+
+	for _, r := range []Record{
+		{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"},
+		{Name: "b.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"},
+	} {
+		if err := s.Upsert(t.Context(), r); err != nil {
+			t.Fatalf("Upsert() error: %v", err)
+		}
+	}
+	report, err := s.Verify(t.Context(), RepairNone)
+	if err != nil {
+		t.Fatalf("Verify() error: %v", err)
+	}
+	if !report.Consistent || len(report.Discrepancies) != 0 {
+		t.Fatalf("Verify() before divergence = %+v, want consistent", report)
+	}
+
+	writeDataFile(t, fp,
+		Record{Name: "a.example.org.", Type: "A", TTL: 600, Value: "10.0.0.1"},
+		Record{Name: "c.example.org.", Type: "a", TTL: 300, Value: "10.0.0.3"},
+	)
+	return s, fp
+}
+
+func TestStore_Verify_DetectsDivergence(t *testing.T) {
+	t.Parallel()
+	s, _ := newDivergedStore(t)
+
+	report, err := s.Verify(t.Context(), RepairNone)
+	if err != nil {
+		t.Fatalf("Verify() error: %v", err)
+	}
+	if report.Consistent || report.Repaired != RepairNone {
+		t.Errorf("Verify() = %+v, want inconsistent and unrepaired", report)
+	}
+	want := []struct {
+		kind string
+		name string
+	}{
+		{DiscrepancyDiffers, "a.example.org."},
+		{DiscrepancyMissingInBackend, "b.example.org."},
+		{DiscrepancyMissingInMemory, "c.example.org."},
+	}
+	if len(report.Discrepancies) != len(want) {
+		t.Fatalf("discrepancies = %+v, want %d", report.Discrepancies, len(want))
+	}
+	for i, w := range want {
+		d := report.Discrepancies[i]
+		if d.Kind != w.kind || d.key().Name != w.name {
+			t.Errorf("discrepancy %d = %s %s, want %s %s", i, d.Kind, d.key().Name, w.kind, w.name)
+		}
+	}
+	if d := report.Discrepancies[0]; d.Memory.TTL != 300 || d.Backend.TTL != 600 {
+		t.Errorf("differs: memory TTL %d, backend TTL %d; want 300 and 600", d.Memory.TTL, d.Backend.TTL)
+	}
+
+	// Without repair nothing changes.
+	if n := len(s.List(t.Context())); n != 2 {
+		t.Errorf("store holds %d records after verify, want 2", n)
+	}
+}
+
+func TestStore_Verify_Repair(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		source RepairSource
+		want   []Record
+	}{
+		{RepairFromMemory, []Record{
+			{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"},
+			{Name: "b.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"},
+		}},
+		{RepairFromBackend, []Record{
+			{Name: "a.example.org.", Type: "A", TTL: 600, Value: "10.0.0.1"},
+			{Name: "c.example.org.", Type: "A", TTL: 300, Value: "10.0.0.3"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.source), func(t *testing.T) {
+			t.Parallel()
+			s, fp := newDivergedStore(t)
+
+			report, err := s.Verify(t.Context(), tt.source)
+			if err != nil {
+				t.Fatalf("Verify() error: %v", err)
+			}
+			if report.Consistent || report.Repaired != tt.source {
+				t.Errorf("Verify() = %+v, want the divergence reported and repaired from %s", report, tt.source)
+			}
+
+			report, err = s.Verify(t.Context(), RepairNone)
+			if err != nil {
+				t.Fatalf("Verify() after repair error: %v", err)
+			}
+			if !report.Consistent {
+				t.Errorf("Verify() after repair = %+v, want consistent", report.Discrepancies)
+			}
+			if got := s.List(t.Context()); !slices.EqualFunc(got, tt.want, Record.equal) {
+				t.Errorf("memory = %v, want %v", got, tt.want)
+			}
+
+			reopened, err := NewStore(fp, 0)
+			if err != nil {
+				t.Fatalf("NewStore() error: %v", err)
+			}
+			defer reopened.Stop()
+			if got := reopened.List(t.Context()); !slices.EqualFunc(got, tt.want, Record.equal) {
+				t.Errorf("backend = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPI_Verify(t *testing.T) {
+	t.Parallel()
+	s, _ := newDivergedStore(t)
+	h := NewAPIServer(s, &Auth{Token: "test-token"}, ":0", nil).handler()
+
+	post := func(query string) (int, VerifyReport) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/verify"+query, nil)
+		req.Header.Set("Authorization", "Bearer test-token")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		var report VerifyReport
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+		}
+		return rec.Code, report
+	}
+
+	for _, query := range []string{"?repair=maybe", "?repair=true", "?repair=true&source=disk"} {
+		if code, _ := post(query); code != http.StatusBadRequest {
+			t.Errorf("POST %s: status = %d, want %d", query, code, http.StatusBadRequest)
+		}
+	}
+
+	code, report := post("")
+	if code != http.StatusOK || report.Consistent || len(report.Discrepancies) != 3 {
+		t.Fatalf("verify: status %d, %+v; want 200 with 3 discrepancies", code, report)
+	}
+	code, report = post("?repair=true&source=memory")
+	if code != http.StatusOK || report.Repaired != RepairFromMemory {
+		t.Fatalf("repair: status %d, %+v; want 200 repaired from memory", code, report)
+	}
+	code, report = post("")
+	if code != http.StatusOK || !report.Consistent || len(report.Discrepancies) != 0 {
+		t.Errorf("after repair: status %d, %+v; want 200 and consistent", code, report)
+	}
+}
@@ -0,0 +1,46 @@
+// ABOUTME: Weighted answer selection for A and AAAA records.
+// ABOUTME: Picks one address per query with probability proportional to the record's Weight.
+
+package dynupdate
+
+import (
+	"math/rand/v2"
+
+	"github.com/miekg/dns"
+)
+
+// weigh narrows an A or AAAA answer set to a single record chosen with
+// probability proportional to its weight, when weighted answers are
+// enabled. Other types and single records are returned unchanged.
+func (d *DynUpdate) weigh(qtype uint16, records []Record) []Record {
+	if !d.Weighted || len(records) < 2 || (qtype != dns.TypeA && qtype != dns.TypeAAAA) {
+		return records
+	}
+	return []Record{records[pickWeighted(records)]}
+}
+
+// pickWeighted returns the index of a record drawn with probability
+// proportional to weightOf. It only reads records and uses the
+// goroutine-safe global source, so concurrent queries may call it freely.
+func pickWeighted(records []Record) int {
+	var total uint64
+	for _, r := range records {
+		total += weightOf(r)
+	}
+	n := rand.Uint64N(total)
+	for i, r := range records {
+		w := weightOf(r)
+		if n < w {
+			return i
+		}
+		n -= w
+	}
+	return len(records) - 1
+}
+
+// weightOf returns the selection weight of an address record. A record
+// without a weight counts as 1, so an unweighted set is picked uniformly;
+// take an address out of rotation by disabling it.
+func weightOf(r Record) uint64 {
+	return max(uint64(r.Weight), 1)
+}
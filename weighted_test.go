@@ -0,0 +1,155 @@
+// ABOUTME: Tests for weighted A/AAAA answer selection.
+// ABOUTME: Checks over many queries that each address is picked in proportion to its weight.
+
+package dynupdate
+
+import (
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// weightedPicks sends n queries for qname and qtype and counts the values of
+// the single address each answer holds.
+func weightedPicks(t *testing.T, d *DynUpdate, qname string, qtype uint16, n int) map[string]int {
+	t.Helper()
+	picks := make(map[string]int)
+	for range n {
+		msg := queryDO(t, d, qname, qtype, false)
+		var addrs []string
+		for _, rr := range msg.Answer {
+			switch v := rr.(type) {
+			case *dns.A:
+				addrs = append(addrs, v.A.String())
+			case *dns.AAAA:
+				addrs = append(addrs, v.AAAA.String())
+			}
+		}
+		if len(addrs) != 1 {
+			t.Fatalf("answer = %v, want a single address", msg.Answer)
+		}
+		picks[addrs[0]]++
+	}
+	return picks
+}
+
+// checkShares fails unless each value's share of picks is within 0.03 of
+// its expected share.
+func checkShares(t *testing.T, picks map[string]int, want map[string]float64) {
+	t.Helper()
+	var total int
+	for _, n := range picks {
+		total += n
+	}
+	for value, share := range want {
+		if got := float64(picks[value]) / float64(total); math.Abs(got-share) > 0.03 {
+			t.Errorf("%s picked %.3f of the time, want about %.3f (picks %v)", value, got, share, picks)
+		}
+	}
+}
+
+func TestServeDNS_Weighted_Distribution(t *testing.T) {
+	t.Parallel()
+	d := newTestHandler(t, []Record{
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1", Weight: 10},
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2", Weight: 30},
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.3", Weight: 60},
+		{Name: "app.example.org.", Type: "AAAA", TTL: 300, Value: "2001:db8::1", Weight: 1},
+		{Name: "app.example.org.", Type: "AAAA", TTL: 300, Value: "2001:db8::2", Weight: 3},
+	})
+	d.Weighted = true
+
+	checkShares(t, weightedPicks(t, d, "app.example.org.", dns.TypeA, 5000), map[string]float64{
+		"10.0.0.1": 0.1, "10.0.0.2": 0.3, "10.0.0.3": 0.6,
+	})
+	checkShares(t, weightedPicks(t, d, "app.example.org.", dns.TypeAAAA, 5000), map[string]float64{
+		"2001:db8::1": 0.25, "2001:db8::2": 0.75,
+	})
+}
+
+func TestServeDNS_Weighted_UnweightedUniform(t *testing.T) {
+	t.Parallel()
+	d := newTestHandler(t, []Record{
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"},
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"},
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.3", Weight: 2},
+	})
+	d.Weighted = true
+
+	// A missing weight counts as 1.
+	checkShares(t, weightedPicks(t, d, "app.example.org.", dns.TypeA, 4000), map[string]float64{
+		"10.0.0.1": 0.25, "10.0.0.2": 0.25, "10.0.0.3": 0.5,
+	})
+}
+
+func TestServeDNS_Weighted_CNAMETarget(t *testing.T) {
+	t.Parallel()
+	d := newTestHandler(t, []Record{
+		{Name: "www.example.org.", Type: "CNAME", TTL: 300, Value: "app.example.org."},
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1", Weight: 1},
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2", Weight: 1},
+	})
+	d.Weighted = true
+
+	msg := queryDO(t, d, "www.example.org.", dns.TypeA, false)
+	if len(msg.Answer) != 2 {
+		t.Fatalf("answer = %v, want the CNAME and one address", msg.Answer)
+	}
+	if _, ok := msg.Answer[0].(*dns.CNAME); !ok {
+		t.Errorf("answer[0] = %v, want the CNAME", msg.Answer[0])
+	}
+}
+
+func TestServeDNS_Weighted_OtherTypesUntouched(t *testing.T) {
+	t.Parallel()
+	d := newTestHandler(t, []Record{
+		{Name: "app.example.org.", Type: "TXT", TTL: 300, Value: "one"},
+		{Name: "app.example.org.", Type: "TXT", TTL: 300, Value: "two"},
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1", Weight: 5},
+		{Name: "app.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2", Weight: 5},
+	})
+	d.Weighted = true
+
+	if msg := queryDO(t, d, "app.example.org.", dns.TypeTXT, false); len(msg.Answer) != 2 {
+		t.Errorf("TXT answer = %v, want both records", msg.Answer)
+	}
+
+	d.Weighted = false
+	if msg := queryDO(t, d, "app.example.org.", dns.TypeA, false); len(msg.Answer) != 2 {
+		t.Errorf("A answer without weighted = %v, want both records", msg.Answer)
+	}
+}
+
+func TestPickWeighted_Concurrent(t *testing.T) {
+	t.Parallel()
+	records := []Record{
+		{Value: "10.0.0.1", Weight: 1},
+		{Value: "10.0.0.2", Weight: 3},
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	counts := make([]int, len(records))
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			local := make([]int, len(records))
+			for range 1000 {
+				local[pickWeighted(records)]++
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for i, n := range local {
+				counts[i] += n
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := float64(counts[1]) / 8000; math.Abs(got-0.75) > 0.03 {
+		t.Errorf("heavier record picked %.3f of the time, want about 0.75 (counts %v)", got, counts)
+	}
+}
@@ -0,0 +1,103 @@
+// ABOUTME: Exports a configured zone as a BIND-style zone file, optionally DNSSEC-signed.
+// ABOUTME: Signing adds the DNSKEY, an NSEC chain in canonical order and RRSIGs over every RRset.
+
+package dynupdate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// ErrUnknownZone is returned when a zone is not one the plugin serves.
+var ErrUnknownZone = errors.New("zone not served")
+
+// ErrZoneUnsigned is returned when a signed export is asked of a zone
+// without a DNSSEC key.
+var ErrZoneUnsigned = errors.New("zone has no dnssec key")
+
+// ZoneFile returns zone as a zone file: its SOA, then every served record.
+// With sign set the zone is signed with the DNSSEC key as of now: the file
+// also holds the DNSKEY, an NSEC record per owner name and an RRSIG over
+// each RRset, so it can be validated offline or loaded into another signer.
+func (d *DynUpdate) ZoneFile(ctx context.Context, zone string, sign bool) ([]byte, error) {
+	zone = dns.Fqdn(strings.ToLower(zone))
+	if !slices.Contains(d.Zones, zone) {
+		return nil, fmt.Errorf("%s: %w", zone, ErrUnknownZone)
+	}
+	if sign && !d.signs(zone) {
+		return nil, fmt.Errorf("%s: %w", zone, ErrZoneUnsigned)
+	}
+
+	rrs := append([]dns.RR{d.soa(zone)}, d.zoneRRs(ctx, zone)...)
+	if sign {
+		rrs = append(rrs, dns.Copy(d.DNSSEC.DNSKEY))
+		rrs = append(rrs, nsecChain(zone, rrs, d.negativeSOA(zone).Header().Ttl)...)
+		rrs = append(rrs, d.DNSSEC.sign(rrs, time.Now())...)
+	}
+	// Group each name's records, and its signatures, together. The SOA
+	// stays first as the apex sorts before every other name.
+	slices.SortStableFunc(rrs, func(a, b dns.RR) int {
+		return compareCanonical(a.Header().Name, b.Header().Name)
+	})
+
+	var buf bytes.Buffer
+	for _, rr := range rrs {
+		buf.WriteString(rr.String())
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// nsecChain returns an NSEC record for every owner name in rrs, linking each
+// to the next in canonical order and the last back to the zone apex.
+func nsecChain(zone string, rrs []dns.RR, ttl uint32) []dns.RR {
+	types := make(map[string][]uint16)
+	for _, rr := range rrs {
+		name := strings.ToLower(rr.Header().Name)
+		if !slices.Contains(types[name], rr.Header().Rrtype) {
+			types[name] = append(types[name], rr.Header().Rrtype)
+		}
+	}
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	slices.SortFunc(names, compareCanonical)
+
+	nsecs := make([]dns.RR, len(names))
+	for i, name := range names {
+		next := zone
+		if i+1 < len(names) {
+			next = names[i+1]
+		}
+		bitmap := append(types[name], dns.TypeNSEC, dns.TypeRRSIG)
+		slices.Sort(bitmap)
+		nsecs[i] = &dns.NSEC{
+			Hdr:        dns.RR_Header{Name: name, Rrtype: dns.TypeNSEC, Class: dns.ClassINET, Ttl: ttl},
+			NextDomain: next,
+			TypeBitMap: bitmap,
+		}
+	}
+	return nsecs
+}
+
+// compareCanonical orders domain names canonically (RFC 4034 section 6.1):
+// label by label from the root, case-insensitively, a name sorting before
+// the names below it.
+func compareCanonical(a, b string) int {
+	la := dns.SplitDomainName(strings.ToLower(a))
+	lb := dns.SplitDomainName(strings.ToLower(b))
+	for i, j := len(la)-1, len(lb)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if c := strings.Compare(la[i], lb[j]); c != 0 {
+			return c
+		}
+	}
+	return len(la) - len(lb)
+}
@@ -0,0 +1,197 @@
+// ABOUTME: Tests for zone file export and the export endpoint.
+// ABOUTME: Parses exported zones back and validates every RRSIG and the NSEC chain against the zone key.
+
+package dynupdate
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+var exportTestRecords = []Record{
+	{Name: "www.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"},
+	{Name: "www.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2"},
+	{Name: "www.example.org.", Type: "TXT", TTL: 300, Value: "hello"},
+	{Name: "*.apps.example.org.", Type: "AAAA", TTL: 60, Value: "2001:db8::1"},
+	{Name: "Mail.example.org.", Type: "MX", TTL: 300, Value: "mx.example.org.", Priority: 10},
+}
+
+// exportZone fetches the zone export for query from api and parses it.
+func exportZone(t *testing.T, api *APIServer, query string) (int, []dns.RR) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/export?"+query, nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	rec := httptest.NewRecorder()
+	api.handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		return rec.Code, nil
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/dns" {
+		t.Errorf("Content-Type = %q, want text/dns", ct)
+	}
+
+	var rrs []dns.RR
+	zp := dns.NewZoneParser(strings.NewReader(rec.Body.String()), "", "export")
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		rrs = append(rrs, rr)
+	}
+	if err := zp.Err(); err != nil {
+		t.Fatalf("parsing exported zone: %v\n%s", err, rec.Body.String())
+	}
+	return rec.Code, rrs
+}
+
+func newExportTestAPI(t *testing.T, d *DynUpdate) *APIServer {
+	t.Helper()
+	return NewAPIServer(d.Store, &Auth{Token: "test-token"}, ":0", nil, WithConfigDump(d))
+}
+
+func TestAPI_Export_Zone(t *testing.T) {
+	t.Parallel()
+	d := newTestHandler(t, exportTestRecords)
+	code, rrs := exportZone(t, newExportTestAPI(t, d), "format=zone")
+	if code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", code)
+	}
+	if len(rrs) != len(exportTestRecords)+1 {
+		t.Fatalf("export holds %d RRs, want the SOA and %d records: %v", len(rrs), len(exportTestRecords), rrs)
+	}
+	if _, ok := rrs[0].(*dns.SOA); !ok {
+		t.Errorf("first RR = %v, want the SOA", rrs[0])
+	}
+	for _, rr := range rrs {
+		switch rr.Header().Rrtype {
+		case dns.TypeRRSIG, dns.TypeNSEC, dns.TypeDNSKEY:
+			t.Errorf("unsigned export holds %v", rr)
+		}
+	}
+}
+
+func TestAPI_Export_SignedValidates(t *testing.T) {
+	t.Parallel()
+	d := newSignedTestHandler(t, exportTestRecords)
+	code, rrs := exportZone(t, newExportTestAPI(t, d), "format=zone&sign=true&zone=example.org.")
+	if code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", code)
+	}
+
+	type rrsetKey struct {
+		name  string
+		rtype uint16
+	}
+	sets := make(map[rrsetKey][]dns.RR)
+	var sigs []*dns.RRSIG
+	var nsecs []*dns.NSEC
+	var key *dns.DNSKEY
+	for _, rr := range rrs {
+		switch v := rr.(type) {
+		case *dns.RRSIG:
+			sigs = append(sigs, v)
+			continue
+		case *dns.DNSKEY:
+			key = v
+		case *dns.NSEC:
+			nsecs = append(nsecs, v)
+		}
+		k := rrsetKey{strings.ToLower(rr.Header().Name), rr.Header().Rrtype}
+		sets[k] = append(sets[k], rr)
+	}
+	if key == nil || key.PublicKey != d.DNSSEC.DNSKEY.PublicKey {
+		t.Fatalf("export DNSKEY = %v, want the zone key", key)
+	}
+
+	// Every RRset carries exactly one signature that validates against the
+	// public key.
+	signed := make(map[rrsetKey]bool)
+	now := time.Now()
+	for _, sig := range sigs {
+		k := rrsetKey{strings.ToLower(sig.Hdr.Name), sig.TypeCovered}
+		set, ok := sets[k]
+		if !ok {
+			t.Errorf("RRSIG %v covers no RRset", sig)
+			continue
+		}
+		if err := sig.Verify(key, set); err != nil {
+			t.Errorf("RRSIG over %s %s does not verify: %v", k.name, dns.TypeToString[k.rtype], err)
+		}
+		if !sig.ValidityPeriod(now) {
+			t.Errorf("RRSIG over %s %s is not valid now", k.name, dns.TypeToString[k.rtype])
+		}
+		signed[k] = true
+	}
+	for k := range sets {
+		if !signed[k] {
+			t.Errorf("RRset %s %s is unsigned", k.name, dns.TypeToString[k.rtype])
+		}
+	}
+
+	// The NSEC chain visits every owner name once, in canonical order,
+	// and closes back at the apex.
+	owners := make(map[string]bool)
+	for k := range sets {
+		owners[k.name] = true
+	}
+	if len(nsecs) != len(owners) {
+		t.Fatalf("export holds %d NSEC records, want one per owner name (%d)", len(nsecs), len(owners))
+	}
+	for i, n := range nsecs {
+		want := "example.org."
+		if i+1 < len(nsecs) {
+			want = nsecs[i+1].Hdr.Name
+			if compareCanonical(n.Hdr.Name, want) >= 0 {
+				t.Errorf("NSEC %s precedes %s out of canonical order", n.Hdr.Name, want)
+			}
+		}
+		if n.NextDomain != want {
+			t.Errorf("NSEC %s next = %s, want %s", n.Hdr.Name, n.NextDomain, want)
+		}
+		for _, rtype := range n.TypeBitMap {
+			if _, ok := sets[rrsetKey{n.Hdr.Name, rtype}]; !ok && rtype != dns.TypeRRSIG {
+				t.Errorf("NSEC %s lists %s, which the name does not hold", n.Hdr.Name, dns.TypeToString[rtype])
+			}
+		}
+	}
+}
+
+func TestAPI_Export_Errors(t *testing.T) {
+	t.Parallel()
+	unsigned := newExportTestAPI(t, newTestHandler(t, nil))
+	tests := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{"missing format", "", http.StatusBadRequest},
+		{"unknown format", "format=json", http.StatusBadRequest},
+		{"bad sign", "format=zone&sign=maybe", http.StatusBadRequest},
+		{"unknown zone", "format=zone&zone=example.com.", http.StatusNotFound},
+		{"unsigned zone", "format=zone&sign=true", http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if code, _ := exportZone(t, unsigned, tt.query); code != tt.want {
+				t.Errorf("GET /api/v1/export?%s status = %d, want %d", tt.query, code, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareCanonical(t *testing.T) {
+	t.Parallel()
+	// RFC 4034 section 6.1 example order.
+	names := []string{
+		"example.", "a.example.", "yljkjljk.a.example.", "Z.a.example.",
+		"zABC.a.EXAMPLE.", "z.example.", "*.z.example.",
+	}
+	for i := 1; i < len(names); i++ {
+		if compareCanonical(names[i-1], names[i]) >= 0 {
+			t.Errorf("compareCanonical(%q, %q) >= 0, want the first to sort first", names[i-1], names[i])
+		}
+	}
+}
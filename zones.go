@@ -0,0 +1,54 @@
+// ABOUTME: Per-zone overview of the store: record counts and apex SOA/NS presence for each served zone.
+// ABOUTME: Records are attributed to the most specific configured zone containing their name.
+
+package dynupdate
+
+import (
+	"context"
+	"strings"
+
+	"github.com/coredns/coredns/plugin"
+)
+
+// ZoneStats summarises one served zone.
+type ZoneStats struct {
+	Zone string `json:"zone"`
+	// Records counts the live records whose most specific zone is Zone,
+	// disabled ones included.
+	Records int `json:"records"`
+	// HasSOA is always true: the apex SOA is synthesized, never stored.
+	HasSOA bool `json:"has_soa"`
+	// HasNS reports whether an NS record at the apex is being served.
+	HasNS bool `json:"has_ns"`
+}
+
+// ZoneStats returns a summary for each of zones, in the given order,
+// counting the live records visible to the owner in ctx. Names outside
+// every zone are not counted.
+func (s *Store) ZoneStats(ctx context.Context, zones []string) []ZoneStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := make([]ZoneStats, len(zones))
+	index := make(map[string]int, len(zones))
+	for i, z := range zones {
+		z = strings.ToLower(z)
+		stats[i] = ZoneStats{Zone: z, HasSOA: true}
+		index[z] = i
+	}
+
+	now := s.now()
+	for key, recs := range s.records {
+		i, ok := index[plugin.Zones(zones).Matches(key)]
+		if !ok {
+			continue
+		}
+		for _, r := range filterOwned(ctx, liveRecords(recs, now)) {
+			stats[i].Records++
+			if key == stats[i].Zone && r.Type == "NS" && r.served(now, s.serveDelay) {
+				stats[i].HasNS = true
+			}
+		}
+	}
+	return stats
+}
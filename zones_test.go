@@ -0,0 +1,82 @@
+// ABOUTME: Tests for the per-zone overview and GET /api/v1/zones.
+// ABOUTME: Seeds a multi-zone store with a child zone, an apex NS and names outside every zone.
+
+package dynupdate
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestAPI_Zones(t *testing.T) {
+	t.Parallel()
+	_, store := newTestAPIHandler(t)
+	for _, r := range []Record{
+		{Name: "example.org.", Type: "NS", TTL: 300, Value: "ns1.example.org."},
+		{Name: "a.example.org.", Type: "A", TTL: 300, Value: "10.0.0.1"},
+		{Name: "a.example.org.", Type: "TXT", TTL: 300, Value: "hello"},
+		{Name: "b.example.org.", Type: "A", TTL: 300, Value: "10.0.0.2", Disabled: true},
+		{Name: "x.sub.example.org.", Type: "A", TTL: 300, Value: "10.0.1.1"},
+		{Name: "sub.example.org.", Type: "NS", TTL: 300, Value: "ns1.example.org.", Disabled: true},
+		{Name: "example.net.", Type: "A", TTL: 300, Value: "10.0.2.1"},
+		{Name: "outside.test.", Type: "A", TTL: 300, Value: "10.0.3.1"},
+	} {
+		if err := store.Upsert(t.Context(), r); err != nil {
+			t.Fatalf("Upsert(%s %s) error: %v", r.Name, r.Type, err)
+		}
+	}
+	d := &DynUpdate{Zones: []string{"example.org.", "example.net.", "sub.example.org.", "empty.test."}, Store: store}
+	h := NewAPIServer(store, &Auth{Token: "test-token"}, ":0", nil, WithConfigDump(d)).handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/zones", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d; body = %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var resp apiZonesResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	want := []ZoneStats{
+		{Zone: "example.org.", Records: 4, HasSOA: true, HasNS: true},
+		{Zone: "example.net.", Records: 1, HasSOA: true},
+		{Zone: "sub.example.org.", Records: 2, HasSOA: true},
+		{Zone: "empty.test.", HasSOA: true},
+	}
+	if !slices.Equal(resp.Zones, want) {
+		t.Errorf("zones = %+v, want %+v", resp.Zones, want)
+	}
+}
+
+func TestAPI_Zones_OwnerScoped(t *testing.T) {
+	t.Parallel()
+	_, store := newTestAPIHandler(t)
+	for _, tc := range []struct {
+		owner string
+		name  string
+	}{
+		{"tenant-a", "a1.example.org."},
+		{"tenant-a", "a2.example.org."},
+		{"tenant-b", "b1.example.org."},
+	} {
+		ctx := ContextWithOwner(t.Context(), Owner{Name: tc.owner})
+		if err := store.Upsert(ctx, Record{Name: tc.name, Type: "A", TTL: 300, Value: "10.0.0.1"}); err != nil {
+			t.Fatalf("Upsert(%s) error: %v", tc.name, err)
+		}
+	}
+
+	ctx := ContextWithOwner(t.Context(), Owner{Name: "tenant-a"})
+	got := store.ZoneStats(ctx, []string{"example.org."})
+	if len(got) != 1 || got[0].Records != 2 {
+		t.Errorf("ZoneStats(tenant-a) = %+v, want 2 records", got)
+	}
+	if got := store.ZoneStats(t.Context(), []string{"example.org."}); got[0].Records != 3 {
+		t.Errorf("ZoneStats(unscoped) = %+v, want 3 records", got)
+	}
+}